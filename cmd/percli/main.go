@@ -18,9 +18,12 @@ import (
 
 	"github.com/perses/perses/internal/cli/cmd/apply"
 	"github.com/perses/perses/internal/cli/cmd/describe"
+	"github.com/perses/perses/internal/cli/cmd/format"
 	"github.com/perses/perses/internal/cli/cmd/get"
 	"github.com/perses/perses/internal/cli/cmd/lint"
 	"github.com/perses/perses/internal/cli/cmd/login"
+	"github.com/perses/perses/internal/cli/cmd/migrate"
+	"github.com/perses/perses/internal/cli/cmd/plugin"
 	"github.com/perses/perses/internal/cli/cmd/project"
 	"github.com/perses/perses/internal/cli/cmd/remove"
 	"github.com/perses/perses/internal/cli/cmd/version"
@@ -43,9 +46,12 @@ func newRootCommand() *cobra.Command {
 	// The list of the commands supported
 	cmd.AddCommand(apply.NewCMD())
 	cmd.AddCommand(describe.NewCMD())
+	cmd.AddCommand(format.NewCMD())
 	cmd.AddCommand(get.NewCMD())
 	cmd.AddCommand(lint.NewCMD())
 	cmd.AddCommand(login.NewCMD())
+	cmd.AddCommand(migrate.NewCMD())
+	cmd.AddCommand(plugin.NewCMD())
 	cmd.AddCommand(project.NewCMD())
 	cmd.AddCommand(remove.NewCMD())
 	cmd.AddCommand(version.NewCMD())
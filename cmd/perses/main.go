@@ -61,14 +61,22 @@ func main() {
 	runner := app.NewRunner().WithDefaultHTTPServer("perses").SetBanner(banner)
 
 	// enable hot reload of CUE schemas for dashboards validation:
-	// - watch for changes on the schemas folders
-	// - register a cron task to reload all the schemas every <interval>
-	watcher, reloader, err := schemas.NewHotReloaders(conf.Schemas, serviceManager.GetDashboard().GetValidator())
+	// - watch for changes on the schemas folders, and expose the result of each loading as metrics
+	// - register a cron task to reload all the schemas every <interval>, and to migrate the panels stored if enabled
+	var afterReload []func()
+	if conf.Schemas.MigratePanels {
+		afterReload = append(afterReload, func() {
+			if _, migrateErr := serviceManager.GetSchema().MigratePanels(false); migrateErr != nil {
+				logrus.WithError(migrateErr).Error("unable to migrate the panels stored")
+			}
+		})
+	}
+	watcher, reloader, err := schemas.NewHotReloaders(conf.Schemas, serviceManager.GetDashboard().GetValidator(), afterReload...)
 
 	if err != nil {
 		logrus.WithError(err).Fatal("unable to instantiate the tasks for hot reload of schemas")
 	}
-	runner.WithTasks(watcher)
+	runner.WithTasks(watcher, schemas.NewMetricsRecorder(serviceManager.GetDashboard().GetValidator()))
 	runner.WithCronTasks(conf.Schemas.Interval, reloader)
 
 	// register the API
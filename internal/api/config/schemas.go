@@ -16,15 +16,28 @@ package config
 import "time"
 
 const (
-	defaultPanelsPath  = "schemas/panels"
-	defaultQueriesPath = "schemas/queries"
-	defaultInterval    = 1 * time.Hour
+	defaultPanelsPath      = "schemas/panels"
+	defaultQueriesPath     = "schemas/queries"
+	defaultVariablesPath   = "schemas/variables"
+	defaultAnnotationsPath = "schemas/annotations"
+	defaultLayoutsPath     = "schemas/layouts"
+	defaultDatasourcesPath = "schemas/datasources"
+	defaultInterval        = 1 * time.Hour
 )
 
 type Schemas struct {
-	PanelsPath  string        `yaml:"panels_path,omitempty"`
-	QueriesPath string        `yaml:"queries_path,omitempty"`
-	Interval    time.Duration `yaml:"interval,omitempty"`
+	PanelsPath      string        `yaml:"panels_path,omitempty"`
+	QueriesPath     string        `yaml:"queries_path,omitempty"`
+	VariablesPath   string        `yaml:"variables_path,omitempty"`
+	AnnotationsPath string        `yaml:"annotations_path,omitempty"`
+	LayoutsPath     string        `yaml:"layouts_path,omitempty"`
+	DatasourcesPath string        `yaml:"datasources_path,omitempty"`
+	Interval        time.Duration `yaml:"interval,omitempty"`
+	// MigratePanels enables the migration of the panels stored to the version of their schema, each time the schemas are reloaded.
+	MigratePanels bool `yaml:"migrate_panels,omitempty"`
+	// PluginsPath is the folder of the plugin bundles, the tar.gz archives providing plugins of any category.
+	// The bundles are not loaded when it's empty.
+	PluginsPath string `yaml:"plugins_path,omitempty"`
 }
 
 func (s *Schemas) Verify() error {
@@ -34,6 +47,18 @@ func (s *Schemas) Verify() error {
 	if len(s.QueriesPath) == 0 {
 		s.QueriesPath = defaultQueriesPath
 	}
+	if len(s.VariablesPath) == 0 {
+		s.VariablesPath = defaultVariablesPath
+	}
+	if len(s.AnnotationsPath) == 0 {
+		s.AnnotationsPath = defaultAnnotationsPath
+	}
+	if len(s.LayoutsPath) == 0 {
+		s.LayoutsPath = defaultLayoutsPath
+	}
+	if len(s.DatasourcesPath) == 0 {
+		s.DatasourcesPath = defaultDatasourcesPath
+	}
 	if s.Interval <= 0 {
 		s.Interval = defaultInterval
 	}
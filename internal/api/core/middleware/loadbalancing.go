@@ -0,0 +1,305 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// latencyWeight is the weight given to the last latency measured when computing the moving average of the latency of a backend.
+	latencyWeight = 0.3
+	// maxRetryBodySize is the biggest body kept in memory to be able to send a request again. A bigger request is not retried.
+	maxRetryBodySize = 10 << 20
+	// backendPoolTTL is how long a pool is kept once it is no longer used, for example because the datasource has been
+	// updated or deleted.
+	backendPoolTTL = time.Hour
+)
+
+var (
+	// backendPools is keeping the health of every backend across the requests, as a new proxy is created for each request.
+	// The key is built from the list of the URLs and the load balancing configuration, see backendPoolKey.
+	backendPools sync.Map
+	// lastPrune is the last time the pools no longer used have been removed from backendPools, see pruneBackendPools.
+	lastPrune      time.Time
+	lastPruneMutex sync.Mutex
+	// readOnlyPostEndpoints are the endpoints of Prometheus that are only reading data but that are queried with POST,
+	// to avoid the length limit of the URL. They can be sent again to another backend like a GET.
+	readOnlyPostEndpoints = regexp.MustCompile(`^/api/v1/(query|query_range|query_exemplars|series|labels)$`)
+)
+
+type backend struct {
+	url          *url.URL
+	ejectedUntil time.Time
+	// latency is an exponentially weighted moving average of the time taken by the backend to answer.
+	latency time.Duration
+}
+
+// backendPool is tracking passively the health of the different URLs of a datasource.
+// A backend answering with an error is ejected for a cooldown period.
+type backendPool struct {
+	mutex    sync.Mutex
+	strategy datasourcev1.LoadBalancingStrategy
+	cooldown time.Duration
+	backends []*backend
+	// next is the index of the backend to use first when the strategy is round-robin.
+	next int
+	// lastUsed is the last time the pool has been used by a request, see pruneBackendPools.
+	lastUsed time.Time
+}
+
+func backendPoolKey(config datasourcev1.HTTPConfig) string {
+	var urls []string
+	for _, u := range config.GetURLs() {
+		urls = append(urls, u.String())
+	}
+	key := strings.Join(urls, ",")
+	if config.LoadBalancing != nil {
+		key = fmt.Sprintf("%s|%s|%s", key, config.LoadBalancing.Strategy, config.LoadBalancing.Cooldown)
+	}
+	return key
+}
+
+func getBackendPool(config datasourcev1.HTTPConfig) *backendPool {
+	now := time.Now()
+	pruneBackendPools(now)
+	key := backendPoolKey(config)
+	if pool, ok := backendPools.Load(key); ok {
+		return pool.(*backendPool)
+	}
+	pool := &backendPool{
+		strategy: datasourcev1.FailoverLoadBalancing,
+		lastUsed: now,
+	}
+	if config.LoadBalancing != nil {
+		pool.strategy = config.LoadBalancing.Strategy
+		pool.cooldown = time.Duration(config.LoadBalancing.Cooldown)
+	}
+	for _, u := range config.GetURLs() {
+		pool.backends = append(pool.backends, &backend{url: u})
+	}
+	actual, _ := backendPools.LoadOrStore(key, pool)
+	return actual.(*backendPool)
+}
+
+// pruneBackendPools removes the pools that haven't been used for a while. Since the key of a pool is built from the
+// configuration of the datasource, the pool of a datasource updated or deleted is never used again.
+// A pool is kept at least as long as the cooldown of its backends, so an ejected backend is not forgotten too early.
+// The pools are checked at most once per backendPoolTTL.
+func pruneBackendPools(now time.Time) {
+	lastPruneMutex.Lock()
+	if now.Sub(lastPrune) < backendPoolTTL {
+		lastPruneMutex.Unlock()
+		return
+	}
+	lastPrune = now
+	lastPruneMutex.Unlock()
+	backendPools.Range(func(key, value interface{}) bool {
+		pool := value.(*backendPool)
+		pool.mutex.Lock()
+		ttl := backendPoolTTL
+		if pool.cooldown > ttl {
+			ttl = pool.cooldown
+		}
+		stale := now.Sub(pool.lastUsed) > ttl
+		pool.mutex.Unlock()
+		if stale {
+			backendPools.Delete(key)
+		}
+		return true
+	})
+}
+
+// candidates returns the backends in the order they should be tried.
+// Healthy backends are ordered according to the strategy. The ejected ones come last as a last resort.
+func (p *backendPool) candidates(now time.Time) []*backend {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.lastUsed = now
+	var healthy []*backend
+	var ejected []*backend
+	for _, b := range p.backends {
+		if now.Before(b.ejectedUntil) {
+			ejected = append(ejected, b)
+		} else {
+			healthy = append(healthy, b)
+		}
+	}
+	switch p.strategy {
+	case datasourcev1.RoundRobinLoadBalancing:
+		if len(healthy) > 0 {
+			start := p.next % len(healthy)
+			healthy = append(healthy[start:], healthy[:start]...)
+			p.next++
+		}
+	case datasourcev1.LeastLatencyLoadBalancing:
+		// backends that never answered have no latency and so are tried first.
+		sort.SliceStable(healthy, func(i, j int) bool {
+			return healthy[i].latency < healthy[j].latency
+		})
+	}
+	// the backend that will be available again the soonest is the one to try first
+	sort.SliceStable(ejected, func(i, j int) bool {
+		return ejected[i].ejectedUntil.Before(ejected[j].ejectedUntil)
+	})
+	return append(healthy, ejected...)
+}
+
+func (p *backendPool) success(b *backend, latency time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b.ejectedUntil = time.Time{}
+	if b.latency == 0 {
+		b.latency = latency
+	} else {
+		b.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(b.latency))
+	}
+}
+
+func (p *backendPool) failure(b *backend, now time.Time) {
+	if p.cooldown <= 0 {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b.ejectedUntil = now.Add(p.cooldown)
+	logrus.Warningf("datasource backend %q ejected until %s", b.url.String(), b.ejectedUntil.Format(time.RFC3339))
+}
+
+// failoverTransport is sending the request to the backends provided by the pool.
+// When a backend fails, an idempotent request is sent again to the next backend, see isRetryable.
+type failoverTransport struct {
+	pool      *backendPool
+	transport http.RoundTripper
+}
+
+func (f *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	candidates := f.pool.candidates(time.Now())
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no URL defined to contact the datasource")
+	}
+	if !isRetryable(req) {
+		candidates = candidates[:1]
+	}
+	// the body has to be kept to be able to send it again to the next backend
+	var body []byte
+	if len(candidates) > 1 && req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(req.Body, maxRetryBodySize+1))
+		if err != nil {
+			_ = req.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxRetryBodySize {
+			// the body is too big to be kept in memory, so the request is sent only once, with what has been read so far.
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+			candidates = candidates[:1]
+			body = nil
+		} else {
+			_ = req.Body.Close()
+		}
+	}
+	var lastErr error
+	for i, b := range candidates {
+		outReq := req.Clone(req.Context())
+		if body != nil {
+			outReq.Body = io.NopCloser(bytes.NewReader(body))
+			outReq.ContentLength = int64(len(body))
+		}
+		rewriteRequestURL(outReq, b.url)
+		start := time.Now()
+		resp, err := f.transport.RoundTrip(outReq)
+		if err == nil && !isBackendFailure(resp.StatusCode) {
+			f.pool.success(b, time.Since(start))
+			return resp, nil
+		}
+		f.pool.failure(b, time.Now())
+		if i == len(candidates)-1 {
+			return resp, err
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+			lastErr = fmt.Errorf("backend %q answered with the status code %d", b.url.String(), resp.StatusCode)
+		} else {
+			lastErr = err
+		}
+		logrus.WithError(lastErr).Debugf("retrying the request on the next datasource backend")
+	}
+	return nil, lastErr
+}
+
+// isRetryable returns true when the request can be sent again to another backend without side effect:
+// its method is idempotent, or it is a POST to an endpoint only reading data.
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return readOnlyPostEndpoints.MatchString(req.URL.Path)
+	}
+	return false
+}
+
+// rewriteRequestURL is changing the URL of the request to point to the target, like the reverse proxy of the standard library does.
+func rewriteRequestURL(req *http.Request, target *url.URL) {
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+	if len(target.RawQuery) == 0 || len(req.URL.RawQuery) == 0 {
+		req.URL.RawQuery = target.RawQuery + req.URL.RawQuery
+	} else {
+		req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+	}
+	// We have to modify the HOST of the request in order to match the host of the targetURL
+	// So far I'm not sure to understand exactly why, but if you are going to remove it, be sure of what you are doing.
+	// It has been done to fix an error returned by Openshift itself saying the target doesn't exist.
+	// Since we are using HTTP/1, setting the HOST is setting also an header so if the host and the header are different
+	// then maybe it is blocked by the Openshift router.
+	req.Host = target.Host
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// isBackendFailure returns true when the status code means the backend itself is not able to answer
+// (and not that the request is wrong).
+func isBackendFailure(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+// newNamedBackend returns a server answering its name, or the given status code when it is not 200.
+func newNamedBackend(name string, statusCode int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+		_, _ = fmt.Fprint(w, name)
+	}))
+}
+
+func newPrometheusSpecWithURLs(t *testing.T, loadBalancing string, urls ...string) *datasourcev1.Prometheus {
+	spec := &datasourcev1.Prometheus{}
+	rawURLs, _ := json.Marshal(urls)
+	data := fmt.Sprintf(`{"kind": "Prometheus", "default": false, "http": {"urls": %s, "load_balancing": %s}}`, rawURLs, loadBalancing)
+	if err := json.Unmarshal([]byte(data), spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+// queryBackends sends a Prometheus query, that can be retried on the next backend as it is only reading data.
+func queryBackends(t *testing.T, spec *datasourcev1.Prometheus) (int, string) {
+	return serveBackends(t, spec, "/api/v1/query", newPrometheusRequest("/api/v1/query", "query=up"))
+}
+
+func newPrometheusRequest(path string, form string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/proxy/globaldatasources/prometheus"+path, strings.NewReader(form))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func serveBackends(t *testing.T, spec *datasourcev1.Prometheus, path string, req *http.Request) (int, string) {
+	rec := httptest.NewRecorder()
+	pr, err := newProxy(spec, path)
+	assert.NoError(t, err)
+	assert.NoError(t, pr.serve(echo.New().NewContext(req, rec)))
+	return rec.Code, rec.Body.String()
+}
+
+func TestFailoverOnUnreachableBackend(t *testing.T) {
+	down := newNamedBackend("down", http.StatusOK)
+	down.Close()
+	up := newNamedBackend("up", http.StatusOK)
+	defer up.Close()
+	spec := newPrometheusSpecWithURLs(t, `{"strategy": "failover"}`, down.URL, up.URL)
+
+	code, body := queryBackends(t, spec)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "up", body)
+}
+
+func TestFailoverOnUnavailableBackend(t *testing.T) {
+	unavailable := newNamedBackend("unavailable", http.StatusServiceUnavailable)
+	defer unavailable.Close()
+	up := newNamedBackend("up", http.StatusOK)
+	defer up.Close()
+	spec := newPrometheusSpecWithURLs(t, `{"strategy": "failover", "cooldown": "1h"}`, unavailable.URL, up.URL)
+
+	for i := 0; i < 3; i++ {
+		code, body := queryBackends(t, spec)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "up", body)
+	}
+	// the unavailable backend is ejected and is now the last one to be tried
+	candidates := getBackendPool(spec.HTTP).candidates(time.Now())
+	assert.Equal(t, up.URL, candidates[0].url.String())
+}
+
+func TestRoundRobin(t *testing.T) {
+	first := newNamedBackend("first", http.StatusOK)
+	defer first.Close()
+	second := newNamedBackend("second", http.StatusOK)
+	defer second.Close()
+	spec := newPrometheusSpecWithURLs(t, `{"strategy": "round-robin"}`, first.URL, second.URL)
+
+	var bodies []string
+	for i := 0; i < 4; i++ {
+		_, body := queryBackends(t, spec)
+		bodies = append(bodies, body)
+	}
+	assert.Equal(t, []string{"first", "second", "first", "second"}, bodies)
+}
+
+func TestFailoverOnlyRetriesIdempotentRequests(t *testing.T) {
+	unavailable := newNamedBackend("unavailable", http.StatusServiceUnavailable)
+	defer unavailable.Close()
+	up := newNamedBackend("up", http.StatusOK)
+	defer up.Close()
+	spec := newPrometheusSpecWithURLs(t, `{"strategy": "failover", "cooldown": "1ms"}`, unavailable.URL, up.URL)
+	spec.HTTP.AllowedEndpoints = append(spec.HTTP.AllowedEndpoints, datasourcev1.HTTPAllowedEndpoint{
+		EndpointPattern: common.MustNewRegexp("/api/v1/admin/tsdb/delete_series"),
+		Method:          http.MethodPost,
+	})
+
+	// a POST that is not a query is sent only once
+	code, body := serveBackends(t, spec, "/api/v1/admin/tsdb/delete_series", newPrometheusRequest("/api/v1/admin/tsdb/delete_series", "match[]=up"))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body)
+
+	// wait for the end of the cooldown so the unavailable backend is the first one to be tried again
+	time.Sleep(5 * time.Millisecond)
+	code, body = queryBackends(t, spec)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "up", body)
+}
+
+func TestPruneBackendPools(t *testing.T) {
+	spec := newPrometheusSpecWithURLs(t, `{"strategy": "failover"}`, "http://prometheus-a.example.com", "http://prometheus-b.example.com")
+	key := backendPoolKey(spec.HTTP)
+	getBackendPool(spec.HTTP).candidates(time.Now())
+
+	pruneBackendPools(time.Now().Add(backendPoolTTL / 2))
+	_, ok := backendPools.Load(key)
+	assert.True(t, ok, "a pool recently used must be kept")
+
+	pruneBackendPools(time.Now().Add(3 * backendPoolTTL))
+	_, ok = backendPools.Load(key)
+	assert.False(t, ok, "a pool no longer used must be removed")
+}
+
+func TestFailoverDoesNotRetryBigRequests(t *testing.T) {
+	var received int
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	up := newNamedBackend("up", http.StatusOK)
+	defer up.Close()
+	spec := newPrometheusSpecWithURLs(t, `{"strategy": "failover"}`, unavailable.URL, up.URL)
+
+	query := "query=" + strings.Repeat("a", maxRetryBodySize)
+	code, _ := serveBackends(t, spec, "/api/v1/query", newPrometheusRequest("/api/v1/query", query))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	// the whole body is still sent to the backend
+	assert.Equal(t, len(query), received)
+}
+
+func TestIsRetryable(t *testing.T) {
+	testSuite := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{method: http.MethodGet, path: "/api/v1/label/job/values", expected: true},
+		{method: http.MethodPost, path: "/api/v1/query", expected: true},
+		{method: http.MethodPost, path: "/api/v1/query_range", expected: true},
+		{method: http.MethodPost, path: "/api/v1/series", expected: true},
+		{method: http.MethodPost, path: "/api/v1/labels", expected: true},
+		{method: http.MethodPost, path: "/api/v1/admin/tsdb/snapshot", expected: false},
+		{method: http.MethodPost, path: "/api/v1/query/other", expected: false},
+		{method: http.MethodPatch, path: "/api/v1/query", expected: false},
+	}
+	for _, test := range testSuite {
+		t.Run(fmt.Sprintf("%s %s", test.method, test.path), func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, nil)
+			// a client cannot force the retry of a request
+			req.Header.Set("Idempotency-Key", "8e03978e-40d5-43e8-bc93-6894a57f9324")
+			assert.Equal(t, test.expected, isRetryable(req))
+		})
+	}
+}
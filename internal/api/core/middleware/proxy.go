@@ -96,8 +96,8 @@ func getGlobalDatasourceAndPath(dao globaldatasource.DAO, requestPath string) (v
 }
 
 func getLocalDatasourceAndPath(dao datasource.DAO, requestPath string) (v1.DatasourceSpec, string, error) {
-	matchingGroups := globalProxyMatcher.FindAllStringSubmatch(requestPath, -1)
-	if len(matchingGroups) > 1 || len(matchingGroups[0]) <= 2 {
+	matchingGroups := localProxyMatcher.FindAllStringSubmatch(requestPath, -1)
+	if len(matchingGroups) != 1 || len(matchingGroups[0]) <= 2 {
 		return nil, "", echo.NewHTTPError(http.StatusBadGateway, "unable to forward the request to the datasource, request not properly formatted")
 	}
 	projectName := matchingGroups[0][1]
@@ -115,8 +115,8 @@ func getLocalDatasourceAndPath(dao datasource.DAO, requestPath string) (v1.Datas
 	// Based on the HTTP 1.1 RFC, a `/` should be the minimum path.
 	// https://datatracker.ietf.org/doc/html/rfc2616#section-5.1.2
 	path := "/"
-	if len(matchingGroups[0]) > 1 {
-		path = matchingGroups[0][2]
+	if len(matchingGroups[0]) > 3 && len(matchingGroups[0][3]) > 0 {
+		path = matchingGroups[0][3]
 	}
 	return dts.Spec, path, nil
 }
@@ -129,6 +129,10 @@ func newProxy(spec v1.DatasourceSpec, path string) (proxy, error) {
 	switch v := spec.(type) {
 	case *datasourcev1.Prometheus:
 		return &httpProxy{config: v.HTTP, path: path}, nil
+	case *datasourcev1.Tempo:
+		return &httpProxy{config: v.HTTP, path: path}, nil
+	case *datasourcev1.TestData:
+		return &testDataProxy{seed: v.Seed, path: path}, nil
 	default:
 		return nil, echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("datasource type '%T' not managed", spec))
 	}
@@ -161,18 +165,22 @@ func (h *httpProxy) serve(c echo.Context) error {
 
 	// redirect the request to the datasource
 	req.URL.Path = h.path
-	logrus.Debugf("request will be redirected to %q", h.config.URL.String())
+	pool := getBackendPool(h.config)
+	logrus.Debugf("request will be redirected to %q", backendPoolKey(h.config))
 
 	// Set up the proxy
 	var proxyErr error
-	reverseProxy := httputil.NewSingleHostReverseProxy(h.config.URL)
+	reverseProxy := &httputil.ReverseProxy{
+		// the URL of the request is rewritten by the transport as it depends on which backend is used.
+		Director: func(*http.Request) {},
+		// use a dedicated HTTP transport to avoid any TLS encryption issues
+		Transport: &failoverTransport{pool: pool, transport: h.prepareTransport()},
+	}
 	reverseProxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, err error) {
-		desc := h.config.URL.String()
+		desc := backendPoolKey(h.config)
 		logrus.WithError(err).Errorf("error proxying, remote unreachable: target=%s, err=%v", desc, err)
 		proxyErr = err
 	}
-	// use a dedicated HTTP transport to avoid any TLS encryption issues
-	reverseProxy.Transport = h.prepareTransport()
 	// Reverse proxy request.
 	reverseProxy.ServeHTTP(res, req)
 	// Return any error handled during proxying request.
@@ -181,12 +189,6 @@ func (h *httpProxy) serve(c echo.Context) error {
 
 func (h *httpProxy) prepareRequest(c echo.Context) error {
 	req := c.Request()
-	// We have to modify the HOST of the request in order to match the host of the targetURL
-	// So far I'm not sure to understand exactly why, but if you are going to remove it, be sure of what you are doing.
-	// It has been done to fix an error returned by Openshift itself saying the target doesn't exist.
-	// Since we are using HTTP/1, setting the HOST is setting also an header so if the host and the header are different
-	// then maybe it is blocked by the Openshift router.
-	req.Host = h.config.URL.Host
 	// Fix header
 	if len(req.Header.Get(echo.HeaderXRealIP)) == 0 {
 		req.Header.Set(echo.HeaderXRealIP, c.RealIP())
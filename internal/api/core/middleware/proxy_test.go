@@ -0,0 +1,110 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTempoStandIn returns a local HTTP server answering like Tempo does for the trace-by-id and the search endpoints.
+func newTempoStandIn() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/traces/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_, _ = fmt.Fprintf(w, `{"batches":[],"trace_id":%q}`, r.URL.Path[len("/api/traces/"):])
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_, _ = fmt.Fprintf(w, `{"traces":[],"q":%q}`, r.URL.Query().Get("q"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTempoSpec(t *testing.T, serverURL string) *datasourcev1.Tempo {
+	spec := &datasourcev1.Tempo{}
+	data := fmt.Sprintf(`{"kind": "Tempo", "default": false, "http": {"url": %q}}`, serverURL)
+	if err := json.Unmarshal([]byte(data), spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestTempoProxy(t *testing.T) {
+	server := newTempoStandIn()
+	defer server.Close()
+	spec := newTempoSpec(t, server.URL)
+
+	testSuite := []struct {
+		title          string
+		method         string
+		path           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			title:          "get a trace by its ID",
+			method:         http.MethodGet,
+			path:           "/api/traces/2f3e0cee77ae5dc9c17ade3689eb2e54",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"batches":[],"trace_id":"2f3e0cee77ae5dc9c17ade3689eb2e54"}`,
+		},
+		{
+			title:          "search traces with TraceQL",
+			method:         http.MethodGet,
+			path:           "/api/search",
+			query:          "q=%7Bduration%3E1s%7D",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"traces":[],"q":"{duration>1s}"}`,
+		},
+		{
+			title:          "endpoint not allowed",
+			method:         http.MethodDelete,
+			path:           "/api/traces/2f3e0cee77ae5dc9c17ade3689eb2e54",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			target := "/proxy/globaldatasources/tempo" + test.path
+			if len(test.query) > 0 {
+				target = fmt.Sprintf("%s?%s", target, test.query)
+			}
+			req := httptest.NewRequest(test.method, target, nil)
+			rec := httptest.NewRecorder()
+			ctx := echo.New().NewContext(req, rec)
+
+			pr, err := newProxy(spec, test.path)
+			assert.NoError(t, err)
+			err = pr.serve(ctx)
+			if test.expectedStatus != http.StatusOK {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.Equal(t, test.expectedBody, rec.Body.String())
+		})
+	}
+}
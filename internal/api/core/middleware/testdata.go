@@ -0,0 +1,479 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	sineGenerator       = "sine"
+	randomWalkGenerator = "random_walk"
+	constantGenerator   = "constant"
+	csvGenerator        = "csv"
+	// maxTestDataPoints is the same limit as the one used by Prometheus for a single series in a range query.
+	maxTestDataPoints = 11000
+	maxTestDataSeries = 100
+	seriesLabel       = "series"
+	// randomWalkResolution is the interval between two moves of the finest octave of the random walk.
+	randomWalkResolution = time.Minute
+	randomWalkOctaves    = 12
+)
+
+var (
+	testDataGenerators      = []string{constantGenerator, csvGenerator, randomWalkGenerator, sineGenerator}
+	testDataLabelValuesPath = regexp.MustCompile(`^/api/v1/label/([a-zA-Z0-9_-]+)/values$`)
+)
+
+// testDataProxy is answering the requests sent to a TestData datasource without contacting any remote server.
+// It implements the following subset of the Prometheus HTTP API:
+//   - /api/v1/query and /api/v1/query_range are returning the series produced by the generator selected in the query.
+//   - /api/v1/labels, /api/v1/label/<name>/values and /api/v1/series are describing the series the generators can produce.
+//
+// The query is the name of the generator followed by its arguments between brackets. For example:
+//   - sine(period=1h, amplitude=10, offset=5)
+//   - random_walk(start=100, step=2)
+//   - constant(42)
+//   - csv(1, 4, 2.5, 8)
+//
+// Every generator accepts the argument `series` to produce more than one series. Each series is labelled with its index.
+type testDataProxy struct {
+	seed int64
+	path string
+}
+
+type testDataResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type testDataQueryData struct {
+	ResultType model.ValueType `json:"resultType"`
+	Result     model.Value     `json:"result"`
+}
+
+func (t *testDataProxy) serve(c echo.Context) error {
+	req := c.Request()
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		return echo.NewHTTPError(http.StatusMethodNotAllowed, fmt.Sprintf("HTTP method %s is not supported by the TestData datasource", req.Method))
+	}
+	switch {
+	case t.path == "/api/v1/query":
+		return t.query(c)
+	case t.path == "/api/v1/query_range":
+		return t.queryRange(c)
+	case t.path == "/api/v1/labels":
+		return t.labels(c)
+	case t.path == "/api/v1/series":
+		return t.series(c)
+	case testDataLabelValuesPath.MatchString(t.path):
+		return t.labelValues(c, testDataLabelValuesPath.FindStringSubmatch(t.path)[1])
+	default:
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("endpoint %q is not supported by the TestData datasource", t.path))
+	}
+}
+
+func (t *testDataProxy) query(c echo.Context) error {
+	generator, err := parseTestDataQuery(c.FormValue("query"))
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	ts, err := parseTestDataTime(c.FormValue("time"), time.Now())
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	timestamps := []model.Time{model.TimeFromUnixNano(ts.UnixNano())}
+	vector := model.Vector{}
+	for i := 0; i < generator.series; i++ {
+		values, valuesErr := generator.values(t.seed, i, timestamps)
+		if valuesErr != nil {
+			return badTestDataRequest(c, valuesErr)
+		}
+		vector = append(vector, &model.Sample{
+			Metric:    generator.metric(i),
+			Value:     model.SampleValue(values[0]),
+			Timestamp: timestamps[0],
+		})
+	}
+	return successTestData(c, &testDataQueryData{ResultType: model.ValVector, Result: vector})
+}
+
+func (t *testDataProxy) queryRange(c echo.Context) error {
+	generator, err := parseTestDataQuery(c.FormValue("query"))
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	end, err := parseTestDataTime(c.FormValue("end"), time.Now())
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	start, err := parseTestDataTime(c.FormValue("start"), end.Add(-1*time.Hour))
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	step, err := parseTestDataStep(c.FormValue("step"))
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	if end.Before(start) {
+		return badTestDataRequest(c, fmt.Errorf("end timestamp must not be before start time"))
+	}
+	if int64(end.Sub(start)/step) >= maxTestDataPoints {
+		return badTestDataRequest(c, fmt.Errorf("exceeded maximum resolution of %d points per timeseries. Try decreasing the query resolution (?step=XX)", maxTestDataPoints))
+	}
+	var timestamps []model.Time
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		timestamps = append(timestamps, model.TimeFromUnixNano(ts.UnixNano()))
+	}
+	matrix := model.Matrix{}
+	for i := 0; i < generator.series; i++ {
+		values, valuesErr := generator.values(t.seed, i, timestamps)
+		if valuesErr != nil {
+			return badTestDataRequest(c, valuesErr)
+		}
+		stream := &model.SampleStream{Metric: generator.metric(i)}
+		for j, ts := range timestamps {
+			stream.Values = append(stream.Values, model.SamplePair{Timestamp: ts, Value: model.SampleValue(values[j])})
+		}
+		matrix = append(matrix, stream)
+	}
+	return successTestData(c, &testDataQueryData{ResultType: model.ValMatrix, Result: matrix})
+}
+
+func (t *testDataProxy) series(c echo.Context) error {
+	metrics, err := matchedTestDataMetrics(c)
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	return successTestData(c, metrics)
+}
+
+func (t *testDataProxy) labels(c echo.Context) error {
+	metrics, err := matchedTestDataMetrics(c)
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	names := make(map[string]bool)
+	for _, metric := range metrics {
+		for name := range metric {
+			names[string(name)] = true
+		}
+	}
+	return successTestData(c, sortedKeys(names))
+}
+
+func (t *testDataProxy) labelValues(c echo.Context, labelName string) error {
+	metrics, err := matchedTestDataMetrics(c)
+	if err != nil {
+		return badTestDataRequest(c, err)
+	}
+	values := make(map[string]bool)
+	for _, metric := range metrics {
+		if value, ok := metric[model.LabelName(labelName)]; ok {
+			values[string(value)] = true
+		}
+	}
+	return successTestData(c, sortedKeys(values))
+}
+
+// matchedTestDataMetrics returns the metrics produced by the generators used in the parameter `match[]`.
+// When no matcher is provided, it returns the metrics of every generator with their default arguments.
+func matchedTestDataMetrics(c echo.Context) ([]model.Metric, error) {
+	if err := c.Request().ParseForm(); err != nil {
+		return nil, err
+	}
+	var generators []*testDataGenerator
+	matchers := c.Request().Form["match[]"]
+	if len(matchers) == 0 {
+		for _, name := range testDataGenerators {
+			generators = append(generators, &testDataGenerator{name: name, series: 1})
+		}
+	}
+	for _, matcher := range matchers {
+		generator, err := parseTestDataQuery(matcher)
+		if err != nil {
+			return nil, err
+		}
+		generators = append(generators, generator)
+	}
+	metrics := []model.Metric{}
+	for _, generator := range generators {
+		for i := 0; i < generator.series; i++ {
+			metrics = append(metrics, generator.metric(i))
+		}
+	}
+	return metrics, nil
+}
+
+type testDataGenerator struct {
+	query      string
+	name       string
+	positional []float64
+	named      map[string]string
+	series     int
+}
+
+func parseTestDataQuery(query string) (*testDataGenerator, error) {
+	query = strings.TrimSpace(query)
+	if len(query) == 0 {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+	g := &testDataGenerator{
+		query:  query,
+		name:   query,
+		named:  make(map[string]string),
+		series: 1,
+	}
+	if i := strings.Index(query, "("); i >= 0 {
+		if !strings.HasSuffix(query, ")") {
+			return nil, fmt.Errorf("missing closing bracket in the query %q", query)
+		}
+		g.name = strings.TrimSpace(query[:i])
+		for _, arg := range strings.Split(query[i+1:len(query)-1], ",") {
+			arg = strings.TrimSpace(arg)
+			if len(arg) == 0 {
+				continue
+			}
+			if key, value, isNamed := strings.Cut(arg, "="); isNamed {
+				g.named[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+				continue
+			}
+			f, err := parseTestDataFloat(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a valid number in the query %q", arg, query)
+			}
+			g.positional = append(g.positional, f)
+		}
+	}
+	if !isTestDataGenerator(g.name) {
+		return nil, fmt.Errorf("unknown generator %q. Supported generators: %s", g.name, strings.Join(testDataGenerators, ", "))
+	}
+	if s, ok := g.named[seriesLabel]; ok {
+		series, err := strconv.Atoi(s)
+		if err != nil || series < 1 || series > maxTestDataSeries {
+			return nil, fmt.Errorf("argument 'series' must be an integer between 1 and %d", maxTestDataSeries)
+		}
+		g.series = series
+	}
+	if g.name == csvGenerator && len(g.positional) == 0 {
+		return nil, fmt.Errorf("the generator %q requires at least one value", csvGenerator)
+	}
+	return g, nil
+}
+
+func (g *testDataGenerator) metric(seriesIndex int) model.Metric {
+	return model.Metric{
+		model.MetricNameLabel: model.LabelValue(g.name),
+		seriesLabel:           model.LabelValue(strconv.Itoa(seriesIndex)),
+	}
+}
+
+// values returns the value of the series for each timestamp. The same arguments always produce the same values.
+func (g *testDataGenerator) values(seed int64, seriesIndex int, timestamps []model.Time) ([]float64, error) {
+	result := make([]float64, len(timestamps))
+	switch g.name {
+	case sineGenerator:
+		period, err := g.durationArg("period", time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		amplitude, err := g.floatArg("amplitude", 1)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := g.floatArg("offset", 0)
+		if err != nil {
+			return nil, err
+		}
+		phase := 2 * math.Pi * float64(seriesIndex) / float64(g.series)
+		for i, ts := range timestamps {
+			seconds := float64(ts) / 1000
+			result[i] = offset + amplitude*math.Sin(2*math.Pi*seconds/period.Seconds()+phase)
+		}
+	case randomWalkGenerator:
+		value, err := g.floatArg("start", 0)
+		if err != nil {
+			return nil, err
+		}
+		step, err := g.floatArg("step", 1)
+		if err != nil {
+			return nil, err
+		}
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(g.query))
+		seriesSeed := uint64(seed) + h.Sum64() + uint64(seriesIndex)
+		for i, ts := range timestamps {
+			result[i] = value + step*randomWalkOffset(seriesSeed, ts)
+		}
+	case constantGenerator:
+		value := 1.0
+		if len(g.positional) > 0 {
+			value = g.positional[0]
+		}
+		value, err := g.floatArg("value", value)
+		if err != nil {
+			return nil, err
+		}
+		for i := range timestamps {
+			result[i] = value
+		}
+	case csvGenerator:
+		for i := range timestamps {
+			result[i] = g.positional[i%len(g.positional)]
+		}
+	}
+	return result, nil
+}
+
+// randomWalkOffset returns how far the random walk of the series has moved at the given timestamp.
+// It only depends on the seed of the series and on the timestamp, so two overlapping time ranges are getting the same
+// values whatever their start and their step. The walk is the sum of random values linearly interpolated between the
+// points of grids getting twice coarser at each octave, each one weighted so the variance grows with the distance like
+// in a real random walk.
+func randomWalkOffset(seriesSeed uint64, ts model.Time) float64 {
+	offset := 0.0
+	for octave := 0; octave < randomWalkOctaves; octave++ {
+		resolution := int64(randomWalkResolution/time.Millisecond) << octave
+		n := int64(ts) / resolution
+		if int64(ts)%resolution < 0 {
+			n--
+		}
+		frac := float64(int64(ts)-n*resolution) / float64(resolution)
+		seed := seriesSeed + uint64(octave)<<56
+		a, b := randomUnit(seed, n), randomUnit(seed, n+1)
+		offset += math.Sqrt(float64(int64(1)<<octave)) * (a + (b-a)*frac)
+	}
+	return offset
+}
+
+// randomUnit returns a pseudo-random number in [-1, 1) derived from the seed and the index of the point (SplitMix64).
+func randomUnit(seed uint64, index int64) float64 {
+	z := seed + uint64(index)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11)/float64(1<<52) - 1
+}
+
+func (g *testDataGenerator) floatArg(name string, defaultValue float64) (float64, error) {
+	value, ok := g.named[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	f, err := parseTestDataFloat(value)
+	if err != nil {
+		return 0, fmt.Errorf("argument %q must be a number", name)
+	}
+	return f, nil
+}
+
+func (g *testDataGenerator) durationArg(name string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := g.named[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	d, err := model.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("argument %q must be a positive duration", name)
+	}
+	return time.Duration(d), nil
+}
+
+func isTestDataGenerator(name string) bool {
+	for _, generator := range testDataGenerators {
+		if generator == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTestDataFloat parses a finite number. NaN and infinities are refused, as no series can be generated from them.
+func parseTestDataFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%q is not a finite number", s)
+	}
+	return f, nil
+}
+
+// parseTestDataTime parses a timestamp like Prometheus does: either a unix timestamp in seconds or a RFC3339 date.
+func parseTestDataTime(s string, defaultValue time.Time) (time.Time, error) {
+	if len(s) == 0 {
+		return defaultValue, nil
+	}
+	if f, err := parseTestDataFloat(s); err == nil {
+		seconds, decimals := math.Modf(f)
+		return time.Unix(int64(seconds), int64(decimals*float64(time.Second))).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+	}
+	return t, nil
+}
+
+// parseTestDataStep parses a step like Prometheus does: either a number of seconds or a duration.
+func parseTestDataStep(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return 0, fmt.Errorf("step cannot be empty")
+	}
+	var step time.Duration
+	if f, err := parseTestDataFloat(s); err == nil {
+		step = time.Duration(f * float64(time.Second))
+	} else {
+		d, durationErr := model.ParseDuration(s)
+		if durationErr != nil {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+		}
+		step = time.Duration(d)
+	}
+	if step <= 0 {
+		return 0, fmt.Errorf("zero or negative query resolution step widths are not accepted. Try a positive integer")
+	}
+	return step, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func successTestData(c echo.Context, data interface{}) error {
+	return c.JSON(http.StatusOK, &testDataResponse{Status: "success", Data: data})
+}
+
+func badTestDataRequest(c echo.Context, err error) error {
+	return c.JSON(http.StatusBadRequest, &testDataResponse{Status: "error", ErrorType: "bad_data", Error: err.Error()})
+}
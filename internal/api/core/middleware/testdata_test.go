@@ -0,0 +1,182 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestDataProxy(t *testing.T) {
+	testSuite := []struct {
+		title          string
+		path           string
+		form           url.Values
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			title:          "instant query on a constant",
+			path:           "/api/v1/query",
+			form:           url.Values{"query": {"constant(42)"}, "time": {"1660000000"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"constant","series":"0"},"value":[1660000000,"42"]}]}}`,
+		},
+		{
+			title:          "range query on csv values",
+			path:           "/api/v1/query_range",
+			form:           url.Values{"query": {"csv(1, 2.5, 3)"}, "start": {"1660000000"}, "end": {"1660000060"}, "step": {"15s"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"csv","series":"0"},"values":[[1660000000,"1"],[1660000015,"2.5"],[1660000030,"3"],[1660000045,"1"],[1660000060,"2.5"]]}]}}`,
+		},
+		{
+			title:          "range query on a sine wave",
+			path:           "/api/v1/query_range",
+			form:           url.Values{"query": {"sine(period=2m, amplitude=2, offset=1)"}, "start": {"0"}, "end": {"30"}, "step": {"30"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"sine","series":"0"},"values":[[0,"1"],[30,"3"]]}]}}`,
+		},
+		{
+			title:          "labels of every generator",
+			path:           "/api/v1/labels",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":["__name__","series"]}`,
+		},
+		{
+			title:          "label values of the metric name",
+			path:           "/api/v1/label/__name__/values",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":["constant","csv","random_walk","sine"]}`,
+		},
+		{
+			title:          "series matching a generator",
+			path:           "/api/v1/series",
+			form:           url.Values{"match[]": {"random_walk(series=2)"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"__name__":"random_walk","series":"0"},{"__name__":"random_walk","series":"1"}]}`,
+		},
+		{
+			title:          "unknown generator",
+			path:           "/api/v1/query",
+			form:           url.Values{"query": {"up"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","errorType":"bad_data","error":"unknown generator \"up\". Supported generators: constant, csv, random_walk, sine"}`,
+		},
+		{
+			title:          "too many points",
+			path:           "/api/v1/query_range",
+			form:           url.Values{"query": {"sine"}, "start": {"0"}, "end": {"11000"}, "step": {"1"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","errorType":"bad_data","error":"exceeded maximum resolution of 11000 points per timeseries. Try decreasing the query resolution (?step=XX)"}`,
+		},
+		{
+			title:          "NaN step",
+			path:           "/api/v1/query_range",
+			form:           url.Values{"query": {"sine"}, "start": {"0"}, "end": {"60"}, "step": {"NaN"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","errorType":"bad_data","error":"cannot parse \"NaN\" to a valid duration"}`,
+		},
+		{
+			title:          "infinite time",
+			path:           "/api/v1/query",
+			form:           url.Values{"query": {"constant(1)"}, "time": {"+Inf"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","errorType":"bad_data","error":"cannot parse \"+Inf\" to a valid timestamp"}`,
+		},
+		{
+			title:          "NaN argument",
+			path:           "/api/v1/query",
+			form:           url.Values{"query": {"constant(NaN)"}, "time": {"0"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","errorType":"bad_data","error":"\"NaN\" is not a valid number in the query \"constant(NaN)\""}`,
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/proxy/globaldatasources/testdata"+test.path, strings.NewReader(test.form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			rec := httptest.NewRecorder()
+			ctx := echo.New().NewContext(req, rec)
+
+			pr, err := newProxy(&datasourcev1.TestData{}, test.path)
+			assert.NoError(t, err)
+			assert.NoError(t, pr.serve(ctx))
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.Equal(t, test.expectedBody, strings.TrimSpace(rec.Body.String()))
+		})
+	}
+}
+
+func TestTestDataRandomWalkIsDeterministic(t *testing.T) {
+	query := func(seed int64) string {
+		form := url.Values{"query": {"random_walk(start=10, step=2)"}, "start": {"0"}, "end": {"600"}, "step": {"60"}}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/query_range", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		pr := &testDataProxy{seed: seed, path: "/api/v1/query_range"}
+		assert.NoError(t, pr.serve(echo.New().NewContext(req, rec)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+	assert.Equal(t, query(1), query(1))
+	assert.NotEqual(t, query(1), query(2))
+}
+
+func TestTestDataRandomWalkOverlappingRanges(t *testing.T) {
+	query := func(start string, end string, step string) map[model.Time]model.SampleValue {
+		form := url.Values{"query": {"random_walk(start=10, step=2, series=2)"}, "start": {start}, "end": {end}, "step": {step}}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/query_range", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		pr := &testDataProxy{seed: 42, path: "/api/v1/query_range"}
+		assert.NoError(t, pr.serve(echo.New().NewContext(req, rec)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response struct {
+			Data struct {
+				Result model.Matrix `json:"result"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		values := make(map[model.Time]model.SampleValue)
+		for _, point := range response.Data.Result[1].Values {
+			values[point.Timestamp] = point.Value
+		}
+		return values
+	}
+	wide := query("1660000000", "1660007200", "60")
+	shifted := query("1660003600", "1660010800", "60")
+	finer := query("1660001800", "1660005400", "15")
+	common := 0
+	for ts, value := range wide {
+		if shiftedValue, ok := shifted[ts]; ok {
+			assert.Equal(t, value, shiftedValue, "the shifted range disagrees at %s", ts)
+			common++
+		}
+		if finerValue, ok := finer[ts]; ok {
+			assert.Equal(t, value, finerValue, "the finer range disagrees at %s", ts)
+			common++
+		}
+	}
+	assert.Equal(t, 61+61, common)
+	assert.NotEqual(t, wide[1660000000000], wide[1660007200000])
+}
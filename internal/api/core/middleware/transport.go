@@ -0,0 +1,110 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+// NewDatasourceTransport returns a http.RoundTripper sending the requests to the datasource through the same proxy as the one
+// behind the endpoints /proxy/... It means the allowed endpoints, the headers, the authentication and the load balancing
+// defined in the datasource are applied exactly like when the UI is querying the datasource.
+// Only the path and the query of the URL of the request are used.
+func NewDatasourceTransport(spec v1.DatasourceSpec) http.RoundTripper {
+	return &datasourceTransport{spec: spec, echo: echo.New()}
+}
+
+type datasourceTransport struct {
+	spec v1.DatasourceSpec
+	// echo is only used to create the context expected by the proxy.
+	echo *echo.Echo
+}
+
+func (d *datasourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the proxy is modifying the request, while a http.RoundTripper must not.
+	req = req.Clone(req.Context())
+	path := req.URL.Path
+	if len(path) == 0 {
+		path = "/"
+	}
+	writer := newResponseBuffer()
+	pr, err := newProxy(d.spec, path)
+	if err == nil {
+		err = pr.serve(d.echo.NewContext(req, writer))
+	}
+	if err != nil {
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) {
+			return nil, err
+		}
+		// the error returned by the proxy is converted to a response, like the echo server would do.
+		writer = newResponseBuffer()
+		writer.WriteHeader(httpErr.Code)
+		_, _ = fmt.Fprint(writer, httpErr.Message)
+	}
+	return writer.toResponse(req), nil
+}
+
+// responseBuffer is a http.ResponseWriter keeping the response in memory.
+type responseBuffer struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (r *responseBuffer) Header() http.Header {
+	return r.header
+}
+
+func (r *responseBuffer) Write(data []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+func (r *responseBuffer) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+}
+
+func (r *responseBuffer) toResponse(req *http.Request) *http.Response {
+	statusCode := r.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.header,
+		Body:          io.NopCloser(bytes.NewReader(r.body.Bytes())),
+		ContentLength: int64(r.body.Len()),
+		Request:       req,
+	}
+}
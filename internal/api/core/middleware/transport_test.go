@@ -0,0 +1,79 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatasourceTransport(t *testing.T) {
+	backend := newNamedBackend("prometheus", http.StatusOK)
+	defer backend.Close()
+	testSuite := []struct {
+		title          string
+		spec           v1.DatasourceSpec
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			title:          "request forwarded to the backend",
+			spec:           newPrometheusSpecWithURLs(t, `{"strategy": "failover"}`, backend.URL),
+			method:         http.MethodPost,
+			path:           "/api/v1/query",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "prometheus",
+		},
+		{
+			title:          "endpoint not allowed",
+			spec:           newPrometheusSpecWithURLs(t, `{"strategy": "failover"}`, backend.URL),
+			method:         http.MethodDelete,
+			path:           "/api/v1/admin/tsdb/delete_series",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `you are not allowed to use this endpoint "/api/v1/admin/tsdb/delete_series" with the HTTP method DELETE`,
+		},
+		{
+			title:          "request answered by the TestData datasource",
+			spec:           &datasourcev1.TestData{BasicDatasource: datasourcev1.BasicDatasource{Kind: datasourcev1.TestDataKind}},
+			method:         http.MethodPost,
+			path:           "/api/v1/labels",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":["__name__","series"]}`,
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.path, strings.NewReader("query=up"))
+			assert.NoError(t, err)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			resp, err := NewDatasourceTransport(test.spec).RoundTrip(req)
+			assert.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, resp.StatusCode)
+			assert.Equal(t, test.expectedBody, strings.TrimSpace(string(body)))
+			// the request given to the transport must not be modified
+			assert.Equal(t, test.path, req.URL.Path)
+		})
+	}
+}
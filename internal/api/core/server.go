@@ -20,8 +20,11 @@ import (
 	"github.com/perses/perses/internal/api/impl/v1/datasource"
 	"github.com/perses/perses/internal/api/impl/v1/folder"
 	"github.com/perses/perses/internal/api/impl/v1/globaldatasource"
+	"github.com/perses/perses/internal/api/impl/v1/globalpanel"
 	"github.com/perses/perses/internal/api/impl/v1/health"
+	"github.com/perses/perses/internal/api/impl/v1/panel"
 	"github.com/perses/perses/internal/api/impl/v1/project"
+	"github.com/perses/perses/internal/api/impl/v1/schema"
 	"github.com/perses/perses/internal/api/impl/v1/user"
 	"github.com/perses/perses/internal/api/shared/dependency"
 )
@@ -41,8 +44,11 @@ func NewPersesAPI(serviceManager dependency.ServiceManager) echoUtils.Register {
 		datasource.NewEndpoint(serviceManager.GetDatasource()),
 		folder.NewEndpoint(serviceManager.GetFolder()),
 		globaldatasource.NewEndpoint(serviceManager.GetGlobalDatasource()),
+		globalpanel.NewEndpoint(serviceManager.GetGlobalPanel()),
 		health.NewEndpoint(serviceManager.GetHealth()),
+		panel.NewEndpoint(serviceManager.GetPanel()),
 		project.NewEndpoint(serviceManager.GetProject()),
+		schema.NewEndpoint(serviceManager.GetSchema()),
 		user.NewEndpoint(serviceManager.GetUser()),
 	}
 	return &api{
@@ -16,7 +16,9 @@ package api
 // this file is just there to run the command generate
 //go:generate go run generate.go -package=user -plural=users -kind=User
 //go:generate go run generate.go -package=globaldatasource -plural=globaldatasources -kind=GlobalDatasource
-//go:generate go run generate.go -package=datasource -plural=datasources -kind=Datasource -isProjectResource=true
+//go:generate go run generate.go -package=datasource -plural=datasources -kind=Datasource -isProjectResource=true -customRoutes=true
 //go:generate go run generate.go -package=project -plural=projects -kind=Project
-//go:generate go run generate.go -package=dashboard -plural=dashboards -kind=Dashboard -isProjectResource=true
+//go:generate go run generate.go -package=dashboard -plural=dashboards -kind=Dashboard -isProjectResource=true -customRoutes=true
 //go:generate go run generate.go -package=folder -plural=folders -kind=Folder -isProjectResource=true
+//go:generate go run generate.go -package=globalpanel -plural=globalpanels -kind=GlobalPanel -customRoutes=true
+//go:generate go run generate.go -package=panel -plural=panels -kind=Panel -isProjectResource=true -customRoutes=true
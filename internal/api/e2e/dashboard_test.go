@@ -0,0 +1,684 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+// +build integration
+
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/perses/perses/internal/api/shared"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	dashboardv1 "github.com/perses/perses/pkg/model/api/v1/dashboard"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/perses/perses/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDashboard(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	// check the document exists in the db
+	_, err := persistenceManager.GetDashboard().Get(entity.Metadata.Project, entity.Metadata.Name)
+	assert.NoError(t, err)
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardNormalization(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+	path := fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)
+
+	e.POST(path).
+		WithQuery(shared.ParamNormalize, "maybe").
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("message", `bad request: the query parameter normalize must be a boolean, got "maybe"`)
+
+	e.POST(path).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	// by default, the panels are stored as they have been sent
+	dashboard, err := persistenceManager.GetDashboard().Get(entity.Metadata.Project, entity.Metadata.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, len(entity.Spec.Panels), len(dashboard.Spec.Panels))
+	for name, panel := range entity.Spec.Panels {
+		assert.JSONEq(t, string(panel), string(dashboard.Spec.Panels[name]))
+	}
+
+	// once normalized, the panels record the version of their schema
+	e.PUT(fmt.Sprintf("%s/%s", path, entity.Metadata.Name)).
+		WithQuery(shared.ParamNormalize, "true").
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+	dashboard, err = persistenceManager.GetDashboard().Get(entity.Metadata.Project, entity.Metadata.Name)
+	assert.NoError(t, err)
+	for name := range entity.Spec.Panels {
+		assert.Contains(t, string(dashboard.Spec.Panels[name]), `"schema_version"`)
+	}
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardWithUnknownDatasource(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	datasource := utils.NewDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	// the global datasource used by the panel MixedCPU doesn't exist
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/panels/MixedCPU/options/queries/1/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the global datasource "GlobalPrometheus" doesn't exist`,
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), datasource.GenerateID())
+}
+
+func TestCreateDashboardWithDatasourceKindMismatch(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Datasource.Name = "TestData"
+	testData := utils.NewTestDataDatasource()
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, testData)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the datasource "TestData" is of kind "TestData" and not "Prometheus"`,
+		},
+		{
+			Path:     "/spec/panels/MixedCPU/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the datasource "PrometheusDemo" doesn't exist in the project "perses"`,
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), testData.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardReportsEveryError(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Variables = map[string]*dashboardv1.Variable{
+		"instance": {
+			Kind:          dashboardv1.KindLabelValuesQueryVariable,
+			DisplayedName: "Instance",
+			Parameter: &dashboardv1.LabelValuesQueryVariableParameter{
+				LabelName:       "instance",
+				Matchers:        []string{"up{job=\"$job\"}"},
+				CapturingRegexp: (*dashboardv1.CapturingRegexp)(regexp.MustCompile("(.*)")),
+			},
+		},
+	}
+	server, _ := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+
+	// none of the datasources exist and the variable is using an undefined variable
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the datasource "PrometheusDemo" doesn't exist in the project "perses"`,
+		},
+		{
+			Path:     "/spec/panels/MixedCPU/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the datasource "PrometheusDemo" doesn't exist in the project "perses"`,
+		},
+		{
+			Path:     "/spec/panels/MixedCPU/options/queries/1/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the global datasource "GlobalPrometheus" doesn't exist`,
+		},
+		{
+			Path:     "/spec/variables/instance",
+			Severity: common.SeverityWarning,
+			Message:  `the variable "instance" is not used by any panel, layout or datasource, neither directly nor through another variable`,
+		},
+		{
+			Path:     "/spec/variables/instance/parameter",
+			Severity: common.SeverityError,
+			Message:  `variable "job" is used in the variable "instance" but not defined`,
+		},
+	})
+}
+
+func TestCreateDashboardWithInvalidPromQL(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Variables = map[string]*dashboardv1.Variable{
+		"job": {
+			Kind:          dashboardv1.KindPromQLQueryVariable,
+			DisplayedName: "Job",
+			Parameter: &dashboardv1.PromQLQueryVariableParameter{
+				Expr:            "group by (job) (up",
+				LabelName:       "job",
+				CapturingRegexp: (*dashboardv1.CapturingRegexp)(regexp.MustCompile("(.*)")),
+			},
+		},
+	}
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/variables/job",
+			Severity: common.SeverityWarning,
+			Message:  `the variable "job" is not used by any panel, layout or datasource, neither directly nor through another variable`,
+		},
+		{
+			Path:     "/spec/variables/job/parameter/expr",
+			Severity: common.SeverityError,
+			Message:  "1:19: parse error: unclosed left parenthesis",
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardReportsWarnings(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Panels["Memory"] = []byte(`{"kind":"LineChart","display":{"name":"Memory"},"datasource":{"kind":"PrometheusDatasource"},"options":{"queries":[{"kind":"PrometheusGraphQuery","options":{"query":"node_memory_MemFree_bytes"}}]}}`)
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	// the panel Memory is not used in any layout, which doesn't prevent the dashboard from being created
+	response := e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	response.Path("$.metadata.name").Equal(entity.Metadata.Name)
+	response.ValueEqual("warnings", common.ValidationReport{
+		{
+			Path:     "/spec/panels/Memory",
+			Severity: common.SeverityWarning,
+			Message:  `the panel "Memory" is not used in any layout`,
+		},
+	})
+
+	// a variable used in a panel must be defined
+	entity.Spec.Panels["Memory"] = []byte(`{"kind":"LineChart","display":{"name":"Memory"},"datasource":{"kind":"PrometheusDatasource"},"options":{"queries":[{"kind":"PrometheusGraphQuery","options":{"query":"node_memory_MemFree_bytes{instance=\"$instance\"}"}}]}}`)
+	e.PUT(fmt.Sprintf("%s/%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/panels/Memory",
+			Severity: common.SeverityWarning,
+			Message:  `the panel "Memory" is not used in any layout`,
+		},
+		{
+			Path:     "/spec/panels/Memory/options/queries/0/options/query",
+			Severity: common.SeverityError,
+			Message:  `variable "instance" is used in the panel "Memory" but not defined`,
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardWithDatasourceVariable(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Datasource.Name = "$ds"
+	entity.Spec.Variables = map[string]*dashboardv1.Variable{
+		"ds": {
+			Kind:          dashboardv1.KindDatasourceVariable,
+			DisplayedName: "Datasource",
+			Parameter: &dashboardv1.DatasourceVariableParameter{
+				Kind: datasourcev1.TestDataKind,
+			},
+		},
+	}
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	// the variable is not listing the datasources of the kind used by the dashboard
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `the variable "ds" is listing the datasources of kind "TestData" and not "Prometheus"`,
+		},
+	})
+
+	entity.Spec.Variables["ds"].Parameter = &dashboardv1.DatasourceVariableParameter{Kind: datasourcev1.PrometheusKind}
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardWithDefaultDatasource(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Datasource.Name = ""
+	datasource := utils.NewDatasource(t)
+	datasource.Spec.(*datasourcev1.Prometheus).Default = true
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	// the query of the panel CPU is using the default datasource
+	e.GET(fmt.Sprintf("%s/%s/%s/%s/%s/datasources", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().First().Object().ValueEqual("datasource", map[string]interface{}{"name": "PrometheusDemo", "kind": "Prometheus", "global": false})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestCreateDashboardWithoutDefaultDatasource(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	entity.Spec.Datasource.Name = ""
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/datasource/name",
+			Severity: common.SeverityError,
+			Message:  `there is no default datasource of kind "Prometheus" in the project "perses" nor a default global one`,
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestResolveDashboardDatasources(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	e.GET(fmt.Sprintf("%s/%s/%s/%s/%s/datasources", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]map[string]interface{}{
+		{
+			"panel":      "CPU",
+			"query":      0,
+			"query_kind": "PrometheusGraphQuery",
+			"datasource": map[string]interface{}{"name": "PrometheusDemo", "kind": "Prometheus", "global": false},
+		},
+		{
+			"panel":      "MixedCPU",
+			"query":      0,
+			"query_kind": "PrometheusGraphQuery",
+			"datasource": map[string]interface{}{"name": "PrometheusDemo", "kind": "Prometheus", "global": false},
+		},
+		{
+			"panel":      "MixedCPU",
+			"query":      1,
+			"query_kind": "PrometheusGraphQuery",
+			"datasource": map[string]interface{}{"name": "GlobalPrometheus", "kind": "Prometheus", "global": true},
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
+
+func TestEvaluateDashboardVariables(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	datasource := utils.NewTestDataDatasource()
+	entity.Spec.Datasource = dashboardv1.Datasource{Name: datasource.Metadata.Name, Kind: datasourcev1.TestDataKind}
+	variables := `{
+  "count": {
+    "kind": "Constant",
+    "hide": true,
+    "parameter": {
+      "values": ["2", "3"]
+    }
+  },
+  "generator": {
+    "kind": "LabelValuesQuery",
+    "hide": true,
+    "parameter": {
+      "label_name": "__name__",
+      "matchers": ["sine", "constant"],
+      "capturing_regexp": "(.*)"
+    }
+  },
+  "series": {
+    "kind": "PromQLQuery",
+    "hide": true,
+    "parameter": {
+      "expr": "$generator(series=$count)",
+      "label_name": "series",
+      "capturing_regexp": "(.*)"
+    }
+  }
+}`
+	if err := json.Unmarshal([]byte(variables), &entity.Spec.Variables); err != nil {
+		t.Fatal(err)
+	}
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s/%s/variables/evaluate", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)).
+		WithJSON(dashboardv1.VariableEvaluationRequest{
+			Selected: map[string]dashboardv1.Selection{"count": {"3"}, "generator": {"sine"}},
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]dashboardv1.VariableEvaluationResult{
+		{Name: "count", Values: []string{"2", "3"}, Selected: dashboardv1.Selection{"3"}},
+		{Name: "generator", Values: []string{"constant", "sine"}, Selected: dashboardv1.Selection{"sine"}},
+		{Name: "series", Values: []string{"0", "1", "2"}, Selected: dashboardv1.Selection{"0"}},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID())
+}
+
+func TestEvaluateDashboardVariablesWithTimeRange(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	entity.Spec.Time = &dashboardv1.TimeSettings{Start: &start, End: &end}
+	variables := `{
+  "range": {
+    "kind": "Custom",
+    "hide": true,
+    "parameter": {
+      "values": "$__range"
+    }
+  }
+}`
+	if err := json.Unmarshal([]byte(variables), &entity.Spec.Variables); err != nil {
+		t.Fatal(err)
+	}
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	// without a time range in the request, the absolute time range of the dashboard is used
+	e.POST(fmt.Sprintf("%s/%s/%s/%s/%s/variables/evaluate", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)).
+		WithJSON(dashboardv1.VariableEvaluationRequest{}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]dashboardv1.VariableEvaluationResult{
+		{Name: "range", Values: []string{"1d"}, Selected: dashboardv1.Selection{"1d"}},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
+
+func TestEvaluateDashboardAnnotations(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	datasource := utils.NewTestDataDatasource()
+	entity.Spec.Datasource = dashboardv1.Datasource{Name: datasource.Metadata.Name, Kind: datasourcev1.TestDataKind}
+	spec := `{
+  "variables": {
+    "count": {"kind": "Constant", "hide": true, "parameter": {"values": ["1"]}}
+  },
+  "annotations": {
+    "deployments": {
+      "kind": "PromQLQuery",
+      "parameter": {"expr": "csv(0, 1, 1, 0, 0, series=$count)", "title": "Deployment of the series {{series}}", "step": "1m"}
+    },
+    "incidents": {
+      "kind": "Static",
+      "parameter": {"events": [{"time": "2022-06-01T00:04:00Z", "title": "Incident"}]}
+    }
+  }
+}`
+	var tmp struct {
+		Variables   map[string]*dashboardv1.Variable   `json:"variables"`
+		Annotations map[string]*dashboardv1.Annotation `json:"annotations"`
+	}
+	if err := json.Unmarshal([]byte(spec), &tmp); err != nil {
+		t.Fatal(err)
+	}
+	entity.Spec.Variables = tmp.Variables
+	entity.Spec.Annotations = tmp.Annotations
+	// the panel CPU is displaying the data of the previous minute
+	var cpu map[string]interface{}
+	if err := json.Unmarshal(entity.Spec.Panels["CPU"], &cpu); err != nil {
+		t.Fatal(err)
+	}
+	cpu["time"] = map[string]string{"shift": "1m"}
+	data, err := json.Marshal(cpu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.Spec.Panels["CPU"] = data
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	path := fmt.Sprintf("%s/%s/%s/%s/%s/annotations/evaluate", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)
+	deploymentEnd := start.Add(2 * time.Minute)
+	e.POST(path).
+		WithJSON(dashboardv1.AnnotationEvaluationRequest{Start: start, End: start.Add(4 * time.Minute)}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]dashboardv1.AnnotationEvaluationResult{
+		{Name: "deployments", Events: []dashboardv1.AnnotationEvent{{Time: start.Add(time.Minute), End: &deploymentEnd, Title: "Deployment of the series 0"}}},
+		{Name: "incidents", Events: []dashboardv1.AnnotationEvent{{Time: start.Add(4 * time.Minute), Title: "Incident"}}},
+	})
+
+	// the time range is shifted for the panel, so are the events
+	shiftedEnd := start.Add(time.Minute)
+	e.POST(path).
+		WithJSON(dashboardv1.AnnotationEvaluationRequest{Start: start, End: start.Add(4 * time.Minute), Panel: "CPU"}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]dashboardv1.AnnotationEvaluationResult{
+		{Name: "deployments", Events: []dashboardv1.AnnotationEvent{{Time: start, End: &shiftedEnd, Title: "Deployment of the series 0"}}},
+		{Name: "incidents", Events: []dashboardv1.AnnotationEvent{}},
+	})
+
+	e.POST(path).
+		WithJSON(dashboardv1.AnnotationEvaluationRequest{Panel: "unknown"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), datasource.GenerateID())
+}
+
+func TestExpandDashboard(t *testing.T) {
+	entity := utils.NewDashboard(t)
+	variables := `{
+  "instance": {
+    "kind": "Constant",
+    "hide": true,
+    "multi": true,
+    "selected": ["a"],
+    "parameter": {
+      "values": ["a", "b", "c"]
+    }
+  }
+}`
+	if err := json.Unmarshal([]byte(variables), &entity.Spec.Variables); err != nil {
+		t.Fatal(err)
+	}
+	grid := entity.Spec.Layouts[0].Spec.(*dashboardv1.GridLayoutSpec)
+	grid.Items[1].X = 0
+	grid.Items[1].Y = 6
+	grid.Items[1].Width = 24
+	grid.Items[0].Repeat = &dashboardv1.ItemRepeat{Variable: "instance"}
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	expandPath := fmt.Sprintf("%s/%s/%s/%s/%s/expand", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard, entity.Metadata.Name)
+	spec := e.POST(expandPath).
+		WithJSON(dashboardv1.ExpansionRequest{
+			Selected: map[string]dashboardv1.Selection{"instance": {"a", "b"}},
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("spec").Object()
+	spec.Value("panels").Object().Keys().ContainsOnly("CPU-0", "CPU-1", "MixedCPU")
+	spec.Value("layouts").Array().Element(0).Object().Value("spec").Object().Value("items").Equal([]map[string]interface{}{
+		{"x": 0, "y": 0, "width": 12, "height": 6, "content": map[string]interface{}{"$ref": "#/spec/panels/CPU-0"}},
+		{"x": 12, "y": 0, "width": 12, "height": 6, "content": map[string]interface{}{"$ref": "#/spec/panels/CPU-1"}},
+		{"x": 0, "y": 6, "width": 24, "height": 6, "content": map[string]interface{}{"$ref": "#/spec/panels/MixedCPU"}},
+	})
+
+	// the default selection is used when the variable is not in the request
+	e.POST(expandPath).
+		WithJSON(dashboardv1.ExpansionRequest{}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Path("$.spec.panels").Object().Keys().ContainsOnly("CPU-0", "MixedCPU")
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
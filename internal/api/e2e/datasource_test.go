@@ -25,6 +25,8 @@ import (
 	"github.com/gavv/httpexpect/v2"
 	"github.com/perses/perses/internal/api/shared"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
 	"github.com/perses/perses/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -238,3 +240,145 @@ func TestListDatasource(t *testing.T) {
 		Status(http.StatusOK)
 	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
 }
+
+func TestCreateSecondDefaultDatasource(t *testing.T) {
+	entity := utils.NewDatasource(t)
+	entity.Spec.(*datasourcev1.Prometheus).Default = true
+	otherEntity := utils.NewDatasource(t)
+	otherEntity.Metadata.Name = "OtherPrometheus"
+	otherEntity.Spec.(*datasourcev1.Prometheus).Default = true
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, otherEntity.Metadata.Project, shared.PathDatasource)).
+		WithJSON(otherEntity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/default",
+			Severity: common.SeverityError,
+			Message:  `the datasource "PrometheusDemo" is already the default one for the kind "Prometheus" in the project "perses"`,
+		},
+	})
+
+	// updating the current default datasource is still possible
+	e.PUT(fmt.Sprintf("%s/%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDatasource, entity.Metadata.Name)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
+
+func TestCreateSecondDefaultGlobalDatasource(t *testing.T) {
+	entity := utils.NewGlobalDatasource(t)
+	entity.Spec.(*datasourcev1.Prometheus).Default = true
+	otherEntity := utils.NewGlobalDatasource(t)
+	otherEntity.Metadata.Name = "OtherGlobalPrometheus"
+	otherEntity.Spec.(*datasourcev1.Prometheus).Default = true
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	e.POST(fmt.Sprintf("%s/%s", shared.APIV1Prefix, shared.PathGlobalDatasource)).
+		WithJSON(otherEntity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/default",
+			Severity: common.SeverityError,
+			Message:  `the global datasource "GlobalPrometheus" is already the default one for the kind "Prometheus"`,
+		},
+	})
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
+
+func TestCreateDatasourceWithoutSchema(t *testing.T) {
+	entity := utils.NewDatasource(t)
+	entity.Spec = &datasourcev1.Plugin{
+		"kind":     "PostgreSQL",
+		"host":     "localhost:5432",
+		"database": "inventory",
+	}
+	server, _ := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDatasource)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("message").String().Contains(`unknown spec.kind "PostgreSQL" used`)
+}
+
+func TestGetDefaultDatasource(t *testing.T) {
+	entity := utils.NewDatasource(t)
+	entity.Spec.(*datasourcev1.Prometheus).Default = true
+	globalEntity := utils.NewGlobalDatasource(t)
+	globalEntity.Spec.(*datasourcev1.Prometheus).Default = true
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalEntity)
+	path := fmt.Sprintf("%s/%s/%s/%s/default", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDatasource)
+
+	// the project doesn't have a default datasource, so the global one is returned
+	e.GET(path).
+		WithQuery("kind", "Prometheus").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("kind", v1.KindGlobalDatasource).
+		Path("$.metadata.name").Equal(globalEntity.Metadata.Name)
+
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+	e.GET(path).
+		WithQuery("kind", "Prometheus").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("kind", v1.KindDatasource).
+		Path("$.metadata.name").Equal(entity.Metadata.Name)
+
+	// there is no default datasource at all for this kind
+	e.GET(path).
+		WithQuery("kind", "Tempo").
+		Expect().
+		Status(http.StatusNotFound)
+
+	// without kind, the path is the one of a datasource named "default"
+	e.GET(path).
+		Expect().
+		Status(http.StatusNotFound)
+
+	namedDefault := utils.NewDatasource(t)
+	namedDefault.Metadata.Name = "default"
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, namedDefault)
+	e.GET(path).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Path("$.metadata.name").Equal("default")
+	e.GET(path).
+		WithQuery("kind", "Prometheus").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Path("$.metadata.name").Equal(entity.Metadata.Name)
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), globalEntity.GenerateID(), namedDefault.GenerateID())
+}
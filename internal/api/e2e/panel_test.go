@@ -0,0 +1,188 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+// +build integration
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	dashboardv1 "github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/perses/perses/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDashboardWithLibraryPanels returns the dashboard of utils.NewDashboard with a line of library panels below its panels.
+func newDashboardWithLibraryPanels(t *testing.T, panel string, globalPanel string) *v1.Dashboard {
+	entity := utils.NewDashboard(t)
+	grid := entity.Spec.Layouts[0].Spec.(*dashboardv1.GridLayoutSpec)
+	grid.Items = append(grid.Items,
+		dashboardv1.GridItem{X: 0, Y: 6, Width: 12, Height: 6, Content: v1.LibraryPanelRef(panel, false)},
+		dashboardv1.GridItem{X: 12, Y: 6, Width: 12, Height: 6, Content: v1.LibraryPanelRef(globalPanel, true)},
+	)
+	return entity
+}
+
+func TestCreatePanel(t *testing.T) {
+	entity := utils.NewPanel()
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathPanel)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	// check the document exists in the db
+	_, err := persistenceManager.GetPanel().Get(entity.Metadata.Project, entity.Metadata.Name)
+	assert.NoError(t, err)
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
+
+func TestCreatePanelWithInvalidSpec(t *testing.T) {
+	entity := utils.NewGlobalPanel()
+	entity.Spec = []byte(`{
+  "kind": "LineChart",
+  "display": {"name": 42},
+  "datasource": {"kind": "PrometheusDatasource"},
+  "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "up"}}]}
+}`)
+	server, _ := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	e.POST(fmt.Sprintf("%s/%s", shared.APIV1Prefix, shared.PathGlobalPanel)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("errors").Array().Element(0).Object().ValueEqual("path", "/spec/display/name")
+}
+
+func TestCreateDashboardWithUnknownLibraryPanels(t *testing.T) {
+	entity := newDashboardWithLibraryPanels(t, "Unknown", "GlobalUnknown")
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+
+	e.POST(fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("errors", common.ValidationReport{
+		{
+			Path:     "/spec/layouts/0/spec/items/2/content",
+			Severity: common.SeverityError,
+			Message:  `the panel "Unknown" doesn't exist in the project "perses"`,
+		},
+		{
+			Path:     "/spec/layouts/0/spec/items/3/content",
+			Severity: common.SeverityError,
+			Message:  `the global panel "GlobalUnknown" doesn't exist`,
+		},
+	})
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
+
+func TestDashboardWithLibraryPanels(t *testing.T) {
+	project := utils.NewProject()
+	panel := utils.NewPanel()
+	globalPanel := utils.NewGlobalPanel()
+	entity := newDashboardWithLibraryPanels(t, panel.Metadata.Name, globalPanel.Metadata.Name)
+	datasource := utils.NewDatasource(t)
+	globalDatasource := utils.NewGlobalDatasource(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, project)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, datasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalDatasource)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, panel)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, globalPanel)
+
+	dashboardPath := fmt.Sprintf("%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, entity.Metadata.Project, shared.PathDashboard)
+	e.POST(dashboardPath).
+		WithJSON(entity).
+		Expect().
+		Status(http.StatusOK)
+
+	// by default, the dashboard is returned with the references to the library panels
+	spec := e.GET(fmt.Sprintf("%s/%s", dashboardPath, entity.Metadata.Name)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("spec").Object()
+	spec.Value("panels").Object().Keys().ContainsOnly("CPU", "MixedCPU")
+	spec.Path("$.layouts[0].spec.items[2].content").Object().ValueEqual("$ref", "/panels/BurnRate")
+
+	// inlined, the dashboard contains a copy of the library panels
+	spec = e.GET(fmt.Sprintf("%s/%s", dashboardPath, entity.Metadata.Name)).
+		WithQuery(shared.ParamInline, true).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("spec").Object()
+	spec.Value("panels").Object().Keys().ContainsOnly("CPU", "MixedCPU", "BurnRate", "GlobalBurnRate")
+	spec.Path("$.layouts[0].spec.items[2].content").Object().ValueEqual("$ref", "#/spec/panels/BurnRate")
+	spec.Path("$.layouts[0].spec.items[3].content").Object().ValueEqual("$ref", "#/spec/panels/GlobalBurnRate")
+
+	panelPath := fmt.Sprintf("%s/%s/%s/%s/%s", shared.APIV1Prefix, shared.PathProject, panel.Metadata.Project, shared.PathPanel, panel.Metadata.Name)
+	e.GET(panelPath + "/usages").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]v1.PanelUsage{{Project: "perses", Dashboard: "Demo", Paths: []string{"/spec/layouts/0/spec/items/2/content"}}})
+	globalPanelPath := fmt.Sprintf("%s/%s/%s", shared.APIV1Prefix, shared.PathGlobalPanel, globalPanel.Metadata.Name)
+	e.GET(globalPanelPath + "/usages").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Equal([]v1.PanelUsage{{Project: "perses", Dashboard: "Demo", Paths: []string{"/spec/layouts/0/spec/items/3/content"}}})
+
+	// the library panels cannot be deleted while the dashboard is using them
+	e.DELETE(panelPath).
+		Expect().
+		Status(http.StatusBadRequest)
+	e.DELETE(globalPanelPath).
+		Expect().
+		Status(http.StatusBadRequest)
+	_, err := persistenceManager.GetPanel().Get(panel.Metadata.Project, panel.Metadata.Name)
+	assert.NoError(t, err)
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+	e.DELETE(panelPath).
+		Expect().
+		Status(http.StatusNoContent)
+	e.DELETE(globalPanelPath).
+		Expect().
+		Status(http.StatusNoContent)
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), project.GenerateID(), datasource.GenerateID(), globalDatasource.GenerateID())
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+// +build integration
+
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/perses/perses/internal/api/shared"
+	"github.com/perses/perses/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSchemas(t *testing.T) {
+	server, _ := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+
+	panels := e.GET(fmt.Sprintf("%s/schemas/panels", shared.APIV1Prefix)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array()
+	panels.NotEmpty()
+	found := false
+	for _, panel := range panels.Iter() {
+		if panel.Object().Value("kind").String().Raw() != "LineChart" {
+			continue
+		}
+		found = true
+		panel.Object().Value("path").String().Equal(filepath.Join("../../../schemas/panels", "line"))
+		panel.Object().Value("schema").Object().ContainsKey("panel")
+	}
+	assert.True(t, found, "LineChart is not part of the panels loaded")
+
+	e.GET(fmt.Sprintf("%s/schemas/queries", shared.APIV1Prefix)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().NotEmpty()
+
+	status := e.GET(fmt.Sprintf("%s/schemas/status", shared.APIV1Prefix)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	status.Value("errors").Array().Empty()
+	status.Value("plugins").Object().Keys().ContainsOnly("panels", "queries", "variables", "annotations", "layouts", "datasources")
+
+	// the events are recorded asynchronously, once the plugins are loaded
+	var events *httpexpect.Array
+	for i := 0; i < 20; i++ {
+		events = e.GET(fmt.Sprintf("%s/schemas/events", shared.APIV1Prefix)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Array()
+		if len(events.Raw()) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	events.NotEmpty()
+	for _, event := range events.Iter() {
+		event.Object().Value("status").String().Equal("loaded")
+		event.Object().Value("category").String().NotEmpty()
+		event.Object().ContainsKey("kind").ContainsKey("path").ContainsKey("time").NotContainsKey("error")
+	}
+}
+
+func TestMigratePanels(t *testing.T) {
+	persesProject := utils.NewProject()
+	entity := utils.NewDashboard(t)
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	path := fmt.Sprintf("%s/schemas/panels/migrate", shared.APIV1Prefix)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, persesProject)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+
+	e.POST(path).
+		WithQuery(shared.ParamDryRun, "maybe").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	// the panels are using the current version of the LineChart schema
+	report := e.POST(path).
+		WithQuery(shared.ParamDryRun, true).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	report.ValueEqual("dry_run", true)
+	report.Value("migrations").Array().Empty()
+
+	// a panel saved with a version of the schema that doesn't exist yet cannot be migrated
+	entity.Spec.Panels["CPU"] = setSchemaVersion(t, entity.Spec.Panels["CPU"], 2)
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+	migrations := e.POST(path).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("dry_run", false).
+		Value("migrations").Array()
+	migrations.Length().Equal(1)
+	migration := migrations.Element(0).Object()
+	migration.ValueEqual("project", entity.Metadata.Project)
+	migration.ValueEqual("dashboard", entity.Metadata.Name)
+	migration.ValueEqual("panel", "CPU")
+	migration.ValueEqual("kind", "LineChart")
+	migration.ValueEqual("from", 2)
+	migration.ValueEqual("to", 1)
+	migration.Value("error").String().Contains("newer than the version 1 available")
+
+	// the dashboard is left untouched
+	stored, err := persistenceManager.GetDashboard().Get(entity.Metadata.Project, entity.Metadata.Name)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(entity.Spec.Panels["CPU"]), string(stored.Spec.Panels["CPU"]))
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID(), persesProject.GenerateID())
+}
+
+func setSchemaVersion(t *testing.T, panel json.RawMessage, version int) json.RawMessage {
+	var result map[string]interface{}
+	if err := json.Unmarshal(panel, &result); err != nil {
+		t.Fatal(err)
+	}
+	result["schema_version"] = version
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+// +build integration
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/perses/perses/utils"
+)
+
+func TestQueryTestDataDatasource(t *testing.T) {
+	entity := utils.NewTestDataDatasource()
+	server, persistenceManager := utils.CreateServer(t)
+	defer server.Close()
+	e := httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+	utils.CreateAndWaitUntilEntityExists(t, persistenceManager, entity)
+	proxyPath := fmt.Sprintf("/proxy/projects/%s/datasources/%s", entity.Metadata.Project, entity.Metadata.Name)
+
+	response := e.POST(fmt.Sprintf("%s/api/v1/query_range", proxyPath)).
+		WithFormField("query", "sine(series=3)").
+		WithFormField("start", "1660000000").
+		WithFormField("end", "1660003600").
+		WithFormField("step", "60").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	response.ValueEqual("status", "success")
+	data := response.Value("data").Object()
+	data.ValueEqual("resultType", "matrix")
+	data.Value("result").Array().Length().Equal(3)
+	data.Value("result").Array().Element(0).Object().Value("values").Array().Length().Equal(61)
+
+	e.GET(fmt.Sprintf("%s/api/v1/label/__name__/values", proxyPath)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("data").Array().Contains("sine", "random_walk")
+
+	utils.ClearAllKeys(t, persistenceManager.GetPersesDAO(), entity.GenerateID())
+}
@@ -60,11 +60,17 @@ import (
 
 type Endpoint struct {
 	toolbox shared.Toolbox
+{{- if $endpoint.HasCustomRoutes }}
+	service {{ $package }}.Service
+{{- end }}
 }
 
 func NewEndpoint(service {{ $package }}.Service) *Endpoint {
 	return &Endpoint{
 		toolbox: shared.NewToolBox(service),
+{{- if $endpoint.HasCustomRoutes }}
+		service: service,
+{{- end }}
 	}
 }
 
@@ -85,6 +91,13 @@ func (e *Endpoint) RegisterRoutes(g *echo.Group) {
 	group.DELETE(fmt.Sprintf("/:%s", shared.ParamName), e.Delete)
 	group.GET(fmt.Sprintf("/:%s", shared.ParamName), e.Get)
 {{- end }}
+{{- if $endpoint.HasCustomRoutes }}
+{{ if $endpoint.IsProjectResource }}
+	e.registerCustomRoutes(group, subGroup)
+{{- else }}
+	e.registerCustomRoutes(group)
+{{- end }}
+{{- end }}
 }
 
 func (e *Endpoint) Create(ctx echo.Context) error {
@@ -382,6 +395,9 @@ type endpoint struct {
 	Kind              string
 	Plural            string
 	IsProjectResource bool
+	// HasCustomRoutes is true when the package is providing the method registerCustomRoutes
+	// to expose additional endpoints next to the CRUD ones.
+	HasCustomRoutes bool
 }
 
 func generateEndpoint(ept endpoint) {
@@ -440,6 +456,7 @@ func main() {
 	kind := flag.String("kind", "", "the name of the resource with the appropriate cases")
 	isProjectResource := flag.Bool("isProjectResource", false, "if the resource is part of a project.")
 	plural := flag.String("plural", "", "")
+	hasCustomRoutes := flag.Bool("customRoutes", false, "if the endpoint is registering additional routes with the method registerCustomRoutes.")
 	flag.Parse()
 
 	if len(*pkg) == 0 || len(*kind) == 0 || len(*plural) == 0 {
@@ -450,6 +467,7 @@ func main() {
 		Kind:              *kind,
 		IsProjectResource: *isProjectResource,
 		Plural:            *plural,
+		HasCustomRoutes:   *hasCustomRoutes,
 	}
 	generateEndpoint(ept)
 	generateInterface(ept)
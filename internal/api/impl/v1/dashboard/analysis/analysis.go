@@ -0,0 +1,262 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package analysis builds the graph of the references between the elements of a dashboard:
+// the variables used by the variables, the variables used by the panels and the panels used by the layouts.
+// It is used to find the elements that are never used and the variables used without being defined.
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/variable"
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+)
+
+// undefinedReference is a variable used in a panel without being defined.
+type undefinedReference struct {
+	path  string
+	panel string
+	name  string
+}
+
+type graph struct {
+	// variables is the variables used by each variable.
+	variables map[string]map[string]bool
+	// panels is the variables used by each panel.
+	panels map[string]map[string]bool
+	// layoutPanels is the panels used by the layouts.
+	layoutPanels map[string]bool
+	// roots is the variables used outside the variables and the panels: in the datasource of the dashboard, in the annotations
+	// and in the layouts, either in a title or to repeat a panel or a row.
+	roots map[string]bool
+	// undefined is the variables used in the queries of the panels that are not defined.
+	undefined []undefinedReference
+}
+
+// Analyze builds the graph of the references of the dashboard and returns a common.ValidationReport with:
+//   - an error for every variable used in the queries of a panel but not defined,
+//   - a warning for every panel that is not used in any layout,
+//   - a warning for every variable that is not used, neither directly nor through another variable.
+//
+// The variables used without being defined in the other variables and in the datasources are not reported here,
+// as they are already reported by the build order of the variables and by the verification of the datasources.
+// It returns nil when nothing is found.
+func Analyze(spec *v1.DashboardSpec) error {
+	g := buildGraph(spec)
+	var report common.ValidationReport
+	for _, ref := range g.undefined {
+		report.AddError(ref.path, "variable %q is used in the panel %q but not defined", ref.name, ref.panel)
+	}
+	for _, name := range sortedKeys(g.panels) {
+		if !g.layoutPanels[name] {
+			report.AddWarning(common.JSONPointer("spec", "panels", name), "the panel %q is not used in any layout", name)
+		}
+	}
+	used := g.usedVariables()
+	for _, name := range sortedKeys(g.variables) {
+		if !used[name] {
+			report.AddWarning(common.JSONPointer("spec", "variables", name), "the variable %q is not used by any panel, layout or datasource, neither directly nor through another variable", name)
+		}
+	}
+	if len(report) == 0 {
+		return nil
+	}
+	report.Sort()
+	return report
+}
+
+func buildGraph(spec *v1.DashboardSpec) *graph {
+	g := &graph{
+		variables:    make(map[string]map[string]bool),
+		panels:       make(map[string]map[string]bool),
+		layoutPanels: make(map[string]bool),
+		roots:        make(map[string]bool),
+	}
+	for name, v := range spec.Variables {
+		g.variables[name] = make(map[string]bool)
+		for _, str := range variable.QueryStrings(v) {
+			addVariables(g.variables[name], str)
+		}
+	}
+	addVariables(g.roots, spec.Datasource.Name)
+	for _, annotation := range spec.Annotations {
+		for _, str := range variable.AnnotationQueryStrings(annotation) {
+			addVariables(g.roots, str)
+		}
+	}
+	for name := range spec.Panels {
+		g.addPanel(spec, name)
+	}
+	for _, layout := range spec.Layouts {
+		for _, title := range layoutTitles(layout) {
+			addVariables(g.roots, title)
+		}
+		if layout.Spec != nil {
+			for _, repeat := range dashboard.LayoutRepeats(layout.Spec) {
+				g.roots[repeat.Variable] = true
+			}
+		}
+	}
+	for _, ref := range spec.LayoutReferences() {
+		if ref.Ref != nil && len(ref.Ref.Path) == 3 && ref.Ref.Path[1] == "panels" {
+			g.layoutPanels[ref.Ref.Path[2]] = true
+		}
+	}
+	return g
+}
+
+// addPanel adds the variables used by the panel: in its datasource and in its queries.
+func (g *graph) addPanel(spec *v1.DashboardSpec, name string) {
+	used := make(map[string]bool)
+	g.panels[name] = used
+	panel, err := dashboard.ExtractPanelQueries(spec.Panels[name])
+	if err != nil {
+		// the panel is not valid, which is reported by the validation of the panels
+		return
+	}
+	if panel.Datasource != nil {
+		addVariables(used, panel.Datasource.Name)
+	}
+	panelPath := common.JSONPointer("spec", "panels", name)
+	for _, query := range panel.Queries {
+		if query.Datasource != nil {
+			addVariables(used, query.Datasource.Name)
+		}
+		var options interface{}
+		if err := json.Unmarshal(query.Options, &options); err != nil {
+			continue
+		}
+		walkStrings(panelPath+query.Path+"/options", options, func(path string, str string) {
+			for _, variableName := range variableNames(str) {
+				used[variableName] = true
+				if _, ok := spec.Variables[variableName]; !ok {
+					g.undefined = append(g.undefined, undefinedReference{path: path, panel: name, name: variableName})
+				}
+			}
+		})
+	}
+}
+
+// layoutTitles returns the titles displayed by the layout, which can use variables.
+func layoutTitles(layout dashboard.Layout) []string {
+	var result []string
+	switch spec := layout.Spec.(type) {
+	case *dashboard.GridLayoutSpec:
+		if spec.Display != nil {
+			result = append(result, spec.Display.Title)
+		}
+	case *dashboard.RowsLayoutSpec:
+		for _, row := range spec.Rows {
+			result = append(result, row.Title)
+		}
+	case *dashboard.TabsLayoutSpec:
+		for _, tab := range spec.Tabs {
+			result = append(result, tab.Title)
+		}
+	}
+	return result
+}
+
+// usedVariables returns the variables used by the panels, the layouts and the datasource of the dashboard,
+// directly or through other variables.
+func (g *graph) usedVariables() map[string]bool {
+	used := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if used[name] {
+			return
+		}
+		used[name] = true
+		for dep := range g.variables[name] {
+			visit(dep)
+		}
+	}
+	for name := range g.roots {
+		visit(name)
+	}
+	for _, vars := range g.panels {
+		for name := range vars {
+			visit(name)
+		}
+	}
+	return used
+}
+
+// addVariables adds to the set the variables used in the string.
+func addVariables(set map[string]bool, str string) {
+	for _, name := range variableNames(str) {
+		set[name] = true
+	}
+}
+
+// variableNames returns the variables used in the string, except the built-in variables and the names only made of digits,
+// as they are more likely to be the groups captured by a regexp, like "$1" in the PromQL function label_replace.
+// The string is ignored when it is using an unknown format, which is reported by the validation of the queries.
+func variableNames(str string) []string {
+	names, err := interpolation.VariableNames(str)
+	if err != nil {
+		return nil
+	}
+	result := names[:0]
+	for _, name := range names {
+		if !isNumber(name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func isNumber(str string) bool {
+	for _, c := range str {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// walkStrings calls f with every string of the JSON value, with its JSON pointer prefixed by path.
+func walkStrings(path string, value interface{}, f func(path string, str string)) {
+	switch v := value.(type) {
+	case string:
+		f(path, v)
+	case []interface{}:
+		for i, item := range v {
+			walkStrings(fmt.Sprintf("%s/%d", path, i), item, f)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkStrings(path+common.JSONPointer(key), v[key], f)
+		}
+	}
+}
+
+func sortedKeys(m map[string]map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
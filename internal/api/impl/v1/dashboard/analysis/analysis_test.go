@@ -0,0 +1,116 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	testSuites := []struct {
+		title  string
+		spec   string
+		result common.ValidationReport
+	}{
+		{
+			title: "every element is used, including the variable only used to repeat a row",
+			spec: `{
+  "datasource": {"name": "$datasource", "kind": "Prometheus"},
+  "duration": "6h",
+  "variables": {
+    "datasource": {"kind": "Datasource", "hide": true, "parameter": {"kind": "Prometheus"}},
+    "job": {"kind": "LabelValuesQuery", "hide": true, "parameter": {"label_name": "job", "capturing_regexp": "(.*)"}},
+    "instance": {"kind": "LabelValuesQuery", "hide": true, "parameter": {"label_name": "instance", "matchers": ["up{job=\"$job\"}"], "capturing_regexp": "(.*)"}},
+    "title": {"kind": "Constant", "hide": true, "parameter": {"values": ["Nodes"]}},
+    "cluster": {"kind": "Constant", "hide": true, "multi": true, "parameter": {"values": ["eu", "us"]}}
+  },
+  "panels": {
+    "CPU": {
+      "kind": "LineChart",
+      "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "label_replace(up{instance=~\"$instance\"}[$__rate_interval], \"host\", \"$1\", \"instance\", \"(.*)\")"}}]}
+    }
+  },
+  "layouts": [
+    {"kind": "Rows", "spec": {"rows": [{"title": "$title", "repeat": {"variable": "cluster"}, "items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]}]}}
+  ]
+}`,
+		},
+		{
+			title: "variable only used by an annotation",
+			spec: `{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus"},
+  "duration": "6h",
+  "variables": {
+    "namespace": {"kind": "Constant", "hide": true, "parameter": {"values": ["default"]}}
+  },
+  "annotations": {
+    "deployments": {"kind": "PromQLQuery", "parameter": {"expr": "changes(kube_deployment_status_observed_generation{namespace=\"$namespace\"}[5m]) > 0", "title": "{{deployment}}"}}
+  },
+  "panels": {
+    "CPU": {"kind": "LineChart"}
+  },
+  "layouts": [
+    {"kind": "Grid", "spec": {"items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]}}
+  ]
+}`,
+		},
+		{
+			title: "orphan panel, unused variables and undefined variable",
+			spec: `{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus"},
+  "duration": "6h",
+  "variables": {
+    "job": {"kind": "LabelValuesQuery", "hide": true, "parameter": {"label_name": "job", "capturing_regexp": "(.*)"}},
+    "instance": {"kind": "LabelValuesQuery", "hide": true, "parameter": {"label_name": "instance", "matchers": ["up{job=\"$job\"}"], "capturing_regexp": "(.*)"}}
+  },
+  "panels": {
+    "CPU": {
+      "kind": "LineChart",
+      "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "up{mode=\"${mode}\"}"}}]}
+    },
+    "Memory": {
+      "kind": "LineChart",
+      "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "node_memory_MemFree_bytes"}}]}
+    }
+  },
+  "layouts": [
+    {"kind": "Grid", "spec": {"items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]}}
+  ]
+}`,
+			result: common.ValidationReport{
+				{Path: "/spec/panels/CPU/options/queries/0/options/query", Severity: common.SeverityError, Message: `variable "mode" is used in the panel "CPU" but not defined`},
+				{Path: "/spec/panels/Memory", Severity: common.SeverityWarning, Message: `the panel "Memory" is not used in any layout`},
+				{Path: "/spec/variables/instance", Severity: common.SeverityWarning, Message: `the variable "instance" is not used by any panel, layout or datasource, neither directly nor through another variable`},
+				{Path: "/spec/variables/job", Severity: common.SeverityWarning, Message: `the variable "job" is not used by any panel, layout or datasource, neither directly nor through another variable`},
+			},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			spec := &v1.DashboardSpec{}
+			assert.NoError(t, json.Unmarshal([]byte(test.spec), spec))
+			err := Analyze(spec)
+			if test.result == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, test.result, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perses/perses/internal/api/shared"
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/sirupsen/logrus"
+)
+
+type panelDatasourceRef struct {
+	path string
+	ref  *dashboard.DatasourceRef
+}
+
+// validateDatasources verifies that the datasource of the dashboard and every datasource referenced by name in the panels
+// exist and have the expected kind. Every violation found is added to the report.
+// An error is returned only when the datasources cannot be retrieved.
+func (s *service) validateDatasources(entity *v1.Dashboard, report *common.ValidationReport) error {
+	if _, message, err := s.resolveDashboardDatasource(entity); err != nil {
+		return err
+	} else if len(message) > 0 {
+		report.AddError("/spec/datasource/name", "%s", message)
+	}
+
+	panelKeys := make([]string, 0, len(entity.Spec.Panels))
+	for key := range entity.Spec.Panels {
+		panelKeys = append(panelKeys, key)
+	}
+	sort.Strings(panelKeys)
+	for _, key := range panelKeys {
+		panelPath := common.JSONPointer("spec", "panels", key)
+		panel, err := dashboard.ExtractPanelQueries(entity.Spec.Panels[key])
+		if err != nil {
+			report.AddError(panelPath, "unable to read the queries of the panel: %s", err)
+			continue
+		}
+		refs := []panelDatasourceRef{{path: panelPath + "/datasource", ref: panel.Datasource}}
+		for _, query := range panel.Queries {
+			refs = append(refs, panelDatasourceRef{path: fmt.Sprintf("%s%s/datasource", panelPath, query.Path), ref: query.Datasource})
+		}
+		for _, r := range refs {
+			if r.ref == nil || len(r.ref.Name) == 0 {
+				continue
+			}
+			var message string
+			if hasVariables(r.ref.Name) {
+				message = checkDatasourceVariables(entity, r.ref.Name, r.ref.GetDatasourceKind(), r.ref.Global)
+			} else if message, err = s.checkDatasource(entity.Metadata.Project, r.ref.Name, r.ref.GetDatasourceKind(), r.ref.Global); err != nil {
+				return err
+			}
+			if len(message) > 0 {
+				report.AddError(r.path+"/name", "%s", message)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveDashboardDatasource returns the datasource of the dashboard.
+// When the name of the datasource is omitted, the default datasource of the kind is used.
+// If the project doesn't have one, the default global datasource is used.
+// A message explaining the violation is returned when the datasource cannot be found or doesn't have the expected kind.
+func (s *service) resolveDashboardDatasource(entity *v1.Dashboard) (*dashboard.Datasource, string, error) {
+	ref := entity.Spec.Datasource
+	project := entity.Metadata.Project
+	if hasVariables(ref.Name) {
+		// the datasource is only known once the variables are evaluated
+		if message := checkDatasourceVariables(entity, ref.Name, ref.Kind, ref.Global); len(message) > 0 {
+			return nil, message, nil
+		}
+		return &ref, "", nil
+	}
+	if len(ref.Name) > 0 {
+		message, err := s.checkDatasource(project, ref.Name, ref.Kind, ref.Global)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(message) > 0 {
+			return nil, message, nil
+		}
+		return &ref, "", nil
+	}
+
+	ds, err := s.findDefaultDatasource(project, ref.Kind, ref.Global)
+	if err != nil {
+		return nil, "", err
+	}
+	if ds == nil {
+		message := fmt.Sprintf("there is no default datasource of kind %q in the project %q nor a default global one", ref.Kind, project)
+		if ref.Global {
+			message = fmt.Sprintf("there is no default global datasource of kind %q", ref.Kind)
+		}
+		return nil, message, nil
+	}
+	return ds, "", nil
+}
+
+// checkDatasource returns a message explaining why the datasource is not valid, or an empty string if it exists with the expected kind.
+// The kind is not verified when it is empty.
+func (s *service) checkDatasource(project string, name string, kind datasourcev1.Kind, global bool) (string, error) {
+	spec, err := s.getDatasourceSpec(project, name, global)
+	if err != nil {
+		if errors.Is(err, shared.NotFoundError) {
+			if global {
+				return fmt.Sprintf("the global datasource %q doesn't exist", name), nil
+			}
+			return fmt.Sprintf("the datasource %q doesn't exist in the project %q", name, project), nil
+		}
+		return "", err
+	}
+	if len(kind) > 0 && spec.GetKind() != kind {
+		return fmt.Sprintf("the datasource %q is of kind %q and not %q", name, spec.GetKind(), kind), nil
+	}
+	return "", nil
+}
+
+// hasVariables returns true when the name of the datasource is using a variable, like "$datasource".
+func hasVariables(name string) bool {
+	return strings.Contains(name, "$")
+}
+
+// checkDatasourceVariables returns a message explaining why the variables used in the name of a datasource are not valid,
+// or an empty string if they are all Datasource variables listing the datasources of the expected kind.
+// The kind is not verified when it is empty.
+func checkDatasourceVariables(entity *v1.Dashboard, name string, kind datasourcev1.Kind, global bool) string {
+	names, err := interpolation.VariableNames(name)
+	if err != nil {
+		return err.Error()
+	}
+	for _, variableName := range names {
+		variable, ok := entity.Spec.Variables[variableName]
+		if !ok {
+			return fmt.Sprintf("the variable %q doesn't exist", variableName)
+		}
+		param, ok := variable.Parameter.(*dashboard.DatasourceVariableParameter)
+		if !ok {
+			return fmt.Sprintf("the variable %q is not of kind %q", variableName, dashboard.KindDatasourceVariable)
+		}
+		if len(kind) > 0 && param.Kind != kind {
+			return fmt.Sprintf("the variable %q is listing the datasources of kind %q and not %q", variableName, param.Kind, kind)
+		}
+		if param.Global != global {
+			if global {
+				return fmt.Sprintf("the variable %q is not listing the global datasources", variableName)
+			}
+			return fmt.Sprintf("the variable %q is listing the global datasources", variableName)
+		}
+	}
+	return ""
+}
+
+// getDatasourceSpec returns the spec of the datasource of the project or of the global datasource when global is true.
+func (s *service) getDatasourceSpec(project string, name string, global bool) (v1.DatasourceSpec, error) {
+	if global {
+		entity, err := s.globalDatasourceService.Get(shared.Parameters{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		return entity.(*v1.GlobalDatasource).Spec, nil
+	}
+	entity, err := s.datasourceService.Get(shared.Parameters{Project: project, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return entity.(*v1.Datasource).Spec, nil
+}
+
+// findDefaultDatasource returns the datasource of the given kind flagged as the default one, or nil if there is none.
+// When global is false, the default datasource of the project is used, and if there is none, the default global datasource.
+func (s *service) findDefaultDatasource(project string, kind datasourcev1.Kind, global bool) (*dashboard.Datasource, error) {
+	var entity interface{}
+	var err error
+	if global {
+		entity, err = s.globalDatasourceService.GetDefault(kind)
+	} else {
+		entity, err = s.datasourceService.GetDefault(project, kind)
+	}
+	if err != nil {
+		if errors.Is(err, shared.NotFoundError) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	switch ds := entity.(type) {
+	case *v1.Datasource:
+		return &dashboard.Datasource{Name: ds.Metadata.Name, Kind: kind}, nil
+	case *v1.GlobalDatasource:
+		return &dashboard.Datasource{Name: ds.Metadata.Name, Kind: kind, Global: true}, nil
+	}
+	return nil, nil
+}
+
+func (s *service) ResolveDatasources(parameters shared.Parameters) ([]dashboard.QueryDatasource, error) {
+	// the queries of the library panels used by the dashboard are resolved too
+	parameters.Inline = true
+	entity, err := s.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	dashboardObject := entity.(*v1.Dashboard)
+	spec := dashboardObject.Spec
+	ds, violation, err := s.resolveDashboardDatasource(dashboardObject)
+	if err != nil {
+		return nil, err
+	}
+	if len(violation) == 0 {
+		spec.Datasource = *ds
+	}
+	result, err := spec.ResolveQueryDatasources()
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to resolve the datasources of the dashboard %q", parameters.Name)
+		return nil, shared.InternalError
+	}
+	if result == nil {
+		result = []dashboard.QueryDatasource{}
+	}
+	return result, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/perses/perses/internal/api/core/middleware"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/variable"
+	"github.com/perses/perses/internal/api/interface/v1/datasource"
+	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *service) EvaluateVariables(ctx context.Context, parameters shared.Parameters, request dashboard.VariableEvaluationRequest) ([]dashboard.VariableEvaluationResult, error) {
+	entity, err := s.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	dashboardObject := entity.(*v1.Dashboard)
+	if len(dashboardObject.Spec.Variables) == 0 {
+		return []dashboard.VariableEvaluationResult{}, nil
+	}
+	request.Start, request.End = timeRange(&dashboardObject.Spec, request.Start, request.End)
+	datasources := &variableDatasources{
+		service: s,
+		project: dashboardObject.Metadata.Project,
+		ref:     dashboardObject.Spec.Datasource,
+	}
+	result, err := variable.Evaluate(ctx, dashboardObject.Metadata.Name, dashboardObject.Spec.Datasource.Name, dashboardObject.Spec.Variables, request, datasources)
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to evaluate the variables of the dashboard %q", parameters.Name)
+		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
+	}
+	return result, nil
+}
+
+func (s *service) EvaluateAnnotations(ctx context.Context, parameters shared.Parameters, request dashboard.AnnotationEvaluationRequest) ([]dashboard.AnnotationEvaluationResult, error) {
+	entity, err := s.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	dashboardObject := entity.(*v1.Dashboard)
+	request.Start, request.End = timeRange(&dashboardObject.Spec, request.Start, request.End)
+	if len(request.Panel) > 0 {
+		panel, ok := dashboardObject.Spec.Panels[request.Panel]
+		if !ok {
+			return nil, fmt.Errorf("%w: the panel %q doesn't exist", shared.BadRequestError, request.Panel)
+		}
+		panelTime, err := dashboard.ExtractPanelTime(panel)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid panel %q: %s", shared.BadRequestError, request.Panel, err)
+		}
+		request.Start, request.End = panelTime.TimeRange(request.Start, request.End)
+	}
+	if len(dashboardObject.Spec.Annotations) == 0 {
+		return []dashboard.AnnotationEvaluationResult{}, nil
+	}
+	datasources := &variableDatasources{
+		service: s,
+		project: dashboardObject.Metadata.Project,
+		ref:     dashboardObject.Spec.Datasource,
+	}
+	result, err := variable.EvaluateAnnotations(ctx, dashboardObject.Metadata.Name, dashboardObject.Spec.Datasource.Name, dashboardObject.Spec.Variables, dashboardObject.Spec.Annotations, request, datasources)
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to evaluate the annotations of the dashboard %q", parameters.Name)
+		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
+	}
+	return result, nil
+}
+
+// timeRange returns the time range of the request, completed with the time range of the dashboard.
+// When both start and end are zero, the time range of the dashboard is used. When only start is zero, it is end minus the duration of the dashboard.
+func timeRange(spec *v1.DashboardSpec, start time.Time, end time.Time) (time.Time, time.Time) {
+	if end.IsZero() {
+		var dashboardStart time.Time
+		dashboardStart, end = spec.TimeRange(time.Now())
+		if start.IsZero() {
+			start = dashboardStart
+		}
+	}
+	if start.IsZero() {
+		start = end.Add(-time.Duration(spec.Duration))
+	}
+	return start, end
+}
+
+// variableDatasources is giving access to the datasources of the project of the dashboard and to the global datasources.
+type variableDatasources struct {
+	service *service
+	project string
+	// ref is the datasource of the dashboard
+	ref dashboard.Datasource
+}
+
+func (v *variableDatasources) List(kind datasourcev1.Kind, global bool) ([]string, error) {
+	var names []string
+	if global {
+		list, err := v.service.globalDatasourceService.List(&globaldatasource.Query{}, shared.Parameters{})
+		if err != nil {
+			return nil, err
+		}
+		for _, ds := range list.([]*v1.GlobalDatasource) {
+			if ds.Spec.GetKind() == kind {
+				names = append(names, ds.Metadata.Name)
+			}
+		}
+	} else {
+		list, err := v.service.datasourceService.List(&datasource.Query{Project: v.project}, shared.Parameters{Project: v.project})
+		if err != nil {
+			return nil, err
+		}
+		for _, ds := range list.([]*v1.Datasource) {
+			if ds.Spec.GetKind() == kind {
+				names = append(names, ds.Metadata.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (v *variableDatasources) Transport(name string) (http.RoundTripper, error) {
+	ds := &dashboard.Datasource{Name: name, Kind: v.ref.Kind, Global: v.ref.Global}
+	if len(name) == 0 {
+		var err error
+		if ds, err = v.service.findDefaultDatasource(v.project, v.ref.Kind, v.ref.Global); err != nil {
+			return nil, err
+		}
+		if ds == nil {
+			return nil, fmt.Errorf("there is no default datasource of kind %q", v.ref.Kind)
+		}
+	}
+	spec, err := v.service.getDatasourceSpec(v.project, ds.Name, ds.Global)
+	if err != nil {
+		if errors.Is(err, shared.NotFoundError) {
+			return nil, fmt.Errorf("the datasource %q doesn't exist", ds.Name)
+		}
+		return nil, err
+	}
+	return middleware.NewDatasourceTransport(spec), nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"fmt"
+
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/repeat"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+)
+
+func (s *service) Expand(parameters shared.Parameters, request dashboard.ExpansionRequest) (*v1.Dashboard, error) {
+	// the library panels are inlined, so their copies can be interpolated with the values of the variables
+	parameters.Inline = true
+	entity, err := s.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	dashboardObject := entity.(*v1.Dashboard)
+	spec, err := repeat.Expand(&dashboardObject.Spec, request.Selected)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
+	}
+	return &v1.Dashboard{
+		Kind:     dashboardObject.Kind,
+		Metadata: dashboardObject.Metadata,
+		Spec:     *spec,
+	}, nil
+}
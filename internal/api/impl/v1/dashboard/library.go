@@ -0,0 +1,108 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/sirupsen/logrus"
+)
+
+// validateLibraryPanels verifies the library panels used in the layouts of the dashboard exist.
+func (s *service) validateLibraryPanels(entity *v1.Dashboard, report *common.ValidationReport) error {
+	for _, ref := range entity.Spec.LibraryPanelReferences() {
+		_, err := s.getLibraryPanel(entity.Metadata.Project, ref)
+		if errors.Is(err, shared.NotFoundError) {
+			if ref.Global {
+				report.AddError("/spec"+ref.Path, "the global panel %q doesn't exist", ref.Name)
+			} else {
+				report.AddError("/spec"+ref.Path, "the panel %q doesn't exist in the project %q", ref.Name, entity.Metadata.Project)
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLibraryPanel returns the spec of the Panel, or of the GlobalPanel, referenced in a layout of a dashboard of the project.
+func (s *service) getLibraryPanel(project string, ref v1.LibraryPanelReference) (json.RawMessage, error) {
+	parameters := shared.Parameters{Project: project, Name: ref.Name}
+	if ref.Global {
+		entity, err := s.globalPanelService.Get(parameters)
+		if err != nil {
+			return nil, err
+		}
+		return entity.(*v1.GlobalPanel).Spec, nil
+	}
+	entity, err := s.panelService.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return entity.(*v1.Panel).Spec, nil
+}
+
+// inlineLibraryPanels copies the library panels used in the layouts into the panels of the dashboard,
+// and replaces the references to the library panels by references to their copies.
+// A copy is named like its library panel, followed by "-<index>" when this name is already used in the dashboard.
+// The references to a library panel that doesn't exist anymore are kept as they are.
+func (s *service) inlineLibraryPanels(entity *v1.Dashboard) error {
+	// keys is the key of the copy of each library panel, by reference
+	keys := make(map[string]string)
+	for _, ref := range entity.Spec.LayoutReferences() {
+		name, global, ok := v1.ParseLibraryPanelRef(ref.Ref)
+		if !ok {
+			continue
+		}
+		key, copied := keys[ref.Ref.Ref]
+		if !copied {
+			panel, err := s.getLibraryPanel(entity.Metadata.Project, v1.LibraryPanelReference{Path: ref.Path, Name: name, Global: global})
+			if errors.Is(err, shared.NotFoundError) {
+				logrus.Warnf("the library panel %q used by the dashboard %s/%s doesn't exist, it cannot be inlined", ref.Ref.Ref, entity.Metadata.Project, entity.Metadata.Name)
+				continue
+			} else if err != nil {
+				return err
+			}
+			if entity.Spec.Panels == nil {
+				entity.Spec.Panels = make(map[string]json.RawMessage)
+			}
+			key = newPanelKey(entity.Spec.Panels, name)
+			entity.Spec.Panels[key] = panel
+			keys[ref.Ref.Ref] = key
+		}
+		*ref.Ref = common.JSONRef{
+			Ref:  fmt.Sprintf("#/spec/panels/%s", key),
+			Path: []string{"spec", "panels", key},
+		}
+	}
+	return nil
+}
+
+// newPanelKey returns name if no panel of the dashboard has this key, or the first key "<name>-<index>" available otherwise.
+func newPanelKey(panels map[string]json.RawMessage, name string) string {
+	if _, exist := panels[name]; !exist {
+		return name
+	}
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("%s-%d", name, i)
+		if _, exist := panels[key]; !exist {
+			return key
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+)
+
+// QueryKind is the kind of the Prometheus queries of the panels, as defined by the plugin of the Prometheus queries.
+const QueryKind = "PrometheusGraphQuery"
+
+// CheckDashboard checks every PromQL query of the dashboard: the queries of the panels, of the variables and of the annotations.
+// It returns a common.ValidationReport with the path of every query having a syntax error or looking wrong, or nil.
+// The report can contain only warnings, meaning the dashboard is still valid.
+func CheckDashboard(spec *v1.DashboardSpec) error {
+	var report common.ValidationReport
+	multiValueVariables := make(map[string]bool)
+	for name, variable := range spec.Variables {
+		if variable.Multi || variable.IncludeAll {
+			multiValueVariables[name] = true
+		}
+	}
+	checkPanels(&report, spec.Panels, multiValueVariables)
+	checkVariables(&report, spec.Variables)
+	checkAnnotations(&report, spec.Annotations, multiValueVariables)
+	if len(report) == 0 {
+		return nil
+	}
+	report.Sort()
+	return report
+}
+
+func checkPanels(report *common.ValidationReport, panels map[string]json.RawMessage, multiValueVariables map[string]bool) {
+	for name, panel := range panels {
+		queries, err := dashboard.ExtractPanelQueries(panel)
+		if err != nil {
+			// the panel is not valid, which is reported by the validation of the panels
+			continue
+		}
+		for _, query := range queries.Queries {
+			if query.Kind != QueryKind {
+				continue
+			}
+			var options struct {
+				Query string `json:"query"`
+			}
+			if err := json.Unmarshal(query.Options, &options); err != nil || len(options.Query) == 0 {
+				continue
+			}
+			report.Merge(common.JSONPointer("spec", "panels", name)+query.Path+"/options/query", check(options.Query, multiValueVariables))
+		}
+	}
+}
+
+func checkVariables(report *common.ValidationReport, variables map[string]*dashboard.Variable) {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := common.JSONPointer("spec", "variables", name, "parameter")
+		switch param := variables[name].Parameter.(type) {
+		case *dashboard.PromQLQueryVariableParameter:
+			report.Merge(path+"/expr", Check(param.Expr))
+		case *dashboard.LabelNamesQueryVariableParameter:
+			checkMatchers(report, path, param.Matchers)
+		case *dashboard.LabelValuesQueryVariableParameter:
+			checkMatchers(report, path, param.Matchers)
+		}
+	}
+}
+
+func checkAnnotations(report *common.ValidationReport, annotations map[string]*dashboard.Annotation, multiValueVariables map[string]bool) {
+	for name, annotation := range annotations {
+		if param, ok := annotation.Parameter.(*dashboard.PromQLQueryAnnotationParameter); ok {
+			report.Merge(common.JSONPointer("spec", "annotations", name, "parameter", "expr"), check(param.Expr, multiValueVariables))
+		}
+	}
+}
+
+func checkMatchers(report *common.ValidationReport, path string, matchers []string) {
+	for i, matcher := range matchers {
+		report.Merge(fmt.Sprintf("%s/matchers/%d", path, i), CheckSelector(matcher))
+	}
+}
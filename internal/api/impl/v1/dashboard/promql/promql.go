@@ -0,0 +1,291 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package promql checks the PromQL queries of the dashboards with the parser of Prometheus.
+// As the variables are only known when the dashboard is displayed, every variable used in a query is replaced by
+// a placeholder having the same length, so the position of a syntax error is the same in the query written by the user.
+package promql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// counterSuffixes are the suffixes of the names of the counters, following the naming conventions of Prometheus.
+var counterSuffixes = []string{"_total", "_count", "_sum", "_bucket"}
+
+// counterFunctions are the functions computing the rate of a counter.
+var counterFunctions = map[string]bool{
+	"rate":     true,
+	"irate":    true,
+	"increase": true,
+}
+
+// placeholder is the kind of value replacing a variable.
+type placeholder int
+
+const (
+	identifierPlaceholder placeholder = iota
+	numberPlaceholder
+	durationPlaceholder
+)
+
+// value returns the placeholder having the given length. The length of a reference to a variable is at least 2 ($a).
+func (p placeholder) value(length int) string {
+	switch p {
+	case numberPlaceholder:
+		return strings.Repeat("1", length)
+	case durationPlaceholder:
+		return strings.Repeat("0", length-2) + "1m"
+	default:
+		return strings.Repeat("_", length)
+	}
+}
+
+// reference is a variable used in a query, at the given offset.
+type reference struct {
+	interpolation.Reference
+	offset int
+}
+
+// Check parses the PromQL expression and returns a common.ValidationReport with the syntax error, prefixed by its position
+// in the expression (line:column), and with a warning for every common mistake found.
+// The paths of the report are empty, as they are relative to the expression. It returns nil when nothing is found.
+func Check(expr string) error {
+	return check(expr, nil)
+}
+
+// CheckSelector parses the series selector (e.g. `up{job="prometheus"}`) and returns a common.ValidationReport with the
+// syntax error, prefixed by its position. It returns nil when the selector is valid.
+func CheckSelector(selector string) error {
+	_, _, err := parseWithPlaceholders(selector, func(query string) error {
+		_, err := parser.ParseMetricSelector(query)
+		return err
+	})
+	return err
+}
+
+// check parses the expression and looks for the common mistakes.
+// multiValueVariables are the variables that can have several values at once.
+func check(expr string, multiValueVariables map[string]bool) error {
+	var parsed parser.Expr
+	query, refs, err := parseWithPlaceholders(expr, func(query string) error {
+		var parseErr error
+		parsed, parseErr = parser.ParseExpr(query)
+		return parseErr
+	})
+	if err != nil {
+		return err
+	}
+	var report common.ValidationReport
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.Call:
+			checkCounterFunction(&report, query, refs, n)
+		case *parser.AggregateExpr:
+			checkSum(&report, query, refs, multiValueVariables, n)
+		}
+		return nil
+	})
+	if len(report) == 0 {
+		return nil
+	}
+	return report
+}
+
+// parseWithPlaceholders replaces the variables of the query by placeholders and parses it.
+// The variables used in a range or in an offset are replaced by a duration. The other ones are replaced by an identifier
+// and, when the parser fails at the position of one of them, by a number and finally by a duration.
+// It returns the query parsed and the references to the variables, or the error of the first attempt.
+func parseWithPlaceholders(query string, parse func(query string) error) (string, []reference, error) {
+	var refs []reference
+	var placeholders []placeholder
+	if _, err := interpolation.ReplaceFunc(query, func(ref interpolation.Reference, offset int) string {
+		refs = append(refs, reference{Reference: ref, offset: offset})
+		if isDurationContext(query[:offset]) {
+			placeholders = append(placeholders, durationPlaceholder)
+		} else {
+			placeholders = append(placeholders, identifierPlaceholder)
+		}
+		return ref.Raw
+	}); err != nil {
+		return "", nil, newReport(err.Error())
+	}
+	var firstErr error
+	for {
+		i := 0
+		replaced, _ := interpolation.ReplaceFunc(query, func(ref interpolation.Reference, _ int) string {
+			value := placeholders[i].value(len(ref.Raw))
+			i++
+			return value
+		})
+		err := parse(replaced)
+		if err == nil {
+			return replaced, refs, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		// try the next kind of placeholder for the variable where the parser fails
+		next := failingReference(refs, placeholders, err)
+		if next < 0 {
+			return "", nil, parseErrorReport(firstErr)
+		}
+		placeholders[next]++
+	}
+}
+
+// failingReference returns the index of the variable used at the position of the parse error that can still be replaced
+// by another kind of placeholder, or -1.
+func failingReference(refs []reference, placeholders []placeholder, err error) int {
+	var parseErrs parser.ParseErrors
+	if !errors.As(err, &parseErrs) || len(parseErrs) == 0 {
+		return -1
+	}
+	positionRange := parseErrs[0].PositionRange
+	for i, ref := range refs {
+		if placeholders[i] == durationPlaceholder {
+			continue
+		}
+		if parser.Pos(ref.offset) < positionRange.End && parser.Pos(ref.offset+len(ref.Raw)) > positionRange.Start {
+			return i
+		}
+	}
+	return -1
+}
+
+// isDurationContext returns true when a variable used after the given prefix of a query is expected to be a duration,
+// i.e. when it is used in a range (e.g. [5m] or [1h:5m]) or after the keyword offset.
+func isDurationContext(prefix string) bool {
+	var brackets []rune
+	var quote rune
+	escaped := false
+	for _, c := range prefix {
+		switch {
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if c == '\\' && quote != '`' {
+				escaped = true
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			brackets = append(brackets, c)
+		case (c == ')' || c == ']' || c == '}') && len(brackets) > 0:
+			brackets = brackets[:len(brackets)-1]
+		}
+	}
+	if quote != 0 {
+		return false
+	}
+	if len(brackets) > 0 && brackets[len(brackets)-1] == '[' {
+		return true
+	}
+	fields := strings.Fields(prefix)
+	return len(fields) > 0 && strings.EqualFold(fields[len(fields)-1], "offset")
+}
+
+// parseErrorReport converts the error returned by the parser, so it is reported with its position.
+// Only the first error is kept, as the next ones are usually a consequence of it.
+func parseErrorReport(err error) error {
+	var parseErrs parser.ParseErrors
+	if !errors.As(err, &parseErrs) || len(parseErrs) == 0 {
+		return newReport(err.Error())
+	}
+	message := parseErrs[0].Error()
+	if strings.Contains(parseErrs[0].Err.Error(), "expected type range vector") {
+		message += ", a range selector like [5m] is missing"
+	}
+	return newReport(message)
+}
+
+func newReport(message string) common.ValidationReport {
+	var report common.ValidationReport
+	report.AddError("", "%s", message)
+	return report
+}
+
+// checkCounterFunction warns when a function like rate() is used with a metric not looking like a counter.
+func checkCounterFunction(report *common.ValidationReport, query string, refs []reference, call *parser.Call) {
+	if !counterFunctions[call.Func.Name] || len(call.Args) == 0 {
+		return
+	}
+	matrix, ok := call.Args[0].(*parser.MatrixSelector)
+	if !ok {
+		return
+	}
+	selector, ok := matrix.VectorSelector.(*parser.VectorSelector)
+	if !ok || len(selector.Name) == 0 || hasReference(refs, selector.PosRange.Start, selector.PosRange.Start+parser.Pos(len(selector.Name))) {
+		return
+	}
+	for _, suffix := range counterSuffixes {
+		if strings.HasSuffix(selector.Name, suffix) {
+			return
+		}
+	}
+	report.AddWarning("", "%s %s() is computing the rate of a counter, but %q looks like a gauge as its name doesn't end with %s",
+		position(query, selector.PosRange.Start), call.Func.Name, selector.Name, strings.Join(counterSuffixes, ", "))
+}
+
+// checkSum warns when sum() without by() or without() is merging the series of a variable having several values.
+// Such a panel is meant to display a series by value selected, while the sum is returning a single series.
+func checkSum(report *common.ValidationReport, query string, refs []reference, multiValueVariables map[string]bool, aggregate *parser.AggregateExpr) {
+	if aggregate.Op != parser.SUM || len(aggregate.Grouping) > 0 || aggregate.Without {
+		return
+	}
+	positionRange := aggregate.Expr.PositionRange()
+	var names []string
+	for _, ref := range refs {
+		if multiValueVariables[ref.Name] && parser.Pos(ref.offset) >= positionRange.Start && parser.Pos(ref.offset) < positionRange.End {
+			names = append(names, ref.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	report.AddWarning("", "%s sum() without by() is merging the series of every value selected for the variable %q into a single series",
+		position(query, aggregate.PosRange.Start), names[0])
+}
+
+// hasReference returns true when a variable is used between the positions start and end of the query.
+func hasReference(refs []reference, start parser.Pos, end parser.Pos) bool {
+	for _, ref := range refs {
+		if parser.Pos(ref.offset) < end && parser.Pos(ref.offset+len(ref.Raw)) > start {
+			return true
+		}
+	}
+	return false
+}
+
+// position returns the position in the query formatted like in the errors of the parser: line:column:
+func position(query string, pos parser.Pos) string {
+	line := 1
+	lastLineBreak := -1
+	for i, c := range query[:pos] {
+		if c == '\n' {
+			lastLineBreak = i
+			line++
+		}
+	}
+	return fmt.Sprintf("%d:%d:", line, int(pos)-lastLineBreak)
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	testSuites := []struct {
+		title               string
+		expr                string
+		multiValueVariables map[string]bool
+		result              common.ValidationReport
+	}{
+		{
+			title: "valid query",
+			expr:  `sum by (instance) (rate(node_cpu_seconds_total{mode!="idle"}[5m]))`,
+		},
+		{
+			title: "variables used as label values, range, offset and grouping",
+			expr:  `sum by ($label) (rate(http_requests_total{job="$job",instance=~"${instance:regex}"}[$__rate_interval] offset $offset))`,
+		},
+		{
+			title: "variables used as a number and as a metric",
+			expr:  `topk($n, rate($metric[5m]))`,
+		},
+		{
+			title: "variables used in a subquery",
+			expr:  `max_over_time(up[$__range:$__interval])`,
+		},
+		{
+			title: "syntax error with its position",
+			expr:  "up{job=\"a\"\n} +",
+			result: common.ValidationReport{
+				{Severity: common.SeverityError, Message: "2:4: parse error: unexpected end of input"},
+			},
+		},
+		{
+			title: "unknown format",
+			expr:  `up{job="${job:unknown}"}`,
+			result: common.ValidationReport{
+				{Severity: common.SeverityError, Message: `unknown format "unknown" used for the variable "job"`},
+			},
+		},
+		{
+			title: "missing range selector",
+			expr:  `rate(http_requests_total)`,
+			result: common.ValidationReport{
+				{Severity: common.SeverityError, Message: `1:6: parse error: expected type range vector in call to function "rate", got instant vector, a range selector like [5m] is missing`},
+			},
+		},
+		{
+			title: "rate of a gauge",
+			expr:  `rate(node_memory_free_bytes[5m])`,
+			result: common.ValidationReport{
+				{Severity: common.SeverityWarning, Message: `1:6: rate() is computing the rate of a counter, but "node_memory_free_bytes" looks like a gauge as its name doesn't end with _total, _count, _sum, _bucket`},
+			},
+		},
+		{
+			title:               "sum without by of a multi-value variable",
+			expr:                `sum(rate(http_requests_total{instance=~"$instance"}[5m]))`,
+			multiValueVariables: map[string]bool{"instance": true},
+			result: common.ValidationReport{
+				{Severity: common.SeverityWarning, Message: `1:1: sum() without by() is merging the series of every value selected for the variable "instance" into a single series`},
+			},
+		},
+		{
+			title:               "sum by of a multi-value variable",
+			expr:                `sum by (instance) (rate(http_requests_total{instance=~"$instance"}[5m]))`,
+			multiValueVariables: map[string]bool{"instance": true},
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			err := check(test.expr, test.multiValueVariables)
+			if test.result == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, test.result, err)
+			}
+		})
+	}
+}
+
+func TestCheckSelector(t *testing.T) {
+	assert.NoError(t, CheckSelector(`up{job="$job"}`))
+	assert.Equal(t, common.ValidationReport{
+		{Severity: common.SeverityError, Message: "1:8: parse error: unexpected end of input inside braces"},
+	}, CheckSelector(`up{job=`))
+}
+
+func TestCheckDashboard(t *testing.T) {
+	spec := &v1.DashboardSpec{
+		Variables: map[string]*dashboard.Variable{
+			"instance": {
+				Kind:  dashboard.KindLabelValuesQueryVariable,
+				Multi: true,
+				Parameter: &dashboard.LabelValuesQueryVariableParameter{
+					LabelName: "instance",
+					Matchers:  []string{`up{job="$job"}`, `up{`},
+				},
+			},
+			"job": {
+				Kind:      dashboard.KindPromQLQueryVariable,
+				Parameter: &dashboard.PromQLQueryVariableParameter{Expr: `group by (job) (up`},
+			},
+		},
+		Annotations: map[string]*dashboard.Annotation{
+			"deployments": {
+				Kind:      dashboard.KindPromQLQueryAnnotation,
+				Parameter: &dashboard.PromQLQueryAnnotationParameter{Expr: `changes(kube_deployment_status_observed_generation) > 0`},
+			},
+		},
+		Panels: map[string]json.RawMessage{
+			"cpu": json.RawMessage(`{"kind":"LineChart","options":{"queries":[
+				{"kind":"PrometheusGraphQuery","options":{"query":"sum(rate(node_cpu_seconds_total{instance=~\"$instance\"}[5m]))"}},
+				{"kind":"PrometheusGraphQuery","options":{"query":"rate(node_cpu_seconds_total)"}}
+			]}}`),
+			"text": json.RawMessage(`{"kind":"Markdown","options":{"text":"rate("}}`),
+		},
+	}
+	assert.Equal(t, common.ValidationReport{
+		{Path: "/spec/annotations/deployments/parameter/expr", Severity: common.SeverityError, Message: "1:9: parse error: expected type range vector in call to function \"changes\", got instant vector, a range selector like [5m] is missing"},
+		{Path: "/spec/panels/cpu/options/queries/0/options/query", Severity: common.SeverityWarning, Message: `1:1: sum() without by() is merging the series of every value selected for the variable "instance" into a single series`},
+		{Path: "/spec/panels/cpu/options/queries/1/options/query", Severity: common.SeverityError, Message: `1:6: parse error: expected type range vector in call to function "rate", got instant vector, a range selector like [5m] is missing`},
+		{Path: "/spec/variables/instance/parameter/matchers/1", Severity: common.SeverityError, Message: "1:4: parse error: unexpected end of input inside braces"},
+		{Path: "/spec/variables/job/parameter/expr", Severity: common.SeverityError, Message: "1:19: parse error: unclosed left parenthesis"},
+	}, CheckDashboard(spec))
+}
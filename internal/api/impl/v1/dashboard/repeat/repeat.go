@@ -0,0 +1,351 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package repeat expands the panels and the rows repeated by the values of a variable,
+// so the UI, the exporters and the renderers are all placing the copies the same way.
+package repeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+)
+
+type expander struct {
+	source   *v1.DashboardSpec
+	selected map[string]dashboard.Selection
+	// values is the values used for each variable repeating a panel or a row.
+	values map[string][]string
+	// panels is the panels of the expanded dashboard.
+	panels map[string]json.RawMessage
+	// repeated is the panels that have been copied. They are removed from the expanded dashboard when no layout is using them anymore.
+	repeated map[string]bool
+}
+
+// Expand returns a copy of the dashboard spec where every repeated panel and every repeated row is replaced by one copy per value selected for its variable.
+// The values are taken from selected, or from the default selection of the variable when it is omitted.
+//
+// Each copy of a panel is a new panel named "<panel>-<index>", in which the variable is replaced by its value.
+// The copies of a panel repeated horizontally are placed on the same line, in the space at the right of the item,
+// and continue on a new line every max_per_row copies. The copies of a panel repeated vertically are placed one below the other.
+// In both cases, the items below are moved down to leave room for the copies.
+// Each copy of a row has its title and its panels interpolated with its value.
+// The spec in parameter is not modified.
+func Expand(spec *v1.DashboardSpec, selected map[string]dashboard.Selection) (*v1.DashboardSpec, error) {
+	e := &expander{
+		source:   spec,
+		selected: selected,
+		values:   make(map[string][]string),
+		panels:   make(map[string]json.RawMessage, len(spec.Panels)),
+		repeated: make(map[string]bool),
+	}
+	for key, panel := range spec.Panels {
+		e.panels[key] = panel
+	}
+	result := &v1.DashboardSpec{
+		Datasource:  spec.Datasource,
+		Duration:    spec.Duration,
+		Time:        spec.Time,
+		Annotations: spec.Annotations,
+		Panels:      e.panels,
+		Layouts:     make([]dashboard.Layout, 0, len(spec.Layouts)),
+	}
+	for i, layout := range spec.Layouts {
+		expanded, err := e.expandLayout(layout)
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand the layout %d: %w", i, err)
+		}
+		result.Layouts = append(result.Layouts, expanded)
+	}
+	e.removeUnusedCopiedPanels(result)
+	if spec.Variables != nil {
+		result.Variables = make(map[string]*dashboard.Variable, len(spec.Variables))
+		for name, variable := range spec.Variables {
+			if values, ok := e.values[name]; ok {
+				copied := *variable
+				copied.Selected = values
+				variable = &copied
+			}
+			result.Variables[name] = variable
+		}
+	}
+	return result, nil
+}
+
+func (e *expander) expandLayout(layout dashboard.Layout) (dashboard.Layout, error) {
+	switch spec := layout.Spec.(type) {
+	case *dashboard.GridLayoutSpec:
+		items, err := e.expandItems(spec.Items, nil)
+		if err != nil {
+			return layout, err
+		}
+		return dashboard.Layout{Kind: layout.Kind, Spec: &dashboard.GridLayoutSpec{Display: spec.Display, Items: items}}, nil
+	case *dashboard.RowsLayoutSpec:
+		rows := make([]dashboard.RowLayout, 0, len(spec.Rows))
+		for _, row := range spec.Rows {
+			expanded, err := e.expandRow(row)
+			if err != nil {
+				return layout, err
+			}
+			rows = append(rows, expanded...)
+		}
+		return dashboard.Layout{Kind: layout.Kind, Spec: &dashboard.RowsLayoutSpec{Rows: rows}}, nil
+	case *dashboard.TabsLayoutSpec:
+		tabs := make([]dashboard.TabLayout, 0, len(spec.Tabs))
+		for _, tab := range spec.Tabs {
+			items, err := e.expandItems(tab.Items, nil)
+			if err != nil {
+				return layout, err
+			}
+			tabs = append(tabs, dashboard.TabLayout{Title: tab.Title, Items: items})
+		}
+		return dashboard.Layout{Kind: layout.Kind, Spec: &dashboard.TabsLayoutSpec{Tabs: tabs}}, nil
+	}
+	// the other layouts don't support the repetition
+	return layout, nil
+}
+
+func (e *expander) expandRow(row dashboard.RowLayout) ([]dashboard.RowLayout, error) {
+	if row.Repeat == nil {
+		items, err := e.expandItems(row.Items, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []dashboard.RowLayout{{Title: row.Title, Collapse: row.Collapse, Items: items}}, nil
+	}
+	values, err := e.variableValues(row.Repeat.Variable)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dashboard.RowLayout, 0, len(values))
+	for _, value := range values {
+		bound := map[string]interpolation.Value{row.Repeat.Variable: interpolation.SingleValue(value)}
+		title, err := interpolation.Interpolate(row.Title, bound)
+		if err != nil {
+			return nil, err
+		}
+		items, err := e.expandItems(row.Items, bound)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dashboard.RowLayout{Title: title, Collapse: row.Collapse, Items: items})
+	}
+	return result, nil
+}
+
+// expandItems replaces every repeated item of the grid by its copies, keeping the order of the items.
+// When bound is not empty, the grid is inside a repeated row and every panel is copied with the value of the row.
+func (e *expander) expandItems(items []dashboard.GridItem, bound map[string]interpolation.Value) ([]dashboard.GridItem, error) {
+	// grid contains, for each item, the item itself or its copies once it has been expanded.
+	grid := make([][]dashboard.GridItem, 0, len(items))
+	var pending []int
+	for i, item := range items {
+		if item.Repeat != nil {
+			pending = append(pending, i)
+		} else if len(bound) > 0 {
+			content, err := e.copyPanel(item.Content, bound)
+			if err != nil {
+				return nil, err
+			}
+			item.Content = content
+		}
+		grid = append(grid, []dashboard.GridItem{item})
+	}
+	// The repeated items are expanded from the top to the bottom,
+	// so an item moved down by a repetition is expanded at its new position.
+	for len(pending) > 0 {
+		sort.SliceStable(pending, func(i, j int) bool {
+			a, b := grid[pending[i]][0], grid[pending[j]][0]
+			if a.Y != b.Y {
+				return a.Y < b.Y
+			}
+			return a.X < b.X
+		})
+		index := pending[0]
+		pending = pending[1:]
+		item := grid[index][0]
+		copies, err := e.repeatItem(item, bound)
+		if err != nil {
+			return nil, err
+		}
+		bottom := item.Y + item.Height
+		extraHeight := 0
+		for _, c := range copies {
+			if c.Y+c.Height-bottom > extraHeight {
+				extraHeight = c.Y + c.Height - bottom
+			}
+		}
+		for i := range grid {
+			for j := range grid[i] {
+				if i != index && grid[i][j].Y >= bottom {
+					grid[i][j].Y += extraHeight
+				}
+			}
+		}
+		grid[index] = copies
+	}
+	result := make([]dashboard.GridItem, 0, len(items))
+	for _, expanded := range grid {
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
+
+// repeatItem returns the copies of the repeated item, one per value of its variable.
+func (e *expander) repeatItem(item dashboard.GridItem, bound map[string]interpolation.Value) ([]dashboard.GridItem, error) {
+	values, err := e.variableValues(item.Repeat.Variable)
+	if err != nil {
+		return nil, err
+	}
+	perRow := len(values)
+	width := item.Width
+	if item.Repeat.Direction != dashboard.RepeatDirectionVertical {
+		if item.Repeat.MaxPerRow > 0 && item.Repeat.MaxPerRow < perRow {
+			perRow = item.Repeat.MaxPerRow
+		}
+		available := dashboard.GridColumns - item.X
+		if perRow > available {
+			perRow = available
+		}
+		if perRow > 0 {
+			width = available / perRow
+		}
+	}
+	result := make([]dashboard.GridItem, 0, len(values))
+	for i, value := range values {
+		variables := map[string]interpolation.Value{item.Repeat.Variable: interpolation.SingleValue(value)}
+		for name, v := range bound {
+			variables[name] = v
+		}
+		content, err := e.copyPanel(item.Content, variables)
+		if err != nil {
+			return nil, err
+		}
+		c := dashboard.GridItem{X: item.X, Y: item.Y + i*item.Height, Width: width, Height: item.Height, Content: content}
+		if item.Repeat.Direction != dashboard.RepeatDirectionVertical {
+			c.X = item.X + (i%perRow)*width
+			c.Y = item.Y + (i/perRow)*item.Height
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// copyPanel creates a copy of the panel referenced, where the variables are replaced by their value, and returns the reference to this copy.
+func (e *expander) copyPanel(ref *common.JSONRef, variables map[string]interpolation.Value) (*common.JSONRef, error) {
+	if ref == nil || len(ref.Path) != 3 {
+		return ref, nil
+	}
+	key := ref.Path[2]
+	panel, ok := e.source.Panels[key]
+	if !ok {
+		return ref, nil
+	}
+	var content interface{}
+	if err := json.Unmarshal(panel, &content); err != nil {
+		return nil, err
+	}
+	content, err := interpolateStrings(content, variables)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy the panel %q: %w", key, err)
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	newKey := e.newPanelKey(key)
+	e.panels[newKey] = data
+	e.repeated[key] = true
+	return &common.JSONRef{
+		Ref:  fmt.Sprintf("#/spec/panels/%s", newKey),
+		Path: []string{"spec", "panels", newKey},
+	}, nil
+}
+
+func (e *expander) newPanelKey(key string) string {
+	for i := 0; ; i++ {
+		newKey := fmt.Sprintf("%s-%d", key, i)
+		if _, exist := e.panels[newKey]; !exist {
+			return newKey
+		}
+	}
+}
+
+// variableValues returns the values used to repeat a panel or a row with the variable.
+func (e *expander) variableValues(name string) ([]string, error) {
+	if values, ok := e.values[name]; ok {
+		return values, nil
+	}
+	variable, ok := e.source.Variables[name]
+	if !ok {
+		return nil, fmt.Errorf("the variable %q used to repeat doesn't exist", name)
+	}
+	selection, ok := e.selected[name]
+	if !ok {
+		selection = variable.Selected
+	}
+	if len(selection) == 0 || selection.IsAll() {
+		return nil, fmt.Errorf("the values of the variable %q used to repeat are unknown, they must be selected in the request", name)
+	}
+	if len(selection) > 1 && !variable.Multi {
+		return nil, fmt.Errorf("several values are selected for the variable %q but it doesn't accept several values", name)
+	}
+	values := []string(selection)
+	e.values[name] = values
+	return values, nil
+}
+
+// removeUnusedCopiedPanels removes the panels that have been copied and that are not used anymore by any layout.
+func (e *expander) removeUnusedCopiedPanels(spec *v1.DashboardSpec) {
+	used := make(map[string]bool)
+	for _, ref := range spec.LayoutReferences() {
+		if ref.Ref != nil && len(ref.Ref.Path) == 3 {
+			used[ref.Ref.Path[2]] = true
+		}
+	}
+	for key := range e.repeated {
+		if !used[key] {
+			delete(e.panels, key)
+		}
+	}
+}
+
+// interpolateStrings replaces the variables in every string of the JSON value.
+func interpolateStrings(value interface{}, variables map[string]interpolation.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return interpolation.Interpolate(v, variables)
+	case []interface{}:
+		for i, item := range v {
+			newItem, err := interpolateStrings(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = newItem
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			newItem, err := interpolateStrings(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = newItem
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repeat
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	testSuites := []struct {
+		title    string
+		spec     string
+		selected map[string]dashboard.Selection
+		result   string
+	}{
+		{
+			title: "panel repeated horizontally moves down the items below",
+			spec: `{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus"},
+  "duration": "6h",
+  "variables": {
+    "instance": {"kind": "Constant", "hide": true, "multi": true, "selected": ["a"], "parameter": {"values": ["a", "b", "c"]}}
+  },
+  "panels": {
+    "CPU": {"kind": "LineChart", "display": {"name": "CPU $instance"}},
+    "Memory": {"kind": "LineChart"}
+  },
+  "layouts": [
+    {"kind": "Grid", "spec": {"items": [
+      {"x": 0, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/CPU"}, "repeat": {"variable": "instance", "max_per_row": 2}},
+      {"x": 0, "y": 4, "width": 24, "height": 6, "content": {"$ref": "#/spec/panels/Memory"}}
+    ]}}
+  ]
+}`,
+			selected: map[string]dashboard.Selection{"instance": {"a", "b", "c"}},
+			result: `{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus", "global": false},
+  "duration": "6h",
+  "variables": {
+    "instance": {"kind": "Constant", "hide": true, "multi": true, "selected": ["a", "b", "c"], "parameter": {"values": ["a", "b", "c"]}}
+  },
+  "panels": {
+    "CPU-0": {"kind": "LineChart", "display": {"name": "CPU a"}},
+    "CPU-1": {"kind": "LineChart", "display": {"name": "CPU b"}},
+    "CPU-2": {"kind": "LineChart", "display": {"name": "CPU c"}},
+    "Memory": {"kind": "LineChart"}
+  },
+  "layouts": [
+    {"kind": "Grid", "spec": {"items": [
+      {"x": 0, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/CPU-0"}},
+      {"x": 12, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/CPU-1"}},
+      {"x": 0, "y": 4, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/CPU-2"}},
+      {"x": 0, "y": 8, "width": 24, "height": 6, "content": {"$ref": "#/spec/panels/Memory"}}
+    ]}}
+  ]
+}`,
+		},
+		{
+			title: "row repeated with the default selection and panel repeated vertically",
+			spec: `{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus"},
+  "duration": "6h",
+  "variables": {
+    "job": {"kind": "Constant", "hide": true, "multi": true, "selected": ["api", "db"], "parameter": {"values": ["api", "db"]}},
+    "instance": {"kind": "Constant", "hide": true, "include_all": true, "selected": "x", "parameter": {"values": ["x"]}}
+  },
+  "panels": {
+    "Up": {"kind": "LineChart", "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "up{job=\"$job\", instance=~\"$instance\"}"}}]}},
+    "Load": {"kind": "LineChart", "display": {"name": "$job on $instance"}}
+  },
+  "layouts": [
+    {"kind": "Rows", "spec": {"rows": [
+      {"title": "Job $job", "repeat": {"variable": "job"}, "items": [
+        {"x": 0, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/Up"}},
+        {"x": 12, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/Load"}, "repeat": {"variable": "instance", "direction": "vertical"}}
+      ]}
+    ]}}
+  ]
+}`,
+			result: `{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus", "global": false},
+  "duration": "6h",
+  "variables": {
+    "job": {"kind": "Constant", "hide": true, "multi": true, "selected": ["api", "db"], "parameter": {"values": ["api", "db"]}},
+    "instance": {"kind": "Constant", "hide": true, "include_all": true, "selected": "x", "parameter": {"values": ["x"]}}
+  },
+  "panels": {
+    "Up-0": {"kind": "LineChart", "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "up{job=\"api\", instance=~\"$instance\"}"}}]}},
+    "Up-1": {"kind": "LineChart", "options": {"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "up{job=\"db\", instance=~\"$instance\"}"}}]}},
+    "Load-0": {"kind": "LineChart", "display": {"name": "api on x"}},
+    "Load-1": {"kind": "LineChart", "display": {"name": "db on x"}}
+  },
+  "layouts": [
+    {"kind": "Rows", "spec": {"rows": [
+      {"title": "Job api", "items": [
+        {"x": 0, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/Up-0"}},
+        {"x": 12, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/Load-0"}}
+      ]},
+      {"title": "Job db", "items": [
+        {"x": 0, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/Up-1"}},
+        {"x": 12, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/Load-1"}}
+      ]}
+    ]}}
+  ]
+}`,
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			spec := &v1.DashboardSpec{}
+			assert.NoError(t, json.Unmarshal([]byte(test.spec), spec))
+			result, err := Expand(spec, test.selected)
+			if assert.NoError(t, err) {
+				data, err := json.Marshal(result)
+				assert.NoError(t, err)
+				assert.JSONEq(t, test.result, string(data))
+			}
+		})
+	}
+}
+
+func TestExpandError(t *testing.T) {
+	testSuites := []struct {
+		title    string
+		selected map[string]dashboard.Selection
+		err      string
+	}{
+		{
+			title: "every value selected by default",
+			err:   `unable to expand the layout 0: the values of the variable "instance" used to repeat are unknown, they must be selected in the request`,
+		},
+		{
+			title:    "every value selected in the request",
+			selected: map[string]dashboard.Selection{"instance": {dashboard.AllSelection}},
+			err:      `unable to expand the layout 0: the values of the variable "instance" used to repeat are unknown, they must be selected in the request`,
+		},
+	}
+	spec := &v1.DashboardSpec{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+  "datasource": {"name": "PrometheusDemo", "kind": "Prometheus"},
+  "duration": "6h",
+  "variables": {
+    "instance": {"kind": "Constant", "hide": true, "multi": true, "include_all": true, "selected": ["$__all"], "parameter": {"values": ["a", "b"]}}
+  },
+  "panels": {
+    "CPU": {"kind": "LineChart"}
+  },
+  "layouts": [
+    {"kind": "Grid", "spec": {"items": [
+      {"x": 0, "y": 0, "width": 12, "height": 4, "content": {"$ref": "#/spec/panels/CPU"}, "repeat": {"variable": "instance"}}
+    ]}}
+  ]
+}`), spec))
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := Expand(spec, test.selected)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/perses/perses/internal/api/shared"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+)
+
+// registerCustomRoutes is called by the generated method RegisterRoutes to add the endpoints that are specific to the dashboards.
+func (e *Endpoint) registerCustomRoutes(_ *echo.Group, subGroup *echo.Group) {
+	subGroup.GET(fmt.Sprintf("/:%s/datasources", shared.ParamName), e.ResolveDatasources)
+	subGroup.POST(fmt.Sprintf("/:%s/variables/evaluate", shared.ParamName), e.EvaluateVariables)
+	subGroup.POST(fmt.Sprintf("/:%s/annotations/evaluate", shared.ParamName), e.EvaluateAnnotations)
+	subGroup.POST(fmt.Sprintf("/:%s/expand", shared.ParamName), e.Expand)
+}
+
+// ResolveDatasources returns the datasource used by every query of the dashboard.
+func (e *Endpoint) ResolveDatasources(ctx echo.Context) error {
+	parameters := shared.Parameters{
+		Project: ctx.Param(shared.ParamProject),
+		Name:    ctx.Param(shared.ParamName),
+	}
+	result, err := e.service.ResolveDatasources(parameters)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// EvaluateVariables computes the list of values of every variable of the dashboard.
+func (e *Endpoint) EvaluateVariables(ctx echo.Context) error {
+	parameters := shared.Parameters{
+		Project: ctx.Param(shared.ParamProject),
+		Name:    ctx.Param(shared.ParamName),
+	}
+	request := dashboard.VariableEvaluationRequest{}
+	if err := ctx.Bind(&request); err != nil {
+		return shared.HandleError(fmt.Errorf("%w: %s", shared.BadRequestError, err))
+	}
+	result, err := e.service.EvaluateVariables(ctx.Request().Context(), parameters, request)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// EvaluateAnnotations computes the events of every annotation of the dashboard.
+func (e *Endpoint) EvaluateAnnotations(ctx echo.Context) error {
+	parameters := shared.Parameters{
+		Project: ctx.Param(shared.ParamProject),
+		Name:    ctx.Param(shared.ParamName),
+	}
+	request := dashboard.AnnotationEvaluationRequest{}
+	if err := ctx.Bind(&request); err != nil {
+		return shared.HandleError(fmt.Errorf("%w: %s", shared.BadRequestError, err))
+	}
+	result, err := e.service.EvaluateAnnotations(ctx.Request().Context(), parameters, request)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// Expand returns the dashboard where the repeated panels and rows are replaced by their copies.
+func (e *Endpoint) Expand(ctx echo.Context) error {
+	parameters := shared.Parameters{
+		Project: ctx.Param(shared.ParamProject),
+		Name:    ctx.Param(shared.ParamName),
+	}
+	request := dashboard.ExpansionRequest{}
+	if err := ctx.Bind(&request); err != nil {
+		return shared.HandleError(fmt.Errorf("%w: %s", shared.BadRequestError, err))
+	}
+	result, err := e.service.Expand(parameters, request)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
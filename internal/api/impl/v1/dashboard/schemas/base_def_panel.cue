@@ -14,20 +14,44 @@
 package base
 
 #panel: close({
-	kind:        string
-	display:     #display
-	datasource?: #datasource
-	options:     _
+	kind:            string
+	schema_version?: int & >=1
+	display:         #display
+	datasource?:     #datasource
+	time?:           #time
+	options:         _
 })
 
+// #version is the version of the plugin schema, to increase each time the schema changes in a breaking way.
+#version: int & >=1 | *1
+
+// #migrations upgrades a panel from the version used as key to the next version.
+// The panel to upgrade is provided in #from, and the panel upgraded is read from to.
+#migrations: [string]: {
+	#from: _
+	to:    _
+}
+
 #display: {
 	name:         string
 	description?: string
 }
 
 #datasource: {
-	kind: string
-	key?: string
+	kind:    string
+	key?:    string
+	name?:   string
+	global?: bool
 }
 
+// #time overrides the time range of the dashboard for the panel.
+#time: {
+	// relative replaces the duration of the dashboard, the end of the time range being kept.
+	relative?: #duration
+	// shift moves the time range back in time, e.g "1w" to compare with the previous week.
+	shift?: #duration
+}
+
+#duration: =~"^(?:(\\d+)y)?(?:(\\d+)w)?(?:(\\d+)d)?(?:(\\d+)h)?(?:(\\d+)m)?(?:(\\d+)s)?(?:(\\d+)ms)?$" & !=""
+
 #query: _
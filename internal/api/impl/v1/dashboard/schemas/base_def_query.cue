@@ -14,10 +14,13 @@
 package base
 
 #datasource: {
-	kind: string
+	kind:    string
+	name?:   string
+	global?: bool
 }
 
 #query: {
-	kind: string
+	kind:        string
+	datasource?: #datasource
 	options: {...}
 }
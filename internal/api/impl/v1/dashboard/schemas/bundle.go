@@ -0,0 +1,174 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/prometheus/common/version"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// BundleExtension is the extension of the plugin bundles
+	BundleExtension = ".tar.gz"
+	manifestFile    = "manifest.yaml"
+)
+
+// ListBundles returns the path of every plugin bundle found in the folder, sorted by name.
+func ListBundles(pluginsPath string) ([]string, error) {
+	files, err := os.ReadDir(pluginsPath)
+	if err != nil {
+		return nil, err
+	}
+	var bundles []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), BundleExtension) {
+			bundles = append(bundles, filepath.Join(pluginsPath, file.Name()))
+		}
+	}
+	sort.Strings(bundles)
+	return bundles, nil
+}
+
+// ReadBundleManifest returns the manifest of the plugin bundle.
+// An error is returned when the bundle requires a more recent version of Perses.
+func ReadBundleManifest(bundlePath string) (*v1.PluginManifest, error) {
+	var manifest *v1.PluginManifest
+	err := walkBundle(bundlePath, func(name string, header *tar.Header, content io.Reader) (bool, error) {
+		if name != manifestFile {
+			return false, nil
+		}
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return false, err
+		}
+		manifest = &v1.PluginManifest{}
+		if err := yaml.Unmarshal(data, manifest); err != nil {
+			return false, fmt.Errorf("invalid %s: %s", manifestFile, err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s not found", manifestFile)
+	}
+	if err := checkCompatibility(manifest, version.Version); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// checkCompatibility returns an error when the bundle requires a version of Perses more recent than persesVersion.
+// The check is skipped when the version of Perses is unknown, e.g. for a development build.
+func checkCompatibility(manifest *v1.PluginManifest, persesVersion string) error {
+	if len(manifest.MinPersesVersion) == 0 {
+		return nil
+	}
+	minVersion, err := semver.NewVersion(strings.TrimPrefix(manifest.MinPersesVersion, "v"))
+	if err != nil {
+		return fmt.Errorf("invalid min_perses_version: %s", err)
+	}
+	current, err := semver.NewVersion(strings.TrimPrefix(persesVersion, "v"))
+	if err != nil {
+		return nil
+	}
+	if current.LessThan(*minVersion) {
+		return fmt.Errorf("the plugin bundle %q requires Perses %s or later, the current version is %s", manifest.Name, minVersion, current)
+	}
+	return nil
+}
+
+// JoinUnder joins the name to the folder dir, like filepath.Join, and returns an error when the result is not a path
+// inside dir, e.g. when the name contains "..".
+func JoinUnder(dir string, name string) (string, error) {
+	result := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, result)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the path %q is outside of the folder %s", name, dir)
+	}
+	return result, nil
+}
+
+// extractBundle extracts the folder of the category from the plugin bundle into dir.
+// It returns false when the bundle doesn't contain any file for the category.
+func extractBundle(bundlePath string, category string, dir string) (bool, error) {
+	found := false
+	err := walkBundle(bundlePath, func(name string, header *tar.Header, content io.Reader) (bool, error) {
+		if !strings.HasPrefix(name, category+"/") || header.Typeflag != tar.TypeReg {
+			return false, nil
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, category+"/")))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return false, err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return false, err
+		}
+		defer file.Close()
+		if _, err := io.Copy(file, content); err != nil {
+			return false, err
+		}
+		found = true
+		return false, nil
+	})
+	return found, err
+}
+
+// walkBundle calls f on every entry of the plugin bundle, until f returns true or an error.
+// The name given to f is the path of the entry in the archive, cleaned. An entry going outside the archive is an error.
+func walkBundle(bundlePath string, f func(name string, header *tar.Header, content io.Reader) (bool, error)) error {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid path %q in the plugin bundle", header.Name)
+		}
+		stop, err := f(name, header, tarReader)
+		if err != nil || stop {
+			return err
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/perses/perses/internal/api/config"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+const acmeChart = `
+package acme
+
+#panel: {
+	kind:       "AcmeChart"
+	datasource: #datasource
+	options: {
+		queries: [...#query]
+		color: string
+	}
+}
+
+#datasource: _
+#query:      _
+`
+
+const acmeQuery = `
+package acme
+
+#datasource: {
+	kind: "AcmeDatasource"
+}
+
+#query: {
+	kind: "AcmeQuery"
+	options: {
+		metric: string
+	}
+}
+`
+
+// writeBundle creates in dir the plugin bundle fileName, made of the files provided indexed by their path in the archive.
+func writeBundle(t *testing.T, dir string, fileName string, files map[string]string) string {
+	bundlePath := filepath.Join(dir, fileName)
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bundlePath
+}
+
+func TestLoadBundles(t *testing.T) {
+	pluginsPath := t.TempDir()
+	writeBundle(t, pluginsPath, "acme.tar.gz", map[string]string{
+		"manifest.yaml": `
+name: acme
+version: 1.0.0
+min_perses_version: 0.1.0
+kinds:
+  panels: [AcmeChart]
+  queries: [AcmeDatasource]
+`,
+		"panels/acme/acme.cue":  acmeChart,
+		"queries/acme/acme.cue": acmeQuery,
+	})
+	validator := NewValidator(config.Schemas{
+		PanelsPath:  "testdata/panels",
+		QueriesPath: "testdata/queries",
+		PluginsPath: pluginsPath,
+	})
+	validator.LoadPanels()
+	validator.LoadQueries()
+
+	var bundled []*v1.SchemaPlugin
+	for _, plugin := range append(validator.GetPanels(), validator.GetQueries()...) {
+		if len(plugin.Bundle) > 0 {
+			bundled = append(bundled, plugin)
+		}
+	}
+	if assert.Len(t, bundled, 2) {
+		assert.Equal(t, "AcmeChart", bundled[0].Kind)
+		assert.Equal(t, "acme@1.0.0", bundled[0].Bundle)
+		assert.Equal(t, "AcmeDatasource", bundled[1].Kind)
+		assert.Equal(t, "acme@1.0.0", bundled[1].Bundle)
+	}
+	assert.Empty(t, validator.GetStatus().Errors)
+
+	panel := json.RawMessage(`
+		{
+			"kind": "AcmeChart",
+			"display": {
+				"name": "acme"
+			},
+			"datasource": {
+				"kind": "AcmeDatasource"
+			},
+			"options": {
+				"queries": [
+					{
+						"kind": "AcmeQuery",
+						"options": {
+							"metric": "up"
+						}
+					}
+				],
+				"color": "red"
+			}
+		}
+	`)
+	assert.NoError(t, validator.Validate(map[string]json.RawMessage{"MyPanel": panel}))
+
+	// the plugins extracted from the previous loading are cleaned
+	previousPath := bundled[0].Path
+	validator.LoadPanels()
+	_, err := os.Stat(previousPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLoadBundlesErrors(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		files  map[string]string
+		errors []string
+	}{
+		{
+			title: "manifest missing",
+			files: map[string]string{
+				"panels/acme/acme.cue": acmeChart,
+			},
+			errors: []string{"manifest.yaml not found"},
+		},
+		{
+			title: "invalid manifest",
+			files: map[string]string{
+				"manifest.yaml":        "name: acme\nkinds:\n  panels: [AcmeChart]\n",
+				"panels/acme/acme.cue": acmeChart,
+			},
+			errors: []string{`invalid manifest.yaml: the version of the plugin bundle "acme" cannot be empty`},
+		},
+		{
+			title: "kind already provided by a folder",
+			files: map[string]string{
+				"manifest.yaml":        "name: acme\nversion: 1.0.0\nkinds:\n  panels: [LegendChart]\n",
+				"panels/acme/acme.cue": "package acme\n\n#panel: {\n\tkind: \"LegendChart\"\n\toptions: {}\n}\n",
+			},
+			errors: []string{"a schema already exists for kind LegendChart"},
+		},
+		{
+			title: "kind not declared in the manifest",
+			files: map[string]string{
+				"manifest.yaml":        "name: acme\nversion: 1.0.0\nkinds:\n  panels: [OtherChart]\n",
+				"panels/acme/acme.cue": acmeChart,
+			},
+			errors: []string{
+				"the kind AcmeChart is not declared in the manifest",
+				"the kind OtherChart declared in the manifest is not provided by the bundle",
+			},
+		},
+		{
+			title: "path outside the bundle",
+			files: map[string]string{
+				"manifest.yaml":           "name: acme\nversion: 1.0.0\nkinds:\n  panels: [AcmeChart]\n",
+				"../panels/acme/acme.cue": acmeChart,
+			},
+			errors: []string{`invalid path "../panels/acme/acme.cue" in the plugin bundle`},
+		},
+		{
+			title: "name going outside the plugins folder",
+			files: map[string]string{
+				"manifest.yaml":        "name: ../../acme\nversion: 1.0.0\nkinds:\n  panels: [AcmeChart]\n",
+				"panels/acme/acme.cue": acmeChart,
+			},
+			errors: []string{`invalid manifest.yaml: invalid name of plugin bundle "../../acme": it must match ^[a-zA-Z0-9_.-]+$ and cannot be . or ..`},
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			pluginsPath := t.TempDir()
+			bundlePath := writeBundle(t, pluginsPath, "acme.tar.gz", test.files)
+			validator := NewValidator(config.Schemas{
+				PanelsPath:  "testdata/panels",
+				PluginsPath: pluginsPath,
+			})
+			validator.LoadPanels()
+
+			var errors []string
+			for _, e := range validator.GetStatus().Errors {
+				assert.Equal(t, bundlePath, e.Path)
+				errors = append(errors, e.Error)
+			}
+			assert.Equal(t, test.errors, errors)
+			for _, plugin := range validator.GetPanels() {
+				if plugin.Kind == "LegendChart" {
+					assert.Empty(t, plugin.Bundle)
+				}
+			}
+		})
+	}
+}
+
+func TestJoinUnder(t *testing.T) {
+	dir := filepath.Join("plugins", "bundles")
+	result, err := JoinUnder(dir, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "acme"), result)
+	for _, name := range []string{"..", "../acme", "acme/../../other", ".", ""} {
+		_, err := JoinUnder(dir, name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	manifest := &v1.PluginManifest{Name: "acme", Version: "1.0.0", MinPersesVersion: "v0.20.0"}
+	assert.NoError(t, checkCompatibility(manifest, "0.20.0"))
+	assert.NoError(t, checkCompatibility(manifest, "0.21.1"))
+	// the version of a development build is unknown
+	assert.NoError(t, checkCompatibility(manifest, ""))
+	assert.EqualError(t, checkCompatibility(manifest, "0.19.2"), `the plugin bundle "acme" requires Perses 0.20.0 or later, the current version is 0.19.2`)
+	manifest.MinPersesVersion = "twenty"
+	assert.Error(t, checkCompatibility(manifest, "0.20.0"))
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// panelSchemaCache keeps the #panel definitions unified with the #query definitions, by kind of panel and kinds of query.
+// Unifying and evaluating the schemas is the most expensive part of the validation of a panel,
+// while it is the same for every panel of the same kinds.
+// Each cueRuntime has its own cache, as the definitions are values of its context. The cache is only used by the goroutine
+// holding the runtime, and it is replaced each time the plugins are reloaded.
+// A nil cache never keeps anything.
+type panelSchemaCache struct {
+	schemas map[string]cue.Value
+}
+
+func newPanelSchemaCache() *panelSchemaCache {
+	return &panelSchemaCache{schemas: make(map[string]cue.Value)}
+}
+
+// panelSchemaKey returns the key of the schema for a panel of the kind panelKind, with queries of the kinds queryKinds.
+// An empty list of query kinds stands for a panel without any datasource.
+func panelSchemaKey(panelKind string, queryKinds []string) string {
+	return panelKind + "/" + strings.Join(queryKinds, ",")
+}
+
+// get returns the schema cached for the key.
+func (c *panelSchemaCache) get(key string) (cue.Value, bool) {
+	if c == nil {
+		return cue.Value{}, false
+	}
+	schema, ok := c.schemas[key]
+	return schema, ok
+}
+
+// store keeps the schema for the key.
+func (c *panelSchemaCache) store(key string, schema cue.Value) {
+	if c == nil {
+		return
+	}
+	c.schemas[key] = schema
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"sync"
+
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// eventBufferSize is the number of events a subscriber can have pending before the next ones are dropped
+const eventBufferSize = 256
+
+// eventBus sends the results of the loading of the plugins to every subscriber.
+type eventBus struct {
+	mutex       sync.RWMutex
+	subscribers []chan v1.SchemaReloadEvent
+}
+
+func (b *eventBus) subscribe() <-chan v1.SchemaReloadEvent {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ch := make(chan v1.SchemaReloadEvent, eventBufferSize)
+	b.subscribers = append(b.subscribers, ch)
+	return ch
+}
+
+// publish never blocks the loading of the plugins: the event is dropped for a subscriber not consuming its events.
+func (b *eventBus) publish(event v1.SchemaReloadEvent) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logrus.Debugf("schemas event dropped for plugin %s, the subscriber is not consuming its events", event.Path)
+		}
+	}
+}
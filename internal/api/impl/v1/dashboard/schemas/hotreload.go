@@ -16,8 +16,12 @@ package schemas
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/perses/common/async"
@@ -26,54 +30,118 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// defaultDebounceDelay is the time the watcher waits without receiving any new event before reloading the plugins changed.
+// Copying or editing a plugin usually generates several events in a row, and the plugin should be loaded only once.
+const defaultDebounceDelay = 500 * time.Millisecond
+
 type watcher struct {
 	async.Task
-	fsWatcher   *fsnotify.Watcher
-	panelsPath  string
-	queriesPath string
-	validator   Validator
+	fsWatcher     *fsnotify.Watcher
+	folders       []schemasFolder
+	debounceDelay time.Duration
+	// pending are the reloads waiting for the end of the debounce delay, indexed by the path of the plugin (or of the folder) to reload
+	pending map[string]func()
+	timer   *time.Timer
+}
+
+// schemasFolder is a folder of schemas plugins, with the functions reloading them.
+type schemasFolder struct {
+	path string
+	// load reloads all the plugins of the folder
+	load func()
+	// reloadPlugin reloads a single plugin of the folder. When it is nil, any change leads to reload all the plugins.
+	reloadPlugin func(pluginPath string)
 }
 
 type reloader struct {
 	async.SimpleTask
 	validator Validator
+	// afterReload are called each time the reloader has loaded all the schemas again
+	afterReload []func()
 }
 
-func NewHotReloaders(conf config.Schemas, v Validator) (async.SimpleTask, async.SimpleTask, error) {
+// NewHotReloaders returns the task watching the schemas folders and the task reloading all the schemas periodically.
+// The functions afterReload are run by the second task, once the schemas are loaded, e.g. to migrate the panels stored.
+func NewHotReloaders(conf config.Schemas, v Validator, afterReload ...func()) (async.SimpleTask, async.SimpleTask, error) {
 	fsWatcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, nil, err
 	}
 
+	folders := []schemasFolder{
+		newSchemasFolder(conf.PanelsPath, "panels", v.LoadPanels, v),
+		newSchemasFolder(conf.QueriesPath, "queries", v.LoadQueries, v),
+		newSchemasFolder(conf.VariablesPath, "variables", v.LoadVariables, v),
+		newSchemasFolder(conf.AnnotationsPath, "annotations", v.LoadAnnotations, v),
+		newSchemasFolder(conf.LayoutsPath, "layouts", v.LoadLayouts, v),
+		newSchemasFolder(conf.DatasourcesPath, "datasources", v.LoadDatasources, v),
+		// a bundle can provide plugins of any category
+		{path: conf.PluginsPath, load: func() {
+			v.LoadPanels()
+			v.LoadQueries()
+			v.LoadVariables()
+			v.LoadAnnotations()
+			v.LoadLayouts()
+			v.LoadDatasources()
+		}},
+	}
+	var watchedFolders []schemasFolder
+	for _, folder := range folders {
+		if len(folder.path) > 0 {
+			watchedFolders = append(watchedFolders, folder)
+		}
+	}
+
 	return &watcher{
-			fsWatcher:   fsWatcher,
-			panelsPath:  conf.PanelsPath,
-			queriesPath: conf.QueriesPath,
-			validator:   v,
+			fsWatcher:     fsWatcher,
+			folders:       watchedFolders,
+			debounceDelay: defaultDebounceDelay,
+			pending:       make(map[string]func()),
 		}, &reloader{
-			validator: v,
+			validator:   v,
+			afterReload: afterReload,
 		},
 		nil
 }
 
-// Initialize implements async.Task.Initialize
-func (w *watcher) Initialize() error {
-	var err error
-	err = w.fsWatcher.Add(w.panelsPath)
-	if err != nil {
-		return err
+func newSchemasFolder(path string, category string, load func(), v Validator) schemasFolder {
+	return schemasFolder{
+		path: path,
+		load: load,
+		reloadPlugin: func(pluginPath string) {
+			v.ReloadPlugin(category, pluginPath)
+		},
 	}
-	logrus.Tracef("Started watching %s", w.panelsPath)
+}
 
-	err = w.fsWatcher.Add(w.queriesPath)
-	if err != nil {
-		return err
+// Initialize implements async.Task.Initialize
+func (w *watcher) Initialize() error {
+	for _, folder := range w.folders {
+		if err := w.watch(folder.path); err != nil {
+			return err
+		}
 	}
-	logrus.Tracef("Started watching %s", w.queriesPath)
-
 	return nil
 }
 
+// watch adds the folder and all its sub-folders to the folders watched,
+// so a change in any file of a plugin is noticed.
+func (w *watcher) watch(folder string) error {
+	return filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if addErr := w.fsWatcher.Add(path); addErr != nil {
+			return addErr
+		}
+		logrus.Tracef("Started watching %s", path)
+		return nil
+	})
+}
+
 // String implements fmt.Stringer
 func (w *watcher) String() string {
 	return "schemas watcher"
@@ -88,17 +156,21 @@ func (w *watcher) Execute(ctx context.Context, cancel context.CancelFunc) error
 				cancel()
 				return fmt.Errorf("schemas watcher channel has been closed unexpectedly")
 			}
-			// NB room for improvement: the event fsnotify.Remove could be used to actually remove the CUE schema from the map
-			if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Remove == fsnotify.Remove {
-				logrus.Tracef("%s event on %s", event.Op, event.Name)
-				if strings.HasPrefix(event.Name, filepath.FromSlash(w.panelsPath)) {
-					w.validator.LoadPanels()
-				} else if strings.HasPrefix(event.Name, filepath.FromSlash(w.queriesPath)) {
-					w.validator.LoadQueries()
-				} else {
-					logrus.Debugf("no match for %s or %s", w.panelsPath, w.queriesPath)
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			logrus.Tracef("%s event on %s", event.Op, event.Name)
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				// a new folder (e.g. a new plugin) must be watched too
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if watchErr := w.watch(event.Name); watchErr != nil {
+						logrus.WithError(watchErr).Errorf("unable to watch the folder %s", event.Name)
+					}
 				}
 			}
+			w.schedule(event.Name)
+		case <-w.timerChannel():
+			w.flush()
 		case err, ok := <-w.fsWatcher.Errors:
 			if !ok {
 				cancel()
@@ -106,12 +178,63 @@ func (w *watcher) Execute(ctx context.Context, cancel context.CancelFunc) error
 			}
 			logrus.Error(err)
 		case <-ctx.Done():
+			if w.timer != nil {
+				w.timer.Stop()
+			}
 			log.Infof("canceled %s", w.String())
 			return nil
 		}
 	}
 }
 
+// schedule registers the reload of the plugin containing the file changed, and restarts the debounce delay.
+// The change of a file directly in a schemas folder, or of the folder itself, leads to reload all the plugins of the folder.
+func (w *watcher) schedule(fileName string) {
+	for _, folder := range w.folders {
+		relPath, err := filepath.Rel(filepath.FromSlash(folder.path), fileName)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		pluginName := strings.Split(relPath, string(filepath.Separator))[0]
+		if folder.reloadPlugin == nil || pluginName == "." {
+			w.pending[folder.path] = folder.load
+		} else {
+			pluginPath := filepath.Join(folder.path, pluginName)
+			reloadPlugin := folder.reloadPlugin
+			w.pending[pluginPath] = func() { reloadPlugin(pluginPath) }
+		}
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.timer = time.NewTimer(w.debounceDelay)
+		return
+	}
+	logrus.Debugf("no schemas folder is matching %s", fileName)
+}
+
+// timerChannel returns the channel of the debounce timer, or nil (i.e. a channel never receiving anything) when no reload is pending.
+func (w *watcher) timerChannel() <-chan time.Time {
+	if w.timer == nil {
+		return nil
+	}
+	return w.timer.C
+}
+
+// flush runs the reloads pending, in the order of the paths to reload.
+func (w *watcher) flush() {
+	w.timer = nil
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		logrus.Debugf("reloading the schemas at %s", path)
+		w.pending[path]()
+		delete(w.pending, path)
+	}
+}
+
 // Finalize implements async.Task.Finalize
 func (w *watcher) Finalize() error {
 	return w.fsWatcher.Close()
@@ -131,6 +254,13 @@ func (r *reloader) Execute(ctx context.Context, _ context.CancelFunc) error {
 	default:
 		r.validator.LoadPanels()
 		r.validator.LoadQueries()
+		r.validator.LoadVariables()
+		r.validator.LoadAnnotations()
+		r.validator.LoadLayouts()
+		r.validator.LoadDatasources()
+		for _, f := range r.afterReload {
+			f()
+		}
 	}
 	return nil
 }
@@ -0,0 +1,172 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perses/perses/internal/api/config"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePlugin(t *testing.T, dir string, content string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plugin.cue"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitEvent returns the first event received with the status and the path given, ignoring the other events.
+func waitEvent(t *testing.T, events <-chan v1.SchemaReloadEvent, status v1.SchemaReloadStatus, path string) v1.SchemaReloadEvent {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Status == status && event.Path == path {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no event %s received for %s", status, path)
+		}
+	}
+}
+
+func kinds(plugins []*v1.SchemaPlugin) []string {
+	result := make([]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		result = append(result, plugin.Kind)
+	}
+	return result
+}
+
+func TestReloadPlugin(t *testing.T) {
+	panelsPath := t.TempDir()
+	pluginPath := filepath.Join(panelsPath, "acme")
+	writePlugin(t, pluginPath, acmeChart)
+	validator := NewValidator(config.Schemas{PanelsPath: panelsPath})
+	events := validator.Subscribe()
+	validator.LoadPanels()
+	event := waitEvent(t, events, v1.SchemaReloadLoaded, pluginPath)
+	assert.Equal(t, "panels", event.Category)
+	assert.Equal(t, "AcmeChart", event.Kind)
+
+	// a plugin failing to load is removed and reported
+	writePlugin(t, pluginPath, "package acme\n#panel: {")
+	validator.ReloadPlugin("panels", pluginPath)
+	event = waitEvent(t, events, v1.SchemaReloadFailed, pluginPath)
+	assert.NotEmpty(t, event.Error)
+	assert.Empty(t, validator.GetPanels())
+	if assert.Len(t, validator.GetStatus().Errors, 1) {
+		assert.Equal(t, pluginPath, validator.GetStatus().Errors[0].Path)
+	}
+
+	// the plugin fixed replaces the error, with its new kind
+	writePlugin(t, pluginPath, strings.Replace(acmeChart, `"AcmeChart"`, `"AcmeChartV2"`, 1))
+	validator.ReloadPlugin("panels", pluginPath)
+	event = waitEvent(t, events, v1.SchemaReloadLoaded, pluginPath)
+	assert.Equal(t, "AcmeChartV2", event.Kind)
+	assert.Equal(t, []string{"AcmeChartV2"}, kinds(validator.GetPanels()))
+	assert.Empty(t, validator.GetStatus().Errors)
+
+	// a plugin whose kind is already provided by another plugin is rejected
+	otherPath := filepath.Join(panelsPath, "other")
+	writePlugin(t, otherPath, strings.Replace(acmeChart, `"AcmeChart"`, `"AcmeChartV2"`, 1))
+	validator.ReloadPlugin("panels", otherPath)
+	event = waitEvent(t, events, v1.SchemaReloadFailed, otherPath)
+	assert.Equal(t, "a schema already exists for kind AcmeChartV2", event.Error)
+	assert.Equal(t, []string{"AcmeChartV2"}, kinds(validator.GetPanels()))
+
+	// the kind of a plugin deleted is removed
+	if err := os.RemoveAll(pluginPath); err != nil {
+		t.Fatal(err)
+	}
+	validator.ReloadPlugin("panels", pluginPath)
+	event = waitEvent(t, events, v1.SchemaReloadRemoved, pluginPath)
+	assert.Equal(t, "AcmeChartV2", event.Kind)
+	assert.Empty(t, validator.GetPanels())
+}
+
+func TestWatcher(t *testing.T) {
+	panelsPath := t.TempDir()
+	// an existing plugin with a nested folder, to check the sub-folders are watched too
+	existingPath := filepath.Join(panelsPath, "existing")
+	writePlugin(t, existingPath, strings.Replace(acmeChart, `"AcmeChart"`, `"ExistingChart"`, 1))
+	writePlugin(t, filepath.Join(existingPath, "nested"), "package nested")
+	validator := NewValidator(config.Schemas{PanelsPath: panelsPath})
+	validator.LoadPanels()
+	events := validator.Subscribe()
+
+	task, _, err := NewHotReloaders(config.Schemas{PanelsPath: panelsPath}, validator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := task.(*watcher)
+	w.debounceDelay = 50 * time.Millisecond
+	if initErr := w.Initialize(); initErr != nil {
+		t.Fatal(initErr)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = w.Execute(ctx, cancel)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		_ = w.Finalize()
+	}()
+
+	// a change in a nested folder reloads only the plugin containing it
+	if writeErr := os.WriteFile(filepath.Join(existingPath, "nested", "plugin.cue"), []byte("package nested\n"), 0600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	event := waitEvent(t, events, v1.SchemaReloadLoaded, existingPath)
+	assert.Equal(t, "ExistingChart", event.Kind)
+
+	// a new plugin is loaded, and its folder is watched
+	pluginPath := filepath.Join(panelsPath, "acme")
+	writePlugin(t, pluginPath, acmeChart)
+	event = waitEvent(t, events, v1.SchemaReloadLoaded, pluginPath)
+	assert.Equal(t, "AcmeChart", event.Kind)
+	writePlugin(t, pluginPath, strings.Replace(acmeChart, `"AcmeChart"`, `"AcmeChartV2"`, 1))
+	event = waitEvent(t, events, v1.SchemaReloadLoaded, pluginPath)
+	assert.Equal(t, "AcmeChartV2", event.Kind)
+
+	// a plugin renamed is removed from its previous path, and loaded from the new one
+	renamedPath := filepath.Join(panelsPath, "renamed")
+	if renameErr := os.Rename(pluginPath, renamedPath); renameErr != nil {
+		t.Fatal(renameErr)
+	}
+	waitEvent(t, events, v1.SchemaReloadRemoved, pluginPath)
+	event = waitEvent(t, events, v1.SchemaReloadLoaded, renamedPath)
+	assert.Equal(t, "AcmeChartV2", event.Kind)
+	assert.ElementsMatch(t, []string{"AcmeChartV2", "ExistingChart"}, kinds(validator.GetPanels()))
+
+	// a plugin deleted is removed
+	if removeErr := os.RemoveAll(renamedPath); removeErr != nil {
+		t.Fatal(removeErr)
+	}
+	event = waitEvent(t, events, v1.SchemaReloadRemoved, renamedPath)
+	assert.Equal(t, "AcmeChartV2", event.Kind)
+	assert.Equal(t, []string{"ExistingChart"}, kinds(validator.GetPanels()))
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"context"
+
+	"github.com/perses/common/async"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	metricsNamespace = "perses"
+	metricsSubsystem = "schemas"
+	labelCategory    = "category"
+	labelStatus      = "status"
+)
+
+var (
+	reloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "reloads_total",
+		Help:      "Total number of plugins loaded, removed, or that failed to load, by category and status.",
+	}, []string{labelCategory, labelStatus})
+	pluginsLoaded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "plugins_loaded",
+		Help:      "Number of plugins currently loaded, by category.",
+	}, []string{labelCategory})
+)
+
+func init() {
+	prometheus.MustRegister(reloadsTotal, pluginsLoaded)
+}
+
+type metricsRecorder struct {
+	async.SimpleTask
+	validator Validator
+	events    <-chan v1.SchemaReloadEvent
+}
+
+// NewMetricsRecorder returns the task exposing as Prometheus metrics the results of the loading of the plugins by the validator.
+func NewMetricsRecorder(v Validator) async.SimpleTask {
+	return &metricsRecorder{
+		validator: v,
+		events:    v.Subscribe(),
+	}
+}
+
+// String implements fmt.Stringer
+func (m *metricsRecorder) String() string {
+	return "schemas metrics recorder"
+}
+
+// Execute implements cron.Executor.Execute
+func (m *metricsRecorder) Execute(ctx context.Context, _ context.CancelFunc) error {
+	for {
+		select {
+		case event := <-m.events:
+			reloadsTotal.WithLabelValues(event.Category, string(event.Status)).Inc()
+			for category, count := range m.validator.GetStatus().Plugins {
+				pluginsLoaded.WithLabelValues(category).Set(float64(count))
+			}
+		case <-ctx.Done():
+			logrus.Infof("canceled %s", m.String())
+			return nil
+		}
+	}
+}
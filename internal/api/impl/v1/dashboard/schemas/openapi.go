@@ -0,0 +1,46 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"encoding/json"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/load"
+	"cuelang.org/go/encoding/openapi"
+	"github.com/sirupsen/logrus"
+)
+
+// openAPISchema returns the definitions of the plugin stored in schemaPath, exported as OpenAPI schemas.
+// The references are expanded, so each schema can be used on its own (e.g. to generate a form).
+// It returns nil when the plugin cannot be exported, as not every CUE constraint has an OpenAPI equivalent.
+func openAPISchema(schemaPath string) json.RawMessage {
+	// the OpenAPI encoder only works with the legacy cue.Instance, that requires its own build of the plugin
+	instances := cue.Build(load.Instances([]string{}, &load.Config{Dir: schemaPath})) //nolint:staticcheck
+	if len(instances) != 1 || instances[0].Err != nil {
+		logrus.Warningf("unable to build %s to export it as OpenAPI schemas", schemaPath)
+		return nil
+	}
+	schemas, err := (&openapi.Config{ExpandReferences: true}).Schemas(instances[0])
+	if err != nil {
+		logrus.WithError(err).Warningf("unable to export %s as OpenAPI schemas", schemaPath)
+		return nil
+	}
+	data, err := schemas.MarshalJSON()
+	if err != nil {
+		logrus.WithError(err).Warningf("unable to marshal the OpenAPI schemas of %s", schemaPath)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemas
+
+import (
+	"sync"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/parser"
+)
+
+// cueRuntime is a CUE context with its own build of the base definitions and of the schemas of the plugins.
+// A cue.Context cannot be used from several goroutines, and the values of a context cannot be mixed with the values of another one.
+// So each goroutine evaluating CUE holds a runtime for itself, taken from a runtimePool.
+type cueRuntime struct {
+	context *cue.Context
+	// baseDefs are the base definitions, by category of plugins
+	baseDefs map[string]cue.Value
+	// schemas are the schemas of the plugins loaded, by category of plugins and then by kind
+	schemas map[string]map[string]cue.Value
+	// panelSchemas caches the panel definitions unified with the query definitions
+	panelSchemas *panelSchemaCache
+}
+
+func newCueRuntime(baseDefs map[string][]byte) *cueRuntime {
+	ctx := cuecontext.New()
+	r := &cueRuntime{
+		context:      ctx,
+		baseDefs:     make(map[string]cue.Value, len(baseDefs)),
+		schemas:      make(map[string]map[string]cue.Value, len(baseDefs)),
+		panelSchemas: newPanelSchemaCache(),
+	}
+	for category, baseDef := range baseDefs {
+		r.baseDefs[category] = ctx.CompileBytes(baseDef)
+	}
+	return r
+}
+
+// setSchemas builds the plugins of the category with the context of the runtime, and replaces the schemas previously built.
+// The instances have already been built once when the plugins were loaded, so they are known to be valid.
+func (r *cueRuntime) setSchemas(category string, instances map[string]*build.Instance) {
+	schemas := make(map[string]cue.Value, len(instances))
+	for kind, instance := range instances {
+		schemas[kind] = r.baseDefs[category].Unify(r.context.BuildInstance(instance))
+	}
+	r.schemas[category] = schemas
+	// the panel definitions cached may have been built from the previous schemas
+	r.panelSchemas = newPanelSchemaCache()
+}
+
+// compile returns the CUE value of the data, written in JSON or in CUE.
+// Unlike Context.CompileBytes, building an expression doesn't add an instance to the runtime,
+// where it would be kept for as long as the validator lives.
+func (r *cueRuntime) compile(data []byte) cue.Value {
+	expr, err := parser.ParseExpr("", data)
+	if err != nil {
+		// the value compiled reports the syntax error
+		return r.context.CompileBytes(data)
+	}
+	return r.context.BuildExpr(expr)
+}
+
+// runtimePool is a fixed set of runtimes, shared by all the validations.
+// Its size bounds the number of goroutines evaluating CUE at the same time.
+type runtimePool struct {
+	runtimes []*cueRuntime
+	// free receives the runtimes that are not used
+	free chan *cueRuntime
+	// updateMutex avoids two updates holding a part of the runtimes each, and waiting for each other forever
+	updateMutex sync.Mutex
+}
+
+func newRuntimePool(size int, baseDefs map[string][]byte) *runtimePool {
+	p := &runtimePool{free: make(chan *cueRuntime, size)}
+	for i := 0; i < size; i++ {
+		r := newCueRuntime(baseDefs)
+		p.runtimes = append(p.runtimes, r)
+		p.free <- r
+	}
+	return p
+}
+
+// acquire waits for a runtime to be free, and returns it. It must be given back with release once the evaluation is done.
+func (p *runtimePool) acquire() *cueRuntime {
+	return <-p.free
+}
+
+func (p *runtimePool) release(r *cueRuntime) {
+	p.free <- r
+}
+
+// update runs f on every runtime. It waits for each runtime in use to be released,
+// and keeps them until all of them are updated, so none is updated twice while another one is missed.
+func (p *runtimePool) update(f func(r *cueRuntime)) {
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
+	updated := make([]*cueRuntime, 0, len(p.runtimes))
+	for range p.runtimes {
+		r := p.acquire()
+		f(r)
+		updated = append(updated, r)
+	}
+	for _, r := range updated {
+		p.release(r)
+	}
+}
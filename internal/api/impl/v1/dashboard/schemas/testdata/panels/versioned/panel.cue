@@ -0,0 +1,59 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package versioned
+
+// the version 2 moved the option show_legend to legend.show,
+// and the version 3 replaced the option size by the options width and height.
+#version: 3
+
+#panel: {
+	kind:       "VersionedChart"
+	datasource: #datasource
+	options: {
+		queries: [...#query]
+		legend: {
+			show:     bool
+			position: *"bottom" | "right"
+		}
+		width:  int
+		height: int
+	}
+}
+
+#migrations: {
+	"1": {
+		#from: _
+		to: {
+			for k, v in #from if k != "options" {"\(k)": v}
+			options: {
+				for k, v in #from.options if k != "show_legend" {"\(k)": v}
+				legend: show: #from.options.show_legend
+			}
+		}
+	}
+	"2": {
+		#from: _
+		to: {
+			for k, v in #from if k != "options" {"\(k)": v}
+			options: {
+				for k, v in #from.options if k != "size" {"\(k)": v}
+				width:  #from.options.size
+				height: #from.options.size
+			}
+		}
+	}
+}
+
+#datasource: _
+#query:      _
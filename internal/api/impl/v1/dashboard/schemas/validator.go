@@ -19,12 +19,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/build"
 	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
 	"cuelang.org/go/cue/load"
+	"cuelang.org/go/cue/token"
 	"github.com/perses/perses/internal/api/config"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
 	"github.com/sirupsen/logrus"
 )
 
@@ -35,6 +48,20 @@ const (
 	panelDatasourcePath = panelDefPath + "." + datasourceField
 	datasourceDefPath   = "#" + datasourceField
 	queryDefPath        = "#query"
+	variableDefPath     = "#variable"
+	annotationDefPath   = "#annotation"
+	layoutDefPath       = "#layout"
+	versionField        = "schema_version"
+	versionDefPath      = "#version"
+	migrationsDef       = "#migrations"
+	migrationFromDef    = "#from"
+	migrationToField    = "to"
+	panelsCategory      = "panels"
+	queriesCategory     = "queries"
+	variablesCategory   = "variables"
+	annotationsCategory = "annotations"
+	layoutsCategory     = "layouts"
+	datasourcesCategory = "datasources"
 )
 
 //go:embed base_def_panel.cue
@@ -43,130 +70,665 @@ var basePanelDef []byte
 //go:embed base_def_query.cue
 var baseQueryDef []byte
 
+//go:embed base_def_variable.cue
+var baseVariableDef []byte
+
+//go:embed base_def_annotation.cue
+var baseAnnotationDef []byte
+
+//go:embed base_def_layout.cue
+var baseLayoutDef []byte
+
+//go:embed base_def_datasource.cue
+var baseDatasourceDef []byte
+
 // retrieveSchemaForKind returns the schema corresponding to the provided kind
-func retrieveSchemaForKind(panelName string, panelVal cue.Value, kindPath string, schemasMap *sync.Map) (cue.Value, error) {
+func retrieveSchemaForKind(panelName string, panelVal cue.Value, kindPath string, schemasMap map[string]cue.Value) (cue.Value, error) {
 	// retrieve the value of the Kind field
 	kind, err := panelVal.LookupPath(cue.ParsePath(kindPath)).String()
 	if err != nil {
-		err = fmt.Errorf("invalid panel %s: %s", panelName, err) // enrich the error message returned by cue lib
-		logrus.Debug(err)
+		logrus.Debugf("invalid panel %s: %s", panelName, err)
 		return cue.Value{}, err
 	}
 
 	// retrieve the corresponding schema
-	schema, ok := schemasMap.Load(kind)
+	schema, ok := schemasMap[kind]
 	if !ok {
-		err := fmt.Errorf("invalid panel %s: Unknown %s %s", panelName, kindPath, kind)
-		logrus.Debug(err)
+		err := fmt.Errorf("Unknown %s %s", kindPath, kind)
+		logrus.Debugf("invalid panel %s: %s", panelName, err)
 		return cue.Value{}, err
 	}
 
-	return schema.(cue.Value), nil
+	return schema, nil
 }
 
-// Validator can be used to run checks on panels, based on cuelang definitions
+// Validator can be used to run checks on panels, variables, annotations, layouts and datasources, based on cuelang definitions
 type Validator interface {
 	Validate(panels map[string]json.RawMessage) error
+	// Normalize validates the panels like Validate, and returns them with the concrete values computed by CUE,
+	// such as the defaults declared in the schemas.
+	Normalize(panels map[string]json.RawMessage) (map[string]json.RawMessage, error)
+	// ValidatePanel verifies a library panel, i.e. a panel stored apart from the dashboards.
+	// The paths of the report are relative to the library panel resource.
+	ValidatePanel(name string, panelJSON json.RawMessage) error
+	// PanelMigration returns the kind of the panel, the schema version it has been saved with (From)
+	// and the version of the schema of its kind (To). From and To are equal when the panel is up to date.
+	PanelMigration(panelJSON json.RawMessage) (*v1.PanelMigration, error)
+	// Migrate upgrades the panels saved with an older version of their schema, and validates them once upgraded.
+	// Unlike Normalize, the defaults declared in the schemas are not added to the panels.
+	Migrate(panels map[string]json.RawMessage) (map[string]json.RawMessage, error)
+	ValidateVariables(variables map[string]*dashboard.Variable) error
+	ValidateAnnotations(annotations map[string]*dashboard.Annotation) error
+	ValidateLayouts(layouts []dashboard.Layout) error
+	ValidateDatasource(spec v1.DatasourceSpec) error
 	LoadPanels()
 	LoadQueries()
+	LoadVariables()
+	LoadAnnotations()
+	LoadLayouts()
+	LoadDatasources()
+	// GetPanels returns the panel plugins currently loaded, sorted by kind.
+	GetPanels() []*v1.SchemaPlugin
+	// GetQueries returns the query plugins currently loaded, sorted by kind.
+	GetQueries() []*v1.SchemaPlugin
+	// GetStatus returns the plugins loaded and the plugins that failed to load, for every category.
+	GetStatus() *v1.SchemaStatus
+	// ReloadPlugin loads again the plugin of the folder schemaPath, which belongs to the category of plugins
+	// (i.e. panels, queries, variables, annotations, layouts or datasources). The plugin is removed when the folder doesn't exist anymore.
+	ReloadPlugin(category string, schemaPath string)
+	// Subscribe returns a channel receiving the result of every loading of a plugin.
+	// The events are dropped when the channel is full, so the channel must be consumed continuously.
+	Subscribe() <-chan v1.SchemaReloadEvent
 }
 
 type validator struct {
-	context *cue.Context
-	panels  cueDefs
-	queries cueDefs
+	events *eventBus
+	// runtimes are the CUE contexts used to validate, each of them with its own build of the schemas
+	runtimes *runtimePool
+	// concurrency is the maximum number of panels of a dashboard validated at the same time
+	concurrency int
+	panels      cueDefs
+	queries     cueDefs
+	variables   cueDefs
+	annotations cueDefs
+	layouts     cueDefs
+	datasources cueDefs
 }
 
 // NewValidator instantiate a validator
 func NewValidator(conf config.Schemas) Validator {
-	ctx := cuecontext.New()
-
-	// compile the base definitions
-	basePanelDefVal := ctx.CompileBytes(basePanelDef)
-	baseQueryDefVal := ctx.CompileBytes(baseQueryDef)
+	events := &eventBus{}
+	concurrency := runtime.GOMAXPROCS(0)
+	runtimes := newRuntimePool(concurrency, map[string][]byte{
+		panelsCategory:      basePanelDef,
+		queriesCategory:     baseQueryDef,
+		variablesCategory:   baseVariableDef,
+		annotationsCategory: baseAnnotationDef,
+		layoutsCategory:     baseLayoutDef,
+		datasourcesCategory: baseDatasourceDef,
+	})
+	newDefs := func(category string, baseDef []byte, schemasPath string, defPath string, onLoad func(kinds ...string)) cueDefs {
+		// the plugins are loaded with a context of their own, then built again in each runtime
+		ctx := cuecontext.New()
+		return cueDefs{
+			context:     ctx,
+			runtimes:    runtimes,
+			baseDef:     ctx.CompileBytes(baseDef),
+			instances:   make(map[string]*build.Instance),
+			schemasPath: schemasPath,
+			pluginsPath: conf.PluginsPath,
+			category:    category,
+			mutex:       &sync.RWMutex{},
+			loadMutex:   &sync.Mutex{},
+			events:      events,
+			onLoad:      onLoad,
+			kindCuePath: fmt.Sprintf("%s.%s", defPath, kindField),
+		}
+	}
 
 	return &validator{
-		context: ctx,
-		panels: cueDefs{
-			context:     ctx,
-			baseDef:     basePanelDefVal,
-			schemas:     &sync.Map{},
-			schemasPath: conf.PanelsPath,
-			kindCuePath: fmt.Sprintf("%s.%s", panelDefPath, kindField),
-		},
-		queries: cueDefs{
-			context:     ctx,
-			baseDef:     baseQueryDefVal,
-			schemas:     &sync.Map{},
-			schemasPath: conf.QueriesPath,
-			kindCuePath: fmt.Sprintf("%s.%s", datasourceDefPath, kindField),
-		},
+		events:      events,
+		runtimes:    runtimes,
+		concurrency: concurrency,
+		panels:      newDefs(panelsCategory, basePanelDef, conf.PanelsPath, panelDefPath, nil),
+		queries:     newDefs(queriesCategory, baseQueryDef, conf.QueriesPath, datasourceDefPath, nil),
+		variables:   newDefs(variablesCategory, baseVariableDef, conf.VariablesPath, variableDefPath, dashboard.RegisterPluginVariableKinds),
+		annotations: newDefs(annotationsCategory, baseAnnotationDef, conf.AnnotationsPath, annotationDefPath, dashboard.RegisterPluginAnnotationKinds),
+		layouts:     newDefs(layoutsCategory, baseLayoutDef, conf.LayoutsPath, layoutDefPath, dashboard.RegisterPluginLayoutKinds),
+		datasources: newDefs(datasourcesCategory, baseDatasourceDef, conf.DatasourcesPath, datasourceDefPath, datasource.RegisterPluginKinds),
 	}
 }
 
 // Validate verify a list of panels.
 // The panels are matched against the known list of CUE definitions (schemas).
 // If no schema matches for at least 1 panel, the validation fails.
+// Every problem found is returned in a common.ValidationReport, with the path of the field in the dashboard.
 func (v *validator) Validate(panels map[string]json.RawMessage) error {
-	var res error
+	var report common.ValidationReport
+	var mutex sync.Mutex
+	v.forEachPanel(panels, func(r *cueRuntime, panelName string, panelJSON json.RawMessage) {
+		_, err := r.unifyPanel(panelName, panelJSON)
+		mutex.Lock()
+		defer mutex.Unlock()
+		report.Merge(common.JSONPointer("spec", "panels", panelName), err)
+	})
+	if len(report) > 0 {
+		report.Sort()
+		return report
+	}
+	logrus.Debug("All panels are valid")
+	return nil
+}
 
-	// go through the panels list
-	// the processing stops as soon as it detects an invalid panel -> TODO: improve this to return a list of all the errors encountered ?
-	for panelName, panelJSON := range panels {
-		logrus.Tracef("Panel to validate: %s", string(panelJSON))
+func (v *validator) ValidatePanel(name string, panelJSON json.RawMessage) error {
+	r := v.runtimes.acquire()
+	defer v.runtimes.release(r)
+	_, err := r.unifyPanel(name, panelJSON)
+	var report common.ValidationReport
+	report.Merge("/spec", err)
+	return report.Err()
+}
 
-		// compile the JSON panel into a CUE Value
-		value := v.context.CompileBytes(panelJSON)
+// Normalize verify a list of panels, and returns each of them exported from the CUE value resulting of the validation.
+// The panels returned are made of the fields of the panels provided and of the defaults declared in the schemas.
+func (v *validator) Normalize(panels map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	var report common.ValidationReport
+	result := make(map[string]json.RawMessage, len(panels))
+	var mutex sync.Mutex
+	v.forEachPanel(panels, func(r *cueRuntime, panelName string, panelJSON json.RawMessage) {
+		path := common.JSONPointer("spec", "panels", panelName)
+		unified, err := r.unifyPanel(panelName, panelJSON)
+		var data []byte
+		if err == nil {
+			data, err = unified.MarshalJSON()
+			if err != nil {
+				err = newReport("", fmt.Errorf("unable to export the panel: %s", err))
+			}
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil {
+			report.Merge(path, err)
+			return
+		}
+		result[panelName] = data
+	})
+	if len(report) > 0 {
+		report.Sort()
+		return nil, report
+	}
+	return result, nil
+}
 
-		// retrieve the corresponding panel schema
-		panelSchema, err := retrieveSchemaForKind(panelName, value, kindField, v.panels.schemas)
+// Migrate upgrades the panels saved with an older version of their schema, then validates them like Validate.
+// The panels already up to date are returned as they are.
+func (v *validator) Migrate(panels map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	var report common.ValidationReport
+	result := make(map[string]json.RawMessage, len(panels))
+	var mutex sync.Mutex
+	v.forEachPanel(panels, func(r *cueRuntime, panelName string, panelJSON json.RawMessage) {
+		data, err := r.migratePanelJSON(panelName, panelJSON)
+		if err == nil {
+			_, err = r.unifyPanel(panelName, data)
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
 		if err != nil {
-			res = err
-			break
+			report.Merge(common.JSONPointer("spec", "panels", panelName), err)
+			return
 		}
-		logrus.Tracef("Panel schema to use: %+v", panelSchema.LookupPath(cue.ParsePath(panelDefPath)))
-		finalSchema := panelSchema
+		result[panelName] = data
+	})
+	if len(report) > 0 {
+		report.Sort()
+		return nil, report
+	}
+	return result, nil
+}
 
-		// retrieve the corresponding query schema
-		// the wrapping `if` tackles the particular case of panels without a datasource (e.g text panel)
-		if err := panelSchema.LookupPath(cue.ParsePath(panelDatasourcePath)).Err(); err == nil {
-			querySchema, err := retrieveSchemaForKind(panelName, value, fmt.Sprintf("%s.%s", datasourceField, kindField), v.queries.schemas)
-			if err != nil {
-				res = err
-				break
+// forEachPanel runs f for every panel. The panels are processed concurrently by at most v.concurrency goroutines,
+// each of them evaluating CUE with a runtime of its own.
+func (v *validator) forEachPanel(panels map[string]json.RawMessage, f func(r *cueRuntime, panelName string, panelJSON json.RawMessage)) {
+	names := make(chan string, len(panels))
+	for panelName := range panels {
+		names <- panelName
+	}
+	close(names)
+	workers := v.concurrency
+	if len(panels) < workers {
+		workers = len(panels)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := v.runtimes.acquire()
+			defer v.runtimes.release(r)
+			for panelName := range names {
+				f(r, panelName, panels[panelName])
 			}
-			logrus.Tracef("Query schema to use: %+v", querySchema.LookupPath(cue.ParsePath(queryDefPath)))
+		}()
+	}
+	wg.Wait()
+}
 
-			// unify panel and query schemas
-			finalSchema = panelSchema.Unify(querySchema)
-			if finalSchema.Err() != nil {
-				logrus.WithError(finalSchema.Err()).Errorf("Error unifying panel and query schemas to validate panel %s", panelName)
-				continue
-			}
+// migratePanelJSON returns the panel upgraded to the version of the schema of its kind, exported in JSON.
+func (r *cueRuntime) migratePanelJSON(panelName string, panelJSON json.RawMessage) (json.RawMessage, error) {
+	value := r.compile(panelJSON)
+	panelSchema, err := retrieveSchemaForKind(panelName, value, kindField, r.schemas[panelsCategory])
+	if err != nil {
+		return nil, newReport("/"+kindField, err)
+	}
+	from, to, err := panelVersions(value, panelSchema)
+	if err != nil {
+		return nil, newReport("/"+versionField, err)
+	}
+	if from == to {
+		return panelJSON, nil
+	}
+	migrated, err := r.migratePanel(panelName, value, panelSchema)
+	if err != nil {
+		return nil, err
+	}
+	data, err := migrated.MarshalJSON()
+	if err != nil {
+		return nil, newReport("", fmt.Errorf("unable to export the panel: %s", err))
+	}
+	return data, nil
+}
+
+// PanelMigration returns the versions between which the panel must be migrated to match the schema of its kind.
+func (v *validator) PanelMigration(panelJSON json.RawMessage) (*v1.PanelMigration, error) {
+	r := v.runtimes.acquire()
+	defer v.runtimes.release(r)
+	value := r.compile(panelJSON)
+	kind, err := value.LookupPath(cue.ParsePath(kindField)).String()
+	if err != nil {
+		return nil, err
+	}
+	panelSchema, err := retrieveSchemaForKind(kind, value, kindField, r.schemas[panelsCategory])
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := panelVersions(value, panelSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.PanelMigration{Kind: kind, From: from, To: to}, nil
+}
+
+// ValidateVariables verify a list of variables against the known list of CUE definitions.
+// Only the kind and the parameter of a variable are checked, the other attributes are common to every variable.
+// The validation is skipped when no path is configured for the variable schemas.
+func (v *validator) ValidateVariables(variables map[string]*dashboard.Variable) error {
+	if !v.variables.enabled() {
+		return nil
+	}
+	r := v.runtimes.acquire()
+	defer v.runtimes.release(r)
+	var report common.ValidationReport
+	for name, variable := range variables {
+		value := struct {
+			Kind      dashboard.VariableKind      `json:"kind"`
+			Parameter dashboard.VariableParameter `json:"parameter"`
+		}{
+			Kind:      variable.Kind,
+			Parameter: variable.Parameter,
 		}
+		path := common.JSONPointer("spec", "variables", name)
+		report.Merge(path, r.validateSpec(variablesCategory, variableDefPath, fmt.Sprintf("variable %s", name), value))
+	}
+	report.Sort()
+	return report.Err()
+}
 
-		// do the validation using the main #panel def of the schema
-		unified := value.Unify(finalSchema.LookupPath(cue.ParsePath(panelDefPath)))
-		opts := []cue.Option{
-			cue.Concrete(true),
-			cue.Attributes(true),
-			cue.Definitions(true),
-			cue.Hidden(true),
+// ValidateAnnotations verify a list of annotations against the known list of CUE definitions.
+// Like for the variables, only the kind and the parameter of an annotation are checked.
+// The validation is skipped when no path is configured for the annotation schemas.
+func (v *validator) ValidateAnnotations(annotations map[string]*dashboard.Annotation) error {
+	if !v.annotations.enabled() {
+		return nil
+	}
+	r := v.runtimes.acquire()
+	defer v.runtimes.release(r)
+	var report common.ValidationReport
+	for name, annotation := range annotations {
+		value := struct {
+			Kind      dashboard.AnnotationKind      `json:"kind"`
+			Parameter dashboard.AnnotationParameter `json:"parameter"`
+		}{
+			Kind:      annotation.Kind,
+			Parameter: annotation.Parameter,
 		}
-		err = unified.Validate(opts...)
-		if err != nil {
-			err = fmt.Errorf("invalid panel %s: %s", panelName, err) // enrich the error message returned by cue lib
-			logrus.Debug(err)
-			res = err
-			break
+		path := common.JSONPointer("spec", "annotations", name)
+		report.Merge(path, r.validateSpec(annotationsCategory, annotationDefPath, fmt.Sprintf("annotation %s", name), value))
+	}
+	report.Sort()
+	return report.Err()
+}
+
+// ValidateLayouts verify a list of layouts against the known list of CUE definitions.
+// The validation is skipped when no path is configured for the layout schemas.
+func (v *validator) ValidateLayouts(layouts []dashboard.Layout) error {
+	if !v.layouts.enabled() {
+		return nil
+	}
+	r := v.runtimes.acquire()
+	defer v.runtimes.release(r)
+	var report common.ValidationReport
+	for i, layout := range layouts {
+		path := common.JSONPointer("spec", "layouts", strconv.Itoa(i))
+		report.Merge(path, r.validateSpec(layoutsCategory, layoutDefPath, fmt.Sprintf("layout %d", i), layout))
+	}
+	return report.Err()
+}
+
+// ValidateDatasource verify the spec of a datasource against the known list of CUE definitions.
+// The validation is skipped when no path is configured for the datasource schemas.
+func (v *validator) ValidateDatasource(spec v1.DatasourceSpec) error {
+	if !v.datasources.enabled() {
+		return nil
+	}
+	r := v.runtimes.acquire()
+	defer v.runtimes.release(r)
+	var report common.ValidationReport
+	report.Merge("/spec", r.validateSpec(datasourcesCategory, datasourceDefPath, fmt.Sprintf("datasource %s", spec.GetKind()), spec))
+	return report.Err()
+}
+
+// validateSpec returns a common.ValidationReport with every problem found in the object, once converted to JSON.
+// The object is validated with the definition defPath of the schema of the category matching its kind.
+// The paths of the report are relative to the object.
+func (r *cueRuntime) validateSpec(category string, defPath string, name string, object interface{}) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to marshal the %s to validate it", name)
+		return newReport("", err)
+	}
+	value := r.compile(data)
+	schema, err := retrieveSchemaForKind(name, value, kindField, r.schemas[category])
+	if err != nil {
+		return newReport("/"+kindField, err)
+	}
+	unified := value.Unify(schema.LookupPath(cue.ParsePath(defPath)))
+	if err := unified.Validate(cue.Concrete(true)); err != nil {
+		logrus.Debugf("invalid %s: %s", name, err)
+		return cueReport(err)
+	}
+	return nil
+}
+
+// unifyPanel returns the panel unified with its schema, or a common.ValidationReport with every problem found in the panel.
+// The paths of the report are relative to the panel.
+func (r *cueRuntime) unifyPanel(panelName string, panelJSON json.RawMessage) (cue.Value, error) {
+	logrus.Tracef("Panel to validate: %s", string(panelJSON))
+
+	// compile the JSON panel into a CUE Value
+	value := r.compile(panelJSON)
+
+	// retrieve the corresponding panel schema
+	panelSchema, err := retrieveSchemaForKind(panelName, value, kindField, r.schemas[panelsCategory])
+	if err != nil {
+		return cue.Value{}, newReport("/"+kindField, err)
+	}
+	logrus.Tracef("Panel schema to use: %+v", panelSchema.LookupPath(cue.ParsePath(panelDefPath)))
+
+	// upgrade the panel when it has been saved with an older version of the schema
+	value, err = r.migratePanel(panelName, value, panelSchema)
+	if err != nil {
+		return cue.Value{}, err
+	}
+
+	panelDef, err := r.panelDefinition(panelName, value, panelSchema)
+	if err != nil {
+		return cue.Value{}, err
+	}
+	if !panelDef.Exists() {
+		return value, nil
+	}
+
+	// do the validation using the main #panel def of the schema
+	unified := value.Unify(panelDef)
+	opts := []cue.Option{
+		cue.Concrete(true),
+		cue.Attributes(true),
+		cue.Definitions(true),
+		cue.Hidden(true),
+	}
+	if err := unified.Validate(opts...); err != nil {
+		logrus.Debugf("invalid panel %s: %s", panelName, err)
+		return cue.Value{}, cueReport(err)
+	}
+	return unified, nil
+}
+
+// panelDefinition returns the #panel definition to validate the panel with, i.e. the definition of the panel schema unified
+// with the definitions of the query schemas matching the kinds of datasource used in the panel.
+// The definition is built once for all the panels of the same kinds, then taken from the cache until the plugins are reloaded.
+// It returns a non-existing value when the schemas cannot be unified, in which case the panel is not validated.
+func (r *cueRuntime) panelDefinition(panelName string, panelVal cue.Value, panelSchema cue.Value) (cue.Value, error) {
+	panelKind, _ := panelVal.LookupPath(cue.ParsePath(kindField)).String()
+
+	// the particular case of panels without a datasource (e.g text panel)
+	if err := panelSchema.LookupPath(cue.ParsePath(panelDatasourcePath)).Err(); err != nil {
+		key := panelSchemaKey(panelKind, nil)
+		if panelDef, ok := r.panelSchemas.get(key); ok {
+			return panelDef, nil
+		}
+		panelDef := evaluate(panelSchema.LookupPath(cue.ParsePath(panelDefPath)))
+		r.panelSchemas.store(key, panelDef)
+		return panelDef, nil
+	}
+
+	// retrieve the corresponding query schema
+	querySchema, err := retrieveSchemaForKind(panelName, panelVal, fmt.Sprintf("%s.%s", datasourceField, kindField), r.schemas[queriesCategory])
+	if err != nil {
+		return cue.Value{}, newReport(common.JSONPointer(datasourceField, kindField), err)
+	}
+	logrus.Tracef("Query schema to use: %+v", querySchema.LookupPath(cue.ParsePath(queryDefPath)))
+
+	// the definition depends on the kind of datasource of the panel, and on the other kinds of datasource used by its queries
+	queryKind, _ := panelVal.LookupPath(cue.ParsePath(fmt.Sprintf("%s.%s", datasourceField, kindField))).String()
+	var otherQueryKinds []string
+	for _, kind := range queryDatasourceKinds(panelVal) {
+		if kind != queryKind {
+			otherQueryKinds = append(otherQueryKinds, kind)
+		}
+	}
+	sort.Strings(otherQueryKinds)
+	key := panelSchemaKey(panelKind, append([]string{queryKind}, otherQueryKinds...))
+	if panelDef, ok := r.panelSchemas.get(key); ok {
+		return panelDef, nil
+	}
+
+	// unify panel and query schemas
+	finalSchema := panelSchema.Unify(querySchema)
+	if finalSchema.Err() != nil {
+		logrus.WithError(finalSchema.Err()).Errorf("Error unifying panel and query schemas to validate panel %s", panelName)
+		return cue.Value{}, nil
+	}
+
+	// when some queries are overriding the datasource with another kind, the panel is mixing different kinds of query.
+	// In this case, each query must match one of the query schemas used in the panel.
+	mixedSchema, err := r.mixedQuerySchema(panelName, panelVal, panelSchema, querySchema)
+	if err != nil {
+		return cue.Value{}, newReport("", err)
+	}
+	if mixedSchema.Exists() {
+		finalSchema = mixedSchema
+	}
+	panelDef := evaluate(finalSchema.LookupPath(cue.ParsePath(panelDefPath)))
+	r.panelSchemas.store(key, panelDef)
+	return panelDef, nil
+}
+
+// evaluate evaluates the whole definition once, so the panels validated with it don't evaluate it again.
+func evaluate(def cue.Value) cue.Value {
+	if err := def.Validate(); err != nil {
+		logrus.WithError(err).Debug("the panel definition is not valid on its own")
+	}
+	return def
+}
+
+// panelVersions returns the version of the schema the panel has been saved with, and the version of the schema.
+// A panel without version has been saved before the versioning of the schemas, i.e. with the version 1.
+func panelVersions(panelVal cue.Value, panelSchema cue.Value) (int, int, error) {
+	to, err := panelSchema.LookupPath(cue.ParsePath(versionDefPath)).Int64()
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid version of the schema: %s", err)
+	}
+	from := int64(1)
+	if versionVal := panelVal.LookupPath(cue.ParsePath(versionField)); versionVal.Exists() {
+		if from, err = versionVal.Int64(); err != nil {
+			return 0, 0, err
+		}
+	}
+	return int(from), int(to), nil
+}
+
+// migratePanel upgrades the panel, one version after the other, from the version of the schema it has been saved with
+// to the version of the schema. Each step is done by the migration declared in the schema for the version of the panel.
+// The panel returned records the version of the schema in the field schema_version.
+func (r *cueRuntime) migratePanel(panelName string, panelVal cue.Value, panelSchema cue.Value) (cue.Value, error) {
+	from, to, err := panelVersions(panelVal, panelSchema)
+	if err != nil {
+		return cue.Value{}, newReport("/"+versionField, err)
+	}
+	if from > to {
+		return cue.Value{}, newReport("/"+versionField, fmt.Errorf("the panel has been saved with the version %d of the schema, which is newer than the version %d available", from, to))
+	}
+	if from == to {
+		return panelVal.FillPath(cue.ParsePath(versionField), to), nil
+	}
+	data, err := panelVal.MarshalJSON()
+	if err != nil {
+		return cue.Value{}, newReport("", err)
+	}
+	for version := from; version < to; version++ {
+		migration := panelSchema.LookupPath(cue.MakePath(cue.Def(migrationsDef), cue.Str(strconv.Itoa(version))))
+		if !migration.Exists() {
+			return cue.Value{}, newReport("/"+versionField, fmt.Errorf("no migration is declared from the version %d of the schema", version))
+		}
+		// the version is removed so the migration doesn't have to deal with it
+		if data, err = setVersion(data, 0); err != nil {
+			return cue.Value{}, newReport("", err)
+		}
+		migrated := migration.FillPath(cue.MakePath(cue.Def(migrationFromDef)), r.compile(data)).LookupPath(cue.ParsePath(migrationToField))
+		if data, err = migrated.MarshalJSON(); err != nil {
+			logrus.Debugf("unable to migrate panel %s from version %d: %s", panelName, version, err)
+			return cue.Value{}, newReport("", fmt.Errorf("unable to migrate the panel from the version %d to the version %d: %s", version, version+1, err))
+		}
+	}
+	if data, err = setVersion(data, to); err != nil {
+		return cue.Value{}, newReport("", err)
+	}
+	return r.compile(data), nil
+}
+
+// setVersion sets the field schema_version of the JSON panel, or removes it when the version is 0.
+// The other fields are kept as they are, so the numbers are not converted.
+func setVersion(panelJSON []byte, version int) ([]byte, error) {
+	var panel map[string]json.RawMessage
+	if err := json.Unmarshal(panelJSON, &panel); err != nil || panel == nil {
+		return nil, fmt.Errorf("the panel is not an object")
+	}
+	if version == 0 {
+		delete(panel, versionField)
+	} else {
+		panel[versionField] = json.RawMessage(strconv.Itoa(version))
+	}
+	return json.Marshal(panel)
+}
+
+// newReport returns a report made of the given error.
+func newReport(path string, err error) common.ValidationReport {
+	var report common.ValidationReport
+	report.AddError(path, "%s", err)
+	return report
+}
+
+// cueReport converts the errors returned by the CUE lib to a report, using the path of the field in error.
+func cueReport(err error) common.ValidationReport {
+	var report common.ValidationReport
+	for _, e := range cueerrors.Errors(err) {
+		format, args := e.Msg()
+		// the summary of a disjunction is followed by the error of each branch
+		report.AddError(common.JSONPointer(e.Path()...), strings.TrimSuffix(format, ":"), args...)
+	}
+	return report
+}
+
+// mixedQuerySchema returns the panel schema where the query definition is the disjunction of every query schema used in the panel.
+// It returns a non-existing value if every query is using the same kind of datasource as the panel.
+func (r *cueRuntime) mixedQuerySchema(panelName string, panelVal cue.Value, panelSchema cue.Value, panelQuerySchema cue.Value) (cue.Value, error) {
+	panelDatasourceKind, _ := panelVal.LookupPath(cue.ParsePath(fmt.Sprintf("%s.%s", datasourceField, kindField))).String()
+	var querySchemas []cue.Value
+	for _, kind := range queryDatasourceKinds(panelVal) {
+		if kind == panelDatasourceKind {
+			continue
+		}
+		schema, ok := r.schemas[queriesCategory][kind]
+		if !ok {
+			err := fmt.Errorf("Unknown query datasource.kind %s", kind)
+			logrus.Debugf("invalid panel %s: %s", panelName, err)
+			return cue.Value{}, err
+		}
+		querySchemas = append(querySchemas, schema)
+	}
+	if len(querySchemas) == 0 {
+		return cue.Value{}, nil
+	}
+	querySchemas = append([]cue.Value{panelQuerySchema}, querySchemas...)
+
+	// build the disjunction of the different query definitions
+	scope := r.compile([]byte("{}"))
+	var disjunction ast.Expr
+	for i, schema := range querySchemas {
+		label := fmt.Sprintf("query%d", i)
+		scope = scope.FillPath(cue.ParsePath(label), schema.LookupPath(cue.ParsePath(queryDefPath)))
+		if disjunction == nil {
+			disjunction = ast.NewIdent(label)
+		} else {
+			disjunction = ast.NewBinExpr(token.OR, disjunction, ast.NewIdent(label))
 		}
 	}
+	queryDef := r.context.BuildExpr(disjunction, cue.Scope(scope))
 
-	if res == nil {
-		logrus.Debug("All panels are valid")
+	finalSchema := panelSchema.
+		FillPath(cue.ParsePath(datasourceDefPath), panelQuerySchema.LookupPath(cue.ParsePath(datasourceDefPath))).
+		FillPath(cue.ParsePath(queryDefPath), queryDef)
+	if finalSchema.Err() != nil {
+		logrus.WithError(finalSchema.Err()).Errorf("Error unifying panel and query schemas to validate panel %s", panelName)
+		return cue.Value{}, finalSchema.Err()
 	}
+	return finalSchema, nil
+}
 
-	return res
+// queryDatasourceKinds returns the kinds of the datasources set in the queries of the panel.
+// By convention, the queries of a panel are set in options.query or in options.queries.
+func queryDatasourceKinds(panelVal cue.Value) []string {
+	var kinds []string
+	queryDatasourceKindPath := cue.ParsePath(fmt.Sprintf("%s.%s", datasourceField, kindField))
+	addKind := func(query cue.Value) {
+		kind, err := query.LookupPath(queryDatasourceKindPath).String()
+		if err != nil {
+			return
+		}
+		for _, k := range kinds {
+			if k == kind {
+				return
+			}
+		}
+		kinds = append(kinds, kind)
+	}
+	addKind(panelVal.LookupPath(cue.ParsePath("options.query")))
+	if queries, err := panelVal.LookupPath(cue.ParsePath("options.queries")).List(); err == nil {
+		for queries.Next() {
+			addKind(queries.Value())
+		}
+	}
+	return kinds
 }
 
 // LoadPanels loads the list of available panels plugins as CUE schemas
@@ -179,24 +741,149 @@ func (v *validator) LoadQueries() {
 	v.queries.load()
 }
 
+// LoadVariables loads the list of available variables plugins as CUE schemas
+func (v *validator) LoadVariables() {
+	v.variables.load()
+}
+
+// LoadAnnotations loads the list of available annotations plugins as CUE schemas
+func (v *validator) LoadAnnotations() {
+	v.annotations.load()
+}
+
+// LoadLayouts loads the list of available layouts plugins as CUE schemas
+func (v *validator) LoadLayouts() {
+	v.layouts.load()
+}
+
+// LoadDatasources loads the list of available datasources plugins as CUE schemas
+func (v *validator) LoadDatasources() {
+	v.datasources.load()
+}
+
+// GetPanels returns the panel plugins currently loaded
+func (v *validator) GetPanels() []*v1.SchemaPlugin {
+	return v.panels.getPlugins()
+}
+
+// GetQueries returns the query plugins currently loaded
+func (v *validator) GetQueries() []*v1.SchemaPlugin {
+	return v.queries.getPlugins()
+}
+
+// ReloadPlugin loads again a single plugin of the category
+func (v *validator) ReloadPlugin(category string, schemaPath string) {
+	for _, defs := range v.allDefs() {
+		if defs.category == category {
+			defs.reloadPlugin(schemaPath)
+			return
+		}
+	}
+	logrus.Errorf("unknown category of plugins %q", category)
+}
+
+// Subscribe returns a new channel receiving the results of the loading of the plugins
+func (v *validator) Subscribe() <-chan v1.SchemaReloadEvent {
+	return v.events.subscribe()
+}
+
+func (v *validator) allDefs() []*cueDefs {
+	return []*cueDefs{&v.panels, &v.queries, &v.variables, &v.annotations, &v.layouts, &v.datasources}
+}
+
+// GetStatus returns the number of plugins loaded and the loading errors of every category of plugins
+func (v *validator) GetStatus() *v1.SchemaStatus {
+	status := &v1.SchemaStatus{
+		Plugins: make(map[string]int),
+		Errors:  []v1.SchemaLoadError{},
+	}
+	for _, defs := range v.allDefs() {
+		if !defs.enabled() {
+			continue
+		}
+		status.Plugins[defs.category] = defs.countPlugins()
+		status.Errors = append(status.Errors, defs.getErrors()...)
+	}
+	return status
+}
+
 type cueDefs struct {
-	context     *cue.Context
-	baseDef     cue.Value
-	schemas     *sync.Map
+	// context is only used to load the plugins, under loadMutex
+	context *cue.Context
+	// runtimes are the contexts each plugin loaded is built again with, to validate
+	runtimes *runtimePool
+	baseDef  cue.Value
+	// instances are the CUE packages of the plugins loaded, by kind
+	instances   map[string]*build.Instance
 	schemasPath string
+	// pluginsPath is the folder of the plugin bundles
+	pluginsPath string
+	// bundlesDir is the temporary folder the plugins of the bundles loaded have been extracted to
+	bundlesDir  string
 	kindCuePath string
+	// category is the name of the kind of plugins, as displayed in the status
+	category string
+	// mutex guards the description of the plugins loaded and the errors of the last loading
+	mutex *sync.RWMutex
+	// loadMutex avoids loading all the plugins and reloading a single plugin at the same time
+	loadMutex *sync.Mutex
+	// events receives the result of the loading of every plugin
+	events *eventBus
+	// onLoad, when set, receives the kinds of the plugins loaded, so the model accepts them
+	onLoad  func(kinds ...string)
+	plugins []*v1.SchemaPlugin
+	errors  []v1.SchemaLoadError
+	// exported is true once the plugins loaded have been exported as OpenAPI schemas.
+	// The export is done on demand, as it is only needed to describe the plugins.
+	exported bool
+}
+
+// enabled returns false when no path is set for the schemas, meaning this kind of validation is not wanted.
+func (c *cueDefs) enabled() bool {
+	return len(c.schemasPath) > 0
 }
 
-// load the list of available plugins as CUE schemas
+// load the list of available plugins as CUE schemas, from the folders of the schemas path and from the plugin bundles
 func (c *cueDefs) load() {
+	if !c.enabled() {
+		return
+	}
+	c.loadMutex.Lock()
+	defer c.loadMutex.Unlock()
+	now := time.Now().UTC()
+	previousPlugins := c.getLoadedPlugins()
+	// loadErrors collects the plugins that couldn't be loaded, so they can be reported by the status
+	var loadErrors []v1.SchemaLoadError
+	addError := func(path string, err error) {
+		loadErrors = append(loadErrors, v1.SchemaLoadError{Category: c.category, Path: path, Error: err.Error(), FailedAt: now})
+	}
+
 	files, err := os.ReadDir(c.schemasPath)
 	if err != nil {
 		logrus.WithError(err).Errorf("Not able to read from schemas dir %s", c.schemasPath)
+		addError(c.schemasPath, err)
+		c.setStatus(nil, loadErrors, "")
+		c.publishLoading(previousPlugins, nil, loadErrors)
 		return
 	}
 
-	// newSchemas is used for double buffering, to avoid any issue when there are panels to validate at the same time load() is triggered
-	newSchemas := make(map[string]cue.Value)
+	// newInstances is used for double buffering, the runtimes keep validating with the previous schemas until all the plugins are loaded
+	newInstances := make(map[string]*build.Instance)
+	var newPlugins []*v1.SchemaPlugin
+	// addPlugin registers the plugin loaded, unless another schema is already registered for the same kind
+	addPlugin := func(kind string, schema cue.Value, instance *build.Instance, plugin *v1.SchemaPlugin, errorPath string) {
+		if _, ok := newInstances[kind]; ok {
+			logrus.Errorf("Conflict caused by %s: a schema already exists for kind %s, skipping this schema", errorPath, kind)
+			addError(errorPath, fmt.Errorf("a schema already exists for kind %s", kind))
+			return
+		}
+		newInstances[kind] = instance
+		plugin.Kind = kind
+		plugin.Version = c.version(schema)
+		plugin.LoadedAt = now
+		newPlugins = append(newPlugins, plugin)
+		logrus.Debugf("Loaded schema %s from file %s", kind, plugin.Path)
+	}
 
 	// process each schema plugin to convert it into a CUE Value
 	for _, file := range files {
@@ -204,60 +891,301 @@ func (c *cueDefs) load() {
 			logrus.Warningf("Plugin %s is not a folder", file.Name())
 			continue
 		}
-
 		schemaPath := filepath.Join(c.schemasPath, file.Name())
-
-		// load the cue files into build.Instances slice
-		buildInstances := load.Instances([]string{}, &load.Config{Dir: schemaPath})
-		// we strongly assume that only 1 buildInstance should be returned, otherwise we skip it
-		// TODO can probably be improved
-		if len(buildInstances) != 1 {
-			logrus.Errorf("The number of build instances for %s is != 1, skipping this schema", schemaPath)
+		kind, schema, instance, err := c.loadPlugin(schemaPath)
+		if err != nil {
+			addError(schemaPath, err)
 			continue
 		}
-		buildInstance := buildInstances[0]
+		addPlugin(kind, schema, instance, &v1.SchemaPlugin{Path: schemaPath}, schemaPath)
+	}
 
-		// check for errors on the instances (these are typically parsing errors)
-		if buildInstance.Err != nil {
-			logrus.WithError(buildInstance.Err).Errorf("Error retrieving schema for %s, skipping this schema", schemaPath)
-			continue
+	bundlesDir := c.loadBundles(addPlugin, addError)
+
+	c.setInstances(newInstances)
+	c.setStatus(newPlugins, loadErrors, bundlesDir)
+	c.publishLoading(previousPlugins, newPlugins, loadErrors)
+
+	logrus.Infof("Schemas at %s (re)loaded", c.schemasPath)
+}
+
+// publishLoading publishes the result of the loading of all the plugins: the plugins loaded, the plugins that failed to load,
+// and the plugins previously loaded whose kind is no longer provided.
+func (c *cueDefs) publishLoading(previousPlugins []*v1.SchemaPlugin, plugins []*v1.SchemaPlugin, loadErrors []v1.SchemaLoadError) {
+	kinds := make(map[string]bool, len(plugins))
+	for _, plugin := range plugins {
+		kinds[plugin.Kind] = true
+		c.events.publish(v1.SchemaReloadEvent{Category: c.category, Path: plugin.Path, Kind: plugin.Kind, Status: v1.SchemaReloadLoaded, Time: plugin.LoadedAt})
+	}
+	for _, loadError := range loadErrors {
+		c.events.publish(v1.SchemaReloadEvent{Category: c.category, Path: loadError.Path, Status: v1.SchemaReloadFailed, Error: loadError.Error, Time: loadError.FailedAt})
+	}
+	now := time.Now().UTC()
+	for _, plugin := range previousPlugins {
+		if !kinds[plugin.Kind] {
+			c.events.publish(v1.SchemaReloadEvent{Category: c.category, Path: plugin.Path, Kind: plugin.Kind, Status: v1.SchemaReloadRemoved, Time: now})
 		}
+	}
+}
 
-		// build Value from the Instance
-		schema := c.context.BuildInstance(buildInstance)
-		if schema.Err() != nil {
-			logrus.WithError(schema.Err()).Errorf("Error during build for %s, skipping this schema", schemaPath)
-			continue
+// reloadPlugin loads again the plugin of the folder schemaPath, and replaces the schema previously loaded from this folder.
+// The plugin is removed when the folder doesn't exist anymore, and when it fails to load.
+func (c *cueDefs) reloadPlugin(schemaPath string) {
+	if !c.enabled() {
+		return
+	}
+	c.loadMutex.Lock()
+	defer c.loadMutex.Unlock()
+	now := time.Now().UTC()
+	var previous *v1.SchemaPlugin
+	var plugins []*v1.SchemaPlugin
+	for _, plugin := range c.getLoadedPlugins() {
+		if plugin.Path == schemaPath {
+			previous = plugin
+		} else {
+			plugins = append(plugins, plugin)
+		}
+	}
+	var loadErrors []v1.SchemaLoadError
+	for _, loadError := range c.getErrors() {
+		if loadError.Path != schemaPath {
+			loadErrors = append(loadErrors, loadError)
 		}
+	}
+	instances := make(map[string]*build.Instance, len(c.instances))
+	for kind, instance := range c.instances {
+		instances[kind] = instance
+	}
+	if previous != nil {
+		delete(instances, previous.Kind)
+	}
 
-		// unify with the base def to complete defaults + check if the plugin fulfils the base requirements
-		finalSchema := c.baseDef.Unify(schema)
-		if finalSchema.Err() != nil {
-			logrus.WithError(finalSchema.Err()).Errorf("Error during schema validation for %s, skipping this schema", schemaPath)
-			continue
+	event := v1.SchemaReloadEvent{Category: c.category, Path: schemaPath, Time: now}
+	if info, err := os.Stat(schemaPath); err != nil || !info.IsDir() {
+		if previous == nil {
+			return
+		}
+		logrus.Infof("Schema %s removed, as the folder %s doesn't exist anymore", previous.Kind, schemaPath)
+		event.Kind = previous.Kind
+		event.Status = v1.SchemaReloadRemoved
+	} else {
+		kind, schema, instance, err := c.loadPlugin(schemaPath)
+		if err == nil {
+			if _, ok := instances[kind]; ok {
+				logrus.Errorf("Conflict caused by %s: a schema already exists for kind %s, skipping this schema", schemaPath, kind)
+				err = fmt.Errorf("a schema already exists for kind %s", kind)
+			}
 		}
+		event.Kind = kind
+		if err != nil {
+			loadErrors = append(loadErrors, v1.SchemaLoadError{Category: c.category, Path: schemaPath, Error: err.Error(), FailedAt: now})
+			event.Status = v1.SchemaReloadFailed
+			event.Error = err.Error()
+		} else {
+			instances[kind] = instance
+			plugins = append(plugins, &v1.SchemaPlugin{Kind: kind, Version: c.version(schema), Path: schemaPath, LoadedAt: now})
+			event.Status = v1.SchemaReloadLoaded
+			logrus.Infof("Schema %s reloaded from %s", kind, schemaPath)
+		}
+	}
+	c.setInstances(instances)
+	c.mutex.RLock()
+	bundlesDir := c.bundlesDir
+	c.mutex.RUnlock()
+	c.setStatus(plugins, loadErrors, bundlesDir)
+	c.events.publish(event)
+}
 
-		// check if another schema for the same Kind was already registered
-		kind, _ := finalSchema.LookupPath(cue.ParsePath(c.kindCuePath)).String()
-		if _, ok := newSchemas[kind]; ok {
-			logrus.Errorf("Conflict caused by %s: a schema already exists for kind %s, skipping this schema", schemaPath, kind)
+// loadPlugin returns the kind and the schema of the plugin, made of the CUE package in schemaPath, and the package itself.
+func (c *cueDefs) loadPlugin(schemaPath string) (string, cue.Value, *build.Instance, error) {
+	// load the cue files into build.Instances slice
+	buildInstances := load.Instances([]string{}, &load.Config{Dir: schemaPath})
+	// we strongly assume that only 1 buildInstance should be returned, otherwise we skip it
+	// TODO can probably be improved
+	if len(buildInstances) != 1 {
+		logrus.Errorf("The number of build instances for %s is != 1, skipping this schema", schemaPath)
+		return "", cue.Value{}, nil, fmt.Errorf("the number of build instances is %d instead of 1", len(buildInstances))
+	}
+	buildInstance := buildInstances[0]
+
+	// check for errors on the instances (these are typically parsing errors)
+	if buildInstance.Err != nil {
+		logrus.WithError(buildInstance.Err).Errorf("Error retrieving schema for %s, skipping this schema", schemaPath)
+		return "", cue.Value{}, nil, buildInstance.Err
+	}
+
+	// build Value from the Instance
+	schema := c.context.BuildInstance(buildInstance)
+	if schema.Err() != nil {
+		logrus.WithError(schema.Err()).Errorf("Error during build for %s, skipping this schema", schemaPath)
+		return "", cue.Value{}, nil, schema.Err()
+	}
+
+	// unify with the base def to complete defaults + check if the plugin fulfils the base requirements
+	finalSchema := c.baseDef.Unify(schema)
+	if finalSchema.Err() != nil {
+		logrus.WithError(finalSchema.Err()).Errorf("Error during schema validation for %s, skipping this schema", schemaPath)
+		return "", cue.Value{}, nil, finalSchema.Err()
+	}
+	kind, _ := finalSchema.LookupPath(cue.ParsePath(c.kindCuePath)).String()
+	return kind, finalSchema, buildInstance, nil
+}
+
+// setInstances replaces the plugins loaded, and builds them in every runtime.
+func (c *cueDefs) setInstances(instances map[string]*build.Instance) {
+	c.instances = instances
+	c.runtimes.update(func(r *cueRuntime) {
+		r.setSchemas(c.category, instances)
+	})
+}
+
+// version returns the version of the schema of a plugin. Only the panels are versioned for the moment.
+func (c *cueDefs) version(schema cue.Value) int {
+	version, _ := schema.LookupPath(cue.ParsePath(versionDefPath)).Int64()
+	return int(version)
+}
+
+// loadBundles loads the plugins of the category provided by the bundles of the plugins path.
+// The plugins are extracted in a temporary folder, as the CUE packages have to be read from files.
+// It returns the temporary folder, empty when no bundle provides plugins of the category.
+func (c *cueDefs) loadBundles(addPlugin func(string, cue.Value, *build.Instance, *v1.SchemaPlugin, string), addError func(string, error)) string {
+	if len(c.pluginsPath) == 0 {
+		return ""
+	}
+	bundles, err := ListBundles(c.pluginsPath)
+	if err != nil {
+		logrus.WithError(err).Errorf("Not able to read from plugins dir %s", c.pluginsPath)
+		addError(c.pluginsPath, err)
+		return ""
+	}
+	bundlesDir := ""
+	for _, bundlePath := range bundles {
+		manifest, err := ReadBundleManifest(bundlePath)
+		if err != nil {
+			logrus.WithError(err).Errorf("Invalid plugin bundle %s, skipping this bundle", bundlePath)
+			addError(bundlePath, err)
 			continue
 		}
-
-		newSchemas[kind] = finalSchema
-		logrus.Debugf("Loaded schema %s from file %s", kind, schemaPath)
+		kinds := manifest.Kinds[c.category]
+		if len(kinds) == 0 {
+			continue
+		}
+		if len(bundlesDir) == 0 {
+			if bundlesDir, err = os.MkdirTemp("", fmt.Sprintf("perses-%s-", c.category)); err != nil {
+				logrus.WithError(err).Error("Not able to create a folder to extract the plugin bundles")
+				addError(c.pluginsPath, err)
+				return ""
+			}
+		}
+		dir, err := JoinUnder(bundlesDir, manifest.Name)
+		if err != nil {
+			logrus.WithError(err).Errorf("Invalid name of the plugin bundle %s, skipping this bundle", bundlePath)
+			addError(bundlePath, err)
+			continue
+		}
+		if _, err := extractBundle(bundlePath, c.category, dir); err != nil {
+			logrus.WithError(err).Errorf("Not able to extract the plugin bundle %s, skipping this bundle", bundlePath)
+			addError(bundlePath, err)
+			continue
+		}
+		c.loadBundle(bundlePath, manifest, kinds, dir, addPlugin, addError)
 	}
+	return bundlesDir
+}
 
-	// make c.schemas equal to newSchemas: deep copy newSchemas to c.schemas, then remove any value of c.schemas not existing in newSchemas
-	for key, value := range newSchemas {
-		c.schemas.Store(key, value)
+// loadBundle loads the plugins extracted from the bundle into dir. Each of them must provide one of the kinds of the manifest,
+// and each of these kinds must be provided. A kind already provided by another plugin is reported as a conflict.
+func (c *cueDefs) loadBundle(bundlePath string, manifest *v1.PluginManifest, kinds []string, dir string, addPlugin func(string, cue.Value, *build.Instance, *v1.SchemaPlugin, string), addError func(string, error)) {
+	declared := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		declared[kind] = false
+	}
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		schemaPath := filepath.Join(dir, file.Name())
+		kind, schema, instance, err := c.loadPlugin(schemaPath)
+		if err != nil {
+			addError(bundlePath, fmt.Errorf("%s: %s", file.Name(), err))
+			continue
+		}
+		if _, ok := declared[kind]; !ok {
+			logrus.Errorf("The kind %s of %s is not declared in the manifest of the bundle %s, skipping this schema", kind, file.Name(), bundlePath)
+			addError(bundlePath, fmt.Errorf("the kind %s is not declared in the manifest", kind))
+			continue
+		}
+		declared[kind] = true
+		addPlugin(kind, schema, instance, &v1.SchemaPlugin{
+			Path:   schemaPath,
+			Bundle: fmt.Sprintf("%s@%s", manifest.Name, manifest.Version),
+		}, bundlePath)
 	}
-	c.schemas.Range(func(key interface{}, value interface{}) bool {
-		if _, ok := newSchemas[key.(string)]; !ok {
-			c.schemas.Delete(key)
+	for _, kind := range kinds {
+		if found := declared[kind]; !found {
+			addError(bundlePath, fmt.Errorf("the kind %s declared in the manifest is not provided by the bundle", kind))
 		}
-		return true
+	}
+}
+
+// setStatus replaces the description of the plugins loaded and the errors of the last loading.
+// bundlesDir is the folder the plugins of the bundles have been extracted to. The folder of the previous loading is removed.
+func (c *cueDefs) setStatus(plugins []*v1.SchemaPlugin, loadErrors []v1.SchemaLoadError, bundlesDir string) {
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Kind < plugins[j].Kind
 	})
+	c.mutex.Lock()
+	previousBundlesDir := c.bundlesDir
+	c.plugins = plugins
+	c.errors = loadErrors
+	c.exported = false
+	c.bundlesDir = bundlesDir
+	c.mutex.Unlock()
+	if c.onLoad != nil {
+		kinds := make([]string, 0, len(plugins))
+		for _, plugin := range plugins {
+			kinds = append(kinds, plugin.Kind)
+		}
+		c.onLoad(kinds...)
+	}
+	if len(previousBundlesDir) > 0 && previousBundlesDir != bundlesDir {
+		if err := os.RemoveAll(previousBundlesDir); err != nil {
+			logrus.WithError(err).Warningf("unable to remove the folder %s", previousBundlesDir)
+		}
+	}
+}
 
-	logrus.Infof("Schemas at %s (re)loaded", c.schemasPath)
+// getPlugins returns the description of the plugins loaded, sorted by kind, with their OpenAPI schemas.
+func (c *cueDefs) getPlugins() []*v1.SchemaPlugin {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.exported {
+		for _, plugin := range c.plugins {
+			plugin.Schema = openAPISchema(plugin.Path)
+		}
+		c.exported = true
+	}
+	return c.plugins
+}
+
+// getLoadedPlugins returns the description of the plugins loaded, without exporting their schemas.
+func (c *cueDefs) getLoadedPlugins() []*v1.SchemaPlugin {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.plugins
+}
+
+// countPlugins returns the number of plugins loaded.
+func (c *cueDefs) countPlugins() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.plugins)
+}
+
+// getErrors returns the plugins that failed to load during the last loading.
+func (c *cueDefs) getErrors() []v1.SchemaLoadError {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.errors
 }
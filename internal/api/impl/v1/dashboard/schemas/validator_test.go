@@ -15,6 +15,12 @@ package schemas
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -247,7 +253,7 @@ func TestValidateDashboard(t *testing.T) {
 					},
 				},
 			},
-			result: "invalid panel MyInvalidPanel: Unknown kind UnknownChart",
+			result: "/spec/panels/MyInvalidPanel/kind: Unknown kind UnknownChart",
 		},
 		{
 			title: "dashboard containing an invalid panel (unknown datasource kind)",
@@ -319,7 +325,7 @@ func TestValidateDashboard(t *testing.T) {
 					},
 				},
 			},
-			result: "invalid panel MyInvalidPanel: Unknown datasource.kind UnknownDatasource",
+			result: "/spec/panels/MyInvalidPanel/datasource/kind: Unknown datasource.kind UnknownDatasource",
 		},
 		{
 			title: "dashboard containing an invalid panel (missing mandatory attribute)",
@@ -396,7 +402,7 @@ func TestValidateDashboard(t *testing.T) {
 					},
 				},
 			},
-			result: "invalid panel MyInvalidPanel: field \"kind\" not found",
+			result: "/spec/panels/MyInvalidPanel/kind: field \"kind\" not found",
 		},
 		{
 			title: "dashboard containing an invalid panel (panel field not allowed)",
@@ -474,7 +480,7 @@ func TestValidateDashboard(t *testing.T) {
 					},
 				},
 			},
-			result: "invalid panel MyInvalidPanel: display: field not allowed: aaaaaa",
+			result: "/spec/panels/MyInvalidPanel/display: field not allowed: aaaaaa",
 		},
 		{
 			title: "dashboard containing an invalid panel (query field not allowed)",
@@ -547,7 +553,7 @@ func TestValidateDashboard(t *testing.T) {
 					},
 				},
 			},
-			result: "invalid panel MyInvalidPanel: options.queries.0: field not allowed: unwanted",
+			result: "/spec/panels/MyInvalidPanel/options/queries/0: field not allowed: unwanted",
 		},
 		{
 			title: "dashboard containing an invalid panel (query not matching datasource type)",
@@ -627,7 +633,7 @@ func TestValidateDashboard(t *testing.T) {
 					},
 				},
 			},
-			result: "invalid panel MyInvalidPanel: options.queries.1.kind: conflicting values \"CustomGraphQuery\" and \"SQLGraphQuery\"",
+			result: "/spec/panels/MyInvalidPanel/options/queries/1/kind: conflicting values \"CustomGraphQuery\" and \"SQLGraphQuery\"",
 		},
 	}
 	for _, test := range testSuite {
@@ -648,3 +654,1535 @@ func TestValidateDashboard(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTracePanels(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		panels map[string]json.RawMessage
+		result string
+	}{
+		{
+			title: "trace table with a TraceQL query",
+			panels: map[string]json.RawMessage{
+				"SlowCheckouts": []byte(`
+					{
+						"kind": "TraceTable",
+						"display": {
+							"name": "slow checkouts"
+						},
+						"datasource": {
+							"kind": "TempoDatasource"
+						},
+						"options": {
+							"queries": [
+								{
+									"kind": "TempoTraceQLQuery",
+									"options": {
+										"query": "{ resource.service.name = \"checkout\" && duration > 1s }",
+										"limit": 20
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "",
+		},
+		{
+			title: "trace table mixing search and trace ID queries",
+			panels: map[string]json.RawMessage{
+				"Checkout": []byte(`
+					{
+						"kind": "TraceTable",
+						"display": {
+							"name": "checkout"
+						},
+						"datasource": {
+							"kind": "TempoDatasource"
+						},
+						"options": {
+							"queries": [
+								{
+									"kind": "TempoTraceSearchQuery",
+									"options": {
+										"service_name": "checkout",
+										"tags": {
+											"http.status_code": "500"
+										},
+										"min_duration": "1s500ms"
+									}
+								},
+								{
+									"kind": "TempoTraceIDQuery",
+									"options": {
+										"trace_id": "2f3e0cee77ae5dc9c17ade3689eb2e54"
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "",
+		},
+		{
+			title: "trace table with an invalid trace ID",
+			panels: map[string]json.RawMessage{
+				"Checkout": []byte(`
+					{
+						"kind": "TraceTable",
+						"display": {
+							"name": "checkout"
+						},
+						"datasource": {
+							"kind": "TempoDatasource"
+						},
+						"options": {
+							"queries": [
+								{
+									"kind": "TempoTraceIDQuery",
+									"options": {
+										"trace_id": "not-a-trace-id"
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "/spec/panels/Checkout/options/queries/0: 3 errors in empty disjunction, " +
+				"/spec/panels/Checkout/options/queries/0/kind: conflicting values \"TempoTraceQLQuery\" and \"TempoTraceIDQuery\", " +
+				"/spec/panels/Checkout/options/queries/0/kind: conflicting values \"TempoTraceSearchQuery\" and \"TempoTraceIDQuery\", " +
+				"/spec/panels/Checkout/options/queries/0/options/trace_id: invalid value \"not-a-trace-id\" (out of bound =~\"^[a-fA-F0-9]{1,32}$\")",
+		},
+		{
+			title: "trace table with an empty min duration",
+			panels: map[string]json.RawMessage{
+				"Checkout": []byte(`
+					{
+						"kind": "TraceTable",
+						"display": {
+							"name": "checkout"
+						},
+						"datasource": {
+							"kind": "TempoDatasource"
+						},
+						"options": {
+							"queries": [
+								{
+									"kind": "TempoTraceSearchQuery",
+									"options": {
+										"service_name": "checkout",
+										"min_duration": ""
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "/spec/panels/Checkout/options/queries/0: 3 errors in empty disjunction, " +
+				"/spec/panels/Checkout/options/queries/0/kind: conflicting values \"TempoTraceIDQuery\" and \"TempoTraceSearchQuery\", " +
+				"/spec/panels/Checkout/options/queries/0/kind: conflicting values \"TempoTraceQLQuery\" and \"TempoTraceSearchQuery\", " +
+				"/spec/panels/Checkout/options/queries/0/options/min_duration: invalid value \"\" (out of bound =~\"^(?:\\\\d+(?:h|ms|us|ns|m|s))+$\")",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			validator := NewValidator(config.Schemas{
+				PanelsPath:  "../../../../../../schemas/panels",
+				QueriesPath: "../../../../../../schemas/queries",
+			})
+			validator.LoadPanels()
+			validator.LoadQueries()
+
+			err := validator.Validate(test.panels)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidatePanelTime(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		panels map[string]json.RawMessage
+		result string
+	}{
+		{
+			title: "relative time range shifted by a week",
+			panels: map[string]json.RawMessage{
+				"LastWeek": []byte(`
+					{
+						"kind": "LineChart",
+						"display": {
+							"name": "requests"
+						},
+						"datasource": {
+							"kind": "PrometheusDatasource"
+						},
+						"time": {"relative": "1d", "shift": "1w"},
+						"options": {
+							"queries": [
+								{
+									"kind": "PrometheusGraphQuery",
+									"options": {
+										"query": "sum(rate(http_requests_total[5m]))"
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "",
+		},
+		{
+			title: "invalid durations",
+			panels: map[string]json.RawMessage{
+				"LastWeek": []byte(`
+					{
+						"kind": "LineChart",
+						"display": {
+							"name": "requests"
+						},
+						"datasource": {
+							"kind": "PrometheusDatasource"
+						},
+						"time": {"relative": "", "shift": "last week"},
+						"options": {
+							"queries": [
+								{
+									"kind": "PrometheusGraphQuery",
+									"options": {
+										"query": "sum(rate(http_requests_total[5m]))"
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "/spec/panels/LastWeek/time/relative: invalid value \"\" (out of bound !=\"\"), " +
+				"/spec/panels/LastWeek/time/shift: invalid value \"last week\" " +
+				"(out of bound =~\"^(?:(\\\\d+)y)?(?:(\\\\d+)w)?(?:(\\\\d+)d)?(?:(\\\\d+)h)?(?:(\\\\d+)m)?(?:(\\\\d+)s)?(?:(\\\\d+)ms)?$\")",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			validator := NewValidator(config.Schemas{
+				PanelsPath:  "../../../../../../schemas/panels",
+				QueriesPath: "../../../../../../schemas/queries",
+			})
+			validator.LoadPanels()
+			validator.LoadQueries()
+
+			err := validator.Validate(test.panels)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidateMixedDatasourcePanels(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		panels map[string]json.RawMessage
+		result string
+	}{
+		{
+			title: "query referencing another datasource of the same kind",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "FirstChart",
+						"display": {
+							"name": "first chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource",
+							"name": "MyCustomDatasource"
+						},
+						"options": {
+							"a": "yes",
+							"b": {
+								"c": []
+							},
+							"queries": [
+								{
+									"kind": "CustomGraphQuery",
+									"datasource": {
+										"kind": "CustomDatasource",
+										"name": "MyGlobalCustomDatasource",
+										"global": true
+									},
+									"options": {
+										"custom": true
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "",
+		},
+		{
+			title: "panel mixing two kinds of queries",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "FirstChart",
+						"display": {
+							"name": "first chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"a": "yes",
+							"b": {
+								"c": []
+							},
+							"queries": [
+								{
+									"kind": "CustomGraphQuery",
+									"options": {
+										"custom": true
+									}
+								},
+								{
+									"kind": "SQLGraphQuery",
+									"datasource": {
+										"kind": "SQLDatasource",
+										"name": "MySQLDatasource"
+									},
+									"options": {
+										"select": "*",
+										"from": "TABLE"
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "",
+		},
+		{
+			title: "query not matching the kind of its datasource",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "FirstChart",
+						"display": {
+							"name": "first chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"a": "yes",
+							"b": {
+								"c": []
+							},
+							"queries": [
+								{
+									"kind": "CustomGraphQuery",
+									"datasource": {
+										"kind": "SQLDatasource",
+										"name": "MySQLDatasource"
+									},
+									"options": {
+										"custom": true
+									}
+								}
+							]
+						}
+					}
+				`),
+			},
+			result: "/spec/panels/MyPanel/options/queries/0: 2 errors in empty disjunction, " +
+				"/spec/panels/MyPanel/options/queries/0/datasource/kind: conflicting values \"CustomDatasource\" and \"SQLDatasource\", " +
+				"/spec/panels/MyPanel/options/queries/0/kind: conflicting values \"SQLGraphQuery\" and \"CustomGraphQuery\"",
+		},
+		{
+			title: "query referencing an unknown kind of datasource",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "SecondChart",
+						"display": {
+							"name": "second chart"
+						},
+						"datasource": {
+							"kind": "SQLDatasource"
+						},
+						"options": {
+							"a": "yes",
+							"b": {
+								"d": []
+							},
+							"query": {
+								"kind": "SQLGraphQuery",
+								"datasource": {
+									"kind": "UnknownDatasource",
+									"name": "Unknown"
+								},
+								"options": {
+									"select": "*",
+									"from": "TABLE"
+								}
+							}
+						}
+					}
+				`),
+			},
+			result: "/spec/panels/MyPanel: Unknown query datasource.kind UnknownDatasource",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			validator := NewValidator(config.Schemas{
+				PanelsPath:  "testdata/panels",
+				QueriesPath: "testdata/queries",
+			})
+			validator.LoadPanels()
+			validator.LoadQueries()
+
+			err := validator.Validate(test.panels)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidateReportsEveryPanel(t *testing.T) {
+	panels := map[string]json.RawMessage{
+		"UnknownPanel": []byte(`
+			{
+				"kind": "UnknownChart",
+				"display": {
+					"name": "unknown chart"
+				}
+			}
+		`),
+		"NoKindPanel": []byte(`
+			{
+				"display": {
+					"name": "no kind"
+				}
+			}
+		`),
+	}
+	validator := NewValidator(config.Schemas{
+		PanelsPath:  "testdata/panels",
+		QueriesPath: "testdata/queries",
+	})
+	validator.LoadPanels()
+	validator.LoadQueries()
+
+	err := validator.Validate(panels)
+	assert.Equal(t, common.ValidationReport{
+		{Path: "/spec/panels/NoKindPanel/kind", Severity: common.SeverityError, Message: `field "kind" not found`},
+		{Path: "/spec/panels/UnknownPanel/kind", Severity: common.SeverityError, Message: "Unknown kind UnknownChart"},
+	}, err)
+}
+
+func TestValidateVariables(t *testing.T) {
+	// the model accepts SQLQuery as if a plugin had provided it before, so the schemas are the ones refusing it when it's unknown
+	dashboard.RegisterPluginVariableKinds("SQLQuery")
+	testSuite := []struct {
+		title       string
+		schemasPath string
+		variables   string
+		result      string
+	}{
+		{
+			title:       "valid variables",
+			schemasPath: "../../../../../../schemas/variables",
+			variables: `
+				{
+					"job": {
+						"kind": "PromQLQuery",
+						"hide": true,
+						"parameter": {
+							"expr": "group by (job) (up)",
+							"label_name": "job",
+							"capturing_regexp": "(.*)"
+						}
+					},
+					"interval": {
+						"kind": "Interval",
+						"hide": true,
+						"parameter": {
+							"values": ["1m", "5m"],
+							"auto": true,
+							"auto_step_count": 50
+						}
+					}
+				}
+			`,
+			result: "",
+		},
+		{
+			title:       "variable provided by an unknown plugin",
+			schemasPath: "../../../../../../schemas/variables",
+			variables: `
+				{
+					"table": {
+						"kind": "SQLQuery",
+						"hide": true,
+						"parameter": {
+							"query": "SELECT name FROM tables"
+						}
+					}
+				}
+			`,
+			result: "/spec/variables/table/kind: Unknown kind SQLQuery",
+		},
+		{
+			title:       "valid variable provided by a plugin",
+			schemasPath: "testdata/variables",
+			variables: `
+				{
+					"table": {
+						"kind": "SQLQuery",
+						"hide": true,
+						"parameter": {
+							"query": "SELECT name FROM tables"
+						}
+					}
+				}
+			`,
+			result: "",
+		},
+		{
+			title:       "invalid variables provided by a plugin",
+			schemasPath: "testdata/variables",
+			variables: `
+				{
+					"table": {
+						"kind": "SQLQuery",
+						"hide": true,
+						"parameter": {
+							"query": 42
+						}
+					},
+					"column": {
+						"kind": "SQLQuery",
+						"hide": true,
+						"parameter": {
+							"query": "SELECT name FROM columns",
+							"limit": 10
+						}
+					}
+				}
+			`,
+			result: "/spec/variables/column/parameter: field not allowed: limit, " +
+				"/spec/variables/table/parameter/query: conflicting values 42 and string (mismatched types int and string)",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			var variables map[string]*dashboard.Variable
+			if err := json.Unmarshal([]byte(test.variables), &variables); err != nil {
+				t.Fatal(err)
+			}
+			validator := NewValidator(config.Schemas{
+				VariablesPath: test.schemasPath,
+			})
+			validator.LoadVariables()
+
+			err := validator.ValidateVariables(variables)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidateAnnotations(t *testing.T) {
+	dashboard.RegisterPluginAnnotationKinds("Alertmanager")
+	testSuite := []struct {
+		title       string
+		annotations string
+		result      string
+	}{
+		{
+			title: "valid annotations",
+			annotations: `
+				{
+					"deployments": {
+						"kind": "PromQLQuery",
+						"parameter": {
+							"expr": "changes(kube_deployment_status_observed_generation[5m]) > 0",
+							"title": "Deployment of {{deployment}}",
+							"step": "1m"
+						}
+					},
+					"incidents": {
+						"kind": "Static",
+						"parameter": {
+							"events": [
+								{"time": "2022-06-01T10:00:00Z", "end": "2022-06-01T11:30:00Z", "title": "Incident"}
+							]
+						}
+					}
+				}
+			`,
+			result: "",
+		},
+		{
+			title: "unknown kind",
+			annotations: `
+				{
+					"alerts": {
+						"kind": "Alertmanager",
+						"parameter": {}
+					}
+				}
+			`,
+			result: "/spec/annotations/alerts/kind: Unknown kind Alertmanager",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			var annotations map[string]*dashboard.Annotation
+			if err := json.Unmarshal([]byte(test.annotations), &annotations); err != nil {
+				t.Fatal(err)
+			}
+			validator := NewValidator(config.Schemas{
+				AnnotationsPath: "../../../../../../schemas/annotations",
+			})
+			validator.LoadAnnotations()
+
+			err := validator.ValidateAnnotations(annotations)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidateLayouts(t *testing.T) {
+	testSuite := []struct {
+		title   string
+		layouts string
+		result  string
+	}{
+		{
+			title: "valid grid",
+			layouts: `
+				[
+					{
+						"kind": "Grid",
+						"spec": {
+							"items": [
+								{
+									"x": 0,
+									"y": 0,
+									"width": 12,
+									"height": 6,
+									"content": {
+										"$ref": "#/spec/panels/CPU"
+									}
+								}
+							]
+						}
+					}
+				]
+			`,
+			result: "",
+		},
+		{
+			title: "grid item without width",
+			layouts: `
+				[
+					{
+						"kind": "Grid",
+						"spec": {
+							"items": [
+								{
+									"x": 0,
+									"y": 0,
+									"width": 0,
+									"height": 6,
+									"content": {
+										"$ref": "#/spec/panels/CPU"
+									}
+								}
+							]
+						}
+					}
+				]
+			`,
+			result: "/spec/layouts/0/spec/items/0/width: invalid value 0 (out of bound >0)",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			var layouts []dashboard.Layout
+			if err := json.Unmarshal([]byte(test.layouts), &layouts); err != nil {
+				t.Fatal(err)
+			}
+			validator := NewValidator(config.Schemas{
+				LayoutsPath: "../../../../../../schemas/layouts",
+			})
+			validator.LoadLayouts()
+
+			err := validator.ValidateLayouts(layouts)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidateDatasource(t *testing.T) {
+	datasource.RegisterPluginKinds("PostgreSQL")
+	testSuite := []struct {
+		title       string
+		schemasPath string
+		datasource  string
+		result      string
+	}{
+		{
+			title:       "valid prometheus datasource",
+			schemasPath: "../../../../../../schemas/datasources",
+			datasource: `
+				{
+					"kind": "Datasource",
+					"metadata": {
+						"name": "PrometheusDemo",
+						"project": "perses"
+					},
+					"spec": {
+						"kind": "Prometheus",
+						"default": true,
+						"http": {
+							"url": "https://prometheus.demo.do.prometheus.io",
+							"access": "server"
+						}
+					}
+				}
+			`,
+			result: "",
+		},
+		{
+			title:       "datasource provided by an unknown plugin",
+			schemasPath: "../../../../../../schemas/datasources",
+			datasource: `
+				{
+					"kind": "Datasource",
+					"metadata": {
+						"name": "Inventory",
+						"project": "perses"
+					},
+					"spec": {
+						"kind": "PostgreSQL",
+						"default": false,
+						"host": "localhost:5432",
+						"database": "inventory"
+					}
+				}
+			`,
+			result: "/spec/kind: Unknown kind PostgreSQL",
+		},
+		{
+			title:       "valid datasource provided by a plugin",
+			schemasPath: "testdata/datasources",
+			datasource: `
+				{
+					"kind": "Datasource",
+					"metadata": {
+						"name": "Inventory",
+						"project": "perses"
+					},
+					"spec": {
+						"kind": "PostgreSQL",
+						"default": false,
+						"host": "localhost:5432",
+						"database": "inventory"
+					}
+				}
+			`,
+			result: "",
+		},
+		{
+			title:       "invalid datasource provided by a plugin",
+			schemasPath: "testdata/datasources",
+			datasource: `
+				{
+					"kind": "Datasource",
+					"metadata": {
+						"name": "Inventory",
+						"project": "perses"
+					},
+					"spec": {
+						"kind": "PostgreSQL",
+						"host": 5432,
+						"port": 5432
+					}
+				}
+			`,
+			result: "/spec/host: conflicting values 5432 and string (mismatched types int and string), " +
+				"/spec: field not allowed: port",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			entity := &v1.Datasource{}
+			if err := json.Unmarshal([]byte(test.datasource), entity); err != nil {
+				t.Fatal(err)
+			}
+			validator := NewValidator(config.Schemas{
+				DatasourcesPath: test.schemasPath,
+			})
+			validator.LoadDatasources()
+
+			err := validator.ValidateDatasource(entity.Spec)
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			assert.Equal(t, test.result, errString)
+		})
+	}
+}
+
+func TestValidateSkippedWithoutSchemas(t *testing.T) {
+	dashboard.RegisterPluginVariableKinds("SQLQuery")
+	var variables map[string]*dashboard.Variable
+	if err := json.Unmarshal([]byte(`{"table": {"kind": "SQLQuery", "hide": true, "parameter": {}}}`), &variables); err != nil {
+		t.Fatal(err)
+	}
+	validator := NewValidator(config.Schemas{})
+	validator.LoadVariables()
+	validator.LoadLayouts()
+	validator.LoadDatasources()
+
+	assert.NoError(t, validator.ValidateVariables(variables))
+	assert.NoError(t, validator.ValidateLayouts([]dashboard.Layout{{Kind: "Grid"}}))
+	assert.NoError(t, validator.ValidateDatasource(&datasource.Plugin{"kind": "PostgreSQL"}))
+}
+
+func TestLoadLayoutsAcceptsPluginKinds(t *testing.T) {
+	layoutsPath := t.TempDir()
+	if err := os.Mkdir(filepath.Join(layoutsPath, "kanban"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	plugin := `
+package kanban
+
+#layout: {
+	kind: "Kanban"
+	spec: {
+		columns: [...{
+			title: string
+			items: [...{content: {"$ref": string}}]
+		}]
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(layoutsPath, "kanban", "kanban.cue"), []byte(plugin), 0600); err != nil {
+		t.Fatal(err)
+	}
+	layout := `{"kind": "Kanban", "spec": {"columns": [{"title": "CPU", "items": [{"content": {"$ref": "#/spec/panels/CPU"}}]}]}}`
+	result := dashboard.Layout{}
+	assert.Equal(t, fmt.Errorf("unknown layout.kind %q used", "Kanban"), json.Unmarshal([]byte(layout), &result))
+
+	validator := NewValidator(config.Schemas{LayoutsPath: layoutsPath})
+	validator.LoadLayouts()
+	assert.NoError(t, json.Unmarshal([]byte(layout), &result))
+	assert.NoError(t, validator.ValidateLayouts([]dashboard.Layout{result}))
+	assert.Equal(t, "/columns/0/items/0/content", result.Spec.PanelReferences()[0].Path)
+}
+
+func TestGetPluginsAndStatus(t *testing.T) {
+	panelsPath := t.TempDir()
+	plugins := map[string]string{
+		"text": `
+package text
+
+#panel: {
+	kind: "TextChart"
+	options: {
+		content: string
+	}
+}
+`,
+		"broken": `
+package broken
+
+#panel: {
+	kind: "BrokenChart"
+	options: {
+`,
+	}
+	for name, content := range plugins {
+		if err := os.Mkdir(filepath.Join(panelsPath, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(panelsPath, name, name+".cue"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	validator := NewValidator(config.Schemas{
+		PanelsPath:  panelsPath,
+		QueriesPath: "testdata/queries",
+	})
+	validator.LoadPanels()
+	validator.LoadQueries()
+
+	panels := validator.GetPanels()
+	if assert.Len(t, panels, 1) {
+		assert.Equal(t, "TextChart", panels[0].Kind)
+		assert.Equal(t, filepath.Join(panelsPath, "text"), panels[0].Path)
+		assert.False(t, panels[0].LoadedAt.IsZero())
+		assert.JSONEq(t, `
+			{
+				"panel": {
+					"type": "object",
+					"required": ["kind", "options"],
+					"properties": {
+						"kind": {"type": "string", "enum": ["TextChart"]},
+						"options": {
+							"type": "object",
+							"required": ["content"],
+							"properties": {
+								"content": {"type": "string"}
+							}
+						}
+					}
+				}
+			}
+		`, string(panels[0].Schema))
+	}
+	var queryKinds []string
+	for _, query := range validator.GetQueries() {
+		queryKinds = append(queryKinds, query.Kind)
+	}
+	assert.Equal(t, []string{"CustomDatasource", "SQLDatasource"}, queryKinds)
+
+	status := validator.GetStatus()
+	assert.Equal(t, map[string]int{"panels": 1, "queries": 2}, status.Plugins)
+	if assert.Len(t, status.Errors, 1) {
+		assert.Equal(t, "panels", status.Errors[0].Category)
+		assert.Equal(t, filepath.Join(panelsPath, "broken"), status.Errors[0].Path)
+		assert.NotEmpty(t, status.Errors[0].Error)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		panels map[string]json.RawMessage
+		result map[string]string
+		err    string
+	}{
+		{
+			title: "defaults filled in",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "LegendChart",
+						"display": {
+							"name": "legend chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"queries": [
+								{
+									"kind": "CustomGraphQuery",
+									"options": {
+										"custom": true
+									}
+								}
+							],
+							"legend": {}
+						}
+					}
+				`),
+			},
+			result: map[string]string{
+				"MyPanel": `
+					{
+						"kind": "LegendChart",
+						"schema_version": 1,
+						"display": {
+							"name": "legend chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"queries": [
+								{
+									"kind": "CustomGraphQuery",
+									"options": {
+										"custom": true
+									}
+								}
+							],
+							"legend": {
+								"show": true,
+								"position": "bottom"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			title: "values set are kept",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "LegendChart",
+						"display": {
+							"name": "legend chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"queries": [],
+							"legend": {
+								"show": false,
+								"position": "right"
+							}
+						}
+					}
+				`),
+			},
+			result: map[string]string{
+				"MyPanel": `
+					{
+						"kind": "LegendChart",
+						"schema_version": 1,
+						"display": {
+							"name": "legend chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"queries": [],
+							"legend": {
+								"show": false,
+								"position": "right"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			title: "invalid panel",
+			panels: map[string]json.RawMessage{
+				"MyPanel": []byte(`
+					{
+						"kind": "LegendChart",
+						"display": {
+							"name": "legend chart"
+						},
+						"datasource": {
+							"kind": "CustomDatasource"
+						},
+						"options": {
+							"queries": [],
+							"legend": {
+								"position": "top"
+							}
+						}
+					}
+				`),
+			},
+			err: "/spec/panels/MyPanel/options/legend/position: 2 errors in empty disjunction, " +
+				"/spec/panels/MyPanel/options/legend/position: conflicting values \"bottom\" and \"top\", " +
+				"/spec/panels/MyPanel/options/legend/position: conflicting values \"right\" and \"top\"",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			validator := NewValidator(config.Schemas{
+				PanelsPath:  "testdata/panels",
+				QueriesPath: "testdata/queries",
+			})
+			validator.LoadPanels()
+			validator.LoadQueries()
+
+			panels, err := validator.Normalize(test.panels)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(test.result), len(panels))
+			for name, expected := range test.result {
+				assert.JSONEq(t, expected, string(panels[name]))
+			}
+		})
+	}
+}
+
+func TestMigratePanels(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		panel  string
+		result string
+		err    string
+	}{
+		{
+			title: "panel without version migrated from the version 1",
+			panel: `
+				{
+					"kind": "VersionedChart",
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"show_legend": true,
+						"size": 4
+					}
+				}
+			`,
+			result: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 3,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"legend": {
+							"show": true
+						},
+						"width": 4,
+						"height": 4
+					}
+				}
+			`,
+		},
+		{
+			title: "panel migrated from the version 2",
+			panel: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 2,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"legend": {
+							"show": false
+						},
+						"size": 6
+					}
+				}
+			`,
+			result: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 3,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"legend": {
+							"show": false
+						},
+						"width": 6,
+						"height": 6
+					}
+				}
+			`,
+		},
+		{
+			title: "panel up to date",
+			panel: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 3,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"legend": {
+							"show": false
+						},
+						"width": 6,
+						"height": 3
+					}
+				}
+			`,
+			result: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 3,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"legend": {
+							"show": false
+						},
+						"width": 6,
+						"height": 3
+					}
+				}
+			`,
+		},
+		{
+			title: "panel saved with a newer version",
+			panel: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 4,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": []
+					}
+				}
+			`,
+			err: "/spec/panels/MyPanel/schema_version: the panel has been saved with the version 4 of the schema, which is newer than the version 3 available",
+		},
+		{
+			title: "migration failing",
+			panel: `
+				{
+					"kind": "VersionedChart",
+					"schema_version": 2,
+					"display": {
+						"name": "versioned chart"
+					},
+					"datasource": {
+						"kind": "CustomDatasource"
+					},
+					"options": {
+						"queries": [],
+						"legend": {
+							"show": false
+						}
+					}
+				}
+			`,
+			err: "/spec/panels/MyPanel: unable to migrate the panel from the version 2 to the version 3",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			validator := NewValidator(config.Schemas{
+				PanelsPath:  "testdata/panels",
+				QueriesPath: "testdata/queries",
+			})
+			validator.LoadPanels()
+			validator.LoadQueries()
+
+			panels, err := validator.Migrate(map[string]json.RawMessage{"MyPanel": []byte(test.panel)})
+			if len(test.err) > 0 {
+				if assert.Error(t, err) {
+					assert.True(t, strings.HasPrefix(err.Error(), test.err), err.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.result, string(panels["MyPanel"]))
+		})
+	}
+}
+
+func TestMigrateDoesNotAddDefaults(t *testing.T) {
+	validator := NewValidator(config.Schemas{
+		PanelsPath:  "testdata/panels",
+		QueriesPath: "testdata/queries",
+	})
+	validator.LoadPanels()
+	validator.LoadQueries()
+	panels := map[string]json.RawMessage{"MyPanel": []byte(`
+		{
+			"kind": "VersionedChart",
+			"schema_version": 2,
+			"display": {"name": "versioned chart"},
+			"datasource": {"kind": "CustomDatasource"},
+			"options": {"queries": [], "legend": {"show": true}, "size": 4}
+		}
+	`)}
+
+	migrated, err := validator.Migrate(panels)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `
+		{
+			"kind": "VersionedChart",
+			"schema_version": 3,
+			"display": {"name": "versioned chart"},
+			"datasource": {"kind": "CustomDatasource"},
+			"options": {"queries": [], "legend": {"show": true}, "width": 4, "height": 4}
+		}
+	`, string(migrated["MyPanel"]))
+
+	// the default position of the legend is only added by the normalization
+	normalized, err := validator.Normalize(panels)
+	assert.NoError(t, err)
+	assert.Contains(t, string(normalized["MyPanel"]), `"position":"bottom"`)
+}
+
+func TestPanelMigration(t *testing.T) {
+	validator := NewValidator(config.Schemas{
+		PanelsPath:  "testdata/panels",
+		QueriesPath: "testdata/queries",
+	})
+	validator.LoadPanels()
+
+	migration, err := validator.PanelMigration([]byte(`{"kind": "VersionedChart", "schema_version": 2}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &v1.PanelMigration{Kind: "VersionedChart", From: 2, To: 3}, migration)
+
+	migration, err = validator.PanelMigration([]byte(`{"kind": "LegendChart"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &v1.PanelMigration{Kind: "LegendChart", From: 1, To: 1}, migration)
+
+	_, err = validator.PanelMigration([]byte(`{"kind": "UnknownChart"}`))
+	assert.EqualError(t, err, "Unknown kind UnknownChart")
+}
+
+// manyPanels returns count panels, alternating the kinds of panel and of query, with an invalid panel every 50 panels.
+func manyPanels(count int) map[string]json.RawMessage {
+	firstChart := `
+		{
+			"kind": "FirstChart",
+			"display": {"name": "first chart %d"},
+			"datasource": {"kind": "CustomDatasource"},
+			"options": {
+				"a": "yes",
+				"b": {"c": [{"e": "up", "f": "the up metric"}]},
+				"queries": [{"kind": "CustomGraphQuery", "options": {"custom": %s}}]
+			}
+		}
+	`
+	secondChart := `
+		{
+			"kind": "SecondChart",
+			"display": {"name": "second chart %d"},
+			"datasource": {"kind": "SQLDatasource"},
+			"options": {
+				"a": "yes",
+				"b": {"c": false, "d": [{"f": %d}]},
+				"query": {"kind": "SQLGraphQuery", "options": {"select": "*", "from": "TABLE", "where": "ID > 0"}}
+			}
+		}
+	`
+	panels := make(map[string]json.RawMessage, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("Panel%03d", i)
+		switch {
+		case i%50 == 49:
+			panels[name] = []byte(fmt.Sprintf(firstChart, i, `"yes"`))
+		case i%2 == 0:
+			panels[name] = []byte(fmt.Sprintf(firstChart, i, "true"))
+		default:
+			panels[name] = []byte(fmt.Sprintf(secondChart, i, i))
+		}
+	}
+	return panels
+}
+
+func newTestValidator() *validator {
+	v := NewValidator(config.Schemas{
+		PanelsPath:  "testdata/panels",
+		QueriesPath: "testdata/queries",
+	})
+	v.LoadPanels()
+	v.LoadQueries()
+	return v.(*validator)
+}
+
+func TestValidateManyPanels(t *testing.T) {
+	for _, concurrency := range []int{1, 8} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			v := newTestValidator()
+			v.concurrency = concurrency
+			err := v.Validate(manyPanels(200))
+			var report common.ValidationReport
+			if assert.ErrorAs(t, err, &report) && assert.Len(t, report, 4) {
+				for i, e := range report {
+					assert.Equal(t, fmt.Sprintf("/spec/panels/Panel%03d/options/queries/0/options/custom", 50*i+49), e.Path)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateWhileReloading validates dashboards from several goroutines while the plugins are reloaded,
+// as the API does when a plugin changes. Run with -race to check a CUE context is never used concurrently.
+func TestValidateWhileReloading(t *testing.T) {
+	v := newTestValidator()
+	panels := manyPanels(50)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				// the schemas of a plugin are replaced once it is reloaded, so the panels are always validated
+				var report common.ValidationReport
+				if assert.ErrorAs(t, v.Validate(panels), &report) && assert.Len(t, report, 1) {
+					assert.Equal(t, "/spec/panels/Panel049/options/queries/0/options/custom", report[0].Path)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		v.ReloadPlugin("panels", "testdata/panels/first")
+		v.ReloadPlugin("queries", "testdata/queries/custom")
+	}
+	wg.Wait()
+}
+
+func TestValidateAfterReload(t *testing.T) {
+	panelsPath := t.TempDir()
+	queriesPath := t.TempDir()
+	pluginPath := filepath.Join(panelsPath, "acme")
+	writePlugin(t, pluginPath, acmeChart)
+	writePlugin(t, filepath.Join(queriesPath, "acme"), acmeQuery)
+	validator := NewValidator(config.Schemas{PanelsPath: panelsPath, QueriesPath: queriesPath})
+	validator.LoadPanels()
+	validator.LoadQueries()
+	panels := map[string]json.RawMessage{
+		"MyPanel": []byte(`
+			{
+				"kind": "AcmeChart",
+				"display": {"name": "acme"},
+				"datasource": {"kind": "AcmeDatasource"},
+				"options": {
+					"queries": [{"kind": "AcmeQuery", "options": {"metric": "up"}}],
+					"color": "red"
+				}
+			}
+		`),
+	}
+	assert.NoError(t, validator.Validate(panels))
+
+	// the definition cached for the panel is replaced by the one of the plugin reloaded
+	writePlugin(t, pluginPath, strings.Replace(acmeChart, "color: string", "color: int", 1))
+	validator.ReloadPlugin("panels", pluginPath)
+	err := validator.Validate(panels)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/spec/panels/MyPanel/options/color")
+	}
+}
+
+// BenchmarkValidate validates a dashboard of 200 panels with the plugins provided with Perses,
+// as their schemas are closer to the ones of a real dashboard than the schemas of the testdata.
+func BenchmarkValidate(b *testing.B) {
+	panels := make(map[string]json.RawMessage, 200)
+	for i := 0; i < 200; i++ {
+		panels[fmt.Sprintf("Panel%03d", i)] = []byte(fmt.Sprintf(`
+			{
+				"kind": "LineChart",
+				"display": {"name": "CPU %d"},
+				"datasource": {"kind": "PrometheusDatasource"},
+				"options": {
+					"queries": [{"kind": "PrometheusGraphQuery", "options": {"query": "sum(rate(node_cpu_seconds_total[5m]))"}}]
+				}
+			}
+		`, i))
+	}
+	benchmarks := []struct {
+		title       string
+		cached      bool
+		concurrency int
+	}{
+		{title: "sequential without cache", cached: false, concurrency: 1},
+		{title: "sequential with cache", cached: true, concurrency: 1},
+		{title: "concurrent with cache", cached: true, concurrency: runtime.GOMAXPROCS(0)},
+	}
+	for _, bench := range benchmarks {
+		b.Run(bench.title, func(b *testing.B) {
+			v := NewValidator(config.Schemas{
+				PanelsPath:  "../../../../../../schemas/panels",
+				QueriesPath: "../../../../../../schemas/queries",
+			}).(*validator)
+			v.LoadPanels()
+			v.LoadQueries()
+			if !bench.cached {
+				for _, r := range v.runtimes.runtimes {
+					r.panelSchemas = nil
+				}
+			}
+			v.concurrency = bench.concurrency
+			if err := v.Validate(panels); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = v.Validate(panels)
+			}
+		})
+	}
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	panels := manyPanels(200)
+	// the invalid panels are skipped, as their normalization is failing
+	for name, panel := range panels {
+		if strings.Contains(string(panel), `"custom": "yes"`) {
+			delete(panels, name)
+		}
+	}
+	v := newTestValidator()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := v.Normalize(panels); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -15,51 +15,74 @@ package dashboard
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/perses/common/etcd"
-	"github.com/perses/perses/internal/api/config"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/analysis"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/promql"
 	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
 	"github.com/perses/perses/internal/api/impl/v1/dashboard/variable"
 	"github.com/perses/perses/internal/api/interface/v1/dashboard"
+	"github.com/perses/perses/internal/api/interface/v1/datasource"
+	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
+	"github.com/perses/perses/internal/api/interface/v1/globalpanel"
+	"github.com/perses/perses/internal/api/interface/v1/panel"
 	"github.com/perses/perses/internal/api/shared"
 	"github.com/perses/perses/pkg/model/api"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/sirupsen/logrus"
 )
 
+// savedDashboard is the response to the creation or the update of a dashboard: the dashboard stored,
+// with the warnings found when validating it.
+type savedDashboard struct {
+	*v1.Dashboard
+	Warnings common.ValidationReport `json:"warnings,omitempty"`
+}
+
 type service struct {
 	dashboard.Service
-	dao       dashboard.DAO
-	validator schemas.Validator
+	dao                     dashboard.DAO
+	datasourceService       datasource.Service
+	globalDatasourceService globaldatasource.Service
+	panelService            panel.Service
+	globalPanelService      globalpanel.Service
+	validator               schemas.Validator
+	// libraryPanelMutex is read-locked from the validation of a dashboard to its write, so a library panel used by the
+	// dashboard cannot be deleted in the meantime. The panel services lock it when deleting a library panel.
+	libraryPanelMutex *sync.RWMutex
 }
 
-func NewService(dao dashboard.DAO, conf config.Config) dashboard.Service {
+func NewService(dao dashboard.DAO, datasourceService datasource.Service, globalDatasourceService globaldatasource.Service, panelService panel.Service, globalPanelService globalpanel.Service, validator schemas.Validator, libraryPanelMutex *sync.RWMutex) dashboard.Service {
 	return &service{
-		dao:       dao,
-		validator: schemas.NewValidator(conf.Schemas),
+		dao:                     dao,
+		datasourceService:       datasourceService,
+		globalDatasourceService: globalDatasourceService,
+		panelService:            panelService,
+		globalPanelService:      globalPanelService,
+		validator:               validator,
+		libraryPanelMutex:       libraryPanelMutex,
 	}
 }
 
-func (s *service) Create(entity api.Entity) (interface{}, error) {
+func (s *service) Create(entity api.Entity, parameters shared.Parameters) (interface{}, error) {
 	if dashboardObject, ok := entity.(*v1.Dashboard); ok {
-		return s.create(dashboardObject)
+		return s.create(dashboardObject, parameters)
 	}
 	return nil, fmt.Errorf("%w: wrong entity format, attempting dashboard format, received '%T'", shared.BadRequestError, entity)
 }
 
-func (s *service) create(entity *v1.Dashboard) (*v1.Dashboard, error) {
+func (s *service) create(entity *v1.Dashboard, parameters shared.Parameters) (*savedDashboard, error) {
 	// Note: you don't need to check that the project exists since once the permission middleware will be in place,
 	// it won't be possible to create a resources into a not known project
 
-	// verify it's possible to calculate the build order for the variable.
-	if _, err := variable.BuildOrder(entity.Spec.Variables); err != nil {
-		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
-	}
-
+	s.libraryPanelMutex.RLock()
+	defer s.libraryPanelMutex.RUnlock()
 	// verify this new dashboard passes the validation
-	err := s.validator.Validate(entity.Spec.Panels)
+	warnings, err := s.validate(entity, parameters.Normalize)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
+		return nil, err
 	}
 
 	// Update the time contains in the entity
@@ -72,7 +95,7 @@ func (s *service) create(entity *v1.Dashboard) (*v1.Dashboard, error) {
 		logrus.WithError(err).Errorf("unable to perform the creation of the prometheuRule %q, something wrong with the database", entity.Metadata.Name)
 		return nil, shared.InternalError
 	}
-	return entity, nil
+	return &savedDashboard{Dashboard: entity, Warnings: warnings}, nil
 }
 
 func (s *service) Update(entity api.Entity, parameters shared.Parameters) (interface{}, error) {
@@ -82,7 +105,7 @@ func (s *service) Update(entity api.Entity, parameters shared.Parameters) (inter
 	return nil, fmt.Errorf("%w: wrong entity format, attempting dashboard format, received '%T'", shared.BadRequestError, entity)
 }
 
-func (s *service) update(entity *v1.Dashboard, parameters shared.Parameters) (*v1.Dashboard, error) {
+func (s *service) update(entity *v1.Dashboard, parameters shared.Parameters) (*savedDashboard, error) {
 	if entity.Metadata.Name != parameters.Name {
 		logrus.Debugf("name in dashboard %q and coming from the http request: %q doesn't match", entity.Metadata.Name, parameters.Name)
 		return nil, fmt.Errorf("%w: metadata.name and the name in the http path request doesn't match", shared.BadRequestError)
@@ -93,14 +116,12 @@ func (s *service) update(entity *v1.Dashboard, parameters shared.Parameters) (*v
 		logrus.Debugf("project in dashboard %q and coming from the http request: %q doesn't match", entity.Metadata.Project, parameters.Project)
 		return nil, fmt.Errorf("%w: metadata.project and the project name in the http path request doesn't match", shared.BadRequestError)
 	}
-	// verify it's possible to calculate the build order for the variable.
-	if _, err := variable.BuildOrder(entity.Spec.Variables); err != nil {
-		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
-	}
+	s.libraryPanelMutex.RLock()
+	defer s.libraryPanelMutex.RUnlock()
 	// verify the updated version of the dashboard passes the validation
-	err := s.validator.Validate(entity.Spec.Panels)
+	warnings, err := s.validate(entity, parameters.Normalize)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", shared.BadRequestError, err)
+		return nil, err
 	}
 	// find the previous version of the dashboard
 	oldEntity, err := s.Get(parameters)
@@ -113,7 +134,45 @@ func (s *service) update(entity *v1.Dashboard, parameters shared.Parameters) (*v
 		logrus.WithError(err).Errorf("unable to perform the update of the dashboard %q, something wrong with the database", entity.Metadata.Name)
 		return nil, shared.InternalError
 	}
-	return entity, nil
+	return &savedDashboard{Dashboard: entity, Warnings: warnings}, nil
+}
+
+// validate returns a common.ValidationReport with every problem found in the dashboard:
+// the build order of the variables, the panels, variables, annotations and layouts checked against the schemas, the PromQL queries,
+// the references between the variables, the panels and the layouts, the datasources and the library panels used.
+// The warnings are returned apart, as they don't prevent the dashboard from being stored.
+// When normalize is true, the panels of the dashboard are replaced by their normalized version, completed with the defaults of the schemas.
+func (s *service) validate(entity *v1.Dashboard, normalize bool) (common.ValidationReport, error) {
+	var report common.ValidationReport
+	// verify it's possible to calculate the build order for the variable.
+	_, err := variable.BuildOrder(entity.Spec.Variables, entity.Spec.Annotations, entity.Spec.Datasource.Name)
+	report.Merge("", err)
+	if normalize {
+		panels, panelsErr := s.validator.Normalize(entity.Spec.Panels)
+		report.Merge("", panelsErr)
+		if panelsErr == nil {
+			entity.Spec.Panels = panels
+		}
+	} else {
+		report.Merge("", s.validator.Validate(entity.Spec.Panels))
+	}
+	report.Merge("", s.validator.ValidateVariables(entity.Spec.Variables))
+	report.Merge("", s.validator.ValidateAnnotations(entity.Spec.Annotations))
+	report.Merge("", s.validator.ValidateLayouts(entity.Spec.Layouts))
+	report.Merge("", promql.CheckDashboard(&entity.Spec))
+	report.Merge("", analysis.Analyze(&entity.Spec))
+	// verify the datasources used by the dashboard exist
+	if err := s.validateDatasources(entity, &report); err != nil {
+		return nil, err
+	}
+	if err := s.validateLibraryPanels(entity, &report); err != nil {
+		return nil, err
+	}
+	report.Sort()
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	return report.Warnings(), nil
 }
 
 func (s *service) Delete(parameters shared.Parameters) error {
@@ -138,6 +197,11 @@ func (s *service) Get(parameters shared.Parameters) (interface{}, error) {
 		logrus.WithError(err).Errorf("unable to find the previous version of the project %q, something wrong with the database", parameters.Name)
 		return nil, shared.InternalError
 	}
+	if parameters.Inline {
+		if err := s.inlineLibraryPanels(entity); err != nil {
+			return nil, err
+		}
+	}
 	return entity, nil
 }
 
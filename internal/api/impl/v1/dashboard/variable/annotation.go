@@ -0,0 +1,171 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package variable
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/prometheus/common/model"
+)
+
+// EvaluateAnnotations computes the events of every annotation happening in the time range of the request.
+// The variables are evaluated first, exactly like Evaluate does, so their values can be replaced in the annotations.
+// The PromQL queries are sent to the datasource of the dashboard. The results are sorted by name.
+func EvaluateAnnotations(ctx context.Context, dashboardName string, datasourceName string, variables map[string]*dashboard.Variable, annotations map[string]*dashboard.Annotation, request dashboard.AnnotationEvaluationRequest, datasources Datasources) ([]dashboard.AnnotationEvaluationResult, error) {
+	groups, err := BuildOrder(variables, annotations, datasourceName)
+	if err != nil {
+		return nil, err
+	}
+	variableDeps, err := buildVariableDependencies(variables, datasourceName)
+	if err != nil {
+		return nil, err
+	}
+	annotationDeps, err := buildAnnotationDependencies(variables, annotations, datasourceName)
+	if err != nil {
+		return nil, err
+	}
+	e := newEvaluator(dashboardName, datasourceName, variables, request.Start, request.End, datasources)
+	e.evaluateVariables(ctx, groups, variableDeps, request.Selected)
+	names := make([]string, 0, len(annotations))
+	for name := range annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]dashboard.AnnotationEvaluationResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			result[i] = e.evaluateAnnotation(ctx, name, annotations[name], annotationDeps[name])
+		}(i, name)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+func (e *evaluator) evaluateAnnotation(ctx context.Context, name string, annotation *dashboard.Annotation, deps []string) dashboard.AnnotationEvaluationResult {
+	result := dashboard.AnnotationEvaluationResult{Name: name, Events: []dashboard.AnnotationEvent{}}
+	sort.Strings(deps)
+	for _, dep := range deps {
+		if len(e.results[dep].Error) > 0 {
+			result.Error = fmt.Sprintf("the variable %q it depends on cannot be evaluated", dep)
+			return result
+		}
+	}
+	switch param := annotation.Parameter.(type) {
+	case *dashboard.StaticAnnotationParameter:
+		for _, event := range param.Events {
+			end := event.Time
+			if event.End != nil {
+				end = *event.End
+			}
+			if !end.Before(e.start) && !event.Time.After(e.end) {
+				result.Events = append(result.Events, event)
+			}
+		}
+	case *dashboard.PromQLQueryAnnotationParameter:
+		events, err := e.queryEvents(ctx, param)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Events = events
+	default:
+		result.Error = fmt.Sprintf("annotation of kind %q cannot be evaluated", annotation.Kind)
+	}
+	return result
+}
+
+// queryEvents returns an event for every range of consecutive points of a series having a value different from zero.
+// The events are sorted by time, then by title.
+func (e *evaluator) queryEvents(ctx context.Context, param *dashboard.PromQLQueryAnnotationParameter) ([]dashboard.AnnotationEvent, error) {
+	strs := make([]string, 0, 3)
+	for _, str := range []string{param.Expr, param.Title, param.Text} {
+		interpolated, err := interpolation.Interpolate(str, e.values)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, interpolated)
+	}
+	query, title, text := strs[0], strs[1], strs[2]
+	step := time.Duration(param.Step)
+	if step <= 0 {
+		step = interpolation.Interval(e.start, e.end, interpolation.DefaultMaxDataPoints)
+	}
+	form := e.rangeForm()
+	form.Set("query", query)
+	form.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	var data struct {
+		ResultType model.ValueType `json:"resultType"`
+		Result     model.Matrix    `json:"result"`
+	}
+	if err := e.do(ctx, http.MethodPost, "/api/v1/query_range", form, &data); err != nil {
+		return nil, err
+	}
+	events := []dashboard.AnnotationEvent{}
+	for _, stream := range data.Result {
+		labels := make(map[string]string, len(stream.Metric))
+		for name, value := range stream.Metric {
+			labels[string(name)] = string(value)
+		}
+		newEvent := func(first model.SamplePair, last model.SamplePair) dashboard.AnnotationEvent {
+			event := dashboard.AnnotationEvent{
+				Time:  first.Timestamp.Time().UTC(),
+				Title: dashboard.ExpandLabels(title, labels),
+				Text:  dashboard.ExpandLabels(text, labels),
+			}
+			if last.Timestamp != first.Timestamp {
+				end := last.Timestamp.Time().UTC()
+				event.End = &end
+			}
+			return event
+		}
+		var first, last *model.SamplePair
+		for i := range stream.Values {
+			point := &stream.Values[i]
+			if point.Value == 0 || math.IsNaN(float64(point.Value)) {
+				continue
+			}
+			// a point following the previous one by more than a step doesn't belong to the same event
+			if first != nil && point.Timestamp.Sub(last.Timestamp) > step {
+				events = append(events, newEvent(*first, *last))
+				first = nil
+			}
+			if first == nil {
+				first = point
+			}
+			last = point
+		}
+		if first != nil {
+			events = append(events, newEvent(*first, *last))
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].Title < events[j].Title
+	})
+	return events, nil
+}
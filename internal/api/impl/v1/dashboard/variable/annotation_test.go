@@ -0,0 +1,118 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package variable
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	promModel "github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateAnnotations(t *testing.T) {
+	variables := map[string]*dashboard.Variable{
+		"env": {
+			Kind:     dashboard.KindConstantVariable,
+			Selected: dashboard.Selection{"prod"},
+			Parameter: &dashboard.ConstantVariableParameter{
+				Values: []string{"dev", "prod"},
+			},
+		},
+	}
+	incidentEnd := time.Date(2022, 4, 15, 5, 40, 0, 0, time.UTC)
+	annotations := map[string]*dashboard.Annotation{
+		"deployments": {
+			Kind: dashboard.KindPromQLQueryAnnotation,
+			Parameter: &dashboard.PromQLQueryAnnotationParameter{
+				Expr:  `changes(deployment_generation{env="$env"}[5m]) > 0`,
+				Title: "Deployment of {{job}}",
+				Text:  "Deployed in $env",
+				Step:  promModel.Duration(time.Minute),
+			},
+		},
+		"incidents": {
+			Kind: dashboard.KindStaticAnnotation,
+			Parameter: &dashboard.StaticAnnotationParameter{
+				Events: []dashboard.AnnotationEvent{
+					{Time: time.Date(2022, 4, 14, 10, 0, 0, 0, time.UTC), Title: "Before the time range"},
+					{Time: time.Date(2022, 4, 15, 4, 30, 0, 0, time.UTC), End: &incidentEnd, Title: "Database down"},
+				},
+			},
+		},
+		"broken": {
+			Kind: dashboard.KindPromQLQueryAnnotation,
+			Parameter: &dashboard.PromQLQueryAnnotationParameter{
+				Expr: "broken(",
+			},
+		},
+	}
+	transport := roundTripFunc(func(req *http.Request) *http.Response {
+		if err := req.ParseForm(); err != nil {
+			return newResponse(http.StatusBadRequest, err.Error())
+		}
+		if req.Form.Get("query") != `changes(deployment_generation{env="prod"}[5m]) > 0` || req.Form.Get("step") != "60" {
+			return newResponse(http.StatusBadRequest, `{"status":"error","errorType":"bad_data","error":"unexpected query"}`)
+		}
+		// the deployment of api lasts 3 points and happens again later, the one of db is a single point
+		return newResponse(http.StatusOK, `{"status":"success","data":{"resultType":"matrix","result":[
+{"metric":{"job":"api"},"values":[[1650000000,"1"],[1650000060,"1"],[1650000120,"1"],[1650000300,"1"]]},
+{"metric":{"job":"db"},"values":[[1650000060,"0"],[1650000180,"2"]]}
+]}}`)
+	})
+	start := time.Date(2022, 4, 15, 5, 0, 0, 0, time.UTC)
+	result, err := EvaluateAnnotations(context.Background(), "Demo", "", variables, annotations, dashboard.AnnotationEvaluationRequest{Start: start, End: start.Add(time.Hour)}, &fakeDatasources{transport: transport})
+	assert.NoError(t, err)
+	firstDeploymentEnd := time.Unix(1650000120, 0).UTC()
+	assert.Equal(t, []dashboard.AnnotationEvaluationResult{
+		{
+			Name:   "broken",
+			Events: []dashboard.AnnotationEvent{},
+			Error:  "bad_data: unexpected query",
+		},
+		{
+			Name: "deployments",
+			Events: []dashboard.AnnotationEvent{
+				{Time: time.Unix(1650000000, 0).UTC(), End: &firstDeploymentEnd, Title: "Deployment of api", Text: "Deployed in prod"},
+				{Time: time.Unix(1650000180, 0).UTC(), Title: "Deployment of db", Text: "Deployed in prod"},
+				{Time: time.Unix(1650000300, 0).UTC(), Title: "Deployment of api", Text: "Deployed in prod"},
+			},
+		},
+		{
+			Name: "incidents",
+			Events: []dashboard.AnnotationEvent{
+				{Time: time.Date(2022, 4, 15, 4, 30, 0, 0, time.UTC), End: &incidentEnd, Title: "Database down"},
+			},
+		},
+	}, result)
+}
+
+func TestEvaluateAnnotationsWithUndefinedVariable(t *testing.T) {
+	annotations := map[string]*dashboard.Annotation{
+		"deployments": {
+			Kind: dashboard.KindPromQLQueryAnnotation,
+			Parameter: &dashboard.PromQLQueryAnnotationParameter{
+				Expr: `changes(deployment_generation{env="$env"}[5m]) > 0`,
+			},
+		},
+	}
+	_, err := EvaluateAnnotations(context.Background(), "Demo", "", nil, annotations, dashboard.AnnotationEvaluationRequest{}, &fakeDatasources{})
+	assert.Equal(t, common.ValidationReport{
+		{Path: "/spec/annotations/deployments/parameter", Severity: common.SeverityError, Message: `variable "env" is used in the annotation "deployments" but not defined`},
+	}, err)
+}
@@ -13,20 +13,20 @@
 
 // Package variable is providing the necessary functions:
 // * to calculate the build order of the variables
-// * and to calculate the list of value for a given variable.
+// * to calculate the list of value for a given variable
+// * and to calculate the events of the annotations, once the variables they use are calculated.
 package variable
 
 import (
 	"fmt"
 	"regexp"
 
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/perses/perses/pkg/model/api/v1/dashboard"
 )
 
-var (
-	variableRegexp  = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
-	variableRegexp2 = regexp.MustCompile(`\$([a-zA-Z0-9_-]+)`)
-)
+var variableRegexp = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
 
 type Group struct {
 	Variables []string
@@ -38,16 +38,30 @@ type Group struct {
 // 1. First calculate which variable depend of which other variable
 // 2. Then, thanks to the dependencies, we can create a dependency graph.
 // 3. Then we have to determinate the build order.
-func BuildOrder(variables map[string]*dashboard.Variable) ([]Group, error) {
-	g, err := buildGraph(variables)
+//
+// datasourceName is the name of the datasource of the dashboard. When it is using a Datasource variable,
+// every variable querying the datasource depends on it.
+// The variables used by the annotations are verified as well, since the annotations are evaluated once the variables are.
+func BuildOrder(variables map[string]*dashboard.Variable, annotations map[string]*dashboard.Annotation, datasourceName string) ([]Group, error) {
+	var report common.ValidationReport
+	g, err := buildGraph(variables, datasourceName)
+	report.Merge("", err)
+	_, err = buildAnnotationDependencies(variables, annotations, datasourceName)
+	report.Merge("", err)
+	if len(report) > 0 {
+		report.Sort()
+		return nil, report
+	}
+	groups, err := g.buildOrder()
 	if err != nil {
-		return nil, err
+		report.AddError("/spec/variables", "%s", err)
+		return nil, report
 	}
-	return g.buildOrder()
+	return groups, nil
 }
 
-func buildGraph(variables map[string]*dashboard.Variable) (*graph, error) {
-	deps, err := buildVariableDependencies(variables)
+func buildGraph(variables map[string]*dashboard.Variable, datasourceName string) (*graph, error) {
+	deps, err := buildVariableDependencies(variables, datasourceName)
 	if err != nil {
 		return nil, err
 	}
@@ -58,44 +72,135 @@ func buildGraph(variables map[string]*dashboard.Variable) (*graph, error) {
 	return newGraph(vars, deps), nil
 }
 
-func buildVariableDependencies(variables map[string]*dashboard.Variable) (map[string][]string, error) {
+// buildVariableDependencies returns the variables each variable depends on.
+// Every problem found is returned in a common.ValidationReport.
+func buildVariableDependencies(variables map[string]*dashboard.Variable, datasourceName string) (map[string][]string, error) {
+	var report common.ValidationReport
+	datasourceVariables := findDatasourceVariables(variables, datasourceName)
 	result := make(map[string][]string)
 	for name, variable := range variables {
+		path := common.JSONPointer("spec", "variables", name)
 		if !variableRegexp.MatchString(name) {
-			return nil, fmt.Errorf("%q is not a correct variable name. It should match the regexp: %s", name, variableRegexp.String())
+			report.AddError(path, "%q is not a correct variable name. It should match the regexp: %s", name, variableRegexp.String())
+			continue
 		}
-		var matches [][]string
-		switch param := variable.Parameter.(type) {
-		case *dashboard.PromQLQueryVariableParameter:
-			matches = findAllVariableUsed(param.Expr)
-		case *dashboard.LabelNamesQueryVariableParameter:
-			for _, matcher := range param.Matchers {
-				matches = append(matches, findAllVariableUsed(matcher)...)
+		if interpolation.IsBuiltin(name) {
+			report.AddError(path, "%q is the name of a built-in variable and cannot be used", name)
+			continue
+		}
+		deps := make(map[string]bool)
+		for _, str := range QueryStrings(variable) {
+			used, err := interpolation.VariableNames(str)
+			if err != nil {
+				report.AddError(path+"/parameter", "invalid variable %q: %s", name, err)
+				continue
 			}
-		case *dashboard.LabelValuesQueryVariableParameter:
-			matches = findAllVariableUsed(param.LabelName)
-			for _, matcher := range param.Matchers {
-				matches = append(matches, findAllVariableUsed(matcher)...)
+			for _, dep := range used {
+				if _, ok := variables[dep]; !ok {
+					report.AddError(path+"/parameter", "variable %q is used in the variable %q but not defined", dep, name)
+					continue
+				}
+				deps[dep] = true
+			}
+		}
+		if isQuerying(variable) {
+			for _, dep := range datasourceVariables {
+				deps[dep] = true
 			}
 		}
+		for dep := range deps {
+			result[name] = append(result[name], dep)
+		}
+	}
+	if len(report) > 0 {
+		report.Sort()
+		return nil, report
+	}
+	return result, nil
+}
+
+// buildAnnotationDependencies returns the variables each annotation depends on.
+// Every variable used without being defined is returned in a common.ValidationReport.
+func buildAnnotationDependencies(variables map[string]*dashboard.Variable, annotations map[string]*dashboard.Annotation, datasourceName string) (map[string][]string, error) {
+	var report common.ValidationReport
+	datasourceVariables := findDatasourceVariables(variables, datasourceName)
+	result := make(map[string][]string)
+	for name, annotation := range annotations {
+		path := common.JSONPointer("spec", "annotations", name, "parameter")
 		deps := make(map[string]bool)
-		for _, match := range matches {
-			// match[0] is the string that is matching the regexp (including the $)
-			// match[1] is the string that is matching the group defined by the regexp. (the string without the $)
-			if _, ok := variables[match[1]]; !ok {
-				return nil, fmt.Errorf("variable %q is used in the variable %q but not defined", match[1], name)
+		for _, str := range AnnotationQueryStrings(annotation) {
+			used, err := interpolation.VariableNames(str)
+			if err != nil {
+				report.AddError(path, "invalid annotation %q: %s", name, err)
+				continue
+			}
+			for _, dep := range used {
+				if _, ok := variables[dep]; !ok {
+					report.AddError(path, "variable %q is used in the annotation %q but not defined", dep, name)
+					continue
+				}
+				deps[dep] = true
+			}
+		}
+		if annotation.Kind == dashboard.KindPromQLQueryAnnotation {
+			for _, dep := range datasourceVariables {
+				deps[dep] = true
 			}
-			deps[match[1]] = true
 		}
 		for dep := range deps {
 			result[name] = append(result[name], dep)
 		}
 	}
+	if len(report) > 0 {
+		report.Sort()
+		return nil, report
+	}
 	return result, nil
 }
 
-func findAllVariableUsed(str string) [][]string {
-	return variableRegexp2.FindAllStringSubmatch(str, -1)
+// AnnotationQueryStrings returns the strings of the annotation that can use variables.
+func AnnotationQueryStrings(annotation *dashboard.Annotation) []string {
+	if param, ok := annotation.Parameter.(*dashboard.PromQLQueryAnnotationParameter); ok {
+		return []string{param.Expr, param.Title, param.Text}
+	}
+	return nil
+}
+
+// QueryStrings returns the strings of the variable that can use other variables.
+func QueryStrings(variable *dashboard.Variable) []string {
+	switch param := variable.Parameter.(type) {
+	case *dashboard.PromQLQueryVariableParameter:
+		return []string{param.Expr}
+	case *dashboard.LabelNamesQueryVariableParameter:
+		return param.Matchers
+	case *dashboard.LabelValuesQueryVariableParameter:
+		return append([]string{param.LabelName}, param.Matchers...)
+	case *dashboard.CustomVariableParameter:
+		return []string{param.Values}
+	}
+	return nil
+}
+
+// isQuerying returns true when the values of the variable are coming from the datasource of the dashboard.
+func isQuerying(variable *dashboard.Variable) bool {
+	switch variable.Parameter.(type) {
+	case *dashboard.PromQLQueryVariableParameter, *dashboard.LabelNamesQueryVariableParameter, *dashboard.LabelValuesQueryVariableParameter:
+		return true
+	}
+	return false
+}
+
+// findDatasourceVariables returns the variables of kind Datasource used in the name of the datasource of the dashboard.
+// The other variables are ignored, as the name of the datasource is verified with the other datasources of the dashboard.
+func findDatasourceVariables(variables map[string]*dashboard.Variable, datasourceName string) []string {
+	names, _ := interpolation.VariableNames(datasourceName)
+	var result []string
+	for _, name := range names {
+		if variable, ok := variables[name]; ok && variable.Kind == dashboard.KindDatasourceVariable {
+			result = append(result, name)
+		}
+	}
+	return result
 }
 
 func newGraph(variables []string, dependencies map[string][]string) *graph {
@@ -16,8 +16,11 @@ package variable
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -205,10 +208,38 @@ func TestBuildVariableDependencies(t *testing.T) {
 				},
 			},
 		},
+		{
+			title: "variables used with braces, formats and built-in variables",
+			variables: map[string]*dashboard.Variable{
+				"myVariable": {
+					Kind: dashboard.KindPromQLQueryVariable,
+					Parameter: &dashboard.PromQLQueryVariableParameter{
+						Expr: "sum by(${doe}) (rate(up{instance=~'${foo:regex}',dashboard='$__dashboard'}[$__rate_interval]))",
+					},
+				},
+				"foo": {
+					Kind: dashboard.KindPromQLQueryVariable,
+					Parameter: &dashboard.PromQLQueryVariableParameter{
+						Expr: "test",
+					},
+				},
+				"doe": {
+					Kind: dashboard.KindConstantVariable,
+					Parameter: &dashboard.ConstantVariableParameter{
+						Values: []string{"myConstant"},
+					},
+				},
+			},
+			result: map[string][]string{
+				"myVariable": {
+					"doe", "foo",
+				},
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
-			result, err := buildVariableDependencies(test.variables)
+			result, err := buildVariableDependencies(test.variables, "")
 			assert.NoError(t, err)
 			assert.Equal(t, len(test.result), len(result))
 			for k, v := range test.result {
@@ -222,7 +253,7 @@ func TestBuildVariableDependenciesError(t *testing.T) {
 	testSuite := []struct {
 		title     string
 		variables map[string]*dashboard.Variable
-		err       error
+		report    common.ValidationReport
 	}{
 		{
 			title: "wrong variable name",
@@ -232,7 +263,7 @@ func TestBuildVariableDependenciesError(t *testing.T) {
 					Parameter: &dashboard.PromQLQueryVariableParameter{},
 				},
 			},
-			err: fmt.Errorf("%q is not a correct variable name. It should match the regexp: %s", "VariableW$thI%ValidChar", variableRegexp.String()),
+			report: common.ValidationReport{{Path: "/spec/variables/VariableW$thI%ValidChar", Severity: common.SeverityError, Message: fmt.Sprintf("%q is not a correct variable name. It should match the regexp: %s", "VariableW$thI%ValidChar", variableRegexp.String())}},
 		},
 		{
 			title: "variable used but not defined",
@@ -244,13 +275,71 @@ func TestBuildVariableDependenciesError(t *testing.T) {
 					},
 				},
 			},
-			err: fmt.Errorf("variable %q is used in the variable %q but not defined", "foo", "myVariable"),
+			report: common.ValidationReport{{Path: "/spec/variables/myVariable/parameter", Severity: common.SeverityError, Message: fmt.Sprintf("variable %q is used in the variable %q but not defined", "foo", "myVariable")}},
+		},
+		{
+			title: "variable used with braces but not defined",
+			variables: map[string]*dashboard.Variable{
+				"myVariable": {
+					Kind: dashboard.KindPromQLQueryVariable,
+					Parameter: &dashboard.PromQLQueryVariableParameter{
+						Expr: "${foo:csv}",
+					},
+				},
+			},
+			report: common.ValidationReport{{Path: "/spec/variables/myVariable/parameter", Severity: common.SeverityError, Message: fmt.Sprintf("variable %q is used in the variable %q but not defined", "foo", "myVariable")}},
+		},
+		{
+			title: "unknown format",
+			variables: map[string]*dashboard.Variable{
+				"myVariable": {
+					Kind: dashboard.KindPromQLQueryVariable,
+					Parameter: &dashboard.PromQLQueryVariableParameter{
+						Expr: "${foo:yaml}",
+					},
+				},
+			},
+			report: common.ValidationReport{{Path: "/spec/variables/myVariable/parameter", Severity: common.SeverityError, Message: fmt.Sprintf("invalid variable %q: unknown format %q used for the variable %q", "myVariable", "yaml", "foo")}},
+		},
+		{
+			title: "name of a built-in variable",
+			variables: map[string]*dashboard.Variable{
+				"__interval": {
+					Kind: dashboard.KindConstantVariable,
+					Parameter: &dashboard.ConstantVariableParameter{
+						Values: []string{"5m"},
+					},
+				},
+			},
+			report: common.ValidationReport{{Path: "/spec/variables/__interval", Severity: common.SeverityError, Message: fmt.Sprintf("%q is the name of a built-in variable and cannot be used", "__interval")}},
+		},
+		{
+			title: "every problem is reported",
+			variables: map[string]*dashboard.Variable{
+				"__range": {
+					Kind: dashboard.KindConstantVariable,
+					Parameter: &dashboard.ConstantVariableParameter{
+						Values: []string{"1h"},
+					},
+				},
+				"myVariable": {
+					Kind: dashboard.KindPromQLQueryVariable,
+					Parameter: &dashboard.PromQLQueryVariableParameter{
+						Expr: "$foo + $bar",
+					},
+				},
+			},
+			report: common.ValidationReport{
+				{Path: "/spec/variables/__range", Severity: common.SeverityError, Message: fmt.Sprintf("%q is the name of a built-in variable and cannot be used", "__range")},
+				{Path: "/spec/variables/myVariable/parameter", Severity: common.SeverityError, Message: fmt.Sprintf("variable %q is used in the variable %q but not defined", "bar", "myVariable")},
+				{Path: "/spec/variables/myVariable/parameter", Severity: common.SeverityError, Message: fmt.Sprintf("variable %q is used in the variable %q but not defined", "foo", "myVariable")},
+			},
 		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
-			_, err := buildVariableDependencies(test.variables)
-			assert.Equal(t, test.err, err)
+			_, err := buildVariableDependencies(test.variables, "")
+			assert.Equal(t, test.report, err)
 		})
 	}
 }
@@ -363,9 +452,10 @@ func TestGraph_BuildOrderError(t *testing.T) {
 
 func TestBuildOrder(t *testing.T) {
 	testSuite := []struct {
-		title     string
-		variables map[string]*dashboard.Variable
-		result    []Group
+		title          string
+		variables      map[string]*dashboard.Variable
+		datasourceName string
+		result         []Group
 	}{
 		{
 			title: "no variable",
@@ -416,10 +506,38 @@ func TestBuildOrder(t *testing.T) {
 				{Variables: []string{"myVariable"}},
 			},
 		},
+		{
+			title: "queries depending on the datasource variable",
+			variables: map[string]*dashboard.Variable{
+				"ds": {
+					Kind: dashboard.KindDatasourceVariable,
+					Parameter: &dashboard.DatasourceVariableParameter{
+						Kind: "Prometheus",
+					},
+				},
+				"interval": {
+					Kind: dashboard.KindIntervalVariable,
+					Parameter: &dashboard.IntervalVariableParameter{
+						Values: []model.Duration{model.Duration(time.Minute)},
+					},
+				},
+				"job": {
+					Kind: dashboard.KindLabelValuesQueryVariable,
+					Parameter: &dashboard.LabelValuesQueryVariableParameter{
+						LabelName: "job",
+					},
+				},
+			},
+			datasourceName: "$ds",
+			result: []Group{
+				{Variables: []string{"ds", "interval"}},
+				{Variables: []string{"job"}},
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
-			groups, err := BuildOrder(test.variables)
+			groups, err := BuildOrder(test.variables, nil, test.datasourceName)
 			assert.NoError(t, err)
 			assert.Equal(t, len(test.result), len(groups))
 			for i := 0; i < len(groups); i++ {
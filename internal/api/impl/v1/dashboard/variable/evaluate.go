@@ -0,0 +1,353 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package variable
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perses/perses/internal/api/shared/interpolation"
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/prometheus/common/model"
+)
+
+// Datasources gives access to the datasources needed to evaluate the variables.
+type Datasources interface {
+	// List returns the name of the datasources of the given kind, sorted by name.
+	List(kind datasource.Kind, global bool) ([]string, error)
+	// Transport returns the transport to use to query the datasource of the dashboard, once the variables used in its name are replaced.
+	Transport(name string) (http.RoundTripper, error)
+}
+
+// Evaluate computes the list of values of every variable.
+// The variables are evaluated group by group following the build order, so a variable is always evaluated after the
+// variables it depends on. The variables of a same group don't depend on each other and are then evaluated in parallel.
+// The queries are sent to the datasource of the dashboard, once the variables they use (including the built-in ones) are replaced.
+// The results are returned following the build order, and sorted by name inside a group.
+func Evaluate(ctx context.Context, dashboardName string, datasourceName string, variables map[string]*dashboard.Variable, request dashboard.VariableEvaluationRequest, datasources Datasources) ([]dashboard.VariableEvaluationResult, error) {
+	groups, err := BuildOrder(variables, nil, datasourceName)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := buildVariableDependencies(variables, datasourceName)
+	if err != nil {
+		return nil, err
+	}
+	e := newEvaluator(dashboardName, datasourceName, variables, request.Start, request.End, datasources)
+	return e.evaluateVariables(ctx, groups, deps, request.Selected), nil
+}
+
+func newEvaluator(dashboardName string, datasourceName string, variables map[string]*dashboard.Variable, start time.Time, end time.Time, datasources Datasources) *evaluator {
+	return &evaluator{
+		datasources:    datasources,
+		datasourceName: datasourceName,
+		start:          start,
+		end:            end,
+		variables:      variables,
+		results:        make(map[string]*dashboard.VariableEvaluationResult, len(variables)),
+		values:         interpolation.BuiltinVariables(dashboardName, start, end, interpolation.DefaultMaxDataPoints),
+	}
+}
+
+// evaluateVariables evaluates the variables group by group, and keeps their results and their values for the next evaluations.
+func (e *evaluator) evaluateVariables(ctx context.Context, groups []Group, deps map[string][]string, selected map[string]dashboard.Selection) []dashboard.VariableEvaluationResult {
+	result := make([]dashboard.VariableEvaluationResult, 0, len(e.variables))
+	for _, group := range groups {
+		names := append([]string{}, group.Variables...)
+		sort.Strings(names)
+		groupResults := make([]dashboard.VariableEvaluationResult, len(names))
+		groupValues := make([]interpolation.Value, len(names))
+		var wg sync.WaitGroup
+		for i, name := range names {
+			wg.Add(1)
+			go func(i int, name string) {
+				defer wg.Done()
+				groupResults[i], groupValues[i] = e.evaluate(ctx, name, deps[name], selected[name])
+			}(i, name)
+		}
+		wg.Wait()
+		// the results are only shared once the whole group is evaluated, to be used by the next groups.
+		for i := range groupResults {
+			e.results[groupResults[i].Name] = &groupResults[i]
+			e.values[groupResults[i].Name] = groupValues[i]
+		}
+		result = append(result, groupResults...)
+	}
+	return result
+}
+
+type evaluator struct {
+	datasources    Datasources
+	datasourceName string
+	start          time.Time
+	end            time.Time
+	variables      map[string]*dashboard.Variable
+	// results is holding the result of the variables already evaluated. It must not be modified while a group is evaluated.
+	results map[string]*dashboard.VariableEvaluationResult
+	// values is holding the value of the built-in variables and of the variables already evaluated.
+	// Like results, it must not be modified while a group is evaluated.
+	values map[string]interpolation.Value
+}
+
+// evaluate returns the result of the variable and the value to use when the variable is replaced in the other variables.
+func (e *evaluator) evaluate(ctx context.Context, name string, deps []string, selected dashboard.Selection) (dashboard.VariableEvaluationResult, interpolation.Value) {
+	result := dashboard.VariableEvaluationResult{Name: name, Values: []string{}}
+	sort.Strings(deps)
+	for _, dep := range deps {
+		if len(e.results[dep].Error) > 0 {
+			result.Error = fmt.Sprintf("the variable %q it depends on cannot be evaluated", dep)
+			return result, interpolation.Value{}
+		}
+	}
+	variable := e.variables[name]
+	if param, ok := variable.Parameter.(*dashboard.TextBoxVariableParameter); ok {
+		// the value of a text box is whatever the user typed
+		text := param.Value
+		if len(selected) > 0 {
+			text = selected[0]
+		}
+		if len(text) > 0 {
+			result.Values = []string{text}
+			result.Selected = dashboard.Selection{text}
+		}
+		return result, interpolation.SingleValue(text)
+	}
+	values, err := e.query(ctx, variable)
+	if err != nil {
+		result.Error = err.Error()
+		return result, interpolation.Value{}
+	}
+	result.Values = values
+	result.Selected = selectValues(variable, values, selected, variable.Selected)
+	if result.Selected.IsAll() {
+		value := interpolation.Value{Values: values, All: true, Multi: true}
+		if variable.AllValue != dashboard.AllSelection {
+			value.AllValue = variable.AllValue
+		}
+		return result, value
+	}
+	value := interpolation.Value{Values: result.Selected, Multi: variable.Multi || variable.IncludeAll}
+	if param, ok := variable.Parameter.(*dashboard.IntervalVariableParameter); ok && len(result.Selected) == 1 && result.Selected[0] == dashboard.AutoInterval {
+		value = interpolation.SingleValue(param.ComputeAutoInterval(e.end.Sub(e.start)).String())
+	}
+	return result, value
+}
+
+func (e *evaluator) query(ctx context.Context, variable *dashboard.Variable) ([]string, error) {
+	switch param := variable.Parameter.(type) {
+	case *dashboard.ConstantVariableParameter:
+		return param.Values, nil
+	case *dashboard.IntervalVariableParameter:
+		return param.StringValues(), nil
+	case *dashboard.CustomVariableParameter:
+		values, err := interpolation.Interpolate(param.Values, e.values)
+		if err != nil {
+			return nil, err
+		}
+		return (&dashboard.CustomVariableParameter{Values: values}).StringValues(), nil
+	case *dashboard.DatasourceVariableParameter:
+		names, err := e.datasources.List(param.Kind, param.Global)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the datasources: %s", err)
+		}
+		return names, nil
+	case *dashboard.LabelNamesQueryVariableParameter:
+		form, err := e.matchersForm(param.Matchers)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		if err := e.do(ctx, http.MethodPost, "/api/v1/labels", form, &names); err != nil {
+			return nil, err
+		}
+		return capture(param.CapturingRegexp, names), nil
+	case *dashboard.LabelValuesQueryVariableParameter:
+		form, err := e.matchersForm(param.Matchers)
+		if err != nil {
+			return nil, err
+		}
+		labelName, err := interpolation.Interpolate(param.LabelName, e.values)
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		path := fmt.Sprintf("/api/v1/label/%s/values", url.PathEscape(labelName))
+		if err := e.do(ctx, http.MethodGet, path, form, &values); err != nil {
+			return nil, err
+		}
+		return capture(param.CapturingRegexp, values), nil
+	case *dashboard.PromQLQueryVariableParameter:
+		query, err := interpolation.Interpolate(param.Expr, e.values)
+		if err != nil {
+			return nil, err
+		}
+		form := e.rangeForm()
+		form.Set("query", query)
+		step := interpolation.Interval(e.start, e.end, interpolation.DefaultMaxDataPoints)
+		form.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+		var data struct {
+			ResultType model.ValueType `json:"resultType"`
+			Result     model.Matrix    `json:"result"`
+		}
+		if err := e.do(ctx, http.MethodPost, "/api/v1/query_range", form, &data); err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, stream := range data.Result {
+			if value, ok := stream.Metric[model.LabelName(param.LabelName)]; ok {
+				values = append(values, string(value))
+			}
+		}
+		return capture(param.CapturingRegexp, values), nil
+	default:
+		return nil, fmt.Errorf("variable of kind %q cannot be evaluated", variable.Kind)
+	}
+}
+
+func (e *evaluator) rangeForm() url.Values {
+	form := url.Values{}
+	form.Set("start", e.start.UTC().Format(time.RFC3339Nano))
+	form.Set("end", e.end.UTC().Format(time.RFC3339Nano))
+	return form
+}
+
+// matchersForm returns the time range and the series selectors, once the variables they use are replaced.
+func (e *evaluator) matchersForm(matchers []string) (url.Values, error) {
+	form := e.rangeForm()
+	for _, matcher := range matchers {
+		m, err := interpolation.Interpolate(matcher, e.values)
+		if err != nil {
+			return nil, err
+		}
+		form.Add("match[]", m)
+	}
+	return form, nil
+}
+
+// prometheusResponse is the envelope used by the Prometheus HTTP API.
+type prometheusResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+}
+
+// do sends the request to the datasource and decodes the data of the Prometheus response in the given result.
+func (e *evaluator) do(ctx context.Context, method string, path string, form url.Values, result interface{}) error {
+	var body io.Reader
+	target := path
+	if method == http.MethodGet {
+		target = fmt.Sprintf("%s?%s", path, form.Encode())
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	datasourceName, err := interpolation.Interpolate(e.datasourceName, e.values)
+	if err != nil {
+		return err
+	}
+	transport, err := e.datasources.Transport(datasourceName)
+	if err != nil {
+		return err
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("unable to contact the datasource: %s", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read the response of the datasource: %s", err)
+	}
+	var promResponse prometheusResponse
+	if jsonErr := json.Unmarshal(data, &promResponse); jsonErr != nil || len(promResponse.Status) == 0 {
+		return fmt.Errorf("the datasource answered with the status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if promResponse.Status != "success" {
+		return fmt.Errorf("%s: %s", promResponse.ErrorType, promResponse.Error)
+	}
+	if err := json.Unmarshal(promResponse.Data, result); err != nil {
+		return fmt.Errorf("unable to decode the response of the datasource: %s", err)
+	}
+	return nil
+}
+
+// capture returns what is captured by the first group of the regexp for each value.
+// Like it is documented, a regexp without any group doesn't capture anything.
+// The result is sorted and doesn't contain any duplicate.
+func capture(capturingRegexp *dashboard.CapturingRegexp, values []string) []string {
+	re := capturingRegexp.GetRegexp()
+	set := make(map[string]bool)
+	for _, value := range values {
+		matches := re.FindStringSubmatch(value)
+		if len(matches) > 1 && len(matches[1]) > 0 {
+			set[matches[1]] = true
+		}
+	}
+	result := make([]string, 0, len(set))
+	for value := range set {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// selectValues returns the first candidate having at least one of its values available.
+// Only the values available are kept, and only the first one when the variable doesn't accept several values.
+// AllSelection is kept as it is when the variable accepts it. If there is no candidate, the first value is returned.
+func selectValues(variable *dashboard.Variable, values []string, candidates ...dashboard.Selection) dashboard.Selection {
+	available := make(map[string]bool, len(values))
+	for _, value := range values {
+		available[value] = true
+	}
+	for _, candidate := range candidates {
+		if candidate.IsAll() && variable.IncludeAll {
+			return candidate
+		}
+		var result dashboard.Selection
+		for _, value := range candidate {
+			if available[value] {
+				result = append(result, value)
+				// a value is only kept once
+				delete(available, value)
+			}
+		}
+		if len(result) > 0 {
+			if !variable.Multi {
+				return result[:1]
+			}
+			return result
+		}
+	}
+	if len(values) > 0 {
+		return dashboard.Selection{values[0]}
+	}
+	return nil
+}
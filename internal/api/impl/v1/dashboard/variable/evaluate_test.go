@@ -0,0 +1,370 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package variable
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/perses/perses/pkg/model/api/v1/dashboard"
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
+	promModel "github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(req *http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+func newResponse(statusCode int, body string) *http.Response {
+	return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// fakePrometheus is answering like a Prometheus server having the series up{env, job, instance}.
+func fakePrometheus(req *http.Request) *http.Response {
+	if err := req.ParseForm(); err != nil {
+		return newResponse(http.StatusBadRequest, err.Error())
+	}
+	switch req.URL.Path {
+	case "/api/v1/labels":
+		return newResponse(http.StatusOK, `{"status":"success","data":["__name__","env","instance","job"]}`)
+	case "/api/v1/label/job/values":
+		if req.Form.Get("match[]") == `up{env="prod"}` {
+			return newResponse(http.StatusOK, `{"status":"success","data":["api","node"]}`)
+		}
+		return newResponse(http.StatusOK, `{"status":"success","data":["node"]}`)
+	case "/api/v1/query_range":
+		query := req.Form.Get("query")
+		if query == "broken(" {
+			return newResponse(http.StatusBadRequest, `{"status":"error","errorType":"bad_data","error":"unclosed left parenthesis"}`)
+		}
+		return newResponse(http.StatusOK, fmt.Sprintf(`{"status":"success","data":{"resultType":"matrix","result":[
+{"metric":{"__name__":"up","instance":"%[1]s-1:9090"},"values":[[1650000000,"1"]]},
+{"metric":{"__name__":"up","instance":"%[1]s-2:9090"},"values":[[1650000000,"1"]]}
+]}}`, strings.Trim(strconv.Quote(query), `"`)))
+	}
+	return newResponse(http.StatusNotFound, "404 page not found")
+}
+
+// fakeDatasources is giving access to the datasources "PrometheusDemo" and "PrometheusLocal", both answering with the given transport.
+type fakeDatasources struct {
+	transport http.RoundTripper
+	// used is the name of the last datasource queried
+	used  string
+	mutex sync.Mutex
+}
+
+func (f *fakeDatasources) List(kind datasource.Kind, global bool) ([]string, error) {
+	if kind != datasource.PrometheusKind || global {
+		return []string{}, nil
+	}
+	return []string{"PrometheusDemo", "PrometheusLocal"}, nil
+}
+
+func (f *fakeDatasources) Transport(name string) (http.RoundTripper, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.used = name
+	return f.transport, nil
+}
+
+func newCapturingRegexp(re string) *dashboard.CapturingRegexp {
+	return (*dashboard.CapturingRegexp)(regexp.MustCompile(re))
+}
+
+func TestEvaluate(t *testing.T) {
+	variables := map[string]*dashboard.Variable{
+		"env": {
+			Kind:     dashboard.KindConstantVariable,
+			Selected: dashboard.Selection{"dev"},
+			Parameter: &dashboard.ConstantVariableParameter{
+				Values: []string{"dev", "prod"},
+			},
+		},
+		"label": {
+			Kind: dashboard.KindLabelNamesQueryVariable,
+			Parameter: &dashboard.LabelNamesQueryVariableParameter{
+				CapturingRegexp: newCapturingRegexp("^(job|instance)$"),
+			},
+		},
+		"job": {
+			Kind: dashboard.KindLabelValuesQueryVariable,
+			Parameter: &dashboard.LabelValuesQueryVariableParameter{
+				LabelName:       "job",
+				Matchers:        []string{`up{env="$env"}`},
+				CapturingRegexp: newCapturingRegexp("(.*)"),
+			},
+		},
+		"instance": {
+			Kind: dashboard.KindPromQLQueryVariable,
+			Parameter: &dashboard.PromQLQueryVariableParameter{
+				Expr:            "$job",
+				LabelName:       "instance",
+				CapturingRegexp: newCapturingRegexp(`(.+):\d+`),
+			},
+		},
+		"broken": {
+			Kind: dashboard.KindPromQLQueryVariable,
+			Parameter: &dashboard.PromQLQueryVariableParameter{
+				Expr:            "broken(",
+				LabelName:       "instance",
+				CapturingRegexp: newCapturingRegexp("(.*)"),
+			},
+		},
+		"dependent": {
+			Kind: dashboard.KindPromQLQueryVariable,
+			Parameter: &dashboard.PromQLQueryVariableParameter{
+				Expr:            "$broken",
+				LabelName:       "instance",
+				CapturingRegexp: newCapturingRegexp("(.*)"),
+			},
+		},
+	}
+	end := time.Date(2022, 4, 15, 6, 0, 0, 0, time.UTC)
+	request := dashboard.VariableEvaluationRequest{
+		Start:    end.Add(-time.Hour),
+		End:      end,
+		Selected: map[string]dashboard.Selection{"env": {"prod"}, "job": {"unknown"}},
+	}
+	result, err := Evaluate(context.Background(), "Demo", "", variables, request, &fakeDatasources{transport: roundTripFunc(fakePrometheus)})
+	assert.NoError(t, err)
+	assert.Equal(t, []dashboard.VariableEvaluationResult{
+		{
+			Name:   "broken",
+			Values: []string{},
+			Error:  "bad_data: unclosed left parenthesis",
+		},
+		{
+			Name:     "env",
+			Values:   []string{"dev", "prod"},
+			Selected: dashboard.Selection{"prod"},
+		},
+		{
+			Name:     "label",
+			Values:   []string{"instance", "job"},
+			Selected: dashboard.Selection{"instance"},
+		},
+		{
+			Name:   "dependent",
+			Values: []string{},
+			Error:  `the variable "broken" it depends on cannot be evaluated`,
+		},
+		{
+			Name:     "job",
+			Values:   []string{"api", "node"},
+			Selected: dashboard.Selection{"api"},
+		},
+		{
+			Name:     "instance",
+			Values:   []string{"api-1", "api-2"},
+			Selected: dashboard.Selection{"api-1"},
+		},
+	}, result)
+}
+
+func TestEvaluateWithUnreachableDatasource(t *testing.T) {
+	variables := map[string]*dashboard.Variable{
+		"job": {
+			Kind: dashboard.KindLabelValuesQueryVariable,
+			Parameter: &dashboard.LabelValuesQueryVariableParameter{
+				LabelName:       "job",
+				CapturingRegexp: newCapturingRegexp("(.*)"),
+			},
+		},
+	}
+	transport := roundTripFunc(func(req *http.Request) *http.Response {
+		return newResponse(http.StatusBadGateway, "remote unreachable")
+	})
+	end := time.Date(2022, 4, 15, 6, 0, 0, 0, time.UTC)
+	result, err := Evaluate(context.Background(), "Demo", "", variables, dashboard.VariableEvaluationRequest{Start: end.Add(-time.Hour), End: end}, &fakeDatasources{transport: transport})
+	assert.NoError(t, err)
+	assert.Equal(t, []dashboard.VariableEvaluationResult{
+		{
+			Name:   "job",
+			Values: []string{},
+			Error:  "the datasource answered with the status code 502: remote unreachable",
+		},
+	}, result)
+}
+
+func TestEvaluateOtherKinds(t *testing.T) {
+	variables := map[string]*dashboard.Variable{
+		"ds": {
+			Kind:     dashboard.KindDatasourceVariable,
+			Selected: dashboard.Selection{"PrometheusLocal"},
+			Parameter: &dashboard.DatasourceVariableParameter{
+				Kind: datasource.PrometheusKind,
+			},
+		},
+		"interval": {
+			Kind:     dashboard.KindIntervalVariable,
+			Selected: dashboard.Selection{dashboard.AutoInterval},
+			Parameter: &dashboard.IntervalVariableParameter{
+				Values:        []promModel.Duration{promModel.Duration(time.Minute), promModel.Duration(5 * time.Minute)},
+				Auto:          true,
+				AutoStepCount: 10,
+			},
+		},
+		"env": {
+			Kind: dashboard.KindCustomVariable,
+			Parameter: &dashboard.CustomVariableParameter{
+				Values: `Production : prod,Development : dev,comma\,value`,
+			},
+		},
+		"filter": {
+			Kind: dashboard.KindTextBoxVariable,
+			Parameter: &dashboard.TextBoxVariableParameter{
+				Value: "api",
+			},
+		},
+		"instance": {
+			Kind: dashboard.KindPromQLQueryVariable,
+			Parameter: &dashboard.PromQLQueryVariableParameter{
+				Expr:            "${filter}_${interval}",
+				LabelName:       "instance",
+				CapturingRegexp: newCapturingRegexp(`(.+):\d+`),
+			},
+		},
+	}
+	end := time.Date(2022, 4, 15, 6, 0, 0, 0, time.UTC)
+	datasources := &fakeDatasources{transport: roundTripFunc(fakePrometheus)}
+	request := dashboard.VariableEvaluationRequest{
+		Start:    end.Add(-time.Hour),
+		End:      end,
+		Selected: map[string]dashboard.Selection{"filter": {"node"}},
+	}
+	result, err := Evaluate(context.Background(), "Demo", "$ds", variables, request, datasources)
+	assert.NoError(t, err)
+	assert.Equal(t, []dashboard.VariableEvaluationResult{
+		{
+			Name:     "ds",
+			Values:   []string{"PrometheusDemo", "PrometheusLocal"},
+			Selected: dashboard.Selection{"PrometheusLocal"},
+		},
+		{
+			Name:     "env",
+			Values:   []string{"prod", "dev", "comma,value"},
+			Selected: dashboard.Selection{"prod"},
+		},
+		{
+			Name:     "filter",
+			Values:   []string{"node"},
+			Selected: dashboard.Selection{"node"},
+		},
+		{
+			Name:     "interval",
+			Values:   []string{"auto", "1m", "5m"},
+			Selected: dashboard.Selection{"auto"},
+		},
+		{
+			Name:     "instance",
+			Values:   []string{"node_6m-1", "node_6m-2"},
+			Selected: dashboard.Selection{"node_6m-1"},
+		},
+	}, result)
+	assert.Equal(t, "PrometheusLocal", datasources.used)
+}
+
+func TestEvaluateMultipleValues(t *testing.T) {
+	variables := map[string]*dashboard.Variable{
+		"env": {
+			Kind:     dashboard.KindConstantVariable,
+			Selected: dashboard.Selection{"dev", "prod"},
+			Multi:    true,
+			Parameter: &dashboard.ConstantVariableParameter{
+				Values: []string{"dev", "prod", "staging"},
+			},
+		},
+		"job": {
+			Kind:       dashboard.KindConstantVariable,
+			IncludeAll: true,
+			AllValue:   ".*",
+			Parameter: &dashboard.ConstantVariableParameter{
+				Values: []string{"api", "node"},
+			},
+		},
+		"host": {
+			Kind:       dashboard.KindConstantVariable,
+			Selected:   dashboard.Selection{dashboard.AllSelection},
+			Multi:      true,
+			IncludeAll: true,
+			Parameter: &dashboard.ConstantVariableParameter{
+				Values: []string{"a.example", "b.example"},
+			},
+		},
+		"single": {
+			Kind: dashboard.KindConstantVariable,
+			Parameter: &dashboard.ConstantVariableParameter{
+				Values: []string{"x", "y"},
+			},
+		},
+		"query": {
+			Kind: dashboard.KindPromQLQueryVariable,
+			Parameter: &dashboard.PromQLQueryVariableParameter{
+				Expr:            "$env;$job;$host;$single",
+				LabelName:       "instance",
+				CapturingRegexp: newCapturingRegexp(`(.+)-1:\d+`),
+			},
+		},
+	}
+	end := time.Date(2022, 4, 15, 6, 0, 0, 0, time.UTC)
+	request := dashboard.VariableEvaluationRequest{
+		Start: end.Add(-time.Hour),
+		End:   end,
+		Selected: map[string]dashboard.Selection{
+			"env":    {"staging", "unknown", "prod"},
+			"job":    {dashboard.AllSelection},
+			"single": {"y", "x"},
+		},
+	}
+	result, err := Evaluate(context.Background(), "Demo", "", variables, request, &fakeDatasources{transport: roundTripFunc(fakePrometheus)})
+	assert.NoError(t, err)
+	assert.Equal(t, []dashboard.VariableEvaluationResult{
+		{
+			Name:     "env",
+			Values:   []string{"dev", "prod", "staging"},
+			Selected: dashboard.Selection{"staging", "prod"},
+		},
+		{
+			Name:     "host",
+			Values:   []string{"a.example", "b.example"},
+			Selected: dashboard.Selection{dashboard.AllSelection},
+		},
+		{
+			Name:     "job",
+			Values:   []string{"api", "node"},
+			Selected: dashboard.Selection{dashboard.AllSelection},
+		},
+		{
+			Name:     "single",
+			Values:   []string{"x", "y"},
+			Selected: dashboard.Selection{"y"},
+		},
+		{
+			Name:     "query",
+			Values:   []string{`(staging|prod);.*;(a\.example|b\.example);y`},
+			Selected: dashboard.Selection{`(staging|prod);.*;(a\.example|b\.example);y`},
+		},
+	}, result)
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datasource
+
+import (
+	"errors"
+
+	"github.com/perses/perses/internal/api/interface/v1/datasource"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/sirupsen/logrus"
+)
+
+// validateDefault verifies that no other datasource of the same kind is already the default one in the project.
+// The violation is added to the report, and an error is returned only when the datasources cannot be retrieved.
+// The check and the write that follows are serialised by writeMutex within a Perses instance. The storage doesn't provide
+// any transaction, so it remains best-effort when several instances are sharing the same database.
+func (s *service) validateDefault(entity *v1.Datasource, report *common.ValidationReport) error {
+	if !entity.Spec.IsDefault() {
+		return nil
+	}
+	current, err := s.findDefault(entity.Metadata.Project, entity.Spec.GetKind())
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Metadata.Name != entity.Metadata.Name {
+		report.AddError("/spec/default", "the datasource %q is already the default one for the kind %q in the project %q", current.Metadata.Name, entity.Spec.GetKind(), entity.Metadata.Project)
+	}
+	return nil
+}
+
+// findDefault returns the datasource of the project with the given kind flagged as the default one, or nil if there is none.
+func (s *service) findDefault(project string, kind datasourcev1.Kind) (*v1.Datasource, error) {
+	list, err := s.dao.List(&datasource.Query{Project: project})
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to list the Datasources of the project %q, something wrong with etcd", project)
+		return nil, shared.InternalError
+	}
+	for _, ds := range list {
+		if ds.Spec.GetKind() == kind && ds.Spec.IsDefault() {
+			return ds, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *service) GetDefault(project string, kind datasourcev1.Kind) (interface{}, error) {
+	entity, err := s.findDefault(project, kind)
+	if err != nil {
+		return nil, err
+	}
+	if entity != nil {
+		return entity, nil
+	}
+	// there is no default datasource in the project, so the default global datasource is used instead
+	globalEntity, err := s.globalDatasourceService.GetDefault(kind)
+	if err != nil {
+		if errors.Is(err, shared.NotFoundError) {
+			logrus.Debugf("unable to find a default Datasource for the kind %q in the project %q", kind, project)
+		}
+		return nil, err
+	}
+	return globalEntity, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datasource
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/perses/perses/internal/api/shared"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
+)
+
+const (
+	queryParamKind = "kind"
+	pathDefault    = "default"
+)
+
+// registerCustomRoutes is called by the generated method RegisterRoutes to add the endpoints that are specific to the datasources.
+func (e *Endpoint) registerCustomRoutes(_ *echo.Group, subGroup *echo.Group) {
+	// this route takes precedence over `GET /:name`, so GetDefault still returns a datasource named "default" when no kind is given.
+	// The other methods are not registered here and keep being handled by the routes `/:name`.
+	subGroup.GET(fmt.Sprintf("/%s", pathDefault), e.GetDefault)
+}
+
+// GetDefault returns the default datasource of the kind given in the query parameters.
+// If the project doesn't have one, the default global datasource is returned.
+// Without the query parameter kind, it returns the datasource named "default" like `GET /:name` does.
+func (e *Endpoint) GetDefault(ctx echo.Context) error {
+	kind := ctx.QueryParam(queryParamKind)
+	if len(kind) == 0 {
+		// the parameters are copied, as the names are shared by every request of the route
+		names := append(append([]string{}, ctx.ParamNames()...), shared.ParamName)
+		values := append(append([]string{}, ctx.ParamValues()...), pathDefault)
+		ctx.SetParamNames(names...)
+		ctx.SetParamValues(values...)
+		return e.Get(ctx)
+	}
+	result, err := e.service.GetDefault(ctx.Param(shared.ParamProject), datasourcev1.Kind(kind))
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
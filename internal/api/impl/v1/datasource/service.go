@@ -15,27 +15,38 @@ package datasource
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/perses/common/etcd"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
 	"github.com/perses/perses/internal/api/interface/v1/datasource"
+	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
 	"github.com/perses/perses/internal/api/shared"
 	"github.com/perses/perses/pkg/model/api"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/sirupsen/logrus"
 )
 
 type service struct {
 	datasource.Service
-	dao datasource.DAO
+	dao                     datasource.DAO
+	globalDatasourceService globaldatasource.Service
+	validator               schemas.Validator
+	// writeMutex serialises the creations and the updates, so two of them cannot both flag a datasource as the default
+	// one of the same kind, see validateDefault.
+	writeMutex sync.Mutex
 }
 
-func NewService(dao datasource.DAO) datasource.Service {
+func NewService(dao datasource.DAO, globalDatasourceService globaldatasource.Service, validator schemas.Validator) datasource.Service {
 	return &service{
-		dao: dao,
+		dao:                     dao,
+		globalDatasourceService: globalDatasourceService,
+		validator:               validator,
 	}
 }
 
-func (s *service) Create(entity api.Entity) (interface{}, error) {
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
 	if datasourceObject, ok := entity.(*v1.Datasource); ok {
 		return s.create(datasourceObject)
 	}
@@ -43,6 +54,11 @@ func (s *service) Create(entity api.Entity) (interface{}, error) {
 }
 
 func (s *service) create(entity *v1.Datasource) (*v1.Datasource, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if err := s.validate(entity); err != nil {
+		return nil, err
+	}
 	// Update the time contains in the entity
 	entity.Metadata.CreateNow()
 	if err := s.dao.Create(entity); err != nil {
@@ -56,6 +72,18 @@ func (s *service) create(entity *v1.Datasource) (*v1.Datasource, error) {
 	return entity, nil
 }
 
+// validate returns a common.ValidationReport with every problem found in the datasource:
+// its spec checked against the schemas, and the uniqueness of the default datasource.
+func (s *service) validate(entity *v1.Datasource) error {
+	var report common.ValidationReport
+	report.Merge("", s.validator.ValidateDatasource(entity.Spec))
+	if err := s.validateDefault(entity, &report); err != nil {
+		return err
+	}
+	report.Sort()
+	return report.Err()
+}
+
 func (s *service) Update(entity api.Entity, parameters shared.Parameters) (interface{}, error) {
 	if DatasourceObject, ok := entity.(*v1.Datasource); ok {
 		return s.update(DatasourceObject, parameters)
@@ -74,11 +102,16 @@ func (s *service) update(entity *v1.Datasource, parameters shared.Parameters) (*
 		logrus.Debugf("project in datasource %q and coming from the http request: %q doesn't match", entity.Metadata.Project, parameters.Project)
 		return nil, fmt.Errorf("%w: metadata.project and the project name in the http path request doesn't match", shared.BadRequestError)
 	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
 	// find the previous version of the Datasource
 	oldEntity, err := s.Get(parameters)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.validate(entity); err != nil {
+		return nil, err
+	}
 	oldObject := oldEntity.(*v1.Datasource)
 	entity.Metadata.Update(oldObject.Metadata)
 	if err := s.dao.Update(entity); err != nil {
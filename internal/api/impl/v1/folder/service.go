@@ -35,7 +35,7 @@ func NewService(dao folder.DAO) folder.Service {
 	}
 }
 
-func (s *service) Create(entity api.Entity) (interface{}, error) {
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
 	if datasourceObject, ok := entity.(*v1.Folder); ok {
 		return s.create(datasourceObject)
 	}
@@ -0,0 +1,68 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package globaldatasource
+
+import (
+	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/sirupsen/logrus"
+)
+
+// validateDefault verifies that no other global datasource of the same kind is already the default one.
+// The violation is added to the report, and an error is returned only when the datasources cannot be retrieved.
+// The check and the write that follows are serialised by writeMutex within a Perses instance. The storage doesn't provide
+// any transaction, so it remains best-effort when several instances are sharing the same database.
+func (s *service) validateDefault(entity *v1.GlobalDatasource, report *common.ValidationReport) error {
+	if !entity.Spec.IsDefault() {
+		return nil
+	}
+	current, err := s.findDefault(entity.Spec.GetKind())
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Metadata.Name != entity.Metadata.Name {
+		report.AddError("/spec/default", "the global datasource %q is already the default one for the kind %q", current.Metadata.Name, entity.Spec.GetKind())
+	}
+	return nil
+}
+
+// findDefault returns the global datasource of the given kind flagged as the default one, or nil if there is none.
+func (s *service) findDefault(kind datasource.Kind) (*v1.GlobalDatasource, error) {
+	list, err := s.dao.List(&globaldatasource.Query{})
+	if err != nil {
+		logrus.WithError(err).Error("unable to list the GlobalDatasources, something wrong with etcd")
+		return nil, shared.InternalError
+	}
+	for _, ds := range list {
+		if ds.Spec.GetKind() == kind && ds.Spec.IsDefault() {
+			return ds, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *service) GetDefault(kind datasource.Kind) (*v1.GlobalDatasource, error) {
+	entity, err := s.findDefault(kind)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		logrus.Debugf("unable to find a default GlobalDatasource for the kind %q", kind)
+		return nil, shared.NotFoundError
+	}
+	return entity, nil
+}
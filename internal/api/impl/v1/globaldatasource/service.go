@@ -15,27 +15,35 @@ package globaldatasource
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/perses/common/etcd"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
 	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
 	"github.com/perses/perses/internal/api/shared"
 	"github.com/perses/perses/pkg/model/api"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/sirupsen/logrus"
 )
 
 type service struct {
 	globaldatasource.Service
-	dao globaldatasource.DAO
+	dao       globaldatasource.DAO
+	validator schemas.Validator
+	// writeMutex serialises the creations and the updates, so two of them cannot both flag a global datasource as the
+	// default one of the same kind, see validateDefault.
+	writeMutex sync.Mutex
 }
 
-func NewService(dao globaldatasource.DAO) globaldatasource.Service {
+func NewService(dao globaldatasource.DAO, validator schemas.Validator) globaldatasource.Service {
 	return &service{
-		dao: dao,
+		dao:       dao,
+		validator: validator,
 	}
 }
 
-func (s *service) Create(entity api.Entity) (interface{}, error) {
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
 	if datasourceObject, ok := entity.(*v1.GlobalDatasource); ok {
 		return s.create(datasourceObject)
 	}
@@ -43,6 +51,11 @@ func (s *service) Create(entity api.Entity) (interface{}, error) {
 }
 
 func (s *service) create(entity *v1.GlobalDatasource) (*v1.GlobalDatasource, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if err := s.validate(entity); err != nil {
+		return nil, err
+	}
 	// Update the time contains in the entity
 	entity.Metadata.CreateNow()
 	if err := s.dao.Create(entity); err != nil {
@@ -56,6 +69,18 @@ func (s *service) create(entity *v1.GlobalDatasource) (*v1.GlobalDatasource, err
 	return entity, nil
 }
 
+// validate returns a common.ValidationReport with every problem found in the global datasource:
+// its spec checked against the schemas, and the uniqueness of the default global datasource.
+func (s *service) validate(entity *v1.GlobalDatasource) error {
+	var report common.ValidationReport
+	report.Merge("", s.validator.ValidateDatasource(entity.Spec))
+	if err := s.validateDefault(entity, &report); err != nil {
+		return err
+	}
+	report.Sort()
+	return report.Err()
+}
+
 func (s *service) Update(entity api.Entity, parameters shared.Parameters) (interface{}, error) {
 	if DatasourceObject, ok := entity.(*v1.GlobalDatasource); ok {
 		return s.update(DatasourceObject, parameters)
@@ -68,11 +93,16 @@ func (s *service) update(entity *v1.GlobalDatasource, parameters shared.Paramete
 		logrus.Debugf("name in Datasource %q and coming from the http request: %q doesn't match", entity.Metadata.Name, parameters.Name)
 		return nil, fmt.Errorf("%w: metadata.name and the name in the http path request doesn't match", shared.BadRequestError)
 	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
 	// find the previous version of the Datasource
 	oldEntity, err := s.Get(parameters)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.validate(entity); err != nil {
+		return nil, err
+	}
 	oldObject := oldEntity.(*v1.GlobalDatasource)
 	entity.Metadata.Update(oldObject.Metadata)
 	if err := s.dao.Update(entity); err != nil {
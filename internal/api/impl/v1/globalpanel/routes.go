@@ -0,0 +1,39 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package globalpanel
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/perses/perses/internal/api/shared"
+)
+
+// registerCustomRoutes is called by the generated method RegisterRoutes to add the endpoints that are specific to the global panels.
+func (e *Endpoint) registerCustomRoutes(group *echo.Group) {
+	group.GET(fmt.Sprintf("/:%s/usages", shared.ParamName), e.Usages)
+}
+
+// Usages returns the dashboards, of every project, that are using the global panel.
+func (e *Endpoint) Usages(ctx echo.Context) error {
+	parameters := shared.Parameters{
+		Name: ctx.Param(shared.ParamName),
+	}
+	result, err := e.service.Usages(parameters)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
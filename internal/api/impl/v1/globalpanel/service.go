@@ -0,0 +1,173 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package globalpanel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/perses/common/etcd"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/api/interface/v1/dashboard"
+	"github.com/perses/perses/internal/api/interface/v1/globalpanel"
+	"github.com/perses/perses/internal/api/interface/v1/project"
+	"github.com/perses/perses/internal/api/shared"
+	"github.com/perses/perses/pkg/model/api"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+)
+
+type service struct {
+	globalpanel.Service
+	dao          globalpanel.DAO
+	projectDAO   project.DAO
+	dashboardDAO dashboard.DAO
+	validator    schemas.Validator
+	// libraryPanelMutex is shared with the dashboard service, that read-locks it when saving a dashboard.
+	// It is locked when deleting a global panel, so no dashboard can start using the panel between the check of its usages and its deletion.
+	libraryPanelMutex *sync.RWMutex
+}
+
+func NewService(dao globalpanel.DAO, projectDAO project.DAO, dashboardDAO dashboard.DAO, validator schemas.Validator, libraryPanelMutex *sync.RWMutex) globalpanel.Service {
+	return &service{
+		dao:               dao,
+		projectDAO:        projectDAO,
+		dashboardDAO:      dashboardDAO,
+		validator:         validator,
+		libraryPanelMutex: libraryPanelMutex,
+	}
+}
+
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
+	if panelObject, ok := entity.(*v1.GlobalPanel); ok {
+		return s.create(panelObject)
+	}
+	return nil, fmt.Errorf("%w: wrong entity format, attempting GlobalPanel format, received '%T'", shared.BadRequestError, entity)
+}
+
+func (s *service) create(entity *v1.GlobalPanel) (*v1.GlobalPanel, error) {
+	if err := s.validator.ValidatePanel(entity.Metadata.Name, entity.Spec); err != nil {
+		return nil, err
+	}
+	// Update the time contains in the entity
+	entity.Metadata.CreateNow()
+	if err := s.dao.Create(entity); err != nil {
+		if etcd.IsKeyConflict(err) {
+			logrus.Debugf("unable to create the GlobalPanel %q. It already exits", entity.Metadata.Name)
+			return nil, shared.ConflictError
+		}
+		logrus.WithError(err).Errorf("unable to perform the creation of the GlobalPanel %q, something wrong with etcd", entity.Metadata.Name)
+		return nil, shared.InternalError
+	}
+	return entity, nil
+}
+
+func (s *service) Update(entity api.Entity, parameters shared.Parameters) (interface{}, error) {
+	if panelObject, ok := entity.(*v1.GlobalPanel); ok {
+		return s.update(panelObject, parameters)
+	}
+	return nil, fmt.Errorf("%w: wrong entity format, attempting GlobalPanel format, received '%T'", shared.BadRequestError, entity)
+}
+
+func (s *service) update(entity *v1.GlobalPanel, parameters shared.Parameters) (*v1.GlobalPanel, error) {
+	if entity.Metadata.Name != parameters.Name {
+		logrus.Debugf("name in GlobalPanel %q and coming from the http request: %q doesn't match", entity.Metadata.Name, parameters.Name)
+		return nil, fmt.Errorf("%w: metadata.name and the name in the http path request doesn't match", shared.BadRequestError)
+	}
+	// find the previous version of the GlobalPanel
+	oldEntity, err := s.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidatePanel(entity.Metadata.Name, entity.Spec); err != nil {
+		return nil, err
+	}
+	oldObject := oldEntity.(*v1.GlobalPanel)
+	entity.Metadata.Update(oldObject.Metadata)
+	if err := s.dao.Update(entity); err != nil {
+		logrus.WithError(err).Errorf("unable to perform the update of the GlobalPanel %q, something wrong with etcd", entity.Metadata.Name)
+		return nil, shared.InternalError
+	}
+	return entity, nil
+}
+
+// Delete removes the GlobalPanel, unless a dashboard is still using it.
+// The check is best-effort when several instances of Perses are sharing the same database, as the storage doesn't provide transactions.
+func (s *service) Delete(parameters shared.Parameters) error {
+	s.libraryPanelMutex.Lock()
+	defer s.libraryPanelMutex.Unlock()
+	usages, err := s.Usages(parameters)
+	if err != nil {
+		return err
+	}
+	if len(usages) > 0 {
+		dashboards := make([]string, 0, len(usages))
+		for _, usage := range usages {
+			dashboards = append(dashboards, fmt.Sprintf("%s/%s", usage.Project, usage.Dashboard))
+		}
+		return fmt.Errorf("%w: the global panel %q cannot be deleted, it is still used by the dashboards %s", shared.BadRequestError, parameters.Name, strings.Join(dashboards, ", "))
+	}
+	if err := s.dao.Delete(parameters.Name); err != nil {
+		if etcd.IsKeyNotFound(err) {
+			logrus.Debugf("unable to find the GlobalPanel %q", parameters.Name)
+			return shared.NotFoundError
+		}
+		logrus.WithError(err).Errorf("unable to delete the GlobalPanel %q, something wrong with etcd", parameters.Name)
+		return shared.InternalError
+	}
+	return nil
+}
+
+func (s *service) Get(parameters shared.Parameters) (interface{}, error) {
+	entity, err := s.dao.Get(parameters.Name)
+	if err != nil {
+		if etcd.IsKeyNotFound(err) {
+			logrus.Debugf("unable to find the GlobalPanel %q", parameters.Name)
+			return nil, shared.NotFoundError
+		}
+		logrus.WithError(err).Errorf("unable to find the previous version of the GlobalPanel %q, something wrong with etcd", parameters.Name)
+		return nil, shared.InternalError
+	}
+	return entity, nil
+}
+
+func (s *service) List(q etcd.Query, _ shared.Parameters) (interface{}, error) {
+	return s.dao.List(q)
+}
+
+func (s *service) Usages(parameters shared.Parameters) ([]*v1.PanelUsage, error) {
+	if _, err := s.Get(parameters); err != nil {
+		return nil, err
+	}
+	projects, err := s.projectDAO.List(&project.Query{})
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to list the projects to find where the GlobalPanel %q is used", parameters.Name)
+		return nil, shared.InternalError
+	}
+	result := []*v1.PanelUsage{}
+	for _, p := range projects {
+		dashboards, listErr := s.dashboardDAO.List(&dashboard.Query{Project: p.Metadata.Name})
+		if listErr != nil {
+			logrus.WithError(listErr).Errorf("unable to list the dashboards of the project %q to find where the GlobalPanel %q is used", p.Metadata.Name, parameters.Name)
+			return nil, shared.InternalError
+		}
+		for _, entity := range dashboards {
+			if usage := v1.NewPanelUsage(entity, parameters.Name, true); usage != nil {
+				result = append(result, usage)
+			}
+		}
+	}
+	return result, nil
+}
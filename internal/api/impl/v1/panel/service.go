@@ -0,0 +1,169 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/perses/common/etcd"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/api/interface/v1/dashboard"
+	"github.com/perses/perses/internal/api/interface/v1/panel"
+	"github.com/perses/perses/internal/api/shared"
+	"github.com/perses/perses/pkg/model/api"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+)
+
+type service struct {
+	panel.Service
+	dao          panel.DAO
+	dashboardDAO dashboard.DAO
+	validator    schemas.Validator
+	// libraryPanelMutex is shared with the dashboard service, that read-locks it when saving a dashboard.
+	// It is locked when deleting a panel, so no dashboard can start using the panel between the check of its usages and its deletion.
+	libraryPanelMutex *sync.RWMutex
+}
+
+func NewService(dao panel.DAO, dashboardDAO dashboard.DAO, validator schemas.Validator, libraryPanelMutex *sync.RWMutex) panel.Service {
+	return &service{
+		dao:               dao,
+		dashboardDAO:      dashboardDAO,
+		validator:         validator,
+		libraryPanelMutex: libraryPanelMutex,
+	}
+}
+
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
+	if panelObject, ok := entity.(*v1.Panel); ok {
+		return s.create(panelObject)
+	}
+	return nil, fmt.Errorf("%w: wrong entity format, attempting Panel format, received '%T'", shared.BadRequestError, entity)
+}
+
+func (s *service) create(entity *v1.Panel) (*v1.Panel, error) {
+	if err := s.validator.ValidatePanel(entity.Metadata.Name, entity.Spec); err != nil {
+		return nil, err
+	}
+	// Update the time contains in the entity
+	entity.Metadata.CreateNow()
+	if err := s.dao.Create(entity); err != nil {
+		if etcd.IsKeyConflict(err) {
+			logrus.Debugf("unable to create the Panel %q. It already exits", entity.Metadata.Name)
+			return nil, shared.ConflictError
+		}
+		logrus.WithError(err).Errorf("unable to perform the creation of the Panel %q, something wrong with etcd", entity.Metadata.Name)
+		return nil, shared.InternalError
+	}
+	return entity, nil
+}
+
+func (s *service) Update(entity api.Entity, parameters shared.Parameters) (interface{}, error) {
+	if panelObject, ok := entity.(*v1.Panel); ok {
+		return s.update(panelObject, parameters)
+	}
+	return nil, fmt.Errorf("%w: wrong entity format, attempting Panel format, received '%T'", shared.BadRequestError, entity)
+}
+
+func (s *service) update(entity *v1.Panel, parameters shared.Parameters) (*v1.Panel, error) {
+	if entity.Metadata.Name != parameters.Name {
+		logrus.Debugf("name in Panel %q and coming from the http request: %q doesn't match", entity.Metadata.Name, parameters.Name)
+		return nil, fmt.Errorf("%w: metadata.name and the name in the http path request doesn't match", shared.BadRequestError)
+	}
+	if len(entity.Metadata.Project) == 0 {
+		entity.Metadata.Project = parameters.Project
+	} else if entity.Metadata.Project != parameters.Project {
+		logrus.Debugf("project in Panel %q and coming from the http request: %q doesn't match", entity.Metadata.Project, parameters.Project)
+		return nil, fmt.Errorf("%w: metadata.project and the project name in the http path request doesn't match", shared.BadRequestError)
+	}
+	// find the previous version of the Panel
+	oldEntity, err := s.Get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidatePanel(entity.Metadata.Name, entity.Spec); err != nil {
+		return nil, err
+	}
+	oldObject := oldEntity.(*v1.Panel)
+	entity.Metadata.Update(oldObject.Metadata)
+	if err := s.dao.Update(entity); err != nil {
+		logrus.WithError(err).Errorf("unable to perform the update of the Panel %q, something wrong with etcd", entity.Metadata.Name)
+		return nil, shared.InternalError
+	}
+	return entity, nil
+}
+
+// Delete removes the Panel, unless a dashboard is still using it.
+// The check is best-effort when several instances of Perses are sharing the same database, as the storage doesn't provide transactions.
+func (s *service) Delete(parameters shared.Parameters) error {
+	s.libraryPanelMutex.Lock()
+	defer s.libraryPanelMutex.Unlock()
+	usages, err := s.Usages(parameters)
+	if err != nil {
+		return err
+	}
+	if len(usages) > 0 {
+		dashboards := make([]string, 0, len(usages))
+		for _, usage := range usages {
+			dashboards = append(dashboards, usage.Dashboard)
+		}
+		return fmt.Errorf("%w: the panel %q cannot be deleted, it is still used by the dashboards %s", shared.BadRequestError, parameters.Name, strings.Join(dashboards, ", "))
+	}
+	if err := s.dao.Delete(parameters.Project, parameters.Name); err != nil {
+		if etcd.IsKeyNotFound(err) {
+			logrus.Debugf("unable to find the Panel %q", parameters.Name)
+			return shared.NotFoundError
+		}
+		logrus.WithError(err).Errorf("unable to delete the Panel %q, something wrong with etcd", parameters.Name)
+		return shared.InternalError
+	}
+	return nil
+}
+
+func (s *service) Get(parameters shared.Parameters) (interface{}, error) {
+	entity, err := s.dao.Get(parameters.Project, parameters.Name)
+	if err != nil {
+		if etcd.IsKeyNotFound(err) {
+			logrus.Debugf("unable to find the Panel %q", parameters.Name)
+			return nil, shared.NotFoundError
+		}
+		logrus.WithError(err).Errorf("unable to find the previous version of the Panel %q, something wrong with etcd", parameters.Name)
+		return nil, shared.InternalError
+	}
+	return entity, nil
+}
+
+func (s *service) List(q etcd.Query, _ shared.Parameters) (interface{}, error) {
+	return s.dao.List(q)
+}
+
+func (s *service) Usages(parameters shared.Parameters) ([]*v1.PanelUsage, error) {
+	if _, err := s.Get(parameters); err != nil {
+		return nil, err
+	}
+	dashboards, err := s.dashboardDAO.List(&dashboard.Query{Project: parameters.Project})
+	if err != nil {
+		logrus.WithError(err).Errorf("unable to list the dashboards of the project %q to find where the Panel %q is used", parameters.Project, parameters.Name)
+		return nil, shared.InternalError
+	}
+	result := []*v1.PanelUsage{}
+	for _, entity := range dashboards {
+		if usage := v1.NewPanelUsage(entity, parameters.Name, false); usage != nil {
+			result = append(result, usage)
+		}
+	}
+	return result, nil
+}
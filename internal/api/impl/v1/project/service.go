@@ -35,7 +35,7 @@ func NewService(dao project.DAO) project.Service {
 	}
 }
 
-func (s *service) Create(entity api.Entity) (interface{}, error) {
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
 	if projectObject, ok := entity.(*v1.Project); ok {
 		return s.create(projectObject)
 	}
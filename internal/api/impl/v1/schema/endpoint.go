@@ -0,0 +1,79 @@
+// Copyright 2021 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package schema
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/perses/perses/internal/api/interface/v1/schema"
+	"github.com/perses/perses/internal/api/shared"
+)
+
+// Endpoint is the struct that define all endpoint delivered by the path /schemas
+type Endpoint struct {
+	service schema.Service
+}
+
+// NewEndpoint create an instance of the object Endpoint.
+// You should have at most one instance of this object as it is only used by the struct api in the method api.registerRoute
+func NewEndpoint(service schema.Service) *Endpoint {
+	return &Endpoint{
+		service: service,
+	}
+}
+
+// RegisterRoutes is the method to use to register the routes prefixed by /api
+// If the version is not v1, then look at the same method but in the package with the version as the name.
+func (e *Endpoint) RegisterRoutes(g *echo.Group) {
+	group := g.Group("/schemas")
+	group.GET("/panels", e.ListPanels)
+	group.GET("/queries", e.ListQueries)
+	group.GET("/status", e.GetStatus)
+	group.GET("/events", e.ListEvents)
+	group.POST("/panels/migrate", e.MigratePanels)
+}
+
+// ListPanels returns the panel plugins currently loaded, with their schema exported as OpenAPI.
+func (e *Endpoint) ListPanels(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, e.service.ListPanels())
+}
+
+// ListQueries returns the query plugins currently loaded, with their schema exported as OpenAPI.
+func (e *Endpoint) ListQueries(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, e.service.ListQueries())
+}
+
+// GetStatus returns the number of plugins loaded and the plugins that failed to load.
+func (e *Endpoint) GetStatus(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, e.service.GetStatus())
+}
+
+// ListEvents returns the latest results of the loading of the plugins, i.e. the plugins loaded, removed, or that failed to load.
+func (e *Endpoint) ListEvents(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, e.service.ListEvents())
+}
+
+// MigratePanels upgrades the panels of every dashboard to the version of their schema.
+// With the query parameter dry_run=true, the panels to upgrade are only reported.
+func (e *Endpoint) MigratePanels(ctx echo.Context) error {
+	dryRun, err := shared.GetBoolQueryParameter(ctx, shared.ParamDryRun, false)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	report, err := e.service.MigratePanels(dryRun)
+	if err != nil {
+		return shared.HandleError(err)
+	}
+	return ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package schema
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/api/interface/v1/dashboard"
+	"github.com/perses/perses/internal/api/interface/v1/project"
+	"github.com/perses/perses/internal/api/interface/v1/schema"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// maxEvents is the number of the latest loading events kept to be returned by the API
+const maxEvents = 100
+
+type service struct {
+	schema.Service
+	validator    schemas.Validator
+	projectDAO   project.DAO
+	dashboardDAO dashboard.DAO
+	// eventsMutex guards events, the latest loading events received from the validator
+	eventsMutex sync.RWMutex
+	events      []v1.SchemaReloadEvent
+}
+
+// NewService creates an instance of the interface Service, describing the plugins loaded by the validator.
+// The dashboards of every project are accessed through the DAOs to migrate their panels.
+func NewService(validator schemas.Validator, projectDAO project.DAO, dashboardDAO dashboard.DAO) schema.Service {
+	s := &service{
+		validator:    validator,
+		projectDAO:   projectDAO,
+		dashboardDAO: dashboardDAO,
+	}
+	go s.recordEvents(validator.Subscribe())
+	return s
+}
+
+// recordEvents keeps the latest loading events, for as long as the validator is publishing them.
+func (s *service) recordEvents(events <-chan v1.SchemaReloadEvent) {
+	for event := range events {
+		s.eventsMutex.Lock()
+		s.events = append(s.events, event)
+		if len(s.events) > maxEvents {
+			s.events = s.events[len(s.events)-maxEvents:]
+		}
+		s.eventsMutex.Unlock()
+	}
+}
+
+func (s *service) ListPanels() []*v1.SchemaPlugin {
+	return emptyIfNil(s.validator.GetPanels())
+}
+
+func (s *service) ListQueries() []*v1.SchemaPlugin {
+	return emptyIfNil(s.validator.GetQueries())
+}
+
+func (s *service) GetStatus() *v1.SchemaStatus {
+	return s.validator.GetStatus()
+}
+
+func (s *service) ListEvents() []v1.SchemaReloadEvent {
+	s.eventsMutex.RLock()
+	defer s.eventsMutex.RUnlock()
+	// the most recent event comes first
+	events := make([]v1.SchemaReloadEvent, 0, len(s.events))
+	for i := len(s.events) - 1; i >= 0; i-- {
+		events = append(events, s.events[i])
+	}
+	return events
+}
+
+func (s *service) MigratePanels(dryRun bool) (*v1.PanelMigrationReport, error) {
+	// the dashboards are listed project by project, as not every database is able to list them across the projects
+	projects, err := s.projectDAO.List(&project.Query{})
+	if err != nil {
+		logrus.WithError(err).Error("unable to list the projects to migrate the panels of their dashboards")
+		return nil, shared.InternalError
+	}
+	report := &v1.PanelMigrationReport{
+		DryRun:     dryRun,
+		Migrations: []v1.PanelMigration{},
+	}
+	for _, p := range projects {
+		dashboards, listErr := s.dashboardDAO.List(&dashboard.Query{Project: p.Metadata.Name})
+		if listErr != nil {
+			logrus.WithError(listErr).Errorf("unable to list the dashboards of the project %q to migrate their panels", p.Metadata.Name)
+			return nil, shared.InternalError
+		}
+		for _, entity := range dashboards {
+			report.Migrations = append(report.Migrations, s.migrateDashboard(entity, dryRun)...)
+		}
+	}
+	return report, nil
+}
+
+// migrateDashboard upgrades the panels of the dashboard saved with an older version of their schema, and returns the migrations done.
+// A panel that cannot be upgraded is reported with its error, and the dashboard is not updated, as it would no longer be valid.
+func (s *service) migrateDashboard(entity *v1.Dashboard, dryRun bool) []v1.PanelMigration {
+	var migrations []v1.PanelMigration
+	for name, panel := range entity.Spec.Panels {
+		migration, err := s.validator.PanelMigration(panel)
+		if err != nil {
+			// the panel is invalid for another reason than its version, it is up to the users to fix it
+			logrus.WithError(err).Debugf("unable to find the version of the panel %q in the dashboard %s/%s", name, entity.Metadata.Project, entity.Metadata.Name)
+			continue
+		}
+		if migration.From == migration.To {
+			continue
+		}
+		migration.Project = entity.Metadata.Project
+		migration.Dashboard = entity.Metadata.Name
+		migration.Panel = name
+		migrations = append(migrations, *migration)
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Panel < migrations[j].Panel
+	})
+
+	panels, err := s.validator.Migrate(entity.Spec.Panels)
+	if err == nil && !dryRun {
+		err = s.updatePanels(entity, panels)
+	}
+	if err != nil {
+		for i := range migrations {
+			migrations[i].Error = err.Error()
+		}
+	}
+	return migrations
+}
+
+func (s *service) updatePanels(oldEntity *v1.Dashboard, panels map[string]json.RawMessage) error {
+	entity := *oldEntity
+	entity.Spec.Panels = panels
+	entity.Metadata.Update(oldEntity.Metadata)
+	if err := s.dashboardDAO.Update(&entity); err != nil {
+		logrus.WithError(err).Errorf("unable to update the dashboard %s/%s with its panels migrated", entity.Metadata.Project, entity.Metadata.Name)
+		return shared.InternalError
+	}
+	logrus.Infof("panels of the dashboard %s/%s migrated", entity.Metadata.Project, entity.Metadata.Name)
+	return nil
+}
+
+// emptyIfNil avoids returning null instead of an empty list when no plugin is loaded.
+func emptyIfNil(plugins []*v1.SchemaPlugin) []*v1.SchemaPlugin {
+	if plugins == nil {
+		return []*v1.SchemaPlugin{}
+	}
+	return plugins
+}
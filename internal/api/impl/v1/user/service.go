@@ -41,7 +41,7 @@ func NewService(dao user.DAO) user.Service {
 	}
 }
 
-func (s *service) Create(entity api.Entity) (interface{}, error) {
+func (s *service) Create(entity api.Entity, _ shared.Parameters) (interface{}, error) {
 	if userObject, ok := entity.(*v1.User); ok {
 		return s.create(userObject)
 	}
@@ -14,10 +14,13 @@
 package dashboard
 
 import (
+	"context"
+
 	"github.com/perses/common/etcd"
 	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
 	"github.com/perses/perses/internal/api/shared"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	dashboardv1 "github.com/perses/perses/pkg/model/api/v1/dashboard"
 )
 
 type Query struct {
@@ -45,4 +48,12 @@ type DAO interface {
 type Service interface {
 	shared.ToolboxService
 	GetValidator() schemas.Validator
+	// ResolveDatasources returns the datasource used by every query of the dashboard.
+	ResolveDatasources(parameters shared.Parameters) ([]dashboardv1.QueryDatasource, error)
+	// EvaluateVariables computes the list of values of every variable of the dashboard by querying its datasource.
+	EvaluateVariables(ctx context.Context, parameters shared.Parameters, request dashboardv1.VariableEvaluationRequest) ([]dashboardv1.VariableEvaluationResult, error)
+	// EvaluateAnnotations computes the events of every annotation of the dashboard, querying its datasource when needed.
+	EvaluateAnnotations(ctx context.Context, parameters shared.Parameters, request dashboardv1.AnnotationEvaluationRequest) ([]dashboardv1.AnnotationEvaluationResult, error)
+	// Expand returns the dashboard where every repeated panel and every repeated row is replaced by one copy per value selected for its variable.
+	Expand(parameters shared.Parameters, request dashboardv1.ExpansionRequest) (*v1.Dashboard, error)
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/perses/common/etcd"
 	"github.com/perses/perses/internal/api/shared"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	datasourcev1 "github.com/perses/perses/pkg/model/api/v1/datasource"
 )
 
 type Query struct {
@@ -43,4 +44,8 @@ type DAO interface {
 
 type Service interface {
 	shared.ToolboxService
+	// GetDefault returns the datasource of the given kind flagged as the default one in the project.
+	// When the project doesn't have one, the default global datasource is returned.
+	// It returns a shared.NotFoundError when there is none.
+	GetDefault(project string, kind datasourcev1.Kind) (interface{}, error)
 }
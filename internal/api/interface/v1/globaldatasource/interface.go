@@ -17,6 +17,7 @@ import (
 	"github.com/perses/common/etcd"
 	"github.com/perses/perses/internal/api/shared"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
 )
 
 type Query struct {
@@ -40,4 +41,7 @@ type DAO interface {
 
 type Service interface {
 	shared.ToolboxService
+	// GetDefault returns the global datasource of the given kind flagged as the default one.
+	// It returns a shared.NotFoundError when there is none.
+	GetDefault(kind datasource.Kind) (*v1.GlobalDatasource, error)
 }
@@ -0,0 +1,45 @@
+// Copyright 2021 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package globalpanel
+
+import (
+	"github.com/perses/common/etcd"
+	"github.com/perses/perses/internal/api/shared"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+type Query struct {
+	etcd.Query
+	// NamePrefix is a prefix of the GlobalPanel.metadata.name that is used to filter the list of the GlobalPanel.
+	// NamePrefix can be empty in case you want to return the full list of GlobalPanel available.
+	NamePrefix string `query:"name"`
+}
+
+func (q *Query) Build() (string, error) {
+	return v1.GenerateGlobalPanelID(q.NamePrefix), nil
+}
+
+type DAO interface {
+	Create(entity *v1.GlobalPanel) error
+	Update(entity *v1.GlobalPanel) error
+	Delete(name string) error
+	Get(name string) (*v1.GlobalPanel, error)
+	List(q etcd.Query) ([]*v1.GlobalPanel, error)
+}
+
+type Service interface {
+	shared.ToolboxService
+	// Usages returns the dashboards, of every project, that are using the global panel in their layouts.
+	Usages(parameters shared.Parameters) ([]*v1.PanelUsage, error)
+}
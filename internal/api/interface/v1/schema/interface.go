@@ -0,0 +1,28 @@
+// Copyright 2021 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package schema
+
+import (
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+type Service interface {
+	ListPanels() []*v1.SchemaPlugin
+	ListQueries() []*v1.SchemaPlugin
+	GetStatus() *v1.SchemaStatus
+	// ListEvents returns the latest results of the loading of the plugins, the most recent first.
+	ListEvents() []v1.SchemaReloadEvent
+	// MigratePanels upgrades the panels of every dashboard stored to the version of the schema of their kind.
+	// When dryRun is true, the panels to upgrade are only reported and the dashboards are left untouched.
+	MigratePanels(dryRun bool) (*v1.PanelMigrationReport, error)
+}
@@ -19,14 +19,18 @@ import (
 	datasourceImpl "github.com/perses/perses/internal/api/impl/v1/datasource"
 	folderImpl "github.com/perses/perses/internal/api/impl/v1/folder"
 	globalDatasourceImpl "github.com/perses/perses/internal/api/impl/v1/globaldatasource"
+	globalPanelImpl "github.com/perses/perses/internal/api/impl/v1/globalpanel"
 	healthImpl "github.com/perses/perses/internal/api/impl/v1/health"
+	panelImpl "github.com/perses/perses/internal/api/impl/v1/panel"
 	projectImpl "github.com/perses/perses/internal/api/impl/v1/project"
 	userImpl "github.com/perses/perses/internal/api/impl/v1/user"
 	"github.com/perses/perses/internal/api/interface/v1/dashboard"
 	"github.com/perses/perses/internal/api/interface/v1/datasource"
 	"github.com/perses/perses/internal/api/interface/v1/folder"
 	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
+	"github.com/perses/perses/internal/api/interface/v1/globalpanel"
 	"github.com/perses/perses/internal/api/interface/v1/health"
+	"github.com/perses/perses/internal/api/interface/v1/panel"
 	"github.com/perses/perses/internal/api/interface/v1/project"
 	"github.com/perses/perses/internal/api/interface/v1/user"
 	"github.com/perses/perses/internal/api/shared/database"
@@ -37,7 +41,9 @@ type PersistenceManager interface {
 	GetDatasource() datasource.DAO
 	GetFolder() folder.DAO
 	GetGlobalDatasource() globaldatasource.DAO
+	GetGlobalPanel() globalpanel.DAO
 	GetHealth() health.DAO
+	GetPanel() panel.DAO
 	GetPersesDAO() database.DAO
 	GetProject() project.DAO
 	GetUser() user.DAO
@@ -49,7 +55,9 @@ type persistence struct {
 	datasource       datasource.DAO
 	folder           folder.DAO
 	globalDatasource globaldatasource.DAO
+	globalPanel      globalpanel.DAO
 	health           health.DAO
+	panel            panel.DAO
 	perses           database.DAO
 	project          project.DAO
 	user             user.DAO
@@ -64,7 +72,9 @@ func NewPersistenceManager(conf config.Database) (PersistenceManager, error) {
 	datasourceDAO := datasourceImpl.NewDAO(persesDAO)
 	folderDAO := folderImpl.NewDAO(persesDAO)
 	globalDatatasourceDAO := globalDatasourceImpl.NewDAO(persesDAO)
+	globalPanelDAO := globalPanelImpl.NewDAO(persesDAO)
 	healthDAO := healthImpl.NewDAO(persesDAO)
+	panelDAO := panelImpl.NewDAO(persesDAO)
 	projectDAO := projectImpl.NewDAO(persesDAO)
 	userDAO := userImpl.NewDAO(persesDAO)
 	return &persistence{
@@ -72,7 +82,9 @@ func NewPersistenceManager(conf config.Database) (PersistenceManager, error) {
 		datasource:       datasourceDAO,
 		folder:           folderDAO,
 		globalDatasource: globalDatatasourceDAO,
+		globalPanel:      globalPanelDAO,
 		health:           healthDAO,
+		panel:            panelDAO,
 		perses:           persesDAO,
 		project:          projectDAO,
 		user:             userDAO,
@@ -95,10 +107,18 @@ func (p *persistence) GetGlobalDatasource() globaldatasource.DAO {
 	return p.globalDatasource
 }
 
+func (p *persistence) GetGlobalPanel() globalpanel.DAO {
+	return p.globalPanel
+}
+
 func (p *persistence) GetHealth() health.DAO {
 	return p.health
 }
 
+func (p *persistence) GetPanel() panel.DAO {
+	return p.panel
+}
+
 func (p *persistence) GetPersesDAO() database.DAO {
 	return p.perses
 }
@@ -16,20 +16,29 @@
 package dependency
 
 import (
+	"sync"
+
 	"github.com/perses/perses/internal/api/config"
 	dashboardImpl "github.com/perses/perses/internal/api/impl/v1/dashboard"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
 	datasourceImpl "github.com/perses/perses/internal/api/impl/v1/datasource"
 	folderImpl "github.com/perses/perses/internal/api/impl/v1/folder"
 	globalDatasourceImpl "github.com/perses/perses/internal/api/impl/v1/globaldatasource"
+	globalPanelImpl "github.com/perses/perses/internal/api/impl/v1/globalpanel"
 	healthImpl "github.com/perses/perses/internal/api/impl/v1/health"
+	panelImpl "github.com/perses/perses/internal/api/impl/v1/panel"
 	projectImpl "github.com/perses/perses/internal/api/impl/v1/project"
+	schemaImpl "github.com/perses/perses/internal/api/impl/v1/schema"
 	userImpl "github.com/perses/perses/internal/api/impl/v1/user"
 	"github.com/perses/perses/internal/api/interface/v1/dashboard"
 	"github.com/perses/perses/internal/api/interface/v1/datasource"
 	"github.com/perses/perses/internal/api/interface/v1/folder"
 	"github.com/perses/perses/internal/api/interface/v1/globaldatasource"
+	"github.com/perses/perses/internal/api/interface/v1/globalpanel"
 	"github.com/perses/perses/internal/api/interface/v1/health"
+	"github.com/perses/perses/internal/api/interface/v1/panel"
 	"github.com/perses/perses/internal/api/interface/v1/project"
+	"github.com/perses/perses/internal/api/interface/v1/schema"
 	"github.com/perses/perses/internal/api/interface/v1/user"
 )
 
@@ -38,8 +47,11 @@ type ServiceManager interface {
 	GetDatasource() datasource.Service
 	GetFolder() folder.Service
 	GetGlobalDatasource() globaldatasource.Service
+	GetGlobalPanel() globalpanel.Service
 	GetHealth() health.Service
+	GetPanel() panel.Service
 	GetProject() project.Service
+	GetSchema() schema.Service
 	GetUser() user.Service
 }
 
@@ -49,26 +61,39 @@ type service struct {
 	datasource       datasource.Service
 	folder           folder.Service
 	globalDatasource globaldatasource.Service
+	globalPanel      globalpanel.Service
 	health           health.Service
+	panel            panel.Service
 	project          project.Service
+	schema           schema.Service
 	user             user.Service
 }
 
 func NewServiceManager(dao PersistenceManager, conf config.Config) ServiceManager {
-	dashboardService := dashboardImpl.NewService(dao.GetDashboard(), conf)
-	datasourceService := datasourceImpl.NewService(dao.GetDatasource())
+	// the validator is shared by the services, so the schemas are loaded only once
+	validator := schemas.NewValidator(conf.Schemas)
+	globalDatasourceService := globalDatasourceImpl.NewService(dao.GetGlobalDatasource(), validator)
+	datasourceService := datasourceImpl.NewService(dao.GetDatasource(), globalDatasourceService, validator)
+	// the deletions of the library panels are serialised with the saves of the dashboards that could be using them
+	libraryPanelMutex := &sync.RWMutex{}
+	panelService := panelImpl.NewService(dao.GetPanel(), dao.GetDashboard(), validator, libraryPanelMutex)
+	globalPanelService := globalPanelImpl.NewService(dao.GetGlobalPanel(), dao.GetProject(), dao.GetDashboard(), validator, libraryPanelMutex)
+	dashboardService := dashboardImpl.NewService(dao.GetDashboard(), datasourceService, globalDatasourceService, panelService, globalPanelService, validator, libraryPanelMutex)
 	folderService := folderImpl.NewService(dao.GetFolder())
-	globalDatasourceService := globalDatasourceImpl.NewService(dao.GetGlobalDatasource())
 	healthService := healthImpl.NewService(dao.GetHealth())
 	projectService := projectImpl.NewService(dao.GetProject())
+	schemaService := schemaImpl.NewService(validator, dao.GetProject(), dao.GetDashboard())
 	userService := userImpl.NewService(dao.GetUser())
 	return &service{
 		dashboard:        dashboardService,
 		datasource:       datasourceService,
 		folder:           folderService,
 		globalDatasource: globalDatasourceService,
+		globalPanel:      globalPanelService,
 		health:           healthService,
+		panel:            panelService,
 		project:          projectService,
+		schema:           schemaService,
 		user:             userService,
 	}
 }
@@ -89,14 +114,26 @@ func (s *service) GetGlobalDatasource() globaldatasource.Service {
 	return s.globalDatasource
 }
 
+func (s *service) GetGlobalPanel() globalpanel.Service {
+	return s.globalPanel
+}
+
 func (s *service) GetHealth() health.Service {
 	return s.health
 }
 
+func (s *service) GetPanel() panel.Service {
+	return s.panel
+}
+
 func (s *service) GetProject() project.Service {
 	return s.project
 }
 
+func (s *service) GetSchema() schema.Service {
+	return s.schema
+}
+
 func (s *service) GetUser() user.Service {
 	return s.user
 }
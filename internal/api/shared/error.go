@@ -15,9 +15,11 @@ package shared
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/sirupsen/logrus"
 )
 
@@ -36,12 +38,25 @@ var (
 	BadRequestError = &PersesError{message: "bad request"}
 )
 
+type validationReportResponse struct {
+	Message string                  `json:"message"`
+	Errors  common.ValidationReport `json:"errors"`
+}
+
 // HandleError is translating the given error to the echoHTTPError
 func HandleError(err error) error {
 	if err == nil {
 		return nil
 	}
 
+	var report common.ValidationReport
+	if errors.As(err, &report) {
+		return echo.NewHTTPError(http.StatusBadRequest, validationReportResponse{
+			Message: fmt.Sprintf("%s: %s", BadRequestError.message, report),
+			Errors:  report,
+		})
+	}
+
 	if errors.Is(err, InternalError) {
 		return echo.NewHTTPError(http.StatusInternalServerError, InternalError.message)
 	}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interpolation
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// IntervalVariable is the duration between two points, computed from the time range and the number of points.
+	IntervalVariable = "__interval"
+	// RangeVariable is the duration of the time range.
+	RangeVariable = "__range"
+	// RateIntervalVariable is the range to use in a function like rate(), so it always covers at least four scrapes.
+	RateIntervalVariable = "__rate_interval"
+	// DashboardVariable is the name of the dashboard.
+	DashboardVariable = "__dashboard"
+
+	// DefaultMaxDataPoints is the number of points used to compute the interval when it is not provided.
+	DefaultMaxDataPoints = 100
+	// defaultScrapeInterval is the usual scrape interval of Prometheus. It is used to compute the rate interval.
+	defaultScrapeInterval = 15 * time.Second
+	minInterval           = time.Second
+)
+
+var builtinMap = map[string]bool{
+	IntervalVariable:     true,
+	RangeVariable:        true,
+	RateIntervalVariable: true,
+	DashboardVariable:    true,
+}
+
+// IsBuiltin returns true when the name is the one of a built-in variable. A built-in variable doesn't need to be defined in the dashboard.
+func IsBuiltin(name string) bool {
+	return builtinMap[name]
+}
+
+// Interval returns the duration between two points when the time range is split in maxDataPoints points.
+// It is rounded to the second and cannot be less than a second.
+func Interval(start time.Time, end time.Time, maxDataPoints int) time.Duration {
+	if maxDataPoints <= 0 {
+		maxDataPoints = DefaultMaxDataPoints
+	}
+	interval := (end.Sub(start) / time.Duration(maxDataPoints)).Round(time.Second)
+	if interval < minInterval {
+		return minInterval
+	}
+	return interval
+}
+
+// BuiltinVariables returns the value of the built-in variables for the given dashboard and time range.
+func BuiltinVariables(dashboardName string, start time.Time, end time.Time, maxDataPoints int) map[string]Value {
+	interval := Interval(start, end, maxDataPoints)
+	// like Grafana is doing, the rate interval is covering at least 4 scrapes and is always bigger than the interval.
+	rateInterval := interval + defaultScrapeInterval
+	if rateInterval < 4*defaultScrapeInterval {
+		rateInterval = 4 * defaultScrapeInterval
+	}
+	return map[string]Value{
+		IntervalVariable:     SingleValue(model.Duration(interval).String()),
+		RangeVariable:        SingleValue(model.Duration(end.Sub(start).Round(time.Second)).String()),
+		RateIntervalVariable: SingleValue(model.Duration(rateInterval).String()),
+		DashboardVariable:    SingleValue(dashboardName),
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interpolation is providing the way to find and to replace the variables used in a string (like a query).
+// A variable can be used with the following syntaxes:
+//   - $var
+//   - ${var}
+//   - ${var:format}, where format is one of: csv, regex, pipe, json, lucene, glob.
+//
+// The same parser is used to compute the dependencies between the variables and to replace them with their values.
+package interpolation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Format string
+
+const (
+	// NoFormat means the variable is used without format.
+	// A single value is used as is, while multiple values are formatted like with RegexFormat.
+	// The values of a variable accepting several values are always formatted like with RegexFormat.
+	NoFormat     Format = ""
+	CSVFormat    Format = "csv"
+	RegexFormat  Format = "regex"
+	PipeFormat   Format = "pipe"
+	JSONFormat   Format = "json"
+	LuceneFormat Format = "lucene"
+	GlobFormat   Format = "glob"
+)
+
+var formatMap = map[Format]bool{
+	CSVFormat:    true,
+	RegexFormat:  true,
+	PipeFormat:   true,
+	JSONFormat:   true,
+	LuceneFormat: true,
+	GlobFormat:   true,
+}
+
+// variableRegexp is matching $var, ${var} and ${var:format}.
+var variableRegexp = regexp.MustCompile(`\$(?:([a-zA-Z0-9_-]+)|\{([a-zA-Z0-9_-]+)(?::([^}]*))?})`)
+
+// Reference is a variable used in a string.
+type Reference struct {
+	// Raw is the text used to reference the variable, like ${var:csv}.
+	Raw    string
+	Name   string
+	Format Format
+}
+
+// Parse returns every variable used in the string, in the order they appear.
+// An error is returned when a format is unknown.
+func Parse(str string) ([]Reference, error) {
+	var result []Reference
+	for _, match := range variableRegexp.FindAllStringSubmatch(str, -1) {
+		ref, err := newReference(match)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ref)
+	}
+	return result, nil
+}
+
+// VariableNames returns the name of the variables used in the string, sorted and without any duplicate.
+// The built-in variables are not part of the result.
+func VariableNames(str string) ([]string, error) {
+	refs, err := Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	for _, ref := range refs {
+		if !IsBuiltin(ref.Name) {
+			set[ref.Name] = true
+		}
+	}
+	result := make([]string, 0, len(set))
+	for name := range set {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func newReference(match []string) (Reference, error) {
+	// match[1] is the name when the syntax $var is used, match[2] and match[3] are the name and the format of ${var:format}.
+	ref := Reference{Raw: match[0], Name: match[1]}
+	if len(ref.Name) == 0 {
+		ref.Name = match[2]
+		ref.Format = Format(match[3])
+	}
+	if strings.HasSuffix(ref.Raw, ":}") {
+		return ref, fmt.Errorf("the format cannot be empty in %q", ref.Raw)
+	}
+	if ref.Format != NoFormat && !formatMap[ref.Format] {
+		return ref, fmt.Errorf("unknown format %q used for the variable %q", ref.Format, ref.Name)
+	}
+	return ref, nil
+}
+
+// Value is the value selected for a variable.
+type Value struct {
+	// Values is the list of the values selected. A single value variable has only one element.
+	// When All is true, it is the list of every value available.
+	Values []string
+	// All is true when the user selected every value.
+	All bool
+	// AllValue, when set and when All is true, is used as is instead of the list of values.
+	// For example, it can be `.*` to avoid sending a huge regexp to the datasource.
+	AllValue string
+	// Multi is true when the variable accepts several values, or every value at once.
+	// Such a variable is used with an operator like =~, so even a single value is escaped when there is no format.
+	Multi bool
+}
+
+// SingleValue returns the value of a variable having only one value.
+func SingleValue(value string) Value {
+	return Value{Values: []string{value}}
+}
+
+// ReplaceFunc replaces every variable used in the string by the result of replace,
+// which receives the reference to the variable and the offset of the reference in the string.
+// An error is returned when a format is unknown.
+func ReplaceFunc(str string, replace func(ref Reference, offset int) string) (string, error) {
+	var builder strings.Builder
+	last := 0
+	for _, indexes := range variableRegexp.FindAllStringSubmatchIndex(str, -1) {
+		match := make([]string, len(indexes)/2)
+		for i := range match {
+			if indexes[2*i] >= 0 {
+				match[i] = str[indexes[2*i]:indexes[2*i+1]]
+			}
+		}
+		ref, err := newReference(match)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(str[last:indexes[0]])
+		builder.WriteString(replace(ref, indexes[0]))
+		last = indexes[1]
+	}
+	builder.WriteString(str[last:])
+	return builder.String(), nil
+}
+
+// Interpolate replaces the variables used in the string by their value, formatted according to the format used.
+// The variables that are not known are kept as they are.
+func Interpolate(str string, variables map[string]Value) (string, error) {
+	var err error
+	result := variableRegexp.ReplaceAllStringFunc(str, func(raw string) string {
+		ref, refErr := newReference(variableRegexp.FindStringSubmatch(raw))
+		if refErr != nil {
+			err = refErr
+			return raw
+		}
+		value, ok := variables[ref.Name]
+		if !ok {
+			return raw
+		}
+		return format(value, ref.Format)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+func format(value Value, f Format) string {
+	if value.All && len(value.AllValue) > 0 {
+		return value.AllValue
+	}
+	values := value.Values
+	switch f {
+	case CSVFormat:
+		return strings.Join(values, ",")
+	case PipeFormat:
+		return strings.Join(values, "|")
+	case JSONFormat:
+		var data []byte
+		if len(values) == 1 {
+			data, _ = json.Marshal(values[0])
+		} else {
+			data, _ = json.Marshal(values)
+		}
+		return string(data)
+	case LuceneFormat:
+		if len(values) == 1 {
+			return escapeLucene(values[0])
+		}
+		quoted := make([]string, 0, len(values))
+		for _, v := range values {
+			quoted = append(quoted, fmt.Sprintf(`"%s"`, escapeLucene(v)))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(quoted, " OR "))
+	case GlobFormat:
+		if len(values) == 1 {
+			return values[0]
+		}
+		return fmt.Sprintf("{%s}", strings.Join(values, ","))
+	case RegexFormat:
+		return formatRegex(values)
+	default:
+		if len(values) == 1 && !value.Multi {
+			return values[0]
+		}
+		return formatRegex(values)
+	}
+}
+
+func formatRegex(values []string) string {
+	escaped := make([]string, 0, len(values))
+	for _, v := range values {
+		escaped = append(escaped, regexp.QuoteMeta(v))
+	}
+	switch len(escaped) {
+	case 0:
+		return ""
+	case 1:
+		return escaped[0]
+	}
+	return fmt.Sprintf("(%s)", strings.Join(escaped, "|"))
+}
+
+// luceneSpecialCharacters is the list of the characters that must be escaped in a Lucene query.
+// The characters && and || are covered by & and |.
+const luceneSpecialCharacters = `+-&|!(){}[]^"~*?:\/`
+
+func escapeLucene(value string) string {
+	var builder strings.Builder
+	for _, c := range value {
+		if strings.ContainsRune(luceneSpecialCharacters, c) {
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interpolation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		str    string
+		result []Reference
+	}{
+		{
+			title:  "no variable",
+			str:    "up{job='prometheus'}",
+			result: nil,
+		},
+		{
+			title: "every syntax",
+			str:   "sum by($label) (rate(up{job='${job}',instance=~'${instance:regex}'}[$__rate_interval]))",
+			result: []Reference{
+				{Raw: "$label", Name: "label"},
+				{Raw: "${job}", Name: "job"},
+				{Raw: "${instance:regex}", Name: "instance", Format: RegexFormat},
+				{Raw: "$__rate_interval", Name: "__rate_interval"},
+			},
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Parse(test.str)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testSuite := []struct {
+		title string
+		str   string
+		err   error
+	}{
+		{
+			title: "unknown format",
+			str:   "${job:yaml}",
+			err:   fmt.Errorf("unknown format %q used for the variable %q", "yaml", "job"),
+		},
+		{
+			title: "empty format",
+			str:   "${job:}",
+			err:   fmt.Errorf("the format cannot be empty in %q", "${job:}"),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := Parse(test.str)
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
+
+func TestVariableNames(t *testing.T) {
+	result, err := VariableNames("$job ${instance:csv} ${job} $__interval $__dashboard")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"instance", "job"}, result)
+}
+
+func TestInterpolate(t *testing.T) {
+	variables := map[string]Value{
+		"single": SingleValue("api.example"),
+		"multi":  {Values: []string{"api", "node:9100"}},
+		"all":    {Values: []string{"api", "node"}, All: true, AllValue: ".*"},
+		"allExp": {Values: []string{"api", "node"}, All: true},
+		"oneOf":  {Values: []string{"api.example"}, Multi: true},
+		"none":   {Multi: true},
+	}
+	testSuite := []struct {
+		title  string
+		str    string
+		result string
+	}{
+		{
+			title:  "single value without format",
+			str:    "up{job='$single'}",
+			result: "up{job='api.example'}",
+		},
+		{
+			title:  "single value with braces",
+			str:    "up{job='${single}'}",
+			result: "up{job='api.example'}",
+		},
+		{
+			title:  "multiple values without format",
+			str:    "up{job=~'$multi'}",
+			result: "up{job=~'(api|node:9100)'}",
+		},
+		{
+			title:  "csv",
+			str:    "${multi:csv}",
+			result: "api,node:9100",
+		},
+		{
+			title:  "regex with a single value",
+			str:    "${single:regex}",
+			result: `api\.example`,
+		},
+		{
+			title:  "pipe",
+			str:    "${multi:pipe}",
+			result: "api|node:9100",
+		},
+		{
+			title:  "json",
+			str:    "${multi:json} ${single:json}",
+			result: `["api","node:9100"] "api.example"`,
+		},
+		{
+			title:  "lucene",
+			str:    "job:${multi:lucene}",
+			result: `job:("api" OR "node\:9100")`,
+		},
+		{
+			title:  "glob",
+			str:    "${multi:glob}",
+			result: "{api,node:9100}",
+		},
+		{
+			title:  "all with a custom value",
+			str:    "up{job=~'$all'}",
+			result: "up{job=~'.*'}",
+		},
+		{
+			title:  "all expanded",
+			str:    "up{job=~'${allExp:regex}'}",
+			result: "up{job=~'(api|node)'}",
+		},
+		{
+			title:  "single value of a multi-value variable",
+			str:    "up{job=~'$oneOf'}",
+			result: `up{job=~'api\.example'}`,
+		},
+		{
+			title:  "single value of a multi-value variable with csv",
+			str:    "${oneOf:csv}",
+			result: "api.example",
+		},
+		{
+			title:  "multi-value variable without any value",
+			str:    "up{job=~'$none'}",
+			result: "up{job=~''}",
+		},
+		{
+			title:  "unknown variable kept",
+			str:    "up{job='$unknown'}",
+			result: "up{job='$unknown'}",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := Interpolate(test.str, variables)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestBuiltinVariables(t *testing.T) {
+	end := time.Date(2022, 4, 15, 6, 0, 0, 0, time.UTC)
+	variables := BuiltinVariables("Demo", end.Add(-6*time.Hour), end, DefaultMaxDataPoints)
+	result, err := Interpolate("rate(up[$__rate_interval]) $__interval $__range $__dashboard", variables)
+	assert.NoError(t, err)
+	assert.Equal(t, "rate(up[3m51s]) 3m36s 6h Demo", result)
+}
+
+func TestReplaceFunc(t *testing.T) {
+	var offsets []int
+	result, err := ReplaceFunc(`up{job="$job",instance=~"${instance:regex}"}`, func(ref Reference, offset int) string {
+		offsets = append(offsets, offset)
+		return strings.ToUpper(ref.Name)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `up{job="JOB",instance=~"INSTANCE"}`, result)
+	assert.Equal(t, []int{8, 25}, offsets)
+
+	_, err = ReplaceFunc("${job:unknown}", func(ref Reference, _ int) string { return ref.Raw })
+	assert.Error(t, err)
+}
@@ -14,17 +14,25 @@
 package shared
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/perses/common/etcd"
 	"github.com/perses/perses/pkg/model/api"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 )
 
 type Parameters struct {
 	Project string
 	Name    string
+	// Normalize is true when the entity created or updated can be completed by the API before being stored,
+	// e.g. with the defaults declared in the schemas. The client opts in with the query parameter normalize=true.
+	Normalize bool
+	// Inline is true when the entity read must contain the resources it is referencing,
+	// e.g. the library panels used by a dashboard. The client opts in with the query parameter inline=true.
+	Inline bool
 }
 
 func extractParameters(ctx echo.Context) Parameters {
@@ -34,8 +42,30 @@ func extractParameters(ctx echo.Context) Parameters {
 	}
 }
 
+// extractWriteParameters returns the parameters of a request creating or updating an entity.
+func extractWriteParameters(ctx echo.Context) (Parameters, error) {
+	parameters := extractParameters(ctx)
+	normalize, err := getNormalizeParameter(ctx)
+	if err != nil {
+		return parameters, err
+	}
+	parameters.Normalize = normalize
+	return parameters, nil
+}
+
+// extractReadParameters returns the parameters of a request reading an entity.
+func extractReadParameters(ctx echo.Context) (Parameters, error) {
+	parameters := extractParameters(ctx)
+	inline, err := getInlineParameter(ctx)
+	if err != nil {
+		return parameters, err
+	}
+	parameters.Inline = inline
+	return parameters, nil
+}
+
 type ToolboxService interface {
-	Create(entity api.Entity) (interface{}, error)
+	Create(entity api.Entity, parameters Parameters) (interface{}, error)
 	Update(entity api.Entity, parameters Parameters) (interface{}, error)
 	Delete(parameters Parameters) error
 	Get(parameters Parameters) (interface{}, error)
@@ -67,7 +97,11 @@ func (t *toolbox) Create(ctx echo.Context, entity api.Entity) error {
 	if err := t.bind(ctx, entity); err != nil {
 		return err
 	}
-	newEntity, err := t.service.Create(entity)
+	parameters, err := extractWriteParameters(ctx)
+	if err != nil {
+		return HandleError(err)
+	}
+	newEntity, err := t.service.Create(entity, parameters)
 	if err != nil {
 		return HandleError(err)
 	}
@@ -78,7 +112,10 @@ func (t *toolbox) Update(ctx echo.Context, entity api.Entity) error {
 	if err := t.bind(ctx, entity); err != nil {
 		return err
 	}
-	parameters := extractParameters(ctx)
+	parameters, err := extractWriteParameters(ctx)
+	if err != nil {
+		return HandleError(err)
+	}
 	newEntity, err := t.service.Update(entity, parameters)
 	if err != nil {
 		return HandleError(err)
@@ -95,7 +132,10 @@ func (t *toolbox) Delete(ctx echo.Context) error {
 }
 
 func (t *toolbox) Get(ctx echo.Context) error {
-	parameters := extractParameters(ctx)
+	parameters, err := extractReadParameters(ctx)
+	if err != nil {
+		return HandleError(err)
+	}
 	entity, err := t.service.Get(parameters)
 	if err != nil {
 		return HandleError(err)
@@ -117,6 +157,11 @@ func (t *toolbox) List(ctx echo.Context, q etcd.Query) error {
 
 func (t *toolbox) bind(ctx echo.Context, entity api.Entity) error {
 	if err := ctx.Bind(entity); err != nil {
+		// the echo error is wrapping the error returned when unmarshalling the entity.
+		var report common.ValidationReport
+		if errors.As(err, &report) {
+			return HandleError(report)
+		}
 		return HandleError(fmt.Errorf("%w: %s", BadRequestError, err))
 	}
 	if err := validateMetadata(entity.GetMetadata()); err != nil {
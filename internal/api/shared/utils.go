@@ -15,6 +15,7 @@ package shared
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
@@ -23,11 +24,16 @@ import (
 const (
 	ParamName            = "name"
 	ParamProject         = "project"
+	ParamNormalize       = "normalize"
+	ParamInline          = "inline"
+	ParamDryRun          = "dry_run"
 	APIV1Prefix          = "/api/v1"
 	PathDashboard        = "dashboards"
 	PathDatasource       = "datasources"
 	PathFolder           = "folders"
 	PathGlobalDatasource = "globaldatasources"
+	PathGlobalPanel      = "globalpanels"
+	PathPanel            = "panels"
 	PathProject          = "projects"
 	PathUser             = "users"
 )
@@ -40,6 +46,29 @@ func getProjectParameter(ctx echo.Context) string {
 	return ctx.Param(ParamProject)
 }
 
+// getNormalizeParameter returns the value of the query parameter normalize, false when it is not set.
+func getNormalizeParameter(ctx echo.Context) (bool, error) {
+	return GetBoolQueryParameter(ctx, ParamNormalize, false)
+}
+
+// getInlineParameter returns the value of the query parameter inline, false when it is not set.
+func getInlineParameter(ctx echo.Context) (bool, error) {
+	return GetBoolQueryParameter(ctx, ParamInline, false)
+}
+
+// GetBoolQueryParameter returns the value of the boolean query parameter, or defaultValue when it is not set.
+func GetBoolQueryParameter(ctx echo.Context, name string, defaultValue bool) (bool, error) {
+	value := ctx.QueryParam(name)
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: the query parameter %s must be a boolean, got %q", BadRequestError, name, value)
+	}
+	return result, nil
+}
+
 func validateMetadata(metadata interface{}) error {
 	switch met := metadata.(type) {
 	case *v1.ProjectMetadata:
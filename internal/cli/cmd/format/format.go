@@ -0,0 +1,141 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/perses/perses/internal/api/config"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/cli/cmd"
+	"github.com/perses/perses/internal/cli/file"
+	"github.com/perses/perses/internal/cli/opt"
+	"github.com/perses/perses/internal/cli/output"
+	modelAPI "github.com/perses/perses/pkg/model/api"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type option struct {
+	persesCMD.Option
+	opt.FileOption
+	opt.OutputOption
+	writer         io.Writer
+	chartsSchemas  string
+	queriesSchemas string
+	validator      schemas.Validator
+}
+
+func (o *option) Complete(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("no args are supported by the command 'fmt'")
+	}
+	if outputErr := o.OutputOption.Complete(); outputErr != nil {
+		return outputErr
+	}
+	o.validator = schemas.NewValidator(config.Schemas{
+		PanelsPath:  o.chartsSchemas,
+		QueriesPath: o.queriesSchemas,
+	})
+	o.validator.LoadPanels()
+	o.validator.LoadQueries()
+	return nil
+}
+
+func (o *option) Validate() error {
+	return nil
+}
+
+func (o *option) Execute() error {
+	objects, err := file.UnmarshalEntity(o.File)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		entity, ok := object.(*modelV1.Dashboard)
+		if !ok {
+			continue
+		}
+		panels, normalizeErr := o.validator.Normalize(entity.Spec.Panels)
+		if normalizeErr != nil {
+			return fmt.Errorf("unable to format the %s %q: %w", entity.Kind, entity.Metadata.Name, normalizeErr)
+		}
+		entity.Spec.Panels = panels
+	}
+	result, err := toGeneric(objects)
+	if err != nil {
+		return err
+	}
+	return output.Handle(o.writer, o.Output, result)
+}
+
+func (o *option) SetWriter(writer io.Writer) {
+	o.writer = writer
+}
+
+// toGeneric converts the resources to generic maps through JSON, so the panels (kept as raw JSON) can be printed as YAML too.
+// A single resource is returned as an object, like it is usually written in a file.
+func toGeneric(objects []modelAPI.Entity) (interface{}, error) {
+	var data []byte
+	var err error
+	if len(objects) == 1 {
+		data, err = json.Marshal(objects[0])
+	} else {
+		data, err = json.Marshal(objects)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if unmarshalErr := json.Unmarshal(data, &result); unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	return result, nil
+}
+
+func NewCMD() *cobra.Command {
+	o := &option{}
+	cmd := &cobra.Command{
+		Use:   "fmt -f [FILENAME]",
+		Short: "Normalize the resources",
+		Long: `
+The fmt command prints the resources the way the API would store them.
+The panels of the dashboards are validated and completed with the defaults declared in their CUE schemas.
+`,
+		Example: `
+# Normalize the dashboards of a JSON file.
+percli fmt -f ./dashboards.json --schemas.charts ./schemas/panels --schemas.queries ./schemas/queries
+
+# Normalize the dashboards of a file and print them as JSON.
+percli fmt -f ./dashboards.yaml -ojson --schemas.charts ./schemas/panels --schemas.queries ./schemas/queries
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return persesCMD.Run(o, cmd, args)
+		},
+	}
+	opt.AddFileFlags(cmd, &o.FileOption)
+	opt.MarkFileFlagAsMandatory(cmd)
+	opt.AddOutputFlags(cmd, &o.OutputOption)
+	cmd.Flags().StringVar(&o.chartsSchemas, "schemas.charts", "", "Path to the CUE schemas for charts.")
+	cmd.Flags().StringVar(&o.queriesSchemas, "schemas.queries", "", "Path to the CUE schemas for queries.")
+	for _, flag := range []string{"schemas.charts", "schemas.queries"} {
+		if err := cmd.MarkFlagRequired(flag); err != nil {
+			logrus.Panic(err)
+		}
+	}
+	return cmd
+}
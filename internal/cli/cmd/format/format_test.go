@@ -0,0 +1,51 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"testing"
+
+	cmdTest "github.com/perses/perses/internal/cli/test"
+)
+
+func TestFormatCMD(t *testing.T) {
+	testSuite := []cmdTest.Suite{
+		{
+			Title:           "empty args",
+			Args:            []string{},
+			IsErrorExpected: true,
+			ExpectedMessage: `required flag(s) "file", "schemas.charts", "schemas.queries" not set`,
+		},
+		{
+			Title:           "use args",
+			Args:            []string{"whatever", "-f", "file.json", "--schemas.charts", "../../../../schemas/panels", "--schemas.queries", "../../../../schemas/queries"},
+			IsErrorExpected: true,
+			ExpectedMessage: "no args are supported by the command 'fmt'",
+		},
+		{
+			Title:           "format a dashboard",
+			Args:            []string{"-f", "../../test/sample_resources/dashboard.json", "--schemas.charts", "../../../../schemas/panels", "--schemas.queries", "../../../../schemas/queries", "-ojson"},
+			IsErrorExpected: false,
+			ExpectedMessage: `{"kind":"Dashboard","metadata":{"created_at":"0001-01-01T00:00:00Z","name":"node","project":"perses","updated_at":"0001-01-01T00:00:00Z"},"spec":{"datasource":{"global":false,"kind":"Prometheus","name":"PrometheusDemo"},"duration":"6h","layouts":[{"kind":"Grid","spec":{"items":[{"content":{"$ref":"#/spec/panels/CPU"},"height":6,"width":12,"x":0,"y":0}]}}],"panels":{"CPU":{"datasource":{"kind":"PrometheusDatasource"},"display":{"name":"CPU"},"kind":"LineChart","options":{"queries":[{"kind":"PrometheusGraphQuery","options":{"query":"sum(rate(node_cpu_seconds_total{mode!='idle'}[5m]))"}}]},"schema_version":1}}}}
+`,
+		},
+		{
+			Title:           "format a dashboard without the schemas of its panels",
+			Args:            []string{"-f", "../../test/sample_resources/dashboard.json", "--schemas.charts", "../../test/sample_resources", "--schemas.queries", "../../../../schemas/queries"},
+			IsErrorExpected: true,
+			ExpectedMessage: `unable to format the Dashboard "node": /spec/panels/CPU/kind: Unknown kind LineChart`,
+		},
+	}
+	cmdTest.ExecuteSuiteTest(t, NewCMD, testSuite)
+}
@@ -14,38 +14,71 @@
 package lint
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/perses/perses/internal/api/config"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/analysis"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/promql"
 	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/variable"
 	"github.com/perses/perses/internal/cli/cmd"
 	"github.com/perses/perses/internal/cli/file"
 	"github.com/perses/perses/internal/cli/opt"
 	"github.com/perses/perses/internal/cli/output"
 	modelAPI "github.com/perses/perses/pkg/model/api"
 	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/perses/perses/pkg/model/api/v1/common"
 	"github.com/spf13/cobra"
 )
 
+// lintError is a problem found in one of the resources checked.
+type lintError struct {
+	Resource               string `json:"resource" yaml:"resource"`
+	common.ValidationError `yaml:",inline"`
+}
+
 type option struct {
 	persesCMD.Option
 	opt.FileOption
-	writer         io.Writer
-	chartsSchemas  string
-	queriesSchemas string
-	validator      schemas.Validator
+	opt.OutputOption
+	writer             io.Writer
+	chartsSchemas      string
+	queriesSchemas     string
+	variablesSchemas   string
+	annotationsSchemas string
+	layoutsSchemas     string
+	datasourcesSchemas string
+	validator          schemas.Validator
 }
 
 func (o *option) Complete(args []string) error {
 	if len(args) > 0 {
 		return fmt.Errorf("no args are supported by the command 'lint'")
 	}
-	if len(o.chartsSchemas) > 0 && len(o.queriesSchemas) > 0 {
+	// Complete the output only if it has been set by the user
+	if len(o.Output) > 0 {
+		if outputErr := o.OutputOption.Complete(); outputErr != nil {
+			return outputErr
+		}
+	}
+	if len(o.chartsSchemas) > 0 || len(o.variablesSchemas) > 0 || len(o.annotationsSchemas) > 0 || len(o.layoutsSchemas) > 0 || len(o.datasourcesSchemas) > 0 {
+		// a kind of schemas without path is not loaded, and the corresponding validation is skipped
 		o.validator = schemas.NewValidator(config.Schemas{
-			PanelsPath:  o.chartsSchemas,
-			QueriesPath: o.queriesSchemas,
+			PanelsPath:      o.chartsSchemas,
+			QueriesPath:     o.queriesSchemas,
+			VariablesPath:   o.variablesSchemas,
+			AnnotationsPath: o.annotationsSchemas,
+			LayoutsPath:     o.layoutsSchemas,
+			DatasourcesPath: o.datasourcesSchemas,
 		})
+		o.validator.LoadPanels()
+		o.validator.LoadQueries()
+		o.validator.LoadVariables()
+		o.validator.LoadAnnotations()
+		o.validator.LoadLayouts()
+		o.validator.LoadDatasources()
 	}
 	return nil
 }
@@ -55,35 +88,89 @@ func (o *option) Validate() error {
 }
 
 func (o *option) Execute() error {
+	var errs []lintError
 	objects, err := file.UnmarshalEntity(o.File)
 	if err != nil {
-		return err
+		// the resource that cannot be unmarshalled is not known, only its report is.
+		var report common.ValidationReport
+		if !errors.As(err, &report) {
+			return err
+		}
+		errs = appendReport(errs, "", report)
+	}
+	errs = append(errs, o.validate(objects)...)
+	if len(errs) == 0 {
+		return output.HandleString(o.writer, "your resources look good")
 	}
-	if validateErr := o.validate(objects); validateErr != nil {
-		return validateErr
+	if outputErr := o.print(errs); outputErr != nil {
+		return outputErr
 	}
-	return output.HandleString(o.writer, "your resources look good")
+	for _, e := range errs {
+		if e.Severity == common.SeverityError {
+			return fmt.Errorf("your resources are not valid")
+		}
+	}
+	return nil
 }
 
 func (o *option) SetWriter(writer io.Writer) {
 	o.writer = writer
 }
 
-func (o *option) validate(objects []modelAPI.Entity) error {
-	if o.validator == nil {
-		return nil
-	}
+func (o *option) validate(objects []modelAPI.Entity) []lintError {
+	var errs []lintError
 	for _, object := range objects {
-		entity, ok := object.(*modelV1.Dashboard)
-		if ok {
-			if err := o.validator.Validate(entity.Spec.Panels); err != nil {
-				return err
+		var report common.ValidationReport
+		switch entity := object.(type) {
+		case *modelV1.Dashboard:
+			_, err := variable.BuildOrder(entity.Spec.Variables, entity.Spec.Annotations, entity.Spec.Datasource.Name)
+			report.Merge("", err)
+			report.Merge("", promql.CheckDashboard(&entity.Spec))
+			report.Merge("", analysis.Analyze(&entity.Spec))
+			if o.validator != nil {
+				if len(o.chartsSchemas) > 0 {
+					report.Merge("", o.validator.Validate(entity.Spec.Panels))
+				}
+				report.Merge("", o.validator.ValidateVariables(entity.Spec.Variables))
+				report.Merge("", o.validator.ValidateAnnotations(entity.Spec.Annotations))
+				report.Merge("", o.validator.ValidateLayouts(entity.Spec.Layouts))
+			}
+		case *modelV1.Datasource:
+			if o.validator != nil {
+				report.Merge("", o.validator.ValidateDatasource(entity.Spec))
 			}
+		case *modelV1.GlobalDatasource:
+			if o.validator != nil {
+				report.Merge("", o.validator.ValidateDatasource(entity.Spec))
+			}
+		default:
+			continue
 		}
+		report.Sort()
+		errs = appendReport(errs, fmt.Sprintf("%s/%s", object.GetKind(), object.GetMetadata().GetName()), report)
+	}
+	return errs
+}
+
+func (o *option) print(errs []lintError) error {
+	if len(o.Output) > 0 {
+		return output.Handle(o.writer, o.Output, errs)
+	}
+	data := make([][]string, 0, len(errs))
+	for _, e := range errs {
+		data = append(data, []string{e.Resource, e.Path, string(e.Severity), e.Message})
 	}
+	output.HandlerTable(o.writer, []string{"RESOURCE", "PATH", "SEVERITY", "MESSAGE"}, data)
 	return nil
 }
 
+func appendReport(errs []lintError, resource string, report common.ValidationReport) []lintError {
+	for _, v := range report {
+		errs = append(errs, lintError{Resource: resource, ValidationError: v})
+	}
+	return errs
+}
+
 func NewCMD() *cobra.Command {
 	o := &option{}
 	cmd := &cobra.Command{
@@ -94,6 +181,7 @@ The lint command will check statically that your resources are valid.
 It doesn't necessary mean you won't face any issue when applying them.
 
 JSON and YAML formats are accepted.
+Every problem found is printed with the path of the field concerned, as a table or as JSON / YAML with the flag --output.
 `,
 		Example: `
 # Check resources from a JSON file
@@ -101,6 +189,12 @@ percli lint -f ./resources.json
 
 # Check resources from stdin.
 cat resources.json | percli lint -f -
+
+# Print the problems found as JSON.
+percli lint -f ./resources.json -ojson
+
+# Check the variables and the datasources against their CUE schemas.
+percli lint -f ./resources.json --schemas.variables ./schemas/variables --schemas.datasources ./schemas/datasources
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return persesCMD.Run(o, cmd, args)
@@ -108,8 +202,13 @@ cat resources.json | percli lint -f -
 	}
 	opt.AddFileFlags(cmd, &o.FileOption)
 	opt.MarkFileFlagAsMandatory(cmd)
+	opt.AddOutputFlags(cmd, &o.OutputOption)
 	cmd.Flags().StringVar(&o.chartsSchemas, "schemas.charts", "", "Path to the CUE schemas for charts.")
 	cmd.Flags().StringVar(&o.queriesSchemas, "schemas.queries", "", "Path to the CUE schemas for queries.")
+	cmd.Flags().StringVar(&o.variablesSchemas, "schemas.variables", "", "Path to the CUE schemas for variables.")
+	cmd.Flags().StringVar(&o.annotationsSchemas, "schemas.annotations", "", "Path to the CUE schemas for annotations.")
+	cmd.Flags().StringVar(&o.layoutsSchemas, "schemas.layouts", "", "Path to the CUE schemas for layouts.")
+	cmd.Flags().StringVar(&o.datasourcesSchemas, "schemas.datasources", "", "Path to the CUE schemas for datasources.")
 	cmd.MarkFlagsRequiredTogether("schemas.charts", "schemas.queries")
 	return cmd
 }
@@ -53,6 +53,44 @@ func TestLintCMD(t *testing.T) {
 			ExpectedMessage: `your resources look good
 `,
 		},
+		{
+			Title:           "lint an invalid dashboard",
+			Args:            []string{"-f", "../../test/sample_resources/invalid_dashboard.json"},
+			IsErrorExpected: true,
+			ExpectedMessage: "your resources are not valid",
+		},
+		{
+			Title:           "lint a dashboard with a PromQL query looking wrong",
+			Args:            []string{"-f", "../../test/sample_resources/promql_dashboard.json", "-o", "json"},
+			IsErrorExpected: false,
+			ExpectedMessage: `[{"resource":"Dashboard/node_memory","path":"/spec/panels/CPU/options/queries/0/options/query","severity":"warning","message":"1:6: rate() is computing the rate of a counter, but \"node_memory_MemFree_bytes\" looks like a gauge as its name doesn't end with _total, _count, _sum, _bucket"}]
+`,
+		},
+		{
+			Title:           "lint a dashboard with an orphan panel and an unused variable",
+			Args:            []string{"-f", "../../test/sample_resources/orphan_dashboard.json", "-o", "json"},
+			IsErrorExpected: false,
+			ExpectedMessage: `[{"resource":"Dashboard/orphan","path":"/spec/panels/Memory","severity":"warning","message":"the panel \"Memory\" is not used in any layout"},{"resource":"Dashboard/orphan","path":"/spec/variables/job","severity":"warning","message":"the variable \"job\" is not used by any panel, layout or datasource, neither directly nor through another variable"}]
+`,
+		},
+		{
+			Title:           "lint a datasource provided by a plugin without its schemas",
+			Args:            []string{"-f", "../../test/sample_resources/plugin_datasource.json"},
+			IsErrorExpected: true,
+			ExpectedMessage: `cannot extract Datasource, unmarshalling error: unknown spec.kind "PostgreSQL" used`,
+		},
+		{
+			Title:           "lint a datasource provided by an unknown plugin",
+			Args:            []string{"-f", "../../test/sample_resources/plugin_datasource.json", "--schemas.datasources", "../../../../schemas/datasources", "-o", "json"},
+			IsErrorExpected: true,
+			ExpectedMessage: `cannot extract Datasource, unmarshalling error: unknown spec.kind "PostgreSQL" used`,
+		},
+		{
+			Title:           "use an unknown output",
+			Args:            []string{"-f", "../../test/sample_resources/single_resource.json", "-o", "table"},
+			IsErrorExpected: true,
+			ExpectedMessage: `--ouput must be "json" or "yaml"`,
+		},
 	}
 	cmdTest.ExecuteSuiteTest(t, NewCMD, testSuite)
 }
@@ -0,0 +1,114 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/perses/perses/internal/cli/cmd"
+	"github.com/perses/perses/internal/cli/config"
+	"github.com/perses/perses/internal/cli/opt"
+	"github.com/perses/perses/internal/cli/output"
+	"github.com/perses/perses/pkg/client/api"
+	"github.com/spf13/cobra"
+)
+
+type panelsOption struct {
+	persesCMD.Option
+	opt.OutputOption
+	writer    io.Writer
+	dryRun    bool
+	apiClient api.ClientInterface
+}
+
+func (o *panelsOption) Complete(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("no args are supported by the command 'migrate panels'")
+	}
+	// Complete the output only if it has been set by the user
+	if len(o.Output) > 0 {
+		if outputErr := o.OutputOption.Complete(); outputErr != nil {
+			return outputErr
+		}
+	}
+	apiClient, err := config.Global.GetAPIClient()
+	if err != nil {
+		return err
+	}
+	o.apiClient = apiClient
+	return nil
+}
+
+func (o *panelsOption) Validate() error {
+	return nil
+}
+
+func (o *panelsOption) Execute() error {
+	report, err := o.apiClient.V1().Schema().MigratePanels(o.dryRun)
+	if err != nil {
+		return err
+	}
+	if len(o.Output) > 0 {
+		return output.Handle(o.writer, o.Output, report)
+	}
+	if len(report.Migrations) == 0 {
+		return output.HandleString(o.writer, "all the panels are up to date")
+	}
+	data := make([][]string, 0, len(report.Migrations))
+	for _, m := range report.Migrations {
+		data = append(data, []string{m.Project, m.Dashboard, m.Panel, m.Kind, strconv.Itoa(m.From), strconv.Itoa(m.To), m.Error})
+	}
+	output.HandlerTable(o.writer, []string{"PROJECT", "DASHBOARD", "PANEL", "KIND", "FROM", "TO", "ERROR"}, data)
+	return nil
+}
+
+func (o *panelsOption) SetWriter(writer io.Writer) {
+	o.writer = writer
+}
+
+func newPanelsCMD() *cobra.Command {
+	o := &panelsOption{}
+	cmd := &cobra.Command{
+		Use:   "panels",
+		Short: "Upgrade the panels stored to the version of their schema",
+		Long: `
+Upgrade the panels of every dashboard stored to the version of the schema of their kind, by running the migrations declared in the schemas.
+A dashboard is only updated when all its panels can be upgraded and are valid.
+`,
+		Example: `
+# List the panels that would be upgraded
+percli migrate panels --dry-run
+
+# Upgrade the panels and print the report as JSON
+percli migrate panels -ojson
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return persesCMD.Run(o, cmd, args)
+		},
+	}
+	opt.AddOutputFlags(cmd, &o.OutputOption)
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only report the panels to upgrade, without updating the dashboards.")
+	return cmd
+}
+
+func NewCMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the resources stored by the API",
+	}
+	cmd.AddCommand(newPanelsCMD())
+	return cmd
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"testing"
+
+	cmdTest "github.com/perses/perses/internal/cli/test"
+	"github.com/perses/perses/pkg/client/fake/api"
+	"github.com/perses/perses/pkg/client/fake/api/v1"
+)
+
+func TestMigratePanelsCMD(t *testing.T) {
+	testSuite := []cmdTest.Suite{
+		{
+			Title:           "use args",
+			Args:            []string{"panels", "whatever"},
+			IsErrorExpected: true,
+			ExpectedMessage: "no args are supported by the command 'migrate panels'",
+		},
+		{
+			Title:           "not connected to any API",
+			Args:            []string{"panels", "--dry-run"},
+			IsErrorExpected: true,
+			ExpectedMessage: "you are not connected to any API",
+		},
+		{
+			Title:           "dry run in json format",
+			Args:            []string{"panels", "--dry-run", "-ojson"},
+			APIClient:       fakeapi.New(),
+			IsErrorExpected: false,
+			ExpectedMessage: string(cmdTest.JSONMarshalStrict(fakev1.PanelMigrationReport(true))) + "\n",
+		},
+		{
+			Title:           "migration in yaml format",
+			Args:            []string{"panels", "-oyaml"},
+			APIClient:       fakeapi.New(),
+			IsErrorExpected: false,
+			ExpectedMessage: string(cmdTest.YAMLMarshalStrict(fakev1.PanelMigrationReport(false))) + "\n",
+		},
+	}
+	cmdTest.ExecuteSuiteTest(t, NewCMD, testSuite)
+}
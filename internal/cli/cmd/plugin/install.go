@@ -0,0 +1,127 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/cli/cmd"
+	"github.com/perses/perses/internal/cli/output"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type installOption struct {
+	persesCMD.Option
+	writer      io.Writer
+	pluginsPath string
+	archive     string
+	manifest    *modelV1.PluginManifest
+}
+
+func (o *installOption) Complete(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please specify the plugin bundle to install")
+	}
+	o.archive = args[0]
+	return nil
+}
+
+func (o *installOption) Validate() error {
+	manifest, err := schemas.ReadBundleManifest(o.archive)
+	if err != nil {
+		return fmt.Errorf("invalid plugin bundle %s: %s", o.archive, err)
+	}
+	o.manifest = manifest
+	return o.checkConflicts()
+}
+
+// checkConflicts returns an error when one of the kinds of the bundle is provided by another bundle installed.
+func (o *installOption) checkConflicts() error {
+	installed, err := listManifests(o.pluginsPath)
+	if err != nil {
+		return err
+	}
+	for _, other := range installed {
+		if other.Name == o.manifest.Name {
+			continue
+		}
+		for category, kinds := range o.manifest.Kinds {
+			for _, kind := range kinds {
+				for _, otherKind := range other.Kinds[category] {
+					if kind == otherKind {
+						return fmt.Errorf("the kind %s is already provided by the plugin bundle %q", kind, other.Name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (o *installOption) Execute() error {
+	if err := os.MkdirAll(o.pluginsPath, 0755); err != nil {
+		return err
+	}
+	target, err := bundlePath(o.pluginsPath, o.manifest.Name)
+	if err != nil {
+		return err
+	}
+	previous, previousErr := schemas.ReadBundleManifest(target)
+	data, err := os.ReadFile(o.archive)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+	if previousErr == nil {
+		return output.HandleString(o.writer, fmt.Sprintf("plugin bundle %q upgraded from %s to %s", o.manifest.Name, previous.Version, o.manifest.Version))
+	}
+	if !os.IsNotExist(previousErr) {
+		logrus.WithError(previousErr).Debugf("the plugin bundle %s replaced was invalid", target)
+	}
+	return output.HandleString(o.writer, fmt.Sprintf("plugin bundle %q %s installed", o.manifest.Name, o.manifest.Version))
+}
+
+func (o *installOption) SetWriter(writer io.Writer) {
+	o.writer = writer
+}
+
+func newInstallCMD() *cobra.Command {
+	o := &installOption{}
+	cmd := &cobra.Command{
+		Use:   "install [ARCHIVE]",
+		Short: "Install a plugin bundle in the plugins folder",
+		Long: `
+Install a plugin bundle in the plugins folder, once its manifest has been checked.
+A bundle already installed with the same name is replaced. A bundle providing a kind already provided by another bundle is refused.
+`,
+		Example: `
+# Install a plugin bundle in the folder ./plugins
+percli plugin install ./acme-charts-1.0.0.tar.gz
+
+# Install a plugin bundle in another folder
+percli plugin install ./acme-charts-1.0.0.tar.gz --plugins.path /etc/perses/plugins
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return persesCMD.Run(o, cmd, args)
+		},
+	}
+	addPluginsPathFlag(cmd, &o.pluginsPath)
+	return cmd
+}
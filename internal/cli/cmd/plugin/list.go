@@ -0,0 +1,120 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	"github.com/perses/perses/internal/cli/cmd"
+	"github.com/perses/perses/internal/cli/opt"
+	"github.com/perses/perses/internal/cli/output"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type listOption struct {
+	persesCMD.Option
+	opt.OutputOption
+	writer      io.Writer
+	pluginsPath string
+}
+
+func (o *listOption) Complete(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("no args are supported by the command 'plugin list'")
+	}
+	// Complete the output only if it has been set by the user
+	if len(o.Output) > 0 {
+		if outputErr := o.OutputOption.Complete(); outputErr != nil {
+			return outputErr
+		}
+	}
+	return nil
+}
+
+func (o *listOption) Validate() error {
+	return nil
+}
+
+func (o *listOption) Execute() error {
+	manifests, err := listManifests(o.pluginsPath)
+	if err != nil {
+		return err
+	}
+	if len(o.Output) > 0 {
+		return output.Handle(o.writer, o.Output, manifests)
+	}
+	data := make([][]string, 0, len(manifests))
+	for _, manifest := range manifests {
+		var kinds []string
+		for _, category := range modelV1.PluginCategories {
+			if len(manifest.Kinds[category]) > 0 {
+				kinds = append(kinds, fmt.Sprintf("%s: %s", category, strings.Join(manifest.Kinds[category], ", ")))
+			}
+		}
+		data = append(data, []string{manifest.Name, manifest.Version, manifest.MinPersesVersion, strings.Join(kinds, "; ")})
+	}
+	output.HandlerTable(o.writer, []string{"NAME", "VERSION", "MIN PERSES VERSION", "KINDS"}, data)
+	return nil
+}
+
+func (o *listOption) SetWriter(writer io.Writer) {
+	o.writer = writer
+}
+
+// listManifests returns the manifests of the bundles installed. The invalid bundles are skipped.
+func listManifests(pluginsPath string) ([]*modelV1.PluginManifest, error) {
+	bundles, err := schemas.ListBundles(pluginsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*modelV1.PluginManifest{}, nil
+		}
+		return nil, err
+	}
+	manifests := []*modelV1.PluginManifest{}
+	for _, bundle := range bundles {
+		manifest, err := schemas.ReadBundleManifest(bundle)
+		if err != nil {
+			logrus.WithError(err).Warningf("invalid plugin bundle %s", bundle)
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+func newListCMD() *cobra.Command {
+	o := &listOption{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the plugin bundles of the plugins folder",
+		Example: `
+# List the plugin bundles of the folder ./plugins
+percli plugin list
+
+# List the plugin bundles as JSON
+percli plugin list -ojson
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return persesCMD.Run(o, cmd, args)
+		},
+	}
+	opt.AddOutputFlags(cmd, &o.OutputOption)
+	addPluginsPathFlag(cmd, &o.pluginsPath)
+	return cmd
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/perses/perses/internal/api/impl/v1/dashboard/schemas"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/spf13/cobra"
+)
+
+const defaultPluginsPath = "plugins"
+
+// addPluginsPathFlag adds the flag setting the local folder of the plugin bundles, i.e. the plugins_path of the Perses configuration.
+func addPluginsPathFlag(cmd *cobra.Command, pluginsPath *string) {
+	cmd.Flags().StringVar(pluginsPath, "plugins.path", defaultPluginsPath, "Path to the folder of the plugin bundles loaded by Perses.")
+}
+
+// bundlePath returns the path of the bundle installed in the folder pluginsPath.
+// The bundles are named after the name of their manifest, so there is at most one version of a bundle installed.
+// An error is returned when the name is not a valid name of bundle, so the path cannot be outside pluginsPath.
+func bundlePath(pluginsPath string, name string) (string, error) {
+	if err := modelV1.ValidatePluginBundleName(name); err != nil {
+		return "", err
+	}
+	return schemas.JoinUnder(pluginsPath, fmt.Sprintf("%s%s", name, schemas.BundleExtension))
+}
+
+func NewCMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage the plugin bundles of a local plugins folder",
+		Long: `
+A plugin bundle is a tar.gz archive providing schemas plugins. It's made of a file manifest.yaml
+describing the bundle, and of a folder per category of plugins (panels, queries, variables, layouts, datasources)
+containing one CUE package per plugin.
+`,
+	}
+	cmd.AddCommand(newInstallCMD())
+	cmd.AddCommand(newListCMD())
+	cmd.AddCommand(newRemoveCMD())
+	return cmd
+}
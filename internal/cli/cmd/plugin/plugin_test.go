@@ -0,0 +1,164 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cmdTest "github.com/perses/perses/internal/cli/test"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+const acmeChart = `
+package acme
+
+#panel: {
+	kind: "AcmeChart"
+	options: {
+		color: string
+	}
+}
+`
+
+// writeBundle creates in dir the plugin bundle fileName, made of the manifest and of the panel plugin acme.
+func writeBundle(t *testing.T, dir string, fileName string, manifest string) string {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, file := range []struct{ name, content string }{
+		{name: "manifest.yaml", content: manifest},
+		{name: "panels/acme/acme.cue", content: acmeChart},
+	} {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(dir, fileName)
+	if err := os.WriteFile(bundlePath, buffer.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return bundlePath
+}
+
+func TestPluginCMD(t *testing.T) {
+	archives := t.TempDir()
+	pluginsPath := filepath.Join(t.TempDir(), "plugins")
+	acmeV1 := writeBundle(t, archives, "acme-1.0.0.tar.gz", "name: acme\nversion: 1.0.0\nkinds:\n  panels: [AcmeChart]\n")
+	acmeV2 := writeBundle(t, archives, "acme-2.0.0.tar.gz", "name: acme\nversion: 2.0.0\nmin_perses_version: 0.1.0\nkinds:\n  panels: [AcmeChart]\n")
+	other := writeBundle(t, archives, "other.tar.gz", "name: other\nversion: 1.0.0\nkinds:\n  panels: [AcmeChart]\n")
+	invalid := writeBundle(t, archives, "invalid.tar.gz", "name: invalid\nkinds:\n  panels: [AcmeChart]\n")
+	traversal := writeBundle(t, archives, "traversal.tar.gz", "name: ../../escaped\nversion: 1.0.0\nkinds:\n  panels: [AcmeChart]\n")
+
+	testSuite := []cmdTest.Suite{
+		{
+			Title:           "install without archive",
+			Args:            []string{"install", "--plugins.path", pluginsPath},
+			IsErrorExpected: true,
+			ExpectedMessage: "please specify the plugin bundle to install",
+		},
+		{
+			Title:           "install an invalid bundle",
+			Args:            []string{"install", invalid, "--plugins.path", pluginsPath},
+			IsErrorExpected: true,
+			ExpectedMessage: "invalid plugin bundle " + invalid + ": invalid manifest.yaml: the version of the plugin bundle \"invalid\" cannot be empty",
+		},
+		{
+			Title:           "install a bundle whose name goes outside the plugins folder",
+			Args:            []string{"install", traversal, "--plugins.path", pluginsPath},
+			IsErrorExpected: true,
+			ExpectedMessage: "invalid plugin bundle " + traversal + ": invalid manifest.yaml: invalid name of plugin bundle \"../../escaped\": it must match ^[a-zA-Z0-9_.-]+$ and cannot be . or ..",
+		},
+		{
+			Title:           "list without plugins folder",
+			Args:            []string{"list", "--plugins.path", pluginsPath, "-ojson"},
+			IsErrorExpected: false,
+			ExpectedMessage: "[]\n",
+		},
+		{
+			Title:           "install a bundle",
+			Args:            []string{"install", acmeV1, "--plugins.path", pluginsPath},
+			IsErrorExpected: false,
+			ExpectedMessage: "plugin bundle \"acme\" 1.0.0 installed\n",
+		},
+		{
+			Title:           "upgrade a bundle",
+			Args:            []string{"install", acmeV2, "--plugins.path", pluginsPath},
+			IsErrorExpected: false,
+			ExpectedMessage: "plugin bundle \"acme\" upgraded from 1.0.0 to 2.0.0\n",
+		},
+		{
+			Title:           "install a bundle providing a kind already provided",
+			Args:            []string{"install", other, "--plugins.path", pluginsPath},
+			IsErrorExpected: true,
+			ExpectedMessage: "the kind AcmeChart is already provided by the plugin bundle \"acme\"",
+		},
+		{
+			Title:           "list the bundles",
+			Args:            []string{"list", "--plugins.path", pluginsPath, "-ojson"},
+			IsErrorExpected: false,
+			ExpectedMessage: string(cmdTest.JSONMarshalStrict([]*modelV1.PluginManifest{
+				{
+					Name:             "acme",
+					Version:          "2.0.0",
+					MinPersesVersion: "0.1.0",
+					Kinds:            map[string][]string{"panels": {"AcmeChart"}},
+				},
+			})) + "\n",
+		},
+		{
+			Title:           "remove a bundle not installed",
+			Args:            []string{"remove", "other", "--plugins.path", pluginsPath},
+			IsErrorExpected: true,
+			ExpectedMessage: "plugin bundle \"other\" is not installed",
+		},
+		{
+			Title:           "remove a bundle with an invalid name",
+			Args:            []string{"remove", "../acme", "--plugins.path", pluginsPath},
+			IsErrorExpected: true,
+			ExpectedMessage: "invalid name of plugin bundle \"../acme\": it must match ^[a-zA-Z0-9_.-]+$ and cannot be . or ..",
+		},
+		{
+			Title:           "remove a bundle",
+			Args:            []string{"remove", "acme", "--plugins.path", pluginsPath},
+			IsErrorExpected: false,
+			ExpectedMessage: "plugin bundle \"acme\" removed\n",
+		},
+		{
+			Title:           "list after the removal",
+			Args:            []string{"list", "--plugins.path", pluginsPath, "-ojson"},
+			IsErrorExpected: false,
+			ExpectedMessage: "[]\n",
+		},
+	}
+	cmdTest.ExecuteSuiteTest(t, NewCMD, testSuite)
+	// nothing has been written outside the plugins folder
+	_, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(pluginsPath)), "escaped.tar.gz"))
+	if !os.IsNotExist(err) {
+		t.Errorf("the bundle with a traversal name has been written outside the plugins folder: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/perses/perses/internal/cli/cmd"
+	"github.com/perses/perses/internal/cli/output"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+	"github.com/spf13/cobra"
+)
+
+type removeOption struct {
+	persesCMD.Option
+	writer      io.Writer
+	pluginsPath string
+	name        string
+}
+
+func (o *removeOption) Complete(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please specify the name of the plugin bundle to remove")
+	}
+	o.name = args[0]
+	return modelV1.ValidatePluginBundleName(o.name)
+}
+
+func (o *removeOption) Validate() error {
+	return nil
+}
+
+func (o *removeOption) Execute() error {
+	target, err := bundlePath(o.pluginsPath, o.name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("plugin bundle %q is not installed", o.name)
+		}
+		return err
+	}
+	return output.HandleString(o.writer, fmt.Sprintf("plugin bundle %q removed", o.name))
+}
+
+func (o *removeOption) SetWriter(writer io.Writer) {
+	o.writer = writer
+}
+
+func newRemoveCMD() *cobra.Command {
+	o := &removeOption{}
+	cmd := &cobra.Command{
+		Use:   "remove [NAME]",
+		Short: "Remove a plugin bundle from the plugins folder",
+		Example: `
+# Remove the plugin bundle acme-charts from the folder ./plugins
+percli plugin remove acme-charts
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return persesCMD.Run(o, cmd, args)
+		},
+	}
+	addPluginsPathFlag(cmd, &o.pluginsPath)
+	return cmd
+}
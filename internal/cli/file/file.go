@@ -100,7 +100,7 @@ func (u *unmarshaller) unmarshalEntities() ([]modelAPI.Entity, error) {
 		// Then let's use the service to unmarshal the resource.
 		unmarshalErr := u.unmarshalEntity(data, entity)
 		if unmarshalErr != nil {
-			return nil, fmt.Errorf("cannot extract %s, unmarshalling error: %s", kind, unmarshalErr)
+			return nil, fmt.Errorf("cannot extract %s, unmarshalling error: %w", kind, unmarshalErr)
 		}
 		result = append(result, entity)
 	}
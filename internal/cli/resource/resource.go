@@ -61,6 +61,21 @@ var resources = []resource{
 			"globalDatasources",
 		},
 	},
+	{
+		kind:      modelV1.KindGlobalPanel,
+		shortTerm: "gpnl",
+		aliases: []string{
+			"globalPanels",
+		},
+	},
+	{
+		kind:      modelV1.KindPanel,
+		shortTerm: "pnl",
+		aliases: []string{
+			"panels",
+			"pnls",
+		},
+	},
 	{
 		kind: modelV1.KindProject,
 		aliases: []string{
@@ -83,7 +98,7 @@ func HandleSuccessMessage(writer io.Writer, kind modelV1.Kind, project string, g
 // Returns false otherwise.
 func IsGlobal(kind modelV1.Kind) bool {
 	switch kind {
-	case modelV1.KindProject, modelV1.KindGlobalDatasource:
+	case modelV1.KindProject, modelV1.KindGlobalDatasource, modelV1.KindGlobalPanel:
 		return true
 	default:
 		return false
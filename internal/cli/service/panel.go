@@ -0,0 +1,81 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/perses/perses/internal/cli/output"
+	v1 "github.com/perses/perses/pkg/client/api/v1"
+	modelAPI "github.com/perses/perses/pkg/model/api"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+// panelKind returns the kind of the library panel, or an empty string when the spec cannot be read.
+func panelKind(spec json.RawMessage) string {
+	var tmp struct {
+		Kind string `json:"kind"`
+	}
+	_ = json.Unmarshal(spec, &tmp)
+	return tmp.Kind
+}
+
+type panel struct {
+	Service
+	apiClient v1.PanelInterface
+}
+
+func (p *panel) CreateResource(entity modelAPI.Entity) (modelAPI.Entity, error) {
+	return p.apiClient.Create(entity.(*modelV1.Panel))
+}
+
+func (p *panel) UpdateResource(entity modelAPI.Entity) (modelAPI.Entity, error) {
+	return p.apiClient.Update(entity.(*modelV1.Panel))
+}
+
+func (p *panel) ListResource(prefix string) ([]modelAPI.Entity, error) {
+	return convertToEntityIfNoError(p.apiClient.List(prefix))
+}
+
+func (p *panel) GetResource(name string) (modelAPI.Entity, error) {
+	return p.apiClient.Get(name)
+}
+
+func (p *panel) DeleteResource(name string) error {
+	return p.apiClient.Delete(name)
+}
+
+func (p *panel) BuildMatrix(hits []modelAPI.Entity) [][]string {
+	var data [][]string
+	for _, hit := range hits {
+		entity := hit.(*modelV1.Panel)
+		line := []string{
+			entity.Metadata.Name,
+			entity.Metadata.Project,
+			panelKind(entity.Spec),
+			output.FormatTime(entity.Metadata.UpdatedAt),
+		}
+		data = append(data, line)
+	}
+	return data
+}
+
+func (p *panel) GetColumHeader() []string {
+	return []string{
+		"NAME",
+		"PROJECT",
+		"PANEL_TYPE",
+		"AGE",
+	}
+}
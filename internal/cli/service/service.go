@@ -60,6 +60,14 @@ func New(kind modelV1.Kind, projectName string, apiClient api.ClientInterface) (
 		return &globalDatasource{
 			apiClient: apiClient.V1().GlobalDatasource(),
 		}, nil
+	case modelV1.KindGlobalPanel:
+		return &globalPanel{
+			apiClient: apiClient.V1().GlobalPanel(),
+		}, nil
+	case modelV1.KindPanel:
+		return &panel{
+			apiClient: apiClient.V1().Panel(projectName),
+		}, nil
 	case modelV1.KindProject:
 		return &project{
 			apiClient: apiClient.V1().Project(),
@@ -25,8 +25,11 @@ type ClientInterface interface {
 	Datasource(project string) DatasourceInterface
 	Folder(project string) FolderInterface
 	GlobalDatasource() GlobalDatasourceInterface
+	GlobalPanel() GlobalPanelInterface
 	Health() HealthInterface
+	Panel(project string) PanelInterface
 	Project() ProjectInterface
+	Schema() SchemaInterface
 	User() UserInterface
 }
 
@@ -61,14 +64,26 @@ func (c *client) GlobalDatasource() GlobalDatasourceInterface {
 	return newGlobalDatasource(c.restClient)
 }
 
+func (c *client) GlobalPanel() GlobalPanelInterface {
+	return newGlobalPanel(c.restClient)
+}
+
 func (c *client) Health() HealthInterface {
 	return newHealth(c.restClient)
 }
 
+func (c *client) Panel(project string) PanelInterface {
+	return newPanel(c.restClient, project)
+}
+
 func (c *client) Project() ProjectInterface {
 	return newProject(c.restClient)
 }
 
+func (c *client) Schema() SchemaInterface {
+	return newSchema(c.restClient)
+}
+
 func (c *client) User() UserInterface {
 	return newUser(c.restClient)
 }
@@ -0,0 +1,62 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/perses/perses/pkg/client/perseshttp"
+	v1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+const schemaResource = "schemas"
+
+type SchemaInterface interface {
+	// MigratePanels upgrades the panels of every dashboard to the version of the schema of their kind.
+	// When dryRun is true, the panels to upgrade are only reported.
+	MigratePanels(dryRun bool) (*v1.PanelMigrationReport, error)
+}
+
+type schema struct {
+	SchemaInterface
+	client *perseshttp.RESTClient
+}
+
+func newSchema(client *perseshttp.RESTClient) SchemaInterface {
+	return &schema{
+		client: client,
+	}
+}
+
+func (c *schema) MigratePanels(dryRun bool) (*v1.PanelMigrationReport, error) {
+	result := &v1.PanelMigrationReport{}
+	err := c.client.Post().
+		Resource(schemaResource).
+		Name("panels/migrate").
+		Query(&migrationQuery{dryRun: dryRun}).
+		Do().
+		Object(result)
+	return result, err
+}
+
+type migrationQuery struct {
+	dryRun bool
+}
+
+func (q *migrationQuery) GetValues() url.Values {
+	return url.Values{
+		"dry_run": []string{strconv.FormatBool(q.dryRun)},
+	}
+}
@@ -47,3 +47,7 @@ func (c *client) Health() v1.HealthInterface {
 func (c *client) Project() v1.ProjectInterface {
 	return &project{}
 }
+
+func (c *client) Schema() v1.SchemaInterface {
+	return &schema{}
+}
@@ -0,0 +1,44 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakev1
+
+import (
+	v1 "github.com/perses/perses/pkg/client/api/v1"
+	modelV1 "github.com/perses/perses/pkg/model/api/v1"
+)
+
+type schema struct {
+	v1.SchemaInterface
+}
+
+// PanelMigrationReport returns the report of the migration of one panel.
+func PanelMigrationReport(dryRun bool) *modelV1.PanelMigrationReport {
+	return &modelV1.PanelMigrationReport{
+		DryRun: dryRun,
+		Migrations: []modelV1.PanelMigration{
+			{
+				Project:   "perses",
+				Dashboard: "node",
+				Panel:     "CPU",
+				Kind:      "LineChart",
+				From:      1,
+				To:        2,
+			},
+		},
+	}
+}
+
+func (c *schema) MigratePanels(dryRun bool) (*modelV1.PanelMigrationReport, error) {
+	return PanelMigrationReport(dryRun), nil
+}
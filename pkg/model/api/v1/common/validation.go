@@ -0,0 +1,123 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	// SeverityError means the resource cannot be accepted as it is.
+	SeverityError Severity = "error"
+	// SeverityWarning means the resource is accepted, but something looks wrong.
+	SeverityWarning Severity = "warning"
+)
+
+// ValidationError describes why a field of a resource is not valid.
+type ValidationError struct {
+	// Path is the JSON pointer (RFC 6901) of the field that is not valid, e.g. "/spec/datasource/name".
+	Path     string   `json:"path" yaml:"path"`
+	Severity Severity `json:"severity" yaml:"severity"`
+	Message  string   `json:"message" yaml:"message"`
+}
+
+// ValidationReport is the list of every problem found in a resource.
+// It is used as an error, so the whole report can be returned instead of stopping at the first problem.
+type ValidationReport []ValidationError
+
+func (r ValidationReport) Error() string {
+	messages := make([]string, 0, len(r))
+	for _, v := range r {
+		messages = append(messages, fmt.Sprintf("%s: %s", v.Path, v.Message))
+	}
+	return strings.Join(messages, ", ")
+}
+
+// AddError adds an error to the report. The message is formatted like with fmt.Sprintf.
+func (r *ValidationReport) AddError(path string, format string, args ...interface{}) {
+	*r = append(*r, ValidationError{Path: path, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+// AddWarning adds a warning to the report. The message is formatted like with fmt.Sprintf.
+func (r *ValidationReport) AddWarning(path string, format string, args ...interface{}) {
+	*r = append(*r, ValidationError{Path: path, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// Merge adds the given error to the report. When it is a ValidationReport, every entry is added with the prefix
+// put in front of its path. Otherwise, the error is added as a single entry at the given prefix.
+func (r *ValidationReport) Merge(prefix string, err error) {
+	if err == nil {
+		return
+	}
+	var report ValidationReport
+	if !errors.As(err, &report) {
+		r.AddError(prefix, "%s", err)
+		return
+	}
+	for _, v := range report {
+		v.Path = prefix + v.Path
+		*r = append(*r, v)
+	}
+}
+
+// HasErrors returns true when at least one entry is an error and not just a warning.
+func (r ValidationReport) HasErrors() bool {
+	for _, v := range r {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings returns the entries of the report that are only warnings, or nil if there is none.
+func (r ValidationReport) Warnings() ValidationReport {
+	var result ValidationReport
+	for _, v := range r {
+		if v.Severity == SeverityWarning {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Sort orders the report by path, keeping the order of the entries having the same path.
+func (r ValidationReport) Sort() {
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].Path < r[j].Path
+	})
+}
+
+// Err returns the report as an error if it contains at least one error, nil otherwise.
+func (r ValidationReport) Err() error {
+	if r.HasErrors() {
+		return r
+	}
+	return nil
+}
+
+// JSONPointer builds a JSON pointer from the given tokens, escaping the characters "~" and "/" like required by the RFC 6901.
+func JSONPointer(tokens ...string) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteString("/")
+		builder.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return builder.String()
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPointer(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		tokens []string
+		result string
+	}{
+		{
+			title:  "no token",
+			result: "",
+		},
+		{
+			title:  "simple tokens",
+			tokens: []string{"spec", "panels", "cpu"},
+			result: "/spec/panels/cpu",
+		},
+		{
+			title:  "tokens to escape",
+			tokens: []string{"spec", "panels", "cpu/load~1"},
+			result: "/spec/panels/cpu~1load~01",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			assert.Equal(t, test.result, JSONPointer(test.tokens...))
+		})
+	}
+}
+
+func TestMergeValidationReport(t *testing.T) {
+	var report ValidationReport
+	report.AddWarning("/spec/duration", "duration is quite long")
+	report.Merge("/spec/panels/cpu", ValidationReport{
+		{Path: "/kind", Severity: SeverityError, Message: "unknown kind"},
+	})
+	report.Merge("/spec/variables", fmt.Errorf("circular dependency detected"))
+	report.Merge("/spec/layouts", nil)
+	report.Sort()
+	expected := ValidationReport{
+		{Path: "/spec/duration", Severity: SeverityWarning, Message: "duration is quite long"},
+		{Path: "/spec/panels/cpu/kind", Severity: SeverityError, Message: "unknown kind"},
+		{Path: "/spec/variables", Severity: SeverityError, Message: "circular dependency detected"},
+	}
+	assert.Equal(t, expected, report)
+	assert.Equal(t, "/spec/duration: duration is quite long, /spec/panels/cpu/kind: unknown kind, /spec/variables: circular dependency detected", report.Error())
+	assert.NoError(t, ValidationReport{expected[0]}.Err())
+	assert.Equal(t, ValidationReport{expected[0]}, report.Warnings())
+	assert.Nil(t, ValidationReport{expected[1]}.Warnings())
+}
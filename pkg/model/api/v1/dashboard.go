@@ -15,8 +15,11 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"time"
 
 	modelAPI "github.com/perses/perses/pkg/model/api"
 	"github.com/perses/perses/pkg/model/api/v1/common"
@@ -35,10 +38,14 @@ type DashboardSpec struct {
 	Datasource dashboard.Datasource `json:"datasource" yaml:"datasource"`
 	// Duration is the default time you would like to use to looking in the past when getting data to fill the
 	// dashboard
-	Duration  model.Duration                 `json:"duration" yaml:"duration"`
+	Duration model.Duration `json:"duration" yaml:"duration"`
+	// Time is completing the duration with the refresh of the dashboard, its timezone, an absolute time range and a delay.
+	Time      *dashboard.TimeSettings        `json:"time,omitempty" yaml:"time,omitempty"`
 	Variables map[string]*dashboard.Variable `json:"variables,omitempty" yaml:"variables,omitempty"`
-	Panels    map[string]json.RawMessage     `json:"panels" yaml:"panels"` // kept as raw json as the validation is done with cuelang
-	Layouts   []dashboard.Layout             `json:"layouts" yaml:"layouts"`
+	// Annotations are the sources of the events displayed over the time series panels, such as the deployments or the incidents.
+	Annotations map[string]*dashboard.Annotation `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Panels      map[string]json.RawMessage       `json:"panels" yaml:"panels"` // kept as raw json as the validation is done with cuelang
+	Layouts     []dashboard.Layout               `json:"layouts" yaml:"layouts"`
 }
 
 func (d *DashboardSpec) UnmarshalJSON(data []byte) error {
@@ -67,20 +74,187 @@ func (d *DashboardSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// validate returns a common.ValidationReport with every problem found in the spec.
+// The paths of the report are relative to the spec.
 func (d *DashboardSpec) validate() error {
-	if len(d.Panels) == 0 {
-		return fmt.Errorf("dashboard.spec.panels cannot be empty")
+	var report common.ValidationReport
+	if len(d.Panels) == 0 && len(d.LibraryPanelReferences()) == 0 {
+		report.AddError("/panels", "dashboard.spec.panels cannot be empty")
+	}
+	if d.Time != nil {
+		report.Merge("/time", d.Time.Validate())
 	}
 	for variableKey := range d.Variables {
 		if len(keyRegexp.FindAllString(variableKey, -1)) <= 0 {
-			return fmt.Errorf("variable reference %q is containing spaces or special characters", variableKey)
+			report.AddError(common.JSONPointer("variables", variableKey), "variable reference %q is containing spaces or special characters", variableKey)
+		}
+	}
+	for annotationKey := range d.Annotations {
+		if len(keyRegexp.FindAllString(annotationKey, -1)) <= 0 {
+			report.AddError(common.JSONPointer("annotations", annotationKey), "annotation reference %q is containing spaces or special characters", annotationKey)
 		}
 	}
 	for panelKey := range d.Panels {
 		if len(keyRegexp.FindAllString(panelKey, -1)) <= 0 {
-			return fmt.Errorf("panel reference %q is containing spaces or special characters", panelKey)
+			report.AddError(common.JSONPointer("panels", panelKey), "panel reference %q is containing spaces or special characters", panelKey)
 		}
 	}
+	for i, layout := range d.Layouts {
+		if layout.Spec != nil {
+			report.Merge(fmt.Sprintf("/layouts/%d/spec", i), layout.Spec.Validate())
+			d.verifyRepeats(&report, fmt.Sprintf("/layouts/%d/spec", i), layout.Spec)
+		}
+	}
+	d.verifyAndSetJSONReferences(&report)
+	report.Sort()
+	return report.Err()
+}
+
+// TimeRange returns the time range displayed by default: the absolute time range of the dashboard when it is set,
+// or its duration before now, minus the now delay.
+func (d *DashboardSpec) TimeRange(now time.Time) (start time.Time, end time.Time) {
+	return d.Time.TimeRange(now, d.Duration)
+}
+
+// LayoutReference is a reference to a panel used in a layout.
+type LayoutReference struct {
+	// Path is the JSON pointer of the reference, relative to the spec, e.g. "/layouts/0/spec/items/1/content".
+	Path string
+	Ref  *common.JSONRef
+}
+
+// LayoutReferences returns every reference to a panel used in the layouts, in the order they appear.
+func (d *DashboardSpec) LayoutReferences() []LayoutReference {
+	var result []LayoutReference
+	for i, layout := range d.Layouts {
+		if layout.Spec == nil {
+			continue
+		}
+		for _, ref := range layout.Spec.PanelReferences() {
+			result = append(result, LayoutReference{Path: fmt.Sprintf("/layouts/%d/spec%s", i, ref.Path), Ref: ref.Ref})
+		}
+	}
+	return result
+}
+
+// LibraryPanelReference is a reference to a library panel used in a layout.
+type LibraryPanelReference struct {
+	// Path is the JSON pointer of the reference, relative to the spec, e.g. "/layouts/0/spec/items/1/content".
+	Path string
+	// Name is the name of the Panel, or of the GlobalPanel when Global is true.
+	Name   string
+	Global bool
+}
+
+// LibraryPanelReferences returns every reference to a Panel or to a GlobalPanel used in the layouts, in the order they appear.
+func (d *DashboardSpec) LibraryPanelReferences() []LibraryPanelReference {
+	var result []LibraryPanelReference
+	for _, ref := range d.LayoutReferences() {
+		if name, global, ok := ParseLibraryPanelRef(ref.Ref); ok {
+			result = append(result, LibraryPanelReference{Path: ref.Path, Name: name, Global: global})
+		}
+	}
+	return result
+}
+
+// verifyAndSetJSONReferences will check that each JSON Reference are pointing to an existing object and will set the related pointer in the JSONRef.Object
+func (d *DashboardSpec) verifyAndSetJSONReferences(report *common.ValidationReport) {
+	for _, ref := range d.LayoutReferences() {
+		if err := d.checkAndSetRef(ref.Ref); err != nil {
+			report.AddError(ref.Path, "%s", err)
+		}
+	}
+}
+
+// verifyRepeats checks that every variable used to repeat a panel or a row of the layout exists and accepts several values.
+func (d *DashboardSpec) verifyRepeats(report *common.ValidationReport, path string, spec dashboard.LayoutSpec) {
+	for _, repeat := range dashboard.LayoutRepeats(spec) {
+		if len(repeat.Variable) == 0 {
+			// already reported by the validation of the layout
+			continue
+		}
+		variable, ok := d.Variables[repeat.Variable]
+		if !ok {
+			report.AddError(path+repeat.Path, "the variable %q used to repeat doesn't exist", repeat.Variable)
+		} else if !variable.Multi && !variable.IncludeAll {
+			report.AddError(path+repeat.Path, "the variable %q used to repeat must accept several values, with multi or include_all", repeat.Variable)
+		}
+	}
+}
+
+func (d *DashboardSpec) checkAndSetRef(ref *common.JSONRef) error {
+	if _, _, ok := ParseLibraryPanelRef(ref); ok {
+		// the library panels are stored apart from the dashboard, the API verifies they exist.
+		return nil
+	}
+	// ref.Path should like that [ "spec", "panels", <name> ].
+	// So if the array is not equal to three then the reference is wrong.
+	if len(ref.Path) != 3 {
+		return fmt.Errorf("reference %q is pointing to the void", ref.Ref)
+	}
+	if ref.Path[0] != "spec" {
+		return fmt.Errorf("reference %q doesn't start by 'spec'", ref.Ref)
+	}
+	switch ref.Path[1] {
+	case "panels":
+		obj, ok := d.Panels[ref.Path[2]]
+		if !ok {
+			return fmt.Errorf("there is no existing panel called %q in the current dashboard", ref.Path[2])
+		}
+		ref.Object = obj
+	default:
+		return fmt.Errorf("%q is not a known object", ref.Path[1])
+	}
+	return nil
+}
+
+// ResolveQueryDatasources returns the datasource used by every query of every panel.
+// For a given query, the datasource is the first one that is found in this order:
+//   - the datasource set in the query
+//   - the datasource set in the panel, if it has the same kind
+//   - the datasource of the dashboard, if it has the same kind and if its name is known
+func (d *DashboardSpec) ResolveQueryDatasources() ([]dashboard.QueryDatasource, error) {
+	panelKeys := make([]string, 0, len(d.Panels))
+	for key := range d.Panels {
+		panelKeys = append(panelKeys, key)
+	}
+	sort.Strings(panelKeys)
+	var result []dashboard.QueryDatasource
+	for _, key := range panelKeys {
+		panel, err := dashboard.ExtractPanelQueries(d.Panels[key])
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the queries of the panel %q: %s", key, err)
+		}
+		for i, query := range panel.Queries {
+			result = append(result, dashboard.QueryDatasource{
+				Panel:      key,
+				Query:      i,
+				QueryKind:  query.Kind,
+				Datasource: d.resolveQueryDatasource(panel.Datasource, query.Datasource),
+			})
+		}
+	}
+	return result, nil
+}
+
+func (d *DashboardSpec) resolveQueryDatasource(panelRef *dashboard.DatasourceRef, queryRef *dashboard.DatasourceRef) *dashboard.Datasource {
+	ref := queryRef
+	if ref == nil {
+		ref = panelRef
+	}
+	if ref == nil {
+		return nil
+	}
+	kind := ref.GetDatasourceKind()
+	for _, candidate := range []*dashboard.DatasourceRef{queryRef, panelRef} {
+		if candidate != nil && len(candidate.Name) > 0 && candidate.GetDatasourceKind() == kind {
+			return &dashboard.Datasource{Name: candidate.Name, Kind: kind, Global: candidate.Global}
+		}
+	}
+	if d.Datasource.Kind == kind && len(d.Datasource.Name) > 0 {
+		ds := d.Datasource
+		return &ds
+	}
 	return nil
 }
 
@@ -106,7 +280,7 @@ func (d *Dashboard) UnmarshalJSON(data []byte) error {
 	var tmp Dashboard
 	type plain Dashboard
 	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
-		return err
+		return specReport(err)
 	}
 	if err := (&tmp).validate(); err != nil {
 		return err
@@ -119,7 +293,7 @@ func (d *Dashboard) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var tmp Dashboard
 	type plain Dashboard
 	if err := unmarshal((*plain)(&tmp)); err != nil {
-		return err
+		return specReport(err)
 	}
 	if err := (&tmp).validate(); err != nil {
 		return err
@@ -132,43 +306,16 @@ func (d *Dashboard) validate() error {
 	if d.Kind != KindDashboard {
 		return fmt.Errorf("invalid kind: %q for a Dashboard type", d.Kind)
 	}
-	return d.verifyAndSetJSONReferences()
-}
-
-// verifyAndSetJSONRef will check that each JSON Reference are pointing to an existing object and will set the related pointer in the JSONRef.Object
-func (d *Dashboard) verifyAndSetJSONReferences() error {
-	for _, layout := range d.Spec.Layouts {
-		switch spec := layout.Spec.(type) {
-		case *dashboard.GridLayoutSpec:
-			for _, item := range spec.Items {
-				if err := d.checkAndSetRef(item.Content); err != nil {
-					return err
-				}
-			}
-
-		}
-	}
 	return nil
 }
 
-func (d *Dashboard) checkAndSetRef(ref *common.JSONRef) error {
-	// ref.Path should like that [ "spec", "panels", <name> ].
-	// So if the array is not equal to three then the reference is wrong.
-	if len(ref.Path) != 3 {
-		return fmt.Errorf("reference %q is pointing to the void", ref.Ref)
-	}
-	if ref.Path[0] != "spec" {
-		return fmt.Errorf("reference %q doesn't start by 'spec'", ref.Ref)
-	}
-	switch ref.Path[1] {
-	case "panels":
-		obj, ok := d.Spec.Panels[ref.Path[2]]
-		if !ok {
-			return fmt.Errorf("there is no existing panel called %q in the current dashboard", ref.Path[2])
-		}
-		ref.Object = obj
-	default:
-		return fmt.Errorf("%q is not a known object", ref.Path[1])
+// specReport puts back the report of the spec at its place in the dashboard. Any other error is returned as it is.
+func specReport(err error) error {
+	var report common.ValidationReport
+	if !errors.As(err, &report) {
+		return err
 	}
-	return nil
+	var result common.ValidationReport
+	result.Merge("/spec", report)
+	return result
 }
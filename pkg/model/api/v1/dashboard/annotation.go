@@ -0,0 +1,282 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+type AnnotationKind string
+
+const (
+	KindPromQLQueryAnnotation AnnotationKind = "PromQLQuery"
+	KindStaticAnnotation      AnnotationKind = "Static"
+)
+
+var annotationKindMap = map[AnnotationKind]bool{
+	KindPromQLQueryAnnotation: true,
+	KindStaticAnnotation:      true,
+}
+
+// IsPlugin returns true when the kind is not one of the kinds known by Perses, but a kind provided by a plugin.
+func (k AnnotationKind) IsPlugin() bool {
+	return !annotationKindMap[k]
+}
+
+// pluginAnnotationKinds is the set of the kinds of annotation provided by the plugins, see RegisterPluginAnnotationKinds.
+var pluginAnnotationKinds sync.Map
+
+// RegisterPluginAnnotationKinds records the kinds of annotation provided by the plugins loaded, so they are accepted
+// in addition to the kinds known by Perses. Like RegisterPluginVariableKinds, a kind stays registered once its plugin is removed.
+func RegisterPluginAnnotationKinds(kinds ...string) {
+	for _, kind := range kinds {
+		pluginAnnotationKinds.Store(AnnotationKind(kind), true)
+	}
+}
+
+// labelTemplateRegexp matches the labels used in the title and the text of a PromQLQuery annotation, e.g. "{{job}}".
+var labelTemplateRegexp = regexp.MustCompile(`{{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*}}`)
+
+// ExpandLabels replaces every label written "{{label_name}}" in the template by its value.
+// A label missing from the labels is replaced by an empty string.
+func ExpandLabels(template string, labels map[string]string) string {
+	return labelTemplateRegexp.ReplaceAllStringFunc(template, func(match string) string {
+		return labels[labelTemplateRegexp.FindStringSubmatch(match)[1]]
+	})
+}
+
+type AnnotationParameter interface {
+}
+
+// PromQLQueryAnnotationParameter is representing the events found by a PromQL expression, using the HTTP endpoint
+// `GET /api/v1/query_range`: there is an event wherever a series of the result has a value different from zero.
+// The consecutive points of a series are merged in a single event, spanning from the first point to the last one.
+type PromQLQueryAnnotationParameter struct {
+	AnnotationParameter `json:"-" yaml:"-"`
+	// Expr is the PromQL expression, e.g. `changes(kube_deployment_status_observed_generation[$__interval]) > 0`.
+	Expr string `json:"expr" yaml:"expr"`
+	// Title and Text are describing each event. The labels of the series are used with "{{label_name}}", e.g. "Deploy of {{deployment}}".
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	Text  string `json:"text,omitempty" yaml:"text,omitempty"`
+	// Step is the resolution of the query. By default, it is the interval of the time range, like the variable $__interval.
+	Step model.Duration `json:"step,omitempty" yaml:"step,omitempty"`
+}
+
+func (a *PromQLQueryAnnotationParameter) UnmarshalJSON(data []byte) error {
+	var tmp PromQLQueryAnnotationParameter
+	type plain PromQLQueryAnnotationParameter
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*a = tmp
+	return nil
+}
+
+func (a *PromQLQueryAnnotationParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp PromQLQueryAnnotationParameter
+	type plain PromQLQueryAnnotationParameter
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*a = tmp
+	return nil
+}
+
+func (a *PromQLQueryAnnotationParameter) validate() error {
+	if len(a.Expr) == 0 {
+		return fmt.Errorf("parameter.expr cannot be empty for a PromQLQuery annotation")
+	}
+	if a.Step < 0 {
+		return fmt.Errorf("parameter.step cannot be negative")
+	}
+	return nil
+}
+
+// StaticAnnotationParameter is representing a list of events written in the dashboard, e.g. the incidents of the service.
+type StaticAnnotationParameter struct {
+	AnnotationParameter `json:"-" yaml:"-"`
+	Events              []AnnotationEvent `json:"events" yaml:"events"`
+}
+
+func (a *StaticAnnotationParameter) UnmarshalJSON(data []byte) error {
+	var tmp StaticAnnotationParameter
+	type plain StaticAnnotationParameter
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*a = tmp
+	return nil
+}
+
+func (a *StaticAnnotationParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp StaticAnnotationParameter
+	type plain StaticAnnotationParameter
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*a = tmp
+	return nil
+}
+
+func (a *StaticAnnotationParameter) validate() error {
+	if len(a.Events) == 0 {
+		return fmt.Errorf("parameter.events cannot be empty for a Static annotation")
+	}
+	for i, event := range a.Events {
+		if event.Time.IsZero() {
+			return fmt.Errorf("parameter.events[%d].time cannot be empty", i)
+		}
+		if event.End != nil && event.End.Before(event.Time) {
+			return fmt.Errorf("parameter.events[%d].end cannot be before the time of the event", i)
+		}
+	}
+	return nil
+}
+
+// PluginAnnotationParameter is the parameter of an annotation whose kind is provided by a plugin.
+// It is kept as it is, since it is only validated by the CUE schema of the plugin.
+type PluginAnnotationParameter map[string]interface{}
+
+// AnnotationEvent is an event displayed over the time series panels, at a point in time or over a time range when End is set.
+type AnnotationEvent struct {
+	Time  time.Time  `json:"time" yaml:"time"`
+	End   *time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+	Title string     `json:"title,omitempty" yaml:"title,omitempty"`
+	Text  string     `json:"text,omitempty" yaml:"text,omitempty"`
+}
+
+type tmpDashboardAnnotation struct {
+	Kind          AnnotationKind         `json:"kind" yaml:"kind"`
+	DisplayedName string                 `json:"displayed_name,omitempty" yaml:"displayed_name,omitempty"`
+	Hide          bool                   `json:"hide,omitempty" yaml:"hide,omitempty"`
+	Parameter     map[string]interface{} `json:"parameter" yaml:"parameter"`
+}
+
+// Annotation is a source of events displayed over the time series panels of the dashboard, such as the deployments or the incidents.
+type Annotation struct {
+	// Kind is the type of the annotation. Depending on the value of Kind, it will change the content of Parameter.
+	Kind AnnotationKind `json:"kind" yaml:"kind"`
+	// DisplayedName is the name that would be displayed by the UI. The key of the map of annotations is used when it is omitted.
+	DisplayedName string `json:"displayed_name,omitempty" yaml:"displayed_name,omitempty"`
+	// Hide will be used by the UI to decide if the events are displayed by default.
+	Hide      bool                `json:"hide,omitempty" yaml:"hide,omitempty"`
+	Parameter AnnotationParameter `json:"parameter" yaml:"parameter"`
+}
+
+func (a *Annotation) UnmarshalJSON(data []byte) error {
+	jsonUnmarshalFunc := func(annotation interface{}) error {
+		return json.Unmarshal(data, annotation)
+	}
+	return a.unmarshal(jsonUnmarshalFunc, json.Marshal, json.Unmarshal)
+}
+
+func (a *Annotation) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return a.unmarshal(unmarshal, yaml.Marshal, yaml.Unmarshal)
+}
+
+func (a *Annotation) unmarshal(unmarshal func(interface{}) error, staticMarshal func(interface{}) ([]byte, error), staticUnmarshal func([]byte, interface{}) error) error {
+	var tmpAnnotation tmpDashboardAnnotation
+	if err := unmarshal(&tmpAnnotation); err != nil {
+		return err
+	}
+	a.Kind = tmpAnnotation.Kind
+	a.DisplayedName = tmpAnnotation.DisplayedName
+	a.Hide = tmpAnnotation.Hide
+
+	if len(tmpAnnotation.Kind) == 0 {
+		return fmt.Errorf("annotation.kind cannot be empty")
+	}
+	if tmpAnnotation.Kind.IsPlugin() {
+		if _, isPlugin := pluginAnnotationKinds.Load(tmpAnnotation.Kind); !isPlugin {
+			return fmt.Errorf("unknown annotation.kind %q used", tmpAnnotation.Kind)
+		}
+	}
+
+	rawParameter, err := staticMarshal(tmpAnnotation.Parameter)
+	if err != nil {
+		return err
+	}
+	var parameter interface{}
+	switch tmpAnnotation.Kind {
+	case KindPromQLQueryAnnotation:
+		parameter = &PromQLQueryAnnotationParameter{}
+	case KindStaticAnnotation:
+		parameter = &StaticAnnotationParameter{}
+	default:
+		parameter = &PluginAnnotationParameter{}
+	}
+	if err := staticUnmarshal(rawParameter, parameter); err != nil {
+		return err
+	}
+	a.Parameter = parameter
+	return nil
+}
+
+// AnnotationEvaluationRequest is the body of the request used to compute the events of the annotations of a dashboard.
+type AnnotationEvaluationRequest struct {
+	// Start and End are defining the time range of the events, like in VariableEvaluationRequest.
+	Start time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	End   time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+	// Selected is the list of the values currently selected for each variable, used to evaluate the variables the annotations depend on.
+	Selected map[string]Selection `json:"selected,omitempty" yaml:"selected,omitempty"`
+	// Panel is the name of the panel the events are displayed on. Its time override, if any, is applied to the time range.
+	Panel string `json:"panel,omitempty" yaml:"panel,omitempty"`
+}
+
+func (a *AnnotationEvaluationRequest) UnmarshalJSON(data []byte) error {
+	var tmp AnnotationEvaluationRequest
+	type plain AnnotationEvaluationRequest
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*a = tmp
+	return nil
+}
+
+func (a *AnnotationEvaluationRequest) validate() error {
+	if !a.Start.IsZero() && !a.End.IsZero() && a.End.Before(a.Start) {
+		return fmt.Errorf("end cannot be before start")
+	}
+	return nil
+}
+
+// AnnotationEvaluationResult is the list of events computed for an annotation.
+type AnnotationEvaluationResult struct {
+	Name   string            `json:"name" yaml:"name"`
+	Events []AnnotationEvent `json:"events" yaml:"events"`
+	// Error is set when the events of the annotation cannot be computed.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
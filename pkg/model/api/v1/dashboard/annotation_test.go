@@ -0,0 +1,201 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalJSONAnnotation(t *testing.T) {
+	RegisterPluginAnnotationKinds("Alertmanager")
+	end := time.Date(2022, 6, 1, 11, 30, 0, 0, time.UTC)
+	testSuite := []struct {
+		title  string
+		jason  string
+		result *Annotation
+	}{
+		{
+			title: "PromQLQuery annotation",
+			jason: `
+{
+  "kind": "PromQLQuery",
+  "displayed_name": "Deployments",
+  "parameter": {
+    "expr": "changes(kube_deployment_status_observed_generation[5m]) > 0",
+    "title": "Deployment of {{deployment}}",
+    "step": "1m"
+  }
+}
+`,
+			result: &Annotation{
+				Kind:          KindPromQLQueryAnnotation,
+				DisplayedName: "Deployments",
+				Parameter: &PromQLQueryAnnotationParameter{
+					Expr:  "changes(kube_deployment_status_observed_generation[5m]) > 0",
+					Title: "Deployment of {{deployment}}",
+					Step:  model.Duration(time.Minute),
+				},
+			},
+		},
+		{
+			title: "Static annotation",
+			jason: `
+{
+  "kind": "Static",
+  "hide": true,
+  "parameter": {
+    "events": [
+      {"time": "2022-06-01T10:00:00Z", "end": "2022-06-01T11:30:00Z", "title": "Incident"}
+    ]
+  }
+}
+`,
+			result: &Annotation{
+				Kind: KindStaticAnnotation,
+				Hide: true,
+				Parameter: &StaticAnnotationParameter{
+					Events: []AnnotationEvent{
+						{Time: time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC), End: &end, Title: "Incident"},
+					},
+				},
+			},
+		},
+		{
+			title: "annotation provided by a plugin",
+			jason: `
+{
+  "kind": "Alertmanager",
+  "parameter": {
+    "receiver": "team-a"
+  }
+}
+`,
+			result: &Annotation{
+				Kind:      "Alertmanager",
+				Parameter: &PluginAnnotationParameter{"receiver": "team-a"},
+			},
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result := &Annotation{}
+			assert.NoError(t, json.Unmarshal([]byte(test.jason), result))
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestUnmarshalYAMLAnnotation(t *testing.T) {
+	result := &Annotation{}
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+kind: PromQLQuery
+parameter:
+  expr: up == 0
+  title: "{{instance}} is down"
+`), result))
+	assert.Equal(t, &Annotation{
+		Kind: KindPromQLQueryAnnotation,
+		Parameter: &PromQLQueryAnnotationParameter{
+			Expr:  "up == 0",
+			Title: "{{instance}} is down",
+		},
+	}, result)
+}
+
+func TestUnmarshalAnnotationError(t *testing.T) {
+	testSuite := []struct {
+		title string
+		jsone string
+		err   error
+	}{
+		{
+			title: "no annotation kind",
+			jsone: `
+{
+  "kind": "",
+  "parameter": {}
+}
+`,
+			err: fmt.Errorf("annotation.kind cannot be empty"),
+		},
+		{
+			title: "unsupported annotation kind",
+			jsone: `
+{
+  "kind": "Awkward",
+  "parameter": {}
+}
+`,
+			err: fmt.Errorf("unknown annotation.kind \"Awkward\" used"),
+		},
+		{
+			title: "PromQLQuery annotation without expr",
+			jsone: `
+{
+  "kind": "PromQLQuery",
+  "parameter": {
+    "title": "Deployment"
+  }
+}
+`,
+			err: fmt.Errorf("parameter.expr cannot be empty for a PromQLQuery annotation"),
+		},
+		{
+			title: "Static annotation without events",
+			jsone: `
+{
+  "kind": "Static",
+  "parameter": {
+    "events": []
+  }
+}
+`,
+			err: fmt.Errorf("parameter.events cannot be empty for a Static annotation"),
+		},
+		{
+			title: "Static event ending before it starts",
+			jsone: `
+{
+  "kind": "Static",
+  "parameter": {
+    "events": [
+      {"time": "2022-06-01T10:00:00Z", "end": "2022-06-01T09:00:00Z"}
+    ]
+  }
+}
+`,
+			err: fmt.Errorf("parameter.events[0].end cannot be before the time of the event"),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result := &Annotation{}
+			assert.Equal(t, test.err, json.Unmarshal([]byte(test.jsone), result))
+		})
+	}
+}
+
+func TestExpandLabels(t *testing.T) {
+	labels := map[string]string{"deployment": "api", "namespace": "prod"}
+	assert.Equal(t, "Deployment of api in prod", ExpandLabels("Deployment of {{deployment}} in {{ namespace }}", labels))
+	assert.Equal(t, "Version  deployed", ExpandLabels("Version {{version}} deployed", labels))
+	assert.Equal(t, "no label", ExpandLabels("no label", labels))
+}
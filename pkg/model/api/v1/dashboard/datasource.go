@@ -16,13 +16,19 @@ package dashboard
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/perses/perses/pkg/model/api/v1/datasource"
 )
 
+// pluginDatasourceSuffix is the suffix used by the kinds of datasource declared in the CUE plugins.
+// For example, the queries for the datasource Prometheus are declaring the kind "PrometheusDatasource".
+const pluginDatasourceSuffix = "Datasource"
+
 type Datasource struct {
-	// Name is the name of the datasource
-	Name string `json:"name" yaml:"name"`
+	// Name is the name of the datasource.
+	// When it is omitted, the default datasource of the given kind is used.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 	// Kind is the datasource kind
 	Kind datasource.Kind `json:"kind" yaml:"kind"`
 	// If global is true, we are referencing a global datasource.
@@ -57,8 +63,84 @@ func (d *Datasource) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 func (d *Datasource) validate() error {
-	if len(d.Name) == 0 {
-		return fmt.Errorf("datasource.name cannot be empty")
+	if len(d.Name) == 0 && len(d.Kind) == 0 {
+		return fmt.Errorf("datasource.kind cannot be empty when datasource.name is omitted")
 	}
 	return nil
 }
+
+// DatasourceRef is how a panel or a query of a panel is referencing a datasource.
+type DatasourceRef struct {
+	// Kind is the kind of datasource used by the CUE plugins (e.g. "PrometheusDatasource").
+	Kind string `json:"kind" yaml:"kind"`
+	// Name is the name of the datasource. When it is empty, the datasource is inherited from the panel or from the dashboard.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// If global is true, we are referencing a global datasource.
+	// When set to false, we are referencing a datasource in the same project as the current dashboard.
+	Global bool `json:"global,omitempty" yaml:"global,omitempty"`
+}
+
+// GetDatasourceKind returns the kind of the datasource resource matching the kind used by the plugins.
+func (r *DatasourceRef) GetDatasourceKind() datasource.Kind {
+	return datasource.Kind(strings.TrimSuffix(r.Kind, pluginDatasourceSuffix))
+}
+
+// PanelQuery is a query found in the options of a panel.
+type PanelQuery struct {
+	// Path is the JSON pointer of the query relative to the panel, e.g. "/options/queries/0".
+	Path       string         `json:"-" yaml:"-"`
+	Kind       string         `json:"kind" yaml:"kind"`
+	Datasource *DatasourceRef `json:"datasource,omitempty" yaml:"datasource,omitempty"`
+	// Options are the options of the query, described by the plugin of its kind.
+	Options json.RawMessage `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// PanelQueries is the list of the queries of a panel, with the datasource set at the panel level.
+type PanelQueries struct {
+	Datasource *DatasourceRef
+	Queries    []*PanelQuery
+}
+
+// ExtractPanelQueries returns the queries of the given panel.
+// As the panels are only described by the CUE plugins, the queries are found by convention:
+// a panel is either using a single query set in options.query or a list of queries set in options.queries.
+func ExtractPanelQueries(panel json.RawMessage) (*PanelQueries, error) {
+	var tmp struct {
+		Datasource *DatasourceRef             `json:"datasource,omitempty"`
+		Options    map[string]json.RawMessage `json:"options"`
+	}
+	if err := json.Unmarshal(panel, &tmp); err != nil {
+		return nil, err
+	}
+	result := &PanelQueries{Datasource: tmp.Datasource}
+	if rawQuery, ok := tmp.Options["query"]; ok {
+		query := &PanelQuery{}
+		// a field query that is not an object is not a query (it can be a simple string for example).
+		if err := json.Unmarshal(rawQuery, query); err == nil && len(query.Kind) > 0 {
+			query.Path = "/options/query"
+			result.Queries = append(result.Queries, query)
+		}
+	}
+	if rawQueries, ok := tmp.Options["queries"]; ok {
+		var queries []*PanelQuery
+		if err := json.Unmarshal(rawQueries, &queries); err == nil {
+			for i, query := range queries {
+				query.Path = fmt.Sprintf("/options/queries/%d", i)
+			}
+			result.Queries = append(result.Queries, queries...)
+		}
+	}
+	return result, nil
+}
+
+// QueryDatasource is the datasource used by a query of a panel.
+type QueryDatasource struct {
+	// Panel is the key of the panel in the map dashboard.spec.panels.
+	Panel string `json:"panel" yaml:"panel"`
+	// Query is the position of the query in the panel.
+	Query int `json:"query" yaml:"query"`
+	// QueryKind is the kind of the query.
+	QueryKind string `json:"query_kind" yaml:"query_kind"`
+	// Datasource is the datasource used by the query. It is nil when it cannot be determined.
+	Datasource *Datasource `json:"datasource,omitempty" yaml:"datasource,omitempty"`
+}
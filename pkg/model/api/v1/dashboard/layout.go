@@ -16,6 +16,8 @@ package dashboard
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/perses/perses/pkg/model/api/v1/common"
 	"gopkg.in/yaml.v2"
@@ -25,12 +27,21 @@ type LayoutKind string
 
 const (
 	KindGridLayout LayoutKind = "Grid"
+	KindRowsLayout LayoutKind = "Rows"
+	KindTabsLayout LayoutKind = "Tabs"
+	KindFlexLayout LayoutKind = "Flex"
 )
 
 var layoutKindMap = map[LayoutKind]bool{
 	KindGridLayout: true,
+	KindRowsLayout: true,
+	KindTabsLayout: true,
+	KindFlexLayout: true,
 }
 
+// GridColumns is the number of columns of a grid, like in the UI.
+const GridColumns = 24
+
 func (k *LayoutKind) UnmarshalJSON(data []byte) error {
 	var tmp LayoutKind
 	type plain LayoutKind
@@ -62,17 +73,42 @@ func (k *LayoutKind) validate() error {
 		return fmt.Errorf("layout.kind cannot be empty")
 	}
 	if _, ok := layoutKindMap[*k]; !ok {
-		return fmt.Errorf("unknown layout.kind %q used", *k)
+		if _, isPlugin := pluginLayoutKinds.Load(*k); !isPlugin {
+			return fmt.Errorf("unknown layout.kind %q used", *k)
+		}
 	}
 	return nil
 }
 
+// IsPlugin returns true when the kind is not one of the kinds known by Perses, but a kind provided by a plugin.
+func (k LayoutKind) IsPlugin() bool {
+	return !layoutKindMap[k]
+}
+
+// pluginLayoutKinds is the set of the kinds of layout provided by the plugins, see RegisterPluginLayoutKinds.
+var pluginLayoutKinds sync.Map
+
+// RegisterPluginLayoutKinds records the kinds of layout provided by the plugins loaded, so they are accepted
+// in addition to the kinds known by Perses. Like RegisterPluginVariableKinds, a kind stays registered once its plugin is removed.
+func RegisterPluginLayoutKinds(kinds ...string) {
+	for _, kind := range kinds {
+		pluginLayoutKinds.Store(LayoutKind(kind), true)
+	}
+}
+
 type GridItem struct {
 	X       int             `json:"x" yaml:"x"`
 	Y       int             `json:"y" yaml:"y"`
 	Width   int             `json:"width" yaml:"width"`
 	Height  int             `json:"height" yaml:"height"`
 	Content *common.JSONRef `json:"content" yaml:"content"`
+	// Repeat, when set, is repeating the panel once per value selected for a variable.
+	Repeat *ItemRepeat `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+}
+
+// overlaps returns true when the two items are sharing at least one cell of the grid.
+func (i GridItem) overlaps(other GridItem) bool {
+	return i.X < other.X+other.Width && other.X < i.X+i.Width && i.Y < other.Y+other.Height && other.Y < i.Y+i.Height
 }
 
 type GridLayoutCollapse struct {
@@ -89,7 +125,266 @@ type GridLayoutSpec struct {
 	Items   []GridItem         `json:"items" yaml:"items"`
 }
 
+func (s *GridLayoutSpec) PanelReferences() []PanelReference {
+	return gridPanelReferences("/items", s.Items)
+}
+
+func (s *GridLayoutSpec) Validate() error {
+	var report common.ValidationReport
+	validateGridItems(&report, "/items", s.Items)
+	return report.Err()
+}
+
+// RowLayout is a row of a Rows layout: a grid with a title, that can be collapsed.
+type RowLayout struct {
+	Title    string              `json:"title" yaml:"title"`
+	Collapse *GridLayoutCollapse `json:"collapse,omitempty" yaml:"collapse,omitempty"`
+	// Repeat, when set, is repeating the row and its panels once per value selected for a variable.
+	Repeat *RowRepeat `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+	Items  []GridItem `json:"items" yaml:"items"`
+}
+
+// RowsLayoutSpec is a list of rows displayed one below the other. The positions of the items are relative to their row.
+type RowsLayoutSpec struct {
+	Rows []RowLayout `json:"rows" yaml:"rows"`
+}
+
+func (s *RowsLayoutSpec) PanelReferences() []PanelReference {
+	var result []PanelReference
+	for i, row := range s.Rows {
+		result = append(result, gridPanelReferences(fmt.Sprintf("/rows/%d/items", i), row.Items)...)
+	}
+	return result
+}
+
+func (s *RowsLayoutSpec) Validate() error {
+	var report common.ValidationReport
+	if len(s.Rows) == 0 {
+		report.AddError("/rows", "a Rows layout must have at least one row")
+	}
+	for i, row := range s.Rows {
+		if row.Repeat != nil {
+			row.Repeat.validate(&report, fmt.Sprintf("/rows/%d/repeat", i))
+		}
+		validateGridItems(&report, fmt.Sprintf("/rows/%d/items", i), row.Items)
+	}
+	return report.Err()
+}
+
+// TabLayout is a tab of a Tabs layout: a grid with a title, displayed only when the tab is selected.
+type TabLayout struct {
+	Title string     `json:"title" yaml:"title"`
+	Items []GridItem `json:"items" yaml:"items"`
+}
+
+// TabsLayoutSpec is a list of tabs, only one of them being displayed at once.
+type TabsLayoutSpec struct {
+	Tabs []TabLayout `json:"tabs" yaml:"tabs"`
+}
+
+func (s *TabsLayoutSpec) PanelReferences() []PanelReference {
+	var result []PanelReference
+	for i, tab := range s.Tabs {
+		result = append(result, gridPanelReferences(fmt.Sprintf("/tabs/%d/items", i), tab.Items)...)
+	}
+	return result
+}
+
+func (s *TabsLayoutSpec) Validate() error {
+	var report common.ValidationReport
+	if len(s.Tabs) == 0 {
+		report.AddError("/tabs", "a Tabs layout must have at least one tab")
+	}
+	titles := make(map[string]bool)
+	for i, tab := range s.Tabs {
+		path := fmt.Sprintf("/tabs/%d", i)
+		if len(tab.Title) == 0 {
+			report.AddError(path+"/title", "the title of a tab cannot be empty")
+		} else if titles[tab.Title] {
+			report.AddError(path+"/title", "the title %q is used by several tabs", tab.Title)
+		}
+		titles[tab.Title] = true
+		validateGridItems(&report, path+"/items", tab.Items)
+	}
+	return report.Err()
+}
+
+type FlexDirection string
+
+const (
+	FlexDirectionRow    FlexDirection = "row"
+	FlexDirectionColumn FlexDirection = "column"
+)
+
+// FlexItem is a panel of a Flex layout. Its size is computed by the UI, according to the space available.
+type FlexItem struct {
+	// Grow is the share of the remaining space taken by the item, relatively to the other items. 0 means the item doesn't grow.
+	Grow    int             `json:"grow,omitempty" yaml:"grow,omitempty"`
+	Content *common.JSONRef `json:"content" yaml:"content"`
+}
+
+// FlexLayoutSpec is a list of panels displayed one after the other, in a row or in a column, wrapping when there is no space left.
+type FlexLayoutSpec struct {
+	// Direction is the direction in which the items are placed. It is FlexDirectionRow by default.
+	Direction FlexDirection `json:"direction,omitempty" yaml:"direction,omitempty"`
+	Wrap      bool          `json:"wrap,omitempty" yaml:"wrap,omitempty"`
+	Items     []FlexItem    `json:"items" yaml:"items"`
+}
+
+func (s *FlexLayoutSpec) PanelReferences() []PanelReference {
+	result := make([]PanelReference, 0, len(s.Items))
+	for i, item := range s.Items {
+		result = append(result, PanelReference{Path: fmt.Sprintf("/items/%d/content", i), Ref: item.Content})
+	}
+	return result
+}
+
+func (s *FlexLayoutSpec) Validate() error {
+	var report common.ValidationReport
+	if len(s.Direction) > 0 && s.Direction != FlexDirectionRow && s.Direction != FlexDirectionColumn {
+		report.AddError("/direction", "unknown direction %q, it must be %q or %q", s.Direction, FlexDirectionRow, FlexDirectionColumn)
+	}
+	for i, item := range s.Items {
+		if item.Grow < 0 {
+			report.AddError(fmt.Sprintf("/items/%d/grow", i), "grow cannot be negative")
+		}
+	}
+	return report.Err()
+}
+
+// PanelReference is a reference to a panel used in a layout.
+type PanelReference struct {
+	// Path is the JSON pointer of the reference, relative to the spec of the layout, e.g. "/items/1/content".
+	Path string
+	Ref  *common.JSONRef
+}
+
 type LayoutSpec interface {
+	// PanelReferences returns every reference to a panel used in the layout, in the order they appear.
+	PanelReferences() []PanelReference
+	// Validate returns a common.ValidationReport with the problems found in the layout, or nil.
+	// The paths of the report are relative to the spec of the layout.
+	Validate() error
+}
+
+func gridPanelReferences(path string, items []GridItem) []PanelReference {
+	result := make([]PanelReference, 0, len(items))
+	for i, item := range items {
+		result = append(result, PanelReference{Path: fmt.Sprintf("%s/%d/content", path, i), Ref: item.Content})
+	}
+	return result
+}
+
+// PluginLayoutSpec is the spec of a layout whose kind is provided by a plugin.
+// It is kept as it is, since it is only validated by the CUE schema of the plugin.
+// The only thing Perses knows about it are the references to the panels, i.e. the objects {"$ref": "#/spec/panels/<name>"}
+// that can be found at any level of the spec.
+type PluginLayoutSpec map[string]interface{}
+
+func (s PluginLayoutSpec) PanelReferences() []PanelReference {
+	var result []PanelReference
+	walkPluginLayoutRefs("", map[string]interface{}(s), func(path string, ref string) {
+		// an invalid reference is reported by Validate
+		if jsonRef, err := parsePluginLayoutRef(ref); err == nil {
+			result = append(result, PanelReference{Path: path, Ref: jsonRef})
+		}
+	})
+	return result
+}
+
+func (s PluginLayoutSpec) Validate() error {
+	var report common.ValidationReport
+	walkPluginLayoutRefs("", map[string]interface{}(s), func(path string, ref string) {
+		if _, err := parsePluginLayoutRef(ref); err != nil {
+			report.AddError(path, "%s", err)
+		}
+	})
+	return report.Err()
+}
+
+func parsePluginLayoutRef(ref string) (*common.JSONRef, error) {
+	data, err := json.Marshal(map[string]string{"$ref": ref})
+	if err != nil {
+		return nil, err
+	}
+	result := &common.JSONRef{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walkPluginLayoutRefs calls f with the path and the value of every reference found in the value, in a stable order.
+// The maps can come from JSON or from YAML, which is using interface{} for the keys.
+func walkPluginLayoutRefs(path string, value interface{}, f func(path string, ref string)) {
+	var object map[string]interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			walkPluginLayoutRefs(fmt.Sprintf("%s/%d", path, i), item, f)
+		}
+		return
+	case map[interface{}]interface{}:
+		object = make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[fmt.Sprint(key)] = item
+		}
+	case map[string]interface{}:
+		object = v
+	default:
+		return
+	}
+	if ref, ok := object["$ref"].(string); ok {
+		f(path, ref)
+		return
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		walkPluginLayoutRefs(path+common.JSONPointer(key), object[key], f)
+	}
+}
+
+// validateGridItems verifies the geometry of the items of a grid: their position and size must be positive,
+// they must fit in the GridColumns columns of the grid and they cannot overlap each other.
+func validateGridItems(report *common.ValidationReport, path string, items []GridItem) {
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s/%d", path, i)
+		if item.Repeat != nil {
+			item.Repeat.validate(report, itemPath+"/repeat")
+		}
+		valid := true
+		if item.X < 0 {
+			report.AddError(itemPath+"/x", "x cannot be negative")
+			valid = false
+		}
+		if item.Y < 0 {
+			report.AddError(itemPath+"/y", "y cannot be negative")
+			valid = false
+		}
+		if item.Width <= 0 {
+			report.AddError(itemPath+"/width", "width must be greater than 0")
+			valid = false
+		}
+		if item.Height <= 0 {
+			report.AddError(itemPath+"/height", "height must be greater than 0")
+			valid = false
+		}
+		if !valid {
+			continue
+		}
+		if item.X+item.Width > GridColumns {
+			report.AddError(itemPath+"/width", "the item is going beyond the %d columns of the grid: x + width = %d", GridColumns, item.X+item.Width)
+		}
+		for j := 0; j < i; j++ {
+			if items[j].Width > 0 && items[j].Height > 0 && item.overlaps(items[j]) {
+				report.AddError(itemPath, "the item is overlapping the item %d", j)
+			}
+		}
+	}
 }
 
 type tmpDashboardLayout struct {
@@ -121,17 +416,25 @@ func (d *Layout) unmarshal(unmarshal func(interface{}) error, staticMarshal func
 	d.Kind = tmpLayout.Kind
 
 	if len(tmpLayout.Kind) == 0 {
-		return fmt.Errorf("variable.kind cannot be empty")
+		return fmt.Errorf("layout.kind cannot be empty")
 	}
 
 	rawParameter, err := staticMarshal(tmpLayout.Spec)
 	if err != nil {
 		return err
 	}
-	var spec interface{}
+	var spec LayoutSpec
 	switch tmpLayout.Kind {
 	case KindGridLayout:
 		spec = &GridLayoutSpec{}
+	case KindRowsLayout:
+		spec = &RowsLayoutSpec{}
+	case KindTabsLayout:
+		spec = &TabsLayoutSpec{}
+	case KindFlexLayout:
+		spec = &FlexLayoutSpec{}
+	default:
+		spec = &PluginLayoutSpec{}
 	}
 	if err := staticUnmarshal(rawParameter, spec); err != nil {
 		return err
@@ -15,6 +15,8 @@ package dashboard
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/perses/perses/pkg/model/api/v1/common"
@@ -144,6 +146,184 @@ func TestUnmarshalJSONLayout(t *testing.T) {
 				},
 			},
 		},
+		{
+			title: "rows layout",
+			jason: `
+{
+  "kind": "Rows",
+  "spec": {
+    "rows": [
+      {
+        "title": "CPU",
+        "collapse": { "open": false },
+        "items": [
+          {
+            "x": 0,
+            "y": 0,
+            "width": 12,
+            "height": 6,
+            "content": { "$ref": "#/panels/gaugeCpuBusy" }
+          }
+        ]
+      }
+    ]
+  }
+}
+`,
+			result: Layout{
+				Kind: KindRowsLayout,
+				Spec: &RowsLayoutSpec{
+					Rows: []RowLayout{
+						{
+							Title:    "CPU",
+							Collapse: &GridLayoutCollapse{Open: false},
+							Items: []GridItem{
+								{
+									X:      0,
+									Y:      0,
+									Width:  12,
+									Height: 6,
+									Content: &common.JSONRef{
+										Ref:  "#/panels/gaugeCpuBusy",
+										Path: []string{"panels", "gaugeCpuBusy"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			title: "repeated rows and panels",
+			jason: `
+{
+  "kind": "Rows",
+  "spec": {
+    "rows": [
+      {
+        "title": "$job",
+        "repeat": { "variable": "job" },
+        "items": [
+          {
+            "x": 0,
+            "y": 0,
+            "width": 12,
+            "height": 6,
+            "content": { "$ref": "#/panels/gaugeCpuBusy" },
+            "repeat": { "variable": "instance", "direction": "horizontal", "max_per_row": 4 }
+          }
+        ]
+      }
+    ]
+  }
+}
+`,
+			result: Layout{
+				Kind: KindRowsLayout,
+				Spec: &RowsLayoutSpec{
+					Rows: []RowLayout{
+						{
+							Title:  "$job",
+							Repeat: &RowRepeat{Variable: "job"},
+							Items: []GridItem{
+								{
+									X:      0,
+									Y:      0,
+									Width:  12,
+									Height: 6,
+									Content: &common.JSONRef{
+										Ref:  "#/panels/gaugeCpuBusy",
+										Path: []string{"panels", "gaugeCpuBusy"},
+									},
+									Repeat: &ItemRepeat{Variable: "instance", Direction: RepeatDirectionHorizontal, MaxPerRow: 4},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			title: "tabs layout",
+			jason: `
+{
+  "kind": "Tabs",
+  "spec": {
+    "tabs": [
+      {
+        "title": "CPU",
+        "items": [
+          {
+            "x": 0,
+            "y": 0,
+            "width": 12,
+            "height": 6,
+            "content": { "$ref": "#/panels/gaugeCpuBusy" }
+          }
+        ]
+      }
+    ]
+  }
+}
+`,
+			result: Layout{
+				Kind: KindTabsLayout,
+				Spec: &TabsLayoutSpec{
+					Tabs: []TabLayout{
+						{
+							Title: "CPU",
+							Items: []GridItem{
+								{
+									X:      0,
+									Y:      0,
+									Width:  12,
+									Height: 6,
+									Content: &common.JSONRef{
+										Ref:  "#/panels/gaugeCpuBusy",
+										Path: []string{"panels", "gaugeCpuBusy"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			title: "flex layout",
+			jason: `
+{
+  "kind": "Flex",
+  "spec": {
+    "direction": "column",
+    "wrap": true,
+    "items": [
+      {
+        "grow": 2,
+        "content": { "$ref": "#/panels/gaugeCpuBusy" }
+      }
+    ]
+  }
+}
+`,
+			result: Layout{
+				Kind: KindFlexLayout,
+				Spec: &FlexLayoutSpec{
+					Direction: FlexDirectionColumn,
+					Wrap:      true,
+					Items: []FlexItem{
+						{
+							Grow: 2,
+							Content: &common.JSONRef{
+								Ref:  "#/panels/gaugeCpuBusy",
+								Path: []string{"panels", "gaugeCpuBusy"},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
@@ -271,3 +451,71 @@ spec:
 		})
 	}
 }
+
+func TestPluginLayoutSpec(t *testing.T) {
+	RegisterPluginLayoutKinds("Masonry")
+	yamele := `
+kind: Masonry
+spec:
+  columns:
+  - width: 2
+    items:
+    - content:
+        $ref: "#/spec/panels/CPU"
+    - content:
+        $ref: "#/spec/panels/Memory"
+  header/footer:
+    content:
+      $ref: "#/spec/panels/Summary"
+`
+	result := Layout{}
+	assert.NoError(t, yaml.Unmarshal([]byte(yamele), &result))
+	assert.Equal(t, LayoutKind("Masonry"), result.Kind)
+	assert.NoError(t, result.Spec.Validate())
+	assert.Equal(t, []PanelReference{
+		{Path: "/columns/0/items/0/content", Ref: &common.JSONRef{Ref: "#/spec/panels/CPU", Path: []string{"spec", "panels", "CPU"}}},
+		{Path: "/columns/0/items/1/content", Ref: &common.JSONRef{Ref: "#/spec/panels/Memory", Path: []string{"spec", "panels", "Memory"}}},
+		{Path: "/header~1footer/content", Ref: &common.JSONRef{Ref: "#/spec/panels/Summary", Path: []string{"spec", "panels", "Summary"}}},
+	}, result.Spec.PanelReferences())
+
+	invalid := PluginLayoutSpec{"items": []interface{}{map[string]interface{}{"content": map[string]interface{}{"$ref": "panels/CPU"}}}}
+	var report common.ValidationReport
+	if assert.True(t, errors.As(invalid.Validate(), &report)) {
+		assert.Equal(t, "/items/0/content", report[0].Path)
+	}
+	assert.Empty(t, invalid.PanelReferences())
+}
+
+func TestUnmarshalLayoutError(t *testing.T) {
+	testSuite := []struct {
+		title string
+		jsone string
+		err   error
+	}{
+		{
+			title: "no layout kind",
+			jsone: `
+{
+  "spec": {}
+}
+`,
+			err: fmt.Errorf("layout.kind cannot be empty"),
+		},
+		{
+			title: "unsupported layout kind",
+			jsone: `
+{
+  "kind": "Awkward",
+  "spec": {}
+}
+`,
+			err: fmt.Errorf("unknown layout.kind \"Awkward\" used"),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result := &Layout{}
+			assert.Equal(t, test.err, json.Unmarshal([]byte(test.jsone), result))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"fmt"
+
+	"github.com/perses/perses/pkg/model/api/v1/common"
+)
+
+type RepeatDirection string
+
+const (
+	RepeatDirectionHorizontal RepeatDirection = "horizontal"
+	RepeatDirectionVertical   RepeatDirection = "vertical"
+)
+
+// ItemRepeat repeats the panel of an item of a grid once per value selected for a variable.
+type ItemRepeat struct {
+	// Variable is the name of the variable. It must accept several values.
+	Variable string `json:"variable" yaml:"variable"`
+	// Direction is the direction in which the copies of the panel are placed. It is RepeatDirectionHorizontal by default.
+	Direction RepeatDirection `json:"direction,omitempty" yaml:"direction,omitempty"`
+	// MaxPerRow is the maximum number of copies placed on the same line when the direction is horizontal.
+	// By default, every copy is placed on the same line, as long as there is at least one column for each of them.
+	MaxPerRow int `json:"max_per_row,omitempty" yaml:"max_per_row,omitempty"`
+}
+
+func (r *ItemRepeat) validate(report *common.ValidationReport, path string) {
+	if len(r.Variable) == 0 {
+		report.AddError(path+"/variable", "the variable used to repeat the panel cannot be empty")
+	}
+	if len(r.Direction) > 0 && r.Direction != RepeatDirectionHorizontal && r.Direction != RepeatDirectionVertical {
+		report.AddError(path+"/direction", "unknown direction %q, it must be %q or %q", r.Direction, RepeatDirectionHorizontal, RepeatDirectionVertical)
+	}
+	if r.MaxPerRow < 0 {
+		report.AddError(path+"/max_per_row", "max_per_row cannot be negative")
+	} else if r.MaxPerRow > 0 && r.Direction == RepeatDirectionVertical {
+		report.AddError(path+"/max_per_row", "max_per_row can only be used with the direction %q", RepeatDirectionHorizontal)
+	}
+}
+
+// RowRepeat repeats a row, with all its panels, once per value selected for a variable.
+type RowRepeat struct {
+	// Variable is the name of the variable. It must accept several values.
+	Variable string `json:"variable" yaml:"variable"`
+}
+
+func (r *RowRepeat) validate(report *common.ValidationReport, path string) {
+	if len(r.Variable) == 0 {
+		report.AddError(path+"/variable", "the variable used to repeat the row cannot be empty")
+	}
+}
+
+// RepeatReference is a variable used to repeat a panel or a row of a layout.
+type RepeatReference struct {
+	// Path is the JSON pointer of the variable, relative to the spec of the layout, e.g. "/items/1/repeat/variable".
+	Path     string
+	Variable string
+}
+
+// LayoutRepeats returns every variable used to repeat a panel or a row of the layout, in the order they appear.
+func LayoutRepeats(spec LayoutSpec) []RepeatReference {
+	var result []RepeatReference
+	switch s := spec.(type) {
+	case *GridLayoutSpec:
+		result = gridRepeats("/items", s.Items)
+	case *RowsLayoutSpec:
+		for i, row := range s.Rows {
+			if row.Repeat != nil {
+				result = append(result, RepeatReference{Path: fmt.Sprintf("/rows/%d/repeat/variable", i), Variable: row.Repeat.Variable})
+			}
+			result = append(result, gridRepeats(fmt.Sprintf("/rows/%d/items", i), row.Items)...)
+		}
+	case *TabsLayoutSpec:
+		for i, tab := range s.Tabs {
+			result = append(result, gridRepeats(fmt.Sprintf("/tabs/%d/items", i), tab.Items)...)
+		}
+	}
+	return result
+}
+
+func gridRepeats(path string, items []GridItem) []RepeatReference {
+	var result []RepeatReference
+	for i, item := range items {
+		if item.Repeat != nil {
+			result = append(result, RepeatReference{Path: fmt.Sprintf("%s/%d/repeat/variable", path, i), Variable: item.Repeat.Variable})
+		}
+	}
+	return result
+}
+
+// ExpansionRequest is the body of the request used to expand the panels and the rows repeated in a dashboard.
+type ExpansionRequest struct {
+	// Selected is the list of the values selected for each variable used to repeat a panel or a row.
+	// When a variable is omitted, its default selection is used, unless it is AllSelection.
+	Selected map[string]Selection `json:"selected,omitempty" yaml:"selected,omitempty"`
+}
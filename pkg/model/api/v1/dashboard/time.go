@@ -0,0 +1,125 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	// the timezone database is embedded, so the timezones can be verified even where it isn't installed.
+	_ "time/tzdata"
+
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/prometheus/common/model"
+)
+
+// TimeSettings completes the duration of a dashboard with the settings of its time range and of its refresh.
+type TimeSettings struct {
+	// RefreshInterval is the interval used by default to refresh the dashboard. The dashboard isn't refreshed when it is omitted.
+	RefreshInterval model.Duration `json:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+	// RefreshIntervals is the list of the intervals the user can choose from to refresh the dashboard.
+	// When it is set, it must contain the RefreshInterval.
+	RefreshIntervals []model.Duration `json:"refresh_intervals,omitempty" yaml:"refresh_intervals,omitempty"`
+	// Timezone is the name of the timezone used to display the time, from the IANA Time Zone database (e.g "Europe/Paris" or "UTC").
+	// The timezone of the browser is used when it is omitted.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	// Start and End are an absolute time range, displayed by default instead of the duration of the dashboard.
+	// They must be set together.
+	Start *time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+	// NowDelay is removed from now when the time range is relative,
+	// so the latest data, not completely ingested by the datasources yet, are not displayed.
+	NowDelay model.Duration `json:"now_delay,omitempty" yaml:"now_delay,omitempty"`
+}
+
+// Validate returns a common.ValidationReport with every problem found in the settings.
+// The paths of the report are relative to the settings.
+func (t *TimeSettings) Validate() error {
+	var report common.ValidationReport
+	for i, interval := range t.RefreshIntervals {
+		if interval <= 0 {
+			report.AddError(fmt.Sprintf("/refresh_intervals/%d", i), "a refresh interval must be a positive duration")
+		}
+	}
+	if t.RefreshInterval > 0 && len(t.RefreshIntervals) > 0 && !containsDuration(t.RefreshIntervals, t.RefreshInterval) {
+		report.AddError("/refresh_interval", "the refresh interval %q must be one of the refresh_intervals", t.RefreshInterval)
+	}
+	if len(t.Timezone) > 0 {
+		if _, err := time.LoadLocation(t.Timezone); err != nil || t.Timezone == "Local" {
+			report.AddError("/timezone", "unknown timezone %q", t.Timezone)
+		}
+	}
+	if t.Start == nil && t.End != nil {
+		report.AddError("/start", "start must be set with end")
+	} else if t.Start != nil && t.End == nil {
+		report.AddError("/end", "end must be set with start")
+	} else if t.Start != nil && !t.End.After(*t.Start) {
+		report.AddError("/end", "end must be after start")
+	}
+	return report.Err()
+}
+
+// TimeRange returns the time range displayed by default: the absolute time range when it is set,
+// or the duration before now, minus the now delay. The settings can be nil.
+func (t *TimeSettings) TimeRange(now time.Time, duration model.Duration) (start time.Time, end time.Time) {
+	if t != nil && t.Start != nil && t.End != nil {
+		return *t.Start, *t.End
+	}
+	end = now
+	if t != nil {
+		end = now.Add(-time.Duration(t.NowDelay))
+	}
+	return end.Add(-time.Duration(duration)), end
+}
+
+func containsDuration(durations []model.Duration, duration model.Duration) bool {
+	for _, d := range durations {
+		if d == duration {
+			return true
+		}
+	}
+	return false
+}
+
+// PanelTime overrides the time range of the dashboard for a panel.
+type PanelTime struct {
+	// Relative replaces the duration of the dashboard: the panel displays the data of this duration before the end of the time range.
+	Relative model.Duration `json:"relative,omitempty" yaml:"relative,omitempty"`
+	// Shift moves the time range of the panel back in time, e.g "1w" to compare with the previous week.
+	Shift model.Duration `json:"shift,omitempty" yaml:"shift,omitempty"`
+}
+
+// ExtractPanelTime returns the time override of the given panel, set in its field time, or nil when the panel is using the time range of the dashboard.
+// Like the rest of the panel, the field is verified by the CUE schemas.
+func ExtractPanelTime(panel json.RawMessage) (*PanelTime, error) {
+	var tmp struct {
+		Time *PanelTime `json:"time,omitempty"`
+	}
+	if err := json.Unmarshal(panel, &tmp); err != nil {
+		return nil, err
+	}
+	return tmp.Time, nil
+}
+
+// TimeRange returns the time range of the panel from the time range of the dashboard. The override can be nil.
+func (p *PanelTime) TimeRange(start time.Time, end time.Time) (time.Time, time.Time) {
+	if p == nil {
+		return start, end
+	}
+	if p.Relative > 0 {
+		start = end.Add(-time.Duration(p.Relative))
+	}
+	shift := time.Duration(p.Shift)
+	return start.Add(-shift), end.Add(-shift)
+}
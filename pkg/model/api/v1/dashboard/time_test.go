@@ -0,0 +1,153 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTimeSettings(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		jason  string
+		result common.ValidationReport
+	}{
+		{
+			title: "every setting",
+			jason: `
+{
+  "refresh_interval": "30s",
+  "refresh_intervals": ["10s", "30s", "1m"],
+  "timezone": "Europe/Paris",
+  "start": "2022-10-01T00:00:00Z",
+  "end": "2022-10-02T00:00:00Z",
+  "now_delay": "1m"
+}
+`,
+		},
+		{
+			title: "invalid settings",
+			jason: `
+{
+  "refresh_interval": "5s",
+  "refresh_intervals": ["0s", "30s"],
+  "timezone": "Mars/Olympus_Mons",
+  "start": "2022-10-02T00:00:00Z",
+  "end": "2022-10-01T00:00:00Z"
+}
+`,
+			result: common.ValidationReport{
+				{Path: "/refresh_intervals/0", Severity: common.SeverityError, Message: "a refresh interval must be a positive duration"},
+				{Path: "/refresh_interval", Severity: common.SeverityError, Message: `the refresh interval "5s" must be one of the refresh_intervals`},
+				{Path: "/timezone", Severity: common.SeverityError, Message: `unknown timezone "Mars/Olympus_Mons"`},
+				{Path: "/end", Severity: common.SeverityError, Message: "end must be after start"},
+			},
+		},
+		{
+			title: "start without end",
+			jason: `
+{
+  "start": "2022-10-01T00:00:00Z"
+}
+`,
+			result: common.ValidationReport{
+				{Path: "/end", Severity: common.SeverityError, Message: "end must be set with start"},
+			},
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			settings := &TimeSettings{}
+			assert.NoError(t, json.Unmarshal([]byte(test.jason), settings))
+			err := settings.Validate()
+			if test.result == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var report common.ValidationReport
+			if assert.True(t, errors.As(err, &report)) {
+				assert.Equal(t, test.result, report)
+			}
+		})
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC)
+	testSuite := []struct {
+		title     string
+		settings  *TimeSettings
+		panelTime *PanelTime
+		start     time.Time
+		end       time.Time
+	}{
+		{
+			title: "duration of the dashboard",
+			start: now.Add(-6 * time.Hour),
+			end:   now,
+		},
+		{
+			title:    "duration of the dashboard with a delay",
+			settings: &TimeSettings{NowDelay: model.Duration(time.Minute)},
+			start:    now.Add(-6*time.Hour - time.Minute),
+			end:      now.Add(-time.Minute),
+		},
+		{
+			title:    "absolute time range",
+			settings: &TimeSettings{Start: &start, End: &end, NowDelay: model.Duration(time.Minute)},
+			start:    start,
+			end:      end,
+		},
+		{
+			title:     "panel with a relative time range",
+			panelTime: &PanelTime{Relative: model.Duration(time.Hour)},
+			start:     now.Add(-time.Hour),
+			end:       now,
+		},
+		{
+			title:     "panel shifted by a week",
+			settings:  &TimeSettings{Start: &start, End: &end},
+			panelTime: &PanelTime{Shift: model.Duration(7 * 24 * time.Hour)},
+			start:     start.Add(-7 * 24 * time.Hour),
+			end:       end.Add(-7 * 24 * time.Hour),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			start, end := test.panelTime.TimeRange(test.settings.TimeRange(now, model.Duration(6*time.Hour)))
+			assert.Equal(t, test.start, start)
+			assert.Equal(t, test.end, end)
+		})
+	}
+}
+
+func TestExtractPanelTime(t *testing.T) {
+	panelTime, err := ExtractPanelTime([]byte(`{"kind": "LineChart", "time": {"shift": "1w"}}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &PanelTime{Shift: model.Duration(7 * 24 * time.Hour)}, panelTime)
+	}
+	panelTime, err = ExtractPanelTime([]byte(`{"kind": "LineChart"}`))
+	if assert.NoError(t, err) {
+		assert.Nil(t, panelTime)
+	}
+}
@@ -17,7 +17,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
 )
 
@@ -79,6 +84,10 @@ const (
 	KindLabelNamesQueryVariable  VariableKind = "LabelNamesQuery"
 	KindLabelValuesQueryVariable VariableKind = "LabelValuesQuery"
 	KindConstantVariable         VariableKind = "Constant"
+	KindIntervalVariable         VariableKind = "Interval"
+	KindCustomVariable           VariableKind = "Custom"
+	KindTextBoxVariable          VariableKind = "TextBox"
+	KindDatasourceVariable       VariableKind = "Datasource"
 )
 
 var variableKindMap = map[VariableKind]bool{
@@ -86,6 +95,81 @@ var variableKindMap = map[VariableKind]bool{
 	KindLabelNamesQueryVariable:  true,
 	KindLabelValuesQueryVariable: true,
 	KindConstantVariable:         true,
+	KindIntervalVariable:         true,
+	KindCustomVariable:           true,
+	KindTextBoxVariable:          true,
+	KindDatasourceVariable:       true,
+}
+
+// multiValueKindMap is the list of the kinds of variable that can have several values selected.
+var multiValueKindMap = map[VariableKind]bool{
+	KindPromQLQueryVariable:      true,
+	KindLabelNamesQueryVariable:  true,
+	KindLabelValuesQueryVariable: true,
+	KindConstantVariable:         true,
+	KindCustomVariable:           true,
+}
+
+// AllSelection is the value selected when every value of a variable is selected.
+// It can only be used when the variable has IncludeAll set to true.
+const AllSelection = "$__all"
+
+// Selection is the list of the values selected for a variable.
+// A single value is written as a string, and several values as a list of strings.
+type Selection []string
+
+// IsAll returns true when the selection is AllSelection.
+func (s Selection) IsAll() bool {
+	return len(s) == 1 && s[0] == AllSelection
+}
+
+func (s Selection) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+func (s Selection) MarshalYAML() (interface{}, error) {
+	if len(s) == 1 {
+		return s[0], nil
+	}
+	return []string(s), nil
+}
+
+func (s *Selection) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*s = newSelection(value)
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("a selection must be a string or a list of strings")
+	}
+	*s = values
+	return nil
+}
+
+func (s *Selection) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*s = newSelection(value)
+		return nil
+	}
+	var values []string
+	if err := unmarshal(&values); err != nil {
+		return fmt.Errorf("a selection must be a string or a list of strings")
+	}
+	*s = values
+	return nil
+}
+
+func newSelection(value string) Selection {
+	if len(value) == 0 {
+		return nil
+	}
+	return Selection{value}
 }
 
 func (k *VariableKind) UnmarshalJSON(data []byte) error {
@@ -119,11 +203,31 @@ func (k *VariableKind) validate() error {
 		return fmt.Errorf("variable.kind cannot be empty")
 	}
 	if _, ok := variableKindMap[*k]; !ok {
-		return fmt.Errorf("unknown variable.kind %q used", *k)
+		if _, isPlugin := pluginVariableKinds.Load(*k); !isPlugin {
+			return fmt.Errorf("unknown variable.kind %q used", *k)
+		}
 	}
 	return nil
 }
 
+// IsPlugin returns true when the kind is not one of the kinds known by Perses, but a kind provided by a plugin.
+func (k VariableKind) IsPlugin() bool {
+	return !variableKindMap[k]
+}
+
+// pluginVariableKinds is the set of the kinds of variable provided by the plugins, see RegisterPluginVariableKinds.
+var pluginVariableKinds sync.Map
+
+// RegisterPluginVariableKinds records the kinds of variable provided by the plugins loaded, so they are accepted
+// in addition to the kinds known by Perses.
+// A kind stays registered once its plugin is removed, so the dashboards already using it can still be read.
+// Saving them is refused by the validation of the schemas instead.
+func RegisterPluginVariableKinds(kinds ...string) {
+	for _, kind := range kinds {
+		pluginVariableKinds.Store(VariableKind(kind), true)
+	}
+}
+
 type VariableParameter interface {
 }
 
@@ -307,11 +411,263 @@ func (v *ConstantVariableParameter) validate() error {
 	return nil
 }
 
+const (
+	// AutoInterval is the value of an Interval variable when the interval is computed from the time range.
+	AutoInterval = "auto"
+	// DefaultAutoStepCount is used when IntervalVariableParameter.AutoStepCount is not set.
+	DefaultAutoStepCount = 30
+	// DefaultAutoMinInterval is used when IntervalVariableParameter.AutoMinInterval is not set.
+	DefaultAutoMinInterval = model.Duration(10 * time.Second)
+)
+
+// IntervalVariableParameter is representing a list of durations the user can choose from.
+// When Auto is true, the value "auto" is also available. The interval is then computed from the time range.
+type IntervalVariableParameter struct {
+	VariableParameter `json:"-" yaml:"-"`
+	Values            []model.Duration `json:"values" yaml:"values"`
+	// Auto adds the value "auto" to the list of the values.
+	Auto bool `json:"auto,omitempty" yaml:"auto,omitempty"`
+	// AutoStepCount is the number of times the time range is divided to compute the "auto" interval.
+	// By default, it is DefaultAutoStepCount.
+	AutoStepCount int `json:"auto_step_count,omitempty" yaml:"auto_step_count,omitempty"`
+	// AutoMinInterval is the lower bound of the "auto" interval. By default, it is DefaultAutoMinInterval.
+	AutoMinInterval model.Duration `json:"auto_min_interval,omitempty" yaml:"auto_min_interval,omitempty"`
+}
+
+func (v *IntervalVariableParameter) UnmarshalJSON(data []byte) error {
+	var tmp IntervalVariableParameter
+	type plain IntervalVariableParameter
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *IntervalVariableParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp IntervalVariableParameter
+	type plain IntervalVariableParameter
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *IntervalVariableParameter) validate() error {
+	if len(v.Values) == 0 {
+		return fmt.Errorf("parameter.values cannot be empty for an Interval variable")
+	}
+	for _, value := range v.Values {
+		if value <= 0 {
+			return fmt.Errorf("parameter.values can only contain positive durations for an Interval variable")
+		}
+	}
+	if v.AutoStepCount < 0 {
+		return fmt.Errorf("parameter.auto_step_count cannot be negative")
+	}
+	if !v.Auto && (v.AutoStepCount > 0 || v.AutoMinInterval > 0) {
+		return fmt.Errorf("parameter.auto_step_count and parameter.auto_min_interval can only be used when parameter.auto is true")
+	}
+	return nil
+}
+
+// StringValues returns the values of the variable, starting by "auto" when it is enabled.
+func (v *IntervalVariableParameter) StringValues() []string {
+	var result []string
+	if v.Auto {
+		result = append(result, AutoInterval)
+	}
+	for _, value := range v.Values {
+		result = append(result, value.String())
+	}
+	return result
+}
+
+// ComputeAutoInterval returns the interval to use when "auto" is selected for the given time range.
+func (v *IntervalVariableParameter) ComputeAutoInterval(timeRange time.Duration) model.Duration {
+	stepCount := v.AutoStepCount
+	if stepCount <= 0 {
+		stepCount = DefaultAutoStepCount
+	}
+	minInterval := v.AutoMinInterval
+	if minInterval <= 0 {
+		minInterval = DefaultAutoMinInterval
+	}
+	interval := model.Duration((timeRange / time.Duration(stepCount)).Round(time.Second))
+	if interval < minInterval {
+		return minInterval
+	}
+	return interval
+}
+
+// CustomVariableOption is an option of a Custom variable.
+// Text is what the UI displays, while Value is what replaces the variable.
+type CustomVariableOption struct {
+	Text  string `json:"text" yaml:"text"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// CustomVariableParameter is representing a list of options written by the user.
+// Values is a comma-separated list of options. A comma that is part of an option must be escaped with a backslash.
+// An option can be either a simple value or a pair "text : value" when the text displayed must differ from the value.
+// For example: "1m, 5m, Last hour : 1h"
+type CustomVariableParameter struct {
+	VariableParameter `json:"-" yaml:"-"`
+	Values            string `json:"values" yaml:"values"`
+}
+
+func (v *CustomVariableParameter) UnmarshalJSON(data []byte) error {
+	var tmp CustomVariableParameter
+	type plain CustomVariableParameter
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *CustomVariableParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp CustomVariableParameter
+	type plain CustomVariableParameter
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *CustomVariableParameter) validate() error {
+	if len(strings.TrimSpace(v.Values)) == 0 {
+		return fmt.Errorf("parameter.values cannot be empty for a Custom variable")
+	}
+	values := make(map[string]bool)
+	for _, option := range v.Options() {
+		if len(option.Value) == 0 {
+			return fmt.Errorf("parameter.values cannot contain an empty option for a Custom variable")
+		}
+		if values[option.Value] {
+			return fmt.Errorf("the value %q is used more than once in parameter.values", option.Value)
+		}
+		values[option.Value] = true
+	}
+	return nil
+}
+
+// Options returns the options described by Values.
+func (v *CustomVariableParameter) Options() []CustomVariableOption {
+	var result []CustomVariableOption
+	var current strings.Builder
+	addOption := func() {
+		option := strings.TrimSpace(current.String())
+		current.Reset()
+		text, value, isPair := strings.Cut(option, " : ")
+		if !isPair {
+			text, value = option, option
+		}
+		result = append(result, CustomVariableOption{Text: strings.TrimSpace(text), Value: strings.TrimSpace(value)})
+	}
+	escaped := false
+	for _, c := range v.Values {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',':
+			addOption()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	addOption()
+	return result
+}
+
+// StringValues returns the value of every option.
+func (v *CustomVariableParameter) StringValues() []string {
+	var result []string
+	for _, option := range v.Options() {
+		result = append(result, option.Value)
+	}
+	return result
+}
+
+// TextBoxVariableParameter is representing a free text typed by the user.
+type TextBoxVariableParameter struct {
+	VariableParameter `json:"-" yaml:"-"`
+	// Value is the text used until the user types another one.
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// DatasourceVariableParameter is representing the list of the datasources of a given kind.
+// The variable can then be used as the name of the datasource of the dashboard, to switch all the queries to another datasource.
+type DatasourceVariableParameter struct {
+	VariableParameter `json:"-" yaml:"-"`
+	Kind              datasource.Kind `json:"kind" yaml:"kind"`
+	// If global is true, the variable is listing the global datasources.
+	// When set to false, it is listing the datasources of the same project as the current dashboard.
+	Global bool `json:"global,omitempty" yaml:"global,omitempty"`
+}
+
+func (v *DatasourceVariableParameter) UnmarshalJSON(data []byte) error {
+	var tmp DatasourceVariableParameter
+	type plain DatasourceVariableParameter
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *DatasourceVariableParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp DatasourceVariableParameter
+	type plain DatasourceVariableParameter
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *DatasourceVariableParameter) validate() error {
+	if len(v.Kind) == 0 {
+		return fmt.Errorf("parameter.kind cannot be empty for a Datasource variable")
+	}
+	return nil
+}
+
+// PluginVariableParameter is the parameter of a variable whose kind is provided by a plugin.
+// It is kept as it is, since it is only validated by the CUE schema of the plugin.
+type PluginVariableParameter map[string]interface{}
+
 type tmpDashboardVariable struct {
 	Kind          VariableKind           `json:"kind" yaml:"kind"`
 	DisplayedName string                 `json:"displayed_name,omitempty" yaml:"displayed_name,omitempty"`
 	Hide          bool                   `json:"hide" yaml:"hide"`
-	Selected      string                 `json:"selected,omitempty" yaml:"selected,omitempty"`
+	Selected      Selection              `json:"selected,omitempty" yaml:"selected,omitempty"`
+	Multi         bool                   `json:"multi,omitempty" yaml:"multi,omitempty"`
+	IncludeAll    bool                   `json:"include_all,omitempty" yaml:"include_all,omitempty"`
+	AllValue      string                 `json:"all_value,omitempty" yaml:"all_value,omitempty"`
 	Parameter     map[string]interface{} `json:"parameter" yaml:"parameter"`
 }
 
@@ -324,8 +680,16 @@ type Variable struct {
 	DisplayedName string `json:"displayed_name,omitempty" yaml:"displayed_name,omitempty"`
 	// Hide will be used by the UI to decide if the variable has to be displayed
 	Hide bool `json:"hide" yaml:"hide"`
-	// Selected is the variable selected by default if it exists
-	Selected  string            `json:"selected,omitempty" yaml:"selected,omitempty"`
+	// Selected is the list of the values selected by default if they exist.
+	// It can contain several values only when Multi is true, and it can be AllSelection when IncludeAll is true.
+	Selected Selection `json:"selected,omitempty" yaml:"selected,omitempty"`
+	// Multi allows the user to select several values.
+	Multi bool `json:"multi,omitempty" yaml:"multi,omitempty"`
+	// IncludeAll adds the possibility to select every value at once, with the value AllSelection.
+	IncludeAll bool `json:"include_all,omitempty" yaml:"include_all,omitempty"`
+	// AllValue is the value used in the queries instead of the list of every value when AllSelection is selected.
+	// For example, it can be `.*`. When it is empty or when it is AllSelection, the list of every value is used.
+	AllValue  string            `json:"all_value,omitempty" yaml:"all_value,omitempty"`
 	Parameter VariableParameter `json:"parameter" yaml:"parameter"`
 }
 
@@ -349,6 +713,9 @@ func (d *Variable) unmarshal(unmarshal func(interface{}) error, staticMarshal fu
 	d.Selected = tmpVariable.Selected
 	d.Hide = tmpVariable.Hide
 	d.DisplayedName = tmpVariable.DisplayedName
+	d.Multi = tmpVariable.Multi
+	d.IncludeAll = tmpVariable.IncludeAll
+	d.AllValue = tmpVariable.AllValue
 
 	if len(tmpVariable.DisplayedName) == 0 && !d.Hide {
 		return fmt.Errorf("variable.displayed_name cannot be empty if the variable is not hidden")
@@ -356,6 +723,9 @@ func (d *Variable) unmarshal(unmarshal func(interface{}) error, staticMarshal fu
 	if len(tmpVariable.Kind) == 0 {
 		return fmt.Errorf("variable.kind cannot be empty")
 	}
+	if err := d.validateSelection(); err != nil {
+		return err
+	}
 
 	rawParameter, err := staticMarshal(tmpVariable.Parameter)
 	if err != nil {
@@ -371,6 +741,16 @@ func (d *Variable) unmarshal(unmarshal func(interface{}) error, staticMarshal fu
 		parameter = &LabelValuesQueryVariableParameter{}
 	case KindConstantVariable:
 		parameter = &ConstantVariableParameter{}
+	case KindIntervalVariable:
+		parameter = &IntervalVariableParameter{}
+	case KindCustomVariable:
+		parameter = &CustomVariableParameter{}
+	case KindTextBoxVariable:
+		parameter = &TextBoxVariableParameter{}
+	case KindDatasourceVariable:
+		parameter = &DatasourceVariableParameter{}
+	default:
+		parameter = &PluginVariableParameter{}
 	}
 	if err := staticUnmarshal(rawParameter, parameter); err != nil {
 		return err
@@ -378,3 +758,78 @@ func (d *Variable) unmarshal(unmarshal func(interface{}) error, staticMarshal fu
 	d.Parameter = parameter
 	return nil
 }
+
+func (d *Variable) validateSelection() error {
+	if (d.Multi || d.IncludeAll) && !multiValueKindMap[d.Kind] {
+		return fmt.Errorf("variable.multi and variable.include_all cannot be used with a variable of kind %q", d.Kind)
+	}
+	if len(d.AllValue) > 0 && !d.IncludeAll {
+		return fmt.Errorf("variable.all_value can only be used when variable.include_all is true")
+	}
+	if len(d.Selected) > 1 && !d.Multi {
+		return fmt.Errorf("variable.selected can only contain several values when variable.multi is true")
+	}
+	set := make(map[string]bool, len(d.Selected))
+	for _, value := range d.Selected {
+		if len(value) == 0 {
+			return fmt.Errorf("variable.selected cannot contain an empty value")
+		}
+		if value == AllSelection {
+			if !d.IncludeAll {
+				return fmt.Errorf("variable.selected cannot be %q when variable.include_all is false", AllSelection)
+			}
+			if len(d.Selected) > 1 {
+				return fmt.Errorf("variable.selected cannot contain %q and other values", AllSelection)
+			}
+		}
+		if set[value] {
+			return fmt.Errorf("the value %q is used more than once in variable.selected", value)
+		}
+		set[value] = true
+	}
+	return nil
+}
+
+// VariableEvaluationRequest is the body of the request used to compute the values of the variables of a dashboard.
+type VariableEvaluationRequest struct {
+	// Start and End are defining the time range used by the queries of the variables.
+	// When both are omitted, the time range of the dashboard is used, see TimeSettings.TimeRange.
+	// When only Start is omitted, it is End minus the duration of the dashboard.
+	Start time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	End   time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+	// Selected is the list of the values currently selected for each variable.
+	// It is used to replace the variable in the queries of the variables depending on it.
+	Selected map[string]Selection `json:"selected,omitempty" yaml:"selected,omitempty"`
+}
+
+func (v *VariableEvaluationRequest) UnmarshalJSON(data []byte) error {
+	var tmp VariableEvaluationRequest
+	type plain VariableEvaluationRequest
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*v = tmp
+	return nil
+}
+
+func (v *VariableEvaluationRequest) validate() error {
+	if !v.Start.IsZero() && !v.End.IsZero() && v.End.Before(v.Start) {
+		return fmt.Errorf("end cannot be before start")
+	}
+	return nil
+}
+
+// VariableEvaluationResult is the list of values computed for a variable.
+type VariableEvaluationResult struct {
+	Name   string   `json:"name" yaml:"name"`
+	Values []string `json:"values" yaml:"values"`
+	// Selected is the list of the values used to replace the variable in the queries of the variables depending on it.
+	// It is the values selected in the request that are still available, otherwise the default values of the variable,
+	// and finally the first value of the list. It is AllSelection when every value is selected.
+	Selected Selection `json:"selected,omitempty" yaml:"selected,omitempty"`
+	// Error is set when the values of the variable cannot be computed.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
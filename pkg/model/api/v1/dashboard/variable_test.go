@@ -18,12 +18,16 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/perses/perses/pkg/model/api/v1/datasource"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 )
 
 func TestUnmarshalJSONVariable(t *testing.T) {
+	RegisterPluginVariableKinds("SQLQuery")
 	testSuite := []struct {
 		title  string
 		jason  string
@@ -161,6 +165,161 @@ func TestUnmarshalJSONVariable(t *testing.T) {
 				},
 			},
 		},
+		{
+			title: "interval variable",
+			jason: `
+{
+  "kind": "Interval",
+  "hide": true,
+  "parameter": {
+    "values": ["1m", "5m", "1h"],
+    "auto": true,
+    "auto_step_count": 30,
+    "auto_min_interval": "30s"
+  }
+}
+`,
+			result: &Variable{
+				Kind: KindIntervalVariable,
+				Hide: true,
+				Parameter: &IntervalVariableParameter{
+					Values:          []model.Duration{model.Duration(time.Minute), model.Duration(5 * time.Minute), model.Duration(time.Hour)},
+					Auto:            true,
+					AutoStepCount:   30,
+					AutoMinInterval: model.Duration(30 * time.Second),
+				},
+			},
+		},
+		{
+			title: "custom variable",
+			jason: `
+{
+  "kind": "Custom",
+  "hide": true,
+  "parameter": {
+    "values": "1m, 5m, Last hour : 1h"
+  }
+}
+`,
+			result: &Variable{
+				Kind: KindCustomVariable,
+				Hide: true,
+				Parameter: &CustomVariableParameter{
+					Values: "1m, 5m, Last hour : 1h",
+				},
+			},
+		},
+		{
+			title: "text box variable",
+			jason: `
+{
+  "kind": "TextBox",
+  "displayed_name": "filter",
+  "parameter": {
+    "value": "api"
+  }
+}
+`,
+			result: &Variable{
+				Kind:          KindTextBoxVariable,
+				DisplayedName: "filter",
+				Parameter: &TextBoxVariableParameter{
+					Value: "api",
+				},
+			},
+		},
+		{
+			title: "datasource variable",
+			jason: `
+{
+  "kind": "Datasource",
+  "displayed_name": "datasource",
+  "parameter": {
+    "kind": "Prometheus",
+    "global": true
+  }
+}
+`,
+			result: &Variable{
+				Kind:          KindDatasourceVariable,
+				DisplayedName: "datasource",
+				Parameter: &DatasourceVariableParameter{
+					Kind:   datasource.PrometheusKind,
+					Global: true,
+				},
+			},
+		},
+		{
+			title: "variable with a single value selected",
+			jason: `
+{
+  "kind": "Constant",
+  "hide": true,
+  "selected": "prod",
+  "parameter": {
+    "values": ["dev", "prod"]
+  }
+}
+`,
+			result: &Variable{
+				Kind:     KindConstantVariable,
+				Hide:     true,
+				Selected: Selection{"prod"},
+				Parameter: &ConstantVariableParameter{
+					Values: []string{"dev", "prod"},
+				},
+			},
+		},
+		{
+			title: "multi-value variable including all",
+			jason: `
+{
+  "kind": "LabelValuesQuery",
+  "displayed_name": "instance",
+  "selected": ["demo:9090", "demo:9100"],
+  "multi": true,
+  "include_all": true,
+  "all_value": ".*",
+  "parameter": {
+    "label_name": "instance",
+    "capturing_regexp": "(.*)"
+  }
+}
+`,
+			result: &Variable{
+				Kind:          KindLabelValuesQueryVariable,
+				DisplayedName: "instance",
+				Selected:      Selection{"demo:9090", "demo:9100"},
+				Multi:         true,
+				IncludeAll:    true,
+				AllValue:      ".*",
+				Parameter: &LabelValuesQueryVariableParameter{
+					LabelName:       "instance",
+					CapturingRegexp: (*CapturingRegexp)(regexp.MustCompile("(.*)")),
+				},
+			},
+		},
+		{
+			title: "variable provided by a plugin",
+			jason: `
+{
+  "kind": "SQLQuery",
+  "hide": true,
+  "parameter": {
+    "query": "SELECT name FROM hosts",
+    "limit": 10
+  }
+}
+`,
+			result: &Variable{
+				Kind: "SQLQuery",
+				Hide: true,
+				Parameter: &PluginVariableParameter{
+					"query": "SELECT name FROM hosts",
+					"limit": float64(10),
+				},
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
@@ -287,6 +446,22 @@ parameter:
 				},
 			},
 		},
+		{
+			title: "interval variable",
+			yamele: `
+kind: "Interval"
+hide: true
+parameter:
+  values: ["1m", "1h"]
+`,
+			result: &Variable{
+				Kind: KindIntervalVariable,
+				Hide: true,
+				Parameter: &IntervalVariableParameter{
+					Values: []model.Duration{model.Duration(time.Minute), model.Duration(time.Hour)},
+				},
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
@@ -303,6 +478,16 @@ func TestUnmarshalVariableError(t *testing.T) {
 		jsone string
 		err   error
 	}{
+		{
+			title: "no variable kind",
+			jsone: `
+{
+  "kind": "",
+  "parameter": {}
+}
+`,
+			err: fmt.Errorf("variable.kind cannot be empty"),
+		},
 		{
 			title: "unsupported variable kind",
 			jsone: `
@@ -410,6 +595,155 @@ func TestUnmarshalVariableError(t *testing.T) {
 `,
 			err: fmt.Errorf("parameter.capturing_regexp cannot be empty for a PromQLQuery"),
 		},
+		{
+			title: "interval variable with no values",
+			jsone: `
+{
+  "kind": "Interval",
+  "hide": true,
+  "parameter": {
+    "auto": true
+  }
+}
+`,
+			err: fmt.Errorf("parameter.values cannot be empty for an Interval variable"),
+		},
+		{
+			title: "interval variable with auto options but without auto",
+			jsone: `
+{
+  "kind": "Interval",
+  "hide": true,
+  "parameter": {
+    "values": ["1m"],
+    "auto_step_count": 10
+  }
+}
+`,
+			err: fmt.Errorf("parameter.auto_step_count and parameter.auto_min_interval can only be used when parameter.auto is true"),
+		},
+		{
+			title: "custom variable with an empty option",
+			jsone: `
+{
+  "kind": "Custom",
+  "hide": true,
+  "parameter": {
+    "values": "1m,,5m"
+  }
+}
+`,
+			err: fmt.Errorf("parameter.values cannot contain an empty option for a Custom variable"),
+		},
+		{
+			title: "custom variable with a duplicated value",
+			jsone: `
+{
+  "kind": "Custom",
+  "hide": true,
+  "parameter": {
+    "values": "1h, Last hour : 1h"
+  }
+}
+`,
+			err: fmt.Errorf("the value %q is used more than once in parameter.values", "1h"),
+		},
+		{
+			title: "datasource variable without kind",
+			jsone: `
+{
+  "kind": "Datasource",
+  "hide": true,
+  "parameter": {}
+}
+`,
+			err: fmt.Errorf("parameter.kind cannot be empty for a Datasource variable"),
+		},
+		{
+			title: "multi-value variable of a kind having a single value",
+			jsone: `
+{
+  "kind": "TextBox",
+  "hide": true,
+  "multi": true,
+  "parameter": {}
+}
+`,
+			err: fmt.Errorf("variable.multi and variable.include_all cannot be used with a variable of kind %q", KindTextBoxVariable),
+		},
+		{
+			title: "all value without include all",
+			jsone: `
+{
+  "kind": "Constant",
+  "hide": true,
+  "all_value": ".*",
+  "parameter": {
+    "values": ["dev", "prod"]
+  }
+}
+`,
+			err: fmt.Errorf("variable.all_value can only be used when variable.include_all is true"),
+		},
+		{
+			title: "several values selected without multi",
+			jsone: `
+{
+  "kind": "Constant",
+  "hide": true,
+  "selected": ["dev", "prod"],
+  "parameter": {
+    "values": ["dev", "prod"]
+  }
+}
+`,
+			err: fmt.Errorf("variable.selected can only contain several values when variable.multi is true"),
+		},
+		{
+			title: "all selected without include all",
+			jsone: `
+{
+  "kind": "Constant",
+  "hide": true,
+  "selected": "$__all",
+  "parameter": {
+    "values": ["dev", "prod"]
+  }
+}
+`,
+			err: fmt.Errorf("variable.selected cannot be %q when variable.include_all is false", AllSelection),
+		},
+		{
+			title: "all selected with other values",
+			jsone: `
+{
+  "kind": "Constant",
+  "hide": true,
+  "multi": true,
+  "include_all": true,
+  "selected": ["$__all", "dev"],
+  "parameter": {
+    "values": ["dev", "prod"]
+  }
+}
+`,
+			err: fmt.Errorf("variable.selected cannot contain %q and other values", AllSelection),
+		},
+		{
+			title: "value selected twice",
+			jsone: `
+{
+  "kind": "Constant",
+  "hide": true,
+  "multi": true,
+  "selected": ["dev", "dev"],
+  "parameter": {
+    "values": ["dev", "prod"]
+  }
+}
+`,
+			err: fmt.Errorf("the value %q is used more than once in variable.selected", "dev"),
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
@@ -418,3 +752,71 @@ func TestUnmarshalVariableError(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalPluginVariableKind(t *testing.T) {
+	jason := `
+{
+  "kind": "GraphQLQuery",
+  "hide": true,
+  "parameter": {
+    "query": "{ hosts { name } }"
+  }
+}
+`
+	result := &Variable{}
+	assert.Equal(t, fmt.Errorf("unknown variable.kind \"GraphQLQuery\" used"), json.Unmarshal([]byte(jason), result))
+
+	RegisterPluginVariableKinds("GraphQLQuery")
+	result = &Variable{}
+	assert.NoError(t, json.Unmarshal([]byte(jason), result))
+	assert.Equal(t, &Variable{
+		Kind:      "GraphQLQuery",
+		Hide:      true,
+		Parameter: &PluginVariableParameter{"query": "{ hosts { name } }"},
+	}, result)
+}
+
+func TestCustomVariableOptions(t *testing.T) {
+	param := &CustomVariableParameter{Values: `1m, 5m,Last hour : 1h, a\,b`}
+	assert.Equal(t, []CustomVariableOption{
+		{Text: "1m", Value: "1m"},
+		{Text: "5m", Value: "5m"},
+		{Text: "Last hour", Value: "1h"},
+		{Text: "a,b", Value: "a,b"},
+	}, param.Options())
+}
+
+func TestMarshalSelection(t *testing.T) {
+	testSuite := []struct {
+		title     string
+		selection Selection
+		jason     string
+		yamele    string
+	}{
+		{
+			title:     "single value",
+			selection: Selection{"prod"},
+			jason:     `"prod"`,
+			yamele:    "prod\n",
+		},
+		{
+			title:     "several values",
+			selection: Selection{"dev", "prod"},
+			jason:     `["dev","prod"]`,
+			yamele:    "- dev\n- prod\n",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			data, err := json.Marshal(test.selection)
+			assert.NoError(t, err)
+			assert.Equal(t, test.jason, string(data))
+			data, err = yaml.Marshal(test.selection)
+			assert.NoError(t, err)
+			assert.Equal(t, test.yamele, string(data))
+			var result Selection
+			assert.NoError(t, yaml.Unmarshal(data, &result))
+			assert.Equal(t, test.selection, result)
+		})
+	}
+}
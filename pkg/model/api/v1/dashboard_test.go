@@ -15,6 +15,7 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
@@ -444,3 +445,386 @@ func TestUnmarshallDashboard(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
 }
+
+func TestUnmarshallDashboardReportsEveryError(t *testing.T) {
+	jsonDashboard := `{
+  "kind": "Dashboard",
+  "metadata": {
+    "name": "InvalidDashboard",
+    "project": "perses"
+  },
+  "spec": {
+    "datasource": {
+      "name": "PrometheusDemo",
+      "kind": "Prometheus"
+    },
+    "duration": "6h",
+    "panels": {
+      "~panel": {
+        "kind": "LineChart",
+        "display": {
+          "name": "simple line chart"
+        }
+      }
+    },
+    "layouts": [
+      {
+        "kind": "Grid",
+        "spec": {
+          "items": [
+            {
+              "x": 0,
+              "y": 0,
+              "width": 3,
+              "height": 4,
+              "content": {
+                "$ref": "#/spec/panels/MyPanel"
+              }
+            },
+            {
+              "x": 3,
+              "y": 0,
+              "width": 3,
+              "height": 4,
+              "content": {
+                "$ref": "#/spec/variables/MyVariable"
+              }
+            }
+          ]
+        }
+      }
+    ]
+  }
+}
+`
+	result := &Dashboard{}
+	err := json.Unmarshal([]byte(jsonDashboard), result)
+	expected := common.ValidationReport{
+		{
+			Path:     "/spec/layouts/0/spec/items/0/content",
+			Severity: common.SeverityError,
+			Message:  `there is no existing panel called "MyPanel" in the current dashboard`,
+		},
+		{
+			Path:     "/spec/layouts/0/spec/items/1/content",
+			Severity: common.SeverityError,
+			Message:  `"variables" is not a known object`,
+		},
+		{
+			Path:     "/spec/panels/~0panel",
+			Severity: common.SeverityError,
+			Message:  `panel reference "~panel" is containing spaces or special characters`,
+		},
+	}
+	var report common.ValidationReport
+	if assert.True(t, errors.As(err, &report)) {
+		assert.Equal(t, expected, report)
+	}
+}
+
+func TestUnmarshallDashboardLayouts(t *testing.T) {
+	jsonDashboard := `{
+  "kind": "Dashboard",
+  "metadata": {
+    "name": "Layouts",
+    "project": "perses"
+  },
+  "spec": {
+    "datasource": {
+      "name": "PrometheusDemo",
+      "kind": "Prometheus"
+    },
+    "duration": "6h",
+    "panels": {
+      "CPU": {"kind": "LineChart"},
+      "Memory": {"kind": "LineChart"}
+    },
+    "layouts": [
+      {
+        "kind": "Grid",
+        "spec": {
+          "items": [
+            {"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}},
+            {"x": 6, "y": 3, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/Memory"}},
+            {"x": 18, "y": 0, "width": 12, "height": -1, "content": {"$ref": "#/spec/panels/Memory"}}
+          ]
+        }
+      },
+      {
+        "kind": "Rows",
+        "spec": {
+          "rows": [
+            {"title": "CPU", "items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]},
+            {"title": "Memory", "items": [{"x": 12, "y": 0, "width": 24, "height": 6, "content": {"$ref": "#/spec/panels/Disk"}}]}
+          ]
+        }
+      },
+      {
+        "kind": "Tabs",
+        "spec": {
+          "tabs": [
+            {"title": "CPU", "items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]},
+            {"title": "CPU", "items": []}
+          ]
+        }
+      },
+      {
+        "kind": "Flex",
+        "spec": {
+          "direction": "diagonal",
+          "items": [{"content": {"$ref": "#/spec/panels/Network"}}]
+        }
+      },
+      {
+        "kind": "Masonry",
+        "spec": {
+          "columns": [
+            {"items": [{"content": {"$ref": "#/spec/panels/CPU"}}, {"content": {"$ref": "#/spec/panels/GPU"}}]}
+          ]
+        }
+      }
+    ]
+  }
+}
+`
+	dashboard.RegisterPluginLayoutKinds("Masonry")
+	result := &Dashboard{}
+	err := json.Unmarshal([]byte(jsonDashboard), result)
+	expected := common.ValidationReport{
+		{
+			Path:     "/spec/layouts/0/spec/items/1",
+			Severity: common.SeverityError,
+			Message:  "the item is overlapping the item 0",
+		},
+		{
+			Path:     "/spec/layouts/0/spec/items/2/height",
+			Severity: common.SeverityError,
+			Message:  "height must be greater than 0",
+		},
+		{
+			Path:     "/spec/layouts/1/spec/rows/1/items/0/content",
+			Severity: common.SeverityError,
+			Message:  `there is no existing panel called "Disk" in the current dashboard`,
+		},
+		{
+			Path:     "/spec/layouts/1/spec/rows/1/items/0/width",
+			Severity: common.SeverityError,
+			Message:  "the item is going beyond the 24 columns of the grid: x + width = 36",
+		},
+		{
+			Path:     "/spec/layouts/2/spec/tabs/1/title",
+			Severity: common.SeverityError,
+			Message:  `the title "CPU" is used by several tabs`,
+		},
+		{
+			Path:     "/spec/layouts/3/spec/direction",
+			Severity: common.SeverityError,
+			Message:  `unknown direction "diagonal", it must be "row" or "column"`,
+		},
+		{
+			Path:     "/spec/layouts/3/spec/items/0/content",
+			Severity: common.SeverityError,
+			Message:  `there is no existing panel called "Network" in the current dashboard`,
+		},
+		{
+			Path:     "/spec/layouts/4/spec/columns/0/items/1/content",
+			Severity: common.SeverityError,
+			Message:  `there is no existing panel called "GPU" in the current dashboard`,
+		},
+	}
+	var report common.ValidationReport
+	if assert.True(t, errors.As(err, &report)) {
+		assert.Equal(t, expected, report)
+	}
+}
+
+func TestUnmarshallDashboardRepeats(t *testing.T) {
+	jsonDashboard := `{
+  "kind": "Dashboard",
+  "metadata": {
+    "name": "Repeats",
+    "project": "perses"
+  },
+  "spec": {
+    "datasource": {
+      "name": "PrometheusDemo",
+      "kind": "Prometheus"
+    },
+    "duration": "6h",
+    "variables": {
+      "job": {"kind": "Constant", "hide": true, "parameter": {"values": ["node"]}},
+      "instance": {"kind": "Constant", "hide": true, "multi": true, "parameter": {"values": ["a", "b"]}}
+    },
+    "panels": {
+      "CPU": {"kind": "LineChart"}
+    },
+    "layouts": [
+      {
+        "kind": "Grid",
+        "spec": {
+          "items": [
+            {"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}, "repeat": {"variable": "instance", "max_per_row": 2}},
+            {"x": 0, "y": 6, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}, "repeat": {"variable": "job"}},
+            {"x": 0, "y": 12, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}, "repeat": {"variable": "instance", "direction": "vertical", "max_per_row": 2}}
+          ]
+        }
+      },
+      {
+        "kind": "Rows",
+        "spec": {
+          "rows": [
+            {"title": "$instance", "repeat": {"variable": "instance"}, "items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]},
+            {"title": "$mode", "repeat": {"variable": "mode"}, "items": [{"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "#/spec/panels/CPU"}}]}
+          ]
+        }
+      }
+    ]
+  }
+}
+`
+	result := &Dashboard{}
+	err := json.Unmarshal([]byte(jsonDashboard), result)
+	expected := common.ValidationReport{
+		{
+			Path:     "/spec/layouts/0/spec/items/1/repeat/variable",
+			Severity: common.SeverityError,
+			Message:  `the variable "job" used to repeat must accept several values, with multi or include_all`,
+		},
+		{
+			Path:     "/spec/layouts/0/spec/items/2/repeat/max_per_row",
+			Severity: common.SeverityError,
+			Message:  `max_per_row can only be used with the direction "horizontal"`,
+		},
+		{
+			Path:     "/spec/layouts/1/spec/rows/1/repeat/variable",
+			Severity: common.SeverityError,
+			Message:  `the variable "mode" used to repeat doesn't exist`,
+		},
+	}
+	var report common.ValidationReport
+	if assert.True(t, errors.As(err, &report)) {
+		assert.Equal(t, expected, report)
+	}
+}
+
+func TestUnmarshallDashboardLibraryPanels(t *testing.T) {
+	jsonDashboard := `{
+  "kind": "Dashboard",
+  "metadata": {
+    "name": "Library",
+    "project": "perses"
+  },
+  "spec": {
+    "datasource": {
+      "name": "PrometheusDemo",
+      "kind": "Prometheus"
+    },
+    "duration": "6h",
+    "layouts": [
+      {
+        "kind": "Grid",
+        "spec": {
+          "items": [
+            {"x": 0, "y": 0, "width": 12, "height": 6, "content": {"$ref": "/panels/BurnRate"}},
+            {"x": 12, "y": 0, "width": 12, "height": 6, "content": {"$ref": "/globalpanels/BurnRate"}},
+            {"x": 0, "y": 6, "width": 12, "height": 6, "content": {"$ref": "/panels/BurnRate"}}
+          ]
+        }
+      }
+    ]
+  }
+}
+`
+	result := &Dashboard{}
+	if assert.NoError(t, json.Unmarshal([]byte(jsonDashboard), result)) {
+		assert.Equal(t, []LibraryPanelReference{
+			{Path: "/layouts/0/spec/items/0/content", Name: "BurnRate"},
+			{Path: "/layouts/0/spec/items/1/content", Name: "BurnRate", Global: true},
+			{Path: "/layouts/0/spec/items/2/content", Name: "BurnRate"},
+		}, result.Spec.LibraryPanelReferences())
+		assert.Equal(t, &PanelUsage{
+			Project:   "perses",
+			Dashboard: "Library",
+			Paths:     []string{"/spec/layouts/0/spec/items/0/content", "/spec/layouts/0/spec/items/2/content"},
+		}, NewPanelUsage(result, "BurnRate", false))
+		assert.Nil(t, NewPanelUsage(result, "CPU", true))
+	}
+}
+
+func TestResolveQueryDatasources(t *testing.T) {
+	spec := DashboardSpec{
+		Datasource: dashboard.Datasource{
+			Name: "PrometheusDemo",
+			Kind: datasource.PrometheusKind,
+		},
+		Panels: map[string]json.RawMessage{
+			"Traces": []byte(`{
+  "kind": "TraceTable",
+  "datasource": {"kind": "TempoDatasource"},
+  "options": {
+    "queries": [
+      {"kind": "TempoTraceQLQuery", "options": {"query": "{}"}}
+    ]
+  }
+}`),
+			"CPU": []byte(`{
+  "kind": "LineChart",
+  "datasource": {"kind": "PrometheusDatasource", "name": "PrometheusProd"},
+  "options": {
+    "queries": [
+      {"kind": "PrometheusGraphQuery", "options": {"query": "up"}},
+      {"kind": "PrometheusGraphQuery", "datasource": {"kind": "PrometheusDatasource", "name": "Thanos", "global": true}, "options": {"query": "up"}},
+      {"kind": "TempoTraceQLQuery", "datasource": {"kind": "TempoDatasource", "name": "Tempo"}, "options": {"query": "{}"}}
+    ]
+  }
+}`),
+			"Memory": []byte(`{
+  "kind": "GaugeChart",
+  "datasource": {"kind": "PrometheusDatasource"},
+  "options": {
+    "query": {"kind": "PrometheusGraphQuery", "options": {"query": "up"}}
+  }
+}`),
+			"Text": []byte(`{
+  "kind": "TextPanel",
+  "options": {
+    "content": "no query"
+  }
+}`),
+		},
+	}
+	expected := []dashboard.QueryDatasource{
+		{
+			Panel:      "CPU",
+			Query:      0,
+			QueryKind:  "PrometheusGraphQuery",
+			Datasource: &dashboard.Datasource{Name: "PrometheusProd", Kind: datasource.PrometheusKind},
+		},
+		{
+			Panel:      "CPU",
+			Query:      1,
+			QueryKind:  "PrometheusGraphQuery",
+			Datasource: &dashboard.Datasource{Name: "Thanos", Kind: datasource.PrometheusKind, Global: true},
+		},
+		{
+			Panel:      "CPU",
+			Query:      2,
+			QueryKind:  "TempoTraceQLQuery",
+			Datasource: &dashboard.Datasource{Name: "Tempo", Kind: datasource.TempoKind},
+		},
+		{
+			Panel:      "Memory",
+			Query:      0,
+			QueryKind:  "PrometheusGraphQuery",
+			Datasource: &dashboard.Datasource{Name: "PrometheusDemo", Kind: datasource.PrometheusKind},
+		},
+		{
+			Panel:     "Traces",
+			Query:     0,
+			QueryKind: "TempoTraceQLQuery",
+		},
+	}
+	result, err := spec.ResolveQueryDatasources()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
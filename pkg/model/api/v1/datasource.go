@@ -32,6 +32,7 @@ func GenerateDatasourceID(project string, name string) string {
 
 type DatasourceSpec interface {
 	GetKind() datasource.Kind
+	IsDefault() bool
 }
 
 func unmarshalDatasourceSpec(spec map[string]interface{}, staticMarshal func(interface{}) ([]byte, error), staticUnmarshal func([]byte, interface{}) error) (DatasourceSpec, error) {
@@ -39,6 +40,9 @@ func unmarshalDatasourceSpec(spec map[string]interface{}, staticMarshal func(int
 	if !ok {
 		return nil, fmt.Errorf("attribute 'kind' not found in 'datasource.spec'")
 	}
+	if kind, isString := specKind.(string); !isString || len(kind) == 0 {
+		return nil, fmt.Errorf("spec.kind cannot be empty")
+	}
 	rawSpec, err := staticMarshal(spec)
 	if err != nil {
 		return nil, err
@@ -47,6 +51,12 @@ func unmarshalDatasourceSpec(spec map[string]interface{}, staticMarshal func(int
 	switch specKind {
 	case string(datasource.PrometheusKind):
 		result = &datasource.Prometheus{}
+	case string(datasource.TempoKind):
+		result = &datasource.Tempo{}
+	case string(datasource.TestDataKind):
+		result = &datasource.TestData{}
+	default:
+		result = &datasource.Plugin{}
 	}
 	if err := staticUnmarshal(rawSpec, result); err != nil {
 		return nil, err
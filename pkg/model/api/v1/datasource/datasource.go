@@ -16,14 +16,21 @@ package datasource
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 )
 
 type Kind string
 
-const PrometheusKind Kind = "Prometheus"
+const (
+	PrometheusKind Kind = "Prometheus"
+	TempoKind      Kind = "Tempo"
+	TestDataKind   Kind = "TestData"
+)
 
 var kindMap = map[Kind]bool{
 	PrometheusKind: true,
+	TempoKind:      true,
+	TestDataKind:   true,
 }
 
 func (k *Kind) UnmarshalJSON(data []byte) error {
@@ -57,12 +64,80 @@ func (k *Kind) validate() error {
 		return fmt.Errorf("spec.kind cannot be empty")
 	}
 	if _, ok := kindMap[*k]; !ok {
-		return fmt.Errorf("unknown spec.kind %q used", *k)
+		if _, isPlugin := pluginKinds.Load(*k); !isPlugin {
+			return fmt.Errorf("unknown spec.kind %q used", *k)
+		}
 	}
 	return nil
 }
 
+// IsPlugin returns true when the kind is not one of the kinds known by Perses, but a kind provided by a plugin.
+func (k Kind) IsPlugin() bool {
+	return !kindMap[k]
+}
+
+// pluginKinds is the set of the kinds of datasource provided by the plugins, see RegisterPluginKinds.
+var pluginKinds sync.Map
+
+// RegisterPluginKinds records the kinds of datasource provided by the plugins loaded, so they are accepted
+// in addition to the kinds known by Perses.
+// A kind stays registered once its plugin is removed, so the datasources already using it can still be read.
+// Saving them is refused by the validation of the schemas instead.
+func RegisterPluginKinds(kinds ...string) {
+	for _, kind := range kinds {
+		pluginKinds.Store(Kind(kind), true)
+	}
+}
+
 type BasicDatasource struct {
 	Kind    Kind `json:"kind" yaml:"kind"`
 	Default bool `json:"default" yaml:"default"`
 }
+
+// IsDefault returns true if the datasource is the one to use when a dashboard doesn't give the name of the datasource.
+func (b *BasicDatasource) IsDefault() bool {
+	return b.Default
+}
+
+// Plugin is the spec of a datasource whose kind is provided by a plugin.
+// It is kept as it is, since it is only validated by the CUE schema of the plugin.
+type Plugin map[string]interface{}
+
+func (p *Plugin) UnmarshalJSON(data []byte) error {
+	var tmp map[string]interface{}
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	if err := (Plugin(tmp)).validate(); err != nil {
+		return err
+	}
+	*p = tmp
+	return nil
+}
+
+func (p *Plugin) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp map[string]interface{}
+	if err := unmarshal(&tmp); err != nil {
+		return err
+	}
+	if err := (Plugin(tmp)).validate(); err != nil {
+		return err
+	}
+	*p = tmp
+	return nil
+}
+
+func (p Plugin) validate() error {
+	kind := p.GetKind()
+	return (&kind).validate()
+}
+
+func (p Plugin) GetKind() Kind {
+	kind, _ := p["kind"].(string)
+	return Kind(kind)
+}
+
+func (p Plugin) IsDefault() bool {
+	isDefault, _ := p["default"].(bool)
+	return isDefault
+}
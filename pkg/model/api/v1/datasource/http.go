@@ -19,8 +19,10 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/prometheus/common/model"
 )
 
 type HTTPAccess string
@@ -220,9 +222,84 @@ func (b *HTTPAuth) validate() error {
 	return nil
 }
 
+type LoadBalancingStrategy string
+
+const (
+	// FailoverLoadBalancing is sending every request to the first healthy URL, following the order of the list.
+	FailoverLoadBalancing LoadBalancingStrategy = "failover"
+	// RoundRobinLoadBalancing is sending the requests to each healthy URL in turn.
+	RoundRobinLoadBalancing LoadBalancingStrategy = "round-robin"
+	// LeastLatencyLoadBalancing is sending every request to the healthy URL that answered the fastest recently.
+	LeastLatencyLoadBalancing LoadBalancingStrategy = "least-latency"
+)
+
+const defaultLoadBalancingCooldown = model.Duration(30 * time.Second)
+
+var loadBalancingStrategyMap = map[LoadBalancingStrategy]bool{
+	FailoverLoadBalancing:     true,
+	RoundRobinLoadBalancing:   true,
+	LeastLatencyLoadBalancing: true,
+}
+
+type HTTPLoadBalancing struct {
+	// Strategy is the way to choose the URL that will receive the request. By default, it is set with 'failover'.
+	Strategy LoadBalancingStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	// Cooldown is the period during which a URL that failed to answer is not used anymore (unless every URL failed).
+	// By default, it is set with 30s.
+	Cooldown model.Duration `json:"cooldown,omitempty" yaml:"cooldown,omitempty"`
+}
+
+func (h *HTTPLoadBalancing) UnmarshalJSON(data []byte) error {
+	var tmp HTTPLoadBalancing
+	type plain HTTPLoadBalancing
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*h = tmp
+	return nil
+}
+
+func (h *HTTPLoadBalancing) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp HTTPLoadBalancing
+	type plain HTTPLoadBalancing
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*h = tmp
+	return nil
+}
+
+func (h *HTTPLoadBalancing) validate() error {
+	if len(h.Strategy) == 0 {
+		h.Strategy = FailoverLoadBalancing
+	}
+	if _, ok := loadBalancingStrategyMap[h.Strategy]; !ok {
+		return fmt.Errorf("unknown http.load_balancing.strategy %q used", h.Strategy)
+	}
+	if h.Cooldown < 0 {
+		return fmt.Errorf("http.load_balancing.cooldown cannot be negative")
+	}
+	if h.Cooldown == 0 {
+		h.Cooldown = defaultLoadBalancingCooldown
+	}
+	return nil
+}
+
 type HTTPConfig struct {
 	// URL is the url required to contact the datasource
 	URL *url.URL `json:"url" yaml:"url"`
+	// URLs can be used instead of URL when the datasource is available through multiple replicas (like an HA pair of Prometheus).
+	// The proxy is then spreading the requests across the different URLs according to LoadBalancing.
+	// It cannot be used when access is set to 'browser'.
+	URLs []*url.URL `json:"urls,omitempty" yaml:"urls,omitempty"`
+	// LoadBalancing is defining how the URLs are used. It is only used when URLs is set.
+	LoadBalancing *HTTPLoadBalancing `json:"load_balancing,omitempty" yaml:"load_balancing,omitempty"`
 	// The way the UI will contact the datasource. Or through the Backend or directly.
 	// By default, Access is set with the value 'server'
 	Access HTTPAccess `json:"access,omitempty" yaml:"access,omitempty"`
@@ -237,44 +314,55 @@ type HTTPConfig struct {
 	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
 }
 
+// GetURLs returns the list of URLs that can be used to contact the datasource.
+func (h *HTTPConfig) GetURLs() []*url.URL {
+	if len(h.URLs) > 0 {
+		return h.URLs
+	}
+	if h.URL != nil {
+		return []*url.URL{h.URL}
+	}
+	return nil
+}
+
 // tmpHTTPConfig is only used to custom the json/yaml marshalling/unmarshalling step.
 // It shouldn't be used for other purpose.
 type tmpHTTPConfig struct {
-	URL              string                `json:"url" yaml:"url"`
+	URL              string                `json:"url,omitempty" yaml:"url,omitempty"`
+	URLs             []string              `json:"urls,omitempty" yaml:"urls,omitempty"`
+	LoadBalancing    *HTTPLoadBalancing    `json:"load_balancing,omitempty" yaml:"load_balancing,omitempty"`
 	Access           HTTPAccess            `json:"access,omitempty" yaml:"access,omitempty"`
 	AllowedEndpoints []HTTPAllowedEndpoint `json:"allowed_endpoints,omitempty" yaml:"allowed_endpoints,omitempty"`
 	Auth             *HTTPAuth             `json:"auth,omitempty" yaml:"auth,omitempty"`
 	Headers          map[string]string     `json:"headers,omitempty" yaml:"headers,omitempty"`
 }
 
-func (h *HTTPConfig) MarshalJSON() ([]byte, error) {
+func (h *HTTPConfig) toTmp() *tmpHTTPConfig {
 	urlAsString := ""
 	if h.URL != nil {
 		urlAsString = h.URL.String()
 	}
-	tmp := &tmpHTTPConfig{
+	var urls []string
+	for _, u := range h.URLs {
+		urls = append(urls, u.String())
+	}
+	return &tmpHTTPConfig{
 		URL:              urlAsString,
+		URLs:             urls,
+		LoadBalancing:    h.LoadBalancing,
 		Access:           h.Access,
 		AllowedEndpoints: h.AllowedEndpoints,
 		Auth:             h.Auth,
 		Headers:          h.Headers,
 	}
-	return json.Marshal(tmp)
+}
+
+func (h *HTTPConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.toTmp())
 }
 
 func (h *HTTPConfig) MarshalYAML() (interface{}, error) {
-	urlAsString := ""
-	if h.URL != nil {
-		urlAsString = h.URL.String()
-	}
-	tmp := &tmpHTTPConfig{
-		URL:              urlAsString,
-		Access:           h.Access,
-		AllowedEndpoints: h.AllowedEndpoints,
-		Auth:             h.Auth,
-		Headers:          h.Headers,
-	}
-	return tmp, nil
+	return h.toTmp(), nil
 }
 
 func (h *HTTPConfig) UnmarshalJSON(data []byte) error {
@@ -300,11 +388,32 @@ func (h *HTTPConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 func (h *HTTPConfig) validate(conf tmpHTTPConfig) error {
-	u, err := url.Parse(conf.URL)
-	if err != nil {
-		return err
+	if len(conf.URL) > 0 && len(conf.URLs) > 0 {
+		return fmt.Errorf("http.url and http.urls cannot be set at the same time")
+	}
+	if len(conf.URLs) == 0 {
+		u, err := url.Parse(conf.URL)
+		if err != nil {
+			return err
+		}
+		h.URL = u
+	}
+	for _, rawURL := range conf.URLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		h.URLs = append(h.URLs, u)
+	}
+	if len(conf.URLs) == 0 && conf.LoadBalancing != nil {
+		return fmt.Errorf("http.load_balancing can only be used when http.urls is set")
+	}
+	if len(conf.URLs) > 0 && conf.LoadBalancing == nil {
+		conf.LoadBalancing = &HTTPLoadBalancing{}
+		if err := conf.LoadBalancing.validate(); err != nil {
+			return err
+		}
 	}
-	h.URL = u
 
 	if len(conf.Access) == 0 {
 		conf.Access = ServerHTTPAccess
@@ -319,7 +428,11 @@ func (h *HTTPConfig) validate(conf tmpHTTPConfig) error {
 		if len(conf.Headers) > 0 {
 			return fmt.Errorf("http.headers cannot be set when 'http.access' is set with the value 'browser'")
 		}
+		if len(conf.URLs) > 0 {
+			return fmt.Errorf("http.urls cannot be set when 'http.access' is set with the value 'browser'")
+		}
 	}
+	h.LoadBalancing = conf.LoadBalancing
 	h.Access = conf.Access
 	h.Auth = conf.Auth
 	h.Headers = conf.Headers
@@ -15,11 +15,14 @@ package datasource
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 )
@@ -228,6 +231,48 @@ func TestUnmarshalJSONHTTPConfig(t *testing.T) {
 				Access: ServerHTTPAccess,
 			},
 		},
+		{
+			title: "multiple urls with the default load balancing",
+			jason: `
+{
+  "urls": ["http://prometheus-0:9090", "http://prometheus-1:9090"]
+}
+`,
+			result: HTTPConfig{
+				URLs: []*url.URL{
+					{Scheme: "http", Host: "prometheus-0:9090"},
+					{Scheme: "http", Host: "prometheus-1:9090"},
+				},
+				LoadBalancing: &HTTPLoadBalancing{
+					Strategy: FailoverLoadBalancing,
+					Cooldown: model.Duration(30 * time.Second),
+				},
+				Access: ServerHTTPAccess,
+			},
+		},
+		{
+			title: "multiple urls with round-robin",
+			jason: `
+{
+  "urls": ["http://prometheus-0:9090", "http://prometheus-1:9090"],
+  "load_balancing": {
+    "strategy": "round-robin",
+    "cooldown": "1m"
+  }
+}
+`,
+			result: HTTPConfig{
+				URLs: []*url.URL{
+					{Scheme: "http", Host: "prometheus-0:9090"},
+					{Scheme: "http", Host: "prometheus-1:9090"},
+				},
+				LoadBalancing: &HTTPLoadBalancing{
+					Strategy: RoundRobinLoadBalancing,
+					Cooldown: model.Duration(time.Minute),
+				},
+				Access: ServerHTTPAccess,
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
@@ -238,6 +283,65 @@ func TestUnmarshalJSONHTTPConfig(t *testing.T) {
 	}
 }
 
+func TestUnmarshalJSONHTTPConfigError(t *testing.T) {
+	testSuite := []struct {
+		title string
+		jason string
+		err   error
+	}{
+		{
+			title: "url and urls are both set",
+			jason: `
+{
+  "url": "http://localhost:9090",
+  "urls": ["http://localhost:9091"]
+}
+`,
+			err: fmt.Errorf("http.url and http.urls cannot be set at the same time"),
+		},
+		{
+			title: "load balancing without urls",
+			jason: `
+{
+  "url": "http://localhost:9090",
+  "load_balancing": {
+    "strategy": "failover"
+  }
+}
+`,
+			err: fmt.Errorf("http.load_balancing can only be used when http.urls is set"),
+		},
+		{
+			title: "unknown load balancing strategy",
+			jason: `
+{
+  "urls": ["http://localhost:9090", "http://localhost:9091"],
+  "load_balancing": {
+    "strategy": "random"
+  }
+}
+`,
+			err: fmt.Errorf("unknown http.load_balancing.strategy \"random\" used"),
+		},
+		{
+			title: "urls used from the browser",
+			jason: `
+{
+  "urls": ["http://localhost:9090", "http://localhost:9091"],
+  "access": "browser"
+}
+`,
+			err: fmt.Errorf("http.urls cannot be set when 'http.access' is set with the value 'browser'"),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result := HTTPConfig{}
+			assert.Equal(t, test.err, json.Unmarshal([]byte(test.jason), &result))
+		})
+	}
+}
+
 func TestUnmarshalYAMLHTTPConfig(t *testing.T) {
 	testSuite := []struct {
 		title  string
@@ -0,0 +1,106 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datasource
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/perses/perses/pkg/model/api/v1/common"
+)
+
+// defaultTempoAllowedEndpoints contains the endpoints required to search and to retrieve traces.
+// It covers the Tempo HTTP API and the Jaeger-compatible query API that Tempo (and Jaeger itself) is exposing.
+var defaultTempoAllowedEndpoints = []HTTPAllowedEndpoint{
+	{
+		EndpointPattern: common.MustNewRegexp("/api/traces/([a-fA-F0-9]+)"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/v2/traces/([a-fA-F0-9]+)"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/search"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/search/tags"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/search/tag/([a-zA-Z0-9_.-]+)/values"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/v2/search/tags"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/v2/search/tag/([a-zA-Z0-9_.-]+)/values"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/services"),
+		Method:          http.MethodGet,
+	},
+	{
+		EndpointPattern: common.MustNewRegexp("/api/services/([a-zA-Z0-9_.-]+)/operations"),
+		Method:          http.MethodGet,
+	},
+}
+
+// Tempo is the datasource used to search and to get traces.
+// Any tracing backend exposing the Tempo or the Jaeger query HTTP API can be used with it.
+type Tempo struct {
+	BasicDatasource `json:",inline" yaml:",inline"`
+	HTTP            HTTPConfig `json:"http" yaml:"http"`
+}
+
+func (t *Tempo) GetKind() Kind {
+	return t.Kind
+}
+
+func (t *Tempo) UnmarshalJSON(data []byte) error {
+	var tmp Tempo
+	type plain Tempo
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*t = tmp
+	return nil
+}
+
+func (t *Tempo) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp Tempo
+	type plain Tempo
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*t = tmp
+	return nil
+}
+
+func (t *Tempo) validate() error {
+	if t.HTTP.Access == ServerHTTPAccess && len(t.HTTP.AllowedEndpoints) == 0 {
+		t.HTTP.AllowedEndpoints = defaultTempoAllowedEndpoints
+	}
+	return nil
+}
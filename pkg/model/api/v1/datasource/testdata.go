@@ -0,0 +1,28 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datasource
+
+// TestData is a datasource served entirely by Perses. It doesn't contact any remote server.
+// Its proxy answers the Prometheus HTTP API with deterministic generated data,
+// so it can be used for demos, when developing a schema plugin or when testing Perses without any external service.
+type TestData struct {
+	BasicDatasource `json:",inline" yaml:",inline"`
+	// Seed is used to initialize the random generators (i.e. the random walks).
+	// Two TestData datasources with the same seed generate exactly the same data.
+	Seed int64 `json:"seed,omitempty" yaml:"seed,omitempty"`
+}
+
+func (t *TestData) GetKind() Kind {
+	return t.Kind
+}
@@ -15,6 +15,7 @@ package v1
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
@@ -26,6 +27,7 @@ import (
 )
 
 func TestUnmarshalJSONDatasource(t *testing.T) {
+	datasource.RegisterPluginKinds("PostgreSQL")
 	testSuite := []struct {
 		title  string
 		jason  string
@@ -94,6 +96,90 @@ func TestUnmarshalJSONDatasource(t *testing.T) {
 				},
 			},
 		},
+		{
+			title: "Tempo datasource with a custom list of allowed endpoints",
+			jason: `
+{
+  "kind": "GlobalDatasource",
+  "metadata": {
+    "name": "TempoDemo"
+  },
+  "spec": {
+    "kind": "Tempo",
+    "default": false,
+    "http": {
+      "url": "http://tempo.monitoring.svc:3200",
+      "allowed_endpoints": [
+        {
+          "endpoint_pattern": "/api/traces/([a-fA-F0-9]+)",
+          "method": "GET"
+        },
+        {
+          "endpoint_pattern": "/api/search",
+          "method": "GET"
+        }
+      ]
+    }
+  }
+}
+`,
+			result: GlobalDatasource{
+				Kind: KindGlobalDatasource,
+				Metadata: Metadata{
+					Name: "TempoDemo",
+				},
+				Spec: &datasource.Tempo{
+					BasicDatasource: datasource.BasicDatasource{
+						Kind:    datasource.TempoKind,
+						Default: false,
+					},
+					HTTP: datasource.HTTPConfig{
+						URL: &url.URL{
+							Scheme: "http",
+							Host:   "tempo.monitoring.svc:3200",
+						},
+						Access: datasource.ServerHTTPAccess,
+						AllowedEndpoints: []datasource.HTTPAllowedEndpoint{
+							{
+								EndpointPattern: common.MustNewRegexp("/api/traces/([a-fA-F0-9]+)"),
+								Method:          http.MethodGet,
+							},
+							{
+								EndpointPattern: common.MustNewRegexp("/api/search"),
+								Method:          http.MethodGet,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			title: "datasource provided by a plugin",
+			jason: `
+{
+  "kind": "GlobalDatasource",
+  "metadata": {
+    "name": "Warehouse"
+  },
+  "spec": {
+    "kind": "PostgreSQL",
+    "default": true,
+    "host": "warehouse.demo:5432"
+  }
+}
+`,
+			result: GlobalDatasource{
+				Kind: KindGlobalDatasource,
+				Metadata: Metadata{
+					Name: "Warehouse",
+				},
+				Spec: &datasource.Plugin{
+					"kind":    "PostgreSQL",
+					"default": true,
+					"host":    "warehouse.demo:5432",
+				},
+			},
+		},
 	}
 	for _, test := range testSuite {
 		t.Run(test.title, func(t *testing.T) {
@@ -104,6 +190,37 @@ func TestUnmarshalJSONDatasource(t *testing.T) {
 	}
 }
 
+func TestUnmarshalDatasourceError(t *testing.T) {
+	testSuite := []struct {
+		title string
+		jsone string
+		err   error
+	}{
+		{
+			title: "unsupported datasource kind",
+			jsone: `
+{
+  "kind": "GlobalDatasource",
+  "metadata": {
+    "name": "PrometheusDemo"
+  },
+  "spec": {
+    "kind": "Promethus",
+    "default": true
+  }
+}
+`,
+			err: fmt.Errorf("unknown spec.kind \"Promethus\" used"),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result := GlobalDatasource{}
+			assert.Equal(t, test.err, json.Unmarshal([]byte(test.jsone), &result))
+		})
+	}
+}
+
 func TestUnmarshalYAMLLayout(t *testing.T) {
 	testSuite := []struct {
 		title  string
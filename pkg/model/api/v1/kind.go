@@ -27,6 +27,8 @@ const (
 	KindDatasource       Kind = "Datasource"
 	KindFolder           Kind = "Folder"
 	KindGlobalDatasource Kind = "GlobalDatasource"
+	KindGlobalPanel      Kind = "GlobalPanel"
+	KindPanel            Kind = "Panel"
 	KindProject          Kind = "Project"
 	KindUser             Kind = "User"
 )
@@ -36,6 +38,8 @@ var KindMap = map[Kind]bool{
 	KindDatasource:       true,
 	KindFolder:           true,
 	KindGlobalDatasource: true,
+	KindGlobalPanel:      true,
+	KindPanel:            true,
 	KindProject:          true,
 	KindUser:             true,
 }
@@ -87,6 +91,10 @@ func GetStruct(kind Kind) (modelAPI.Entity, error) {
 		return &Folder{}, nil
 	case KindGlobalDatasource:
 		return &GlobalDatasource{}, nil
+	case KindGlobalPanel:
+		return &GlobalPanel{}, nil
+	case KindPanel:
+		return &Panel{}, nil
 	case KindProject:
 		return &Project{}, nil
 	case KindUser:
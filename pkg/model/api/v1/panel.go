@@ -0,0 +1,217 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	modelAPI "github.com/perses/perses/pkg/model/api"
+	"github.com/perses/perses/pkg/model/api/v1/common"
+)
+
+const (
+	// libraryPanelRefPrefix is the first part of the JSON reference to a Panel of the project of the dashboard: "/panels/<name>".
+	libraryPanelRefPrefix = "panels"
+	// globalLibraryPanelRefPrefix is the first part of the JSON reference to a GlobalPanel: "/globalpanels/<name>".
+	globalLibraryPanelRefPrefix = "globalpanels"
+)
+
+func GeneratePanelID(project string, name string) string {
+	return generateProjectResourceID("panels", project, name)
+}
+
+func GenerateGlobalPanelID(name string) string {
+	return fmt.Sprintf("/globalpanels/%s", name)
+}
+
+// LibraryPanelRef returns the JSON reference used in the layout of a dashboard to use a library panel:
+// a Panel of the project of the dashboard, or a GlobalPanel when global is true.
+func LibraryPanelRef(name string, global bool) *common.JSONRef {
+	prefix := libraryPanelRefPrefix
+	if global {
+		prefix = globalLibraryPanelRefPrefix
+	}
+	return &common.JSONRef{
+		Ref:  fmt.Sprintf("/%s/%s", prefix, name),
+		Path: []string{prefix, name},
+	}
+}
+
+// ParseLibraryPanelRef returns the name of the library panel referenced and true if it's a GlobalPanel.
+// ok is false when the reference isn't pointing to a library panel.
+func ParseLibraryPanelRef(ref *common.JSONRef) (name string, global bool, ok bool) {
+	if ref == nil || len(ref.Path) != 2 || ref.Ref[0] == '#' {
+		return "", false, false
+	}
+	switch ref.Path[0] {
+	case libraryPanelRefPrefix:
+		return ref.Path[1], false, true
+	case globalLibraryPanelRefPrefix:
+		return ref.Path[1], true, true
+	}
+	return "", false, false
+}
+
+// validatePanelSpec verifies the panel is a JSON object with a kind. The rest of the panel is verified by the schemas.
+func validatePanelSpec(spec json.RawMessage) error {
+	var tmp struct {
+		Kind string `json:"kind"`
+	}
+	if len(spec) == 0 {
+		return fmt.Errorf("spec cannot be empty")
+	}
+	if err := json.Unmarshal(spec, &tmp); err != nil {
+		return fmt.Errorf("spec must be a panel: %s", err)
+	}
+	if len(tmp.Kind) == 0 {
+		return fmt.Errorf("spec.kind cannot be empty")
+	}
+	return nil
+}
+
+// Panel is a panel that can be used by every dashboard of its project, also known as a library panel.
+// A dashboard uses it from its layouts with the reference "/panels/<name>".
+type Panel struct {
+	Kind     Kind            `json:"kind" yaml:"kind"`
+	Metadata ProjectMetadata `json:"metadata" yaml:"metadata"`
+	// Spec is the panel, described like the panels of a dashboard. It is verified with the schemas of the panels.
+	Spec json.RawMessage `json:"spec" yaml:"spec"`
+}
+
+func (p *Panel) GenerateID() string {
+	return GeneratePanelID(p.Metadata.Project, p.Metadata.Name)
+}
+
+func (p *Panel) GetMetadata() modelAPI.Metadata {
+	return &p.Metadata
+}
+
+func (p *Panel) GetKind() string {
+	return string(p.Kind)
+}
+
+func (p *Panel) UnmarshalJSON(data []byte) error {
+	var tmp Panel
+	type plain Panel
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*p = tmp
+	return nil
+}
+
+func (p *Panel) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp Panel
+	type plain Panel
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*p = tmp
+	return nil
+}
+
+func (p *Panel) validate() error {
+	if p.Kind != KindPanel {
+		return fmt.Errorf("invalid kind: %q for a Panel type", p.Kind)
+	}
+	return validatePanelSpec(p.Spec)
+}
+
+// GlobalPanel is a library panel that can be used by every dashboard, whatever its project.
+// A dashboard uses it from its layouts with the reference "/globalpanels/<name>".
+type GlobalPanel struct {
+	Kind     Kind     `json:"kind" yaml:"kind"`
+	Metadata Metadata `json:"metadata" yaml:"metadata"`
+	// Spec is the panel, described like the panels of a dashboard. It is verified with the schemas of the panels.
+	Spec json.RawMessage `json:"spec" yaml:"spec"`
+}
+
+func (p *GlobalPanel) GenerateID() string {
+	return GenerateGlobalPanelID(p.Metadata.Name)
+}
+
+func (p *GlobalPanel) GetMetadata() modelAPI.Metadata {
+	return &p.Metadata
+}
+
+func (p *GlobalPanel) GetKind() string {
+	return string(p.Kind)
+}
+
+func (p *GlobalPanel) UnmarshalJSON(data []byte) error {
+	var tmp GlobalPanel
+	type plain GlobalPanel
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*p = tmp
+	return nil
+}
+
+func (p *GlobalPanel) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp GlobalPanel
+	type plain GlobalPanel
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*p = tmp
+	return nil
+}
+
+func (p *GlobalPanel) validate() error {
+	if p.Kind != KindGlobalPanel {
+		return fmt.Errorf("invalid kind: %q for a GlobalPanel type", p.Kind)
+	}
+	return validatePanelSpec(p.Spec)
+}
+
+// PanelUsage is a dashboard using a library panel in its layouts.
+type PanelUsage struct {
+	Project   string `json:"project" yaml:"project"`
+	Dashboard string `json:"dashboard" yaml:"dashboard"`
+	// Paths is the JSON pointers of the references to the library panel in the dashboard, e.g. "/spec/layouts/0/spec/items/1/content".
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+// NewPanelUsage returns where the dashboard is using the library panel, or nil when the dashboard isn't using it.
+// The library panel is a GlobalPanel when global is true, and a Panel of the project of the dashboard otherwise.
+func NewPanelUsage(d *Dashboard, name string, global bool) *PanelUsage {
+	var paths []string
+	for _, ref := range d.Spec.LibraryPanelReferences() {
+		if ref.Name == name && ref.Global == global {
+			paths = append(paths, "/spec"+ref.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return &PanelUsage{
+		Project:   d.Metadata.Project,
+		Dashboard: d.Metadata.Name,
+		Paths:     paths,
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/perses/perses/pkg/model/api/v1/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalPanelError(t *testing.T) {
+	testSuite := []struct {
+		title string
+		jason string
+		err   error
+	}{
+		{
+			title: "spec cannot be empty",
+			jason: `
+{
+  "kind": "Panel",
+  "metadata": {
+    "name": "BurnRate",
+    "project": "perses"
+  }
+}
+`,
+			err: fmt.Errorf("spec cannot be empty"),
+		},
+		{
+			title: "spec must have a kind",
+			jason: `
+{
+  "kind": "Panel",
+  "metadata": {
+    "name": "BurnRate",
+    "project": "perses"
+  },
+  "spec": {
+    "display": {
+      "name": "Burn rate"
+    }
+  }
+}
+`,
+			err: fmt.Errorf("spec.kind cannot be empty"),
+		},
+		{
+			title: "wrong kind",
+			jason: `
+{
+  "kind": "GlobalPanel",
+  "metadata": {
+    "name": "BurnRate",
+    "project": "perses"
+  },
+  "spec": {
+    "kind": "LineChart"
+  }
+}
+`,
+			err: fmt.Errorf("invalid kind: \"GlobalPanel\" for a Panel type"),
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			result := &Panel{}
+			assert.Equal(t, test.err, json.Unmarshal([]byte(test.jason), result))
+		})
+	}
+}
+
+func TestParseLibraryPanelRef(t *testing.T) {
+	testSuite := []struct {
+		title  string
+		ref    string
+		name   string
+		global bool
+		ok     bool
+	}{
+		{
+			title: "panel of the project",
+			ref:   "/panels/BurnRate",
+			name:  "BurnRate",
+			ok:    true,
+		},
+		{
+			title:  "global panel",
+			ref:    "/globalpanels/BurnRate",
+			name:   "BurnRate",
+			global: true,
+			ok:     true,
+		},
+		{
+			title: "panel of the dashboard",
+			ref:   "#/spec/panels/BurnRate",
+		},
+		{
+			title: "local reference to the panels",
+			ref:   "#/panels/BurnRate",
+		},
+	}
+	for _, test := range testSuite {
+		t.Run(test.title, func(t *testing.T) {
+			ref := &common.JSONRef{}
+			assert.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`{"$ref": %q}`, test.ref)), ref))
+			name, global, ok := ParseLibraryPanelRef(ref)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.global, global)
+			if test.ok {
+				assert.Equal(t, ref, LibraryPanelRef(name, global))
+			}
+		})
+	}
+}
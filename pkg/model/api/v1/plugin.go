@@ -0,0 +1,102 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// PluginCategories are the kinds of plugins a bundle can provide, in the order they are loaded.
+var PluginCategories = []string{"panels", "queries", "variables", "annotations", "layouts", "datasources"}
+
+// pluginBundleNameRegexp matches the names a plugin bundle can have. The name is used to build the path of the bundle
+// installed and the folder it's extracted to, so it cannot contain any path separator.
+var pluginBundleNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// ValidatePluginBundleName returns an error when the name cannot be used as the name of a plugin bundle.
+func ValidatePluginBundleName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("the name of the plugin bundle cannot be empty")
+	}
+	if !pluginBundleNameRegexp.MatchString(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid name of plugin bundle %q: it must match %s and cannot be . or ..", name, pluginBundleNameRegexp.String())
+	}
+	return nil
+}
+
+// PluginManifest describes a plugin bundle: a tar.gz archive made of the file manifest.yaml
+// and of a folder per category of plugins provided, containing one CUE package per plugin.
+type PluginManifest struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+	// MinPersesVersion is the first version of Perses able to load the plugins of the bundle.
+	MinPersesVersion string `json:"min_perses_version,omitempty" yaml:"min_perses_version,omitempty"`
+	// Kinds are the kinds provided by the bundle, indexed by category (i.e. panels, queries, variables, annotations, layouts or datasources).
+	Kinds map[string][]string `json:"kinds" yaml:"kinds"`
+}
+
+func (m *PluginManifest) UnmarshalJSON(data []byte) error {
+	var tmp PluginManifest
+	type plain PluginManifest
+	if err := json.Unmarshal(data, (*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*m = tmp
+	return nil
+}
+
+func (m *PluginManifest) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var tmp PluginManifest
+	type plain PluginManifest
+	if err := unmarshal((*plain)(&tmp)); err != nil {
+		return err
+	}
+	if err := (&tmp).validate(); err != nil {
+		return err
+	}
+	*m = tmp
+	return nil
+}
+
+func (m *PluginManifest) validate() error {
+	if err := ValidatePluginBundleName(m.Name); err != nil {
+		return err
+	}
+	if len(m.Version) == 0 {
+		return fmt.Errorf("the version of the plugin bundle %q cannot be empty", m.Name)
+	}
+	if len(m.Kinds) == 0 {
+		return fmt.Errorf("the plugin bundle %q doesn't provide any kind", m.Name)
+	}
+	for category := range m.Kinds {
+		if !isPluginCategory(category) {
+			return fmt.Errorf("unknown category of plugins %q in the plugin bundle %q", category, m.Name)
+		}
+	}
+	return nil
+}
+
+func isPluginCategory(category string) bool {
+	for _, c := range PluginCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
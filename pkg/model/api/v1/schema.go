@@ -0,0 +1,91 @@
+// Copyright 2021 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SchemaPlugin describes a plugin loaded by the API as a CUE schema.
+type SchemaPlugin struct {
+	Kind string `json:"kind"`
+	// Version is the version of the schema declared by the plugin. It is only set for the panels.
+	Version int `json:"version,omitempty"`
+	// Path is the folder the plugin has been loaded from.
+	Path string `json:"path"`
+	// Bundle is the name and the version of the plugin bundle providing the plugin, empty when it's loaded from a folder.
+	Bundle   string    `json:"bundle,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// Schema is the export of the definitions of the plugin as OpenAPI schemas, indexed by the name of the definition.
+	// It is omitted when the definitions cannot be expressed as OpenAPI schemas.
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// SchemaLoadError describes a plugin that the API failed to load.
+type SchemaLoadError struct {
+	// Category is the kind of plugins the plugin belongs to (i.e. panels, queries, variables, layouts or datasources).
+	Category string    `json:"category"`
+	Path     string    `json:"path"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// SchemaStatus is the status of the last loading of the schemas.
+type SchemaStatus struct {
+	// Plugins is the number of plugins loaded by category.
+	Plugins map[string]int    `json:"plugins"`
+	Errors  []SchemaLoadError `json:"errors"`
+}
+
+// PanelMigration describes the upgrade of a stored panel to the version of the schema of its kind.
+type PanelMigration struct {
+	Project   string `json:"project" yaml:"project"`
+	Dashboard string `json:"dashboard" yaml:"dashboard"`
+	Panel     string `json:"panel" yaml:"panel"`
+	Kind      string `json:"kind" yaml:"kind"`
+	// From is the version of the schema the panel has been saved with.
+	From int `json:"from" yaml:"from"`
+	// To is the version of the schema the panel is upgraded to.
+	To int `json:"to" yaml:"to"`
+	// Error is the reason why the panel couldn't be upgraded. The dashboard is then left untouched.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PanelMigrationReport lists the panels upgraded, or to upgrade in case of a dry run.
+type PanelMigrationReport struct {
+	DryRun     bool             `json:"dry_run" yaml:"dry_run"`
+	Migrations []PanelMigration `json:"migrations" yaml:"migrations"`
+}
+
+type SchemaReloadStatus string
+
+const (
+	SchemaReloadLoaded  SchemaReloadStatus = "loaded"
+	SchemaReloadFailed  SchemaReloadStatus = "failed"
+	SchemaReloadRemoved SchemaReloadStatus = "removed"
+)
+
+// SchemaReloadEvent is the result of the (re)loading of a plugin.
+type SchemaReloadEvent struct {
+	// Category is the kind of plugins the plugin belongs to (i.e. panels, queries, variables, layouts or datasources).
+	Category string `json:"category"`
+	Path     string `json:"path"`
+	// Kind is the kind provided by the plugin. It's empty when the plugin failed to load before its kind is known.
+	Kind   string             `json:"kind,omitempty"`
+	Status SchemaReloadStatus `json:"status"`
+	// Error is the reason why the plugin failed to load.
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
@@ -0,0 +1,35 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package static
+
+import (
+	"time"
+)
+
+#annotation: {
+	kind: "Static"
+	parameter: {
+		events: [#event, ...#event]
+	}
+}
+
+// #timestamp is defined apart, since the field time of an event hides the package time.
+#timestamp: time.Time
+
+#event: {
+	time:   #timestamp
+	end?:   #timestamp
+	title?: string
+	text?:  string
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+#http: {
+	url?: string
+	urls?: [...string]
+	load_balancing?: {
+		strategy?: "failover" | "round-robin" | "least-latency"
+		cooldown?: #duration
+	}
+	access?: "server" | "browser"
+	allowed_endpoints?: [...{
+		endpoint_pattern: string
+		method:           "GET" | "POST" | "PUT" | "PATCH" | "DELETE"
+	}]
+	auth?: {
+		insecure_tls?: bool
+		bearer_token?: string
+		basic_auth?: {
+			username:       string
+			password?:      string
+			password_file?: string
+		}
+		ca_cert?: string
+	}
+	headers?: [string]: string
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tabs
+
+#layout: {
+	kind: "Tabs"
+	spec: {
+		tabs: [#tab, ...#tab]
+	}
+}
+
+#tab: {
+	title: string & !=""
+	items: [...#item]
+}
+
+#item: {
+	x:      int & >=0
+	y:      int & >=0
+	width:  int & >0 & <=24
+	height: int & >0
+	content: {
+		"$ref": string
+	}
+	repeat?: {
+		variable:     string & !=""
+		direction?:   "horizontal" | "vertical"
+		max_per_row?: int & >0
+	}
+}
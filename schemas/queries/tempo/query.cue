@@ -0,0 +1,52 @@
+// Copyright 2022 The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tempo
+
+#datasource: {
+	kind: "TempoDatasource"
+}
+
+#query: #traceQLQuery | #traceSearchQuery | #traceIDQuery
+
+#duration: =~"^(?:\\d+(?:h|ms|us|ns|m|s))+$"
+
+// #traceQLQuery is searching for traces using a TraceQL expression
+#traceQLQuery: {
+	kind: "TempoTraceQLQuery"
+	options: {
+		query:  string
+		limit?: int & >0
+	}
+}
+
+// #traceSearchQuery is searching for traces using the tags-based search API
+#traceSearchQuery: {
+	kind: "TempoTraceSearchQuery"
+	options: {
+		service_name?: string
+		span_name?:    string
+		tags?: [string]: string
+		min_duration?: #duration
+		max_duration?: #duration
+		limit?:        int & >0
+	}
+}
+
+// #traceIDQuery is retrieving a single trace by its ID
+#traceIDQuery: {
+	kind: "TempoTraceIDQuery"
+	options: {
+		trace_id: =~"^[a-fA-F0-9]{1,32}$"
+	}
+}
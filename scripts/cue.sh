@@ -5,12 +5,15 @@
 set -e
 
 function test() {
-  cd schemas/panels
-  for d in *; do
-    if [ -d "${d}" ]; then
-      echo "testing charts ${d}"
-      cue vet "${d}/${d}.json" "${d}/${d}.cue"
-    fi
+  for folder in panels variables annotations layouts datasources; do
+    pushd "schemas/${folder}" > /dev/null
+    for d in *; do
+      if [ -d "${d}" ]; then
+        echo "testing ${folder} ${d}"
+        cue vet "${d}/${d}.json" "${d}/${d}.cue"
+      fi
+    done
+    popd > /dev/null
   done
 }
 
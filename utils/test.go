@@ -17,6 +17,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -26,6 +27,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/perses/perses/internal/api/config"
 	"github.com/perses/perses/internal/api/core"
+	"github.com/perses/perses/internal/api/core/middleware"
 	"github.com/perses/perses/internal/api/shared/database"
 	"github.com/perses/perses/internal/api/shared/dependency"
 	v1 "github.com/perses/perses/pkg/model/api/v1"
@@ -60,6 +62,34 @@ func CreateAndWaitUntilEntityExists(t *testing.T, persistenceManager dependency.
 		upsertFunc = func() error {
 			return persistenceManager.GetDatasource().Update(entity)
 		}
+	case *v1.GlobalDatasource:
+		getFunc = func() (interface{}, error) {
+			return persistenceManager.GetGlobalDatasource().Get(entity.Metadata.Name)
+		}
+		upsertFunc = func() error {
+			return persistenceManager.GetGlobalDatasource().Update(entity)
+		}
+	case *v1.Dashboard:
+		getFunc = func() (interface{}, error) {
+			return persistenceManager.GetDashboard().Get(entity.Metadata.Project, entity.Metadata.Name)
+		}
+		upsertFunc = func() error {
+			return persistenceManager.GetDashboard().Update(entity)
+		}
+	case *v1.Panel:
+		getFunc = func() (interface{}, error) {
+			return persistenceManager.GetPanel().Get(entity.Metadata.Project, entity.Metadata.Name)
+		}
+		upsertFunc = func() error {
+			return persistenceManager.GetPanel().Update(entity)
+		}
+	case *v1.GlobalPanel:
+		getFunc = func() (interface{}, error) {
+			return persistenceManager.GetGlobalPanel().Get(entity.Metadata.Name)
+		}
+		upsertFunc = func() error {
+			return persistenceManager.GetGlobalPanel().Update(entity)
+		}
 	case *v1.User:
 		getFunc = func() (interface{}, error) {
 			return persistenceManager.GetUser().Get(entity.Metadata.Name)
@@ -156,6 +186,209 @@ func NewDatasource(t *testing.T) *v1.Datasource {
 	return entity
 }
 
+// NewTestDataDatasource returns a datasource generating its data inside Perses.
+// It can be used by any test that needs to query a datasource without depending on an external service.
+func NewTestDataDatasource() *v1.Datasource {
+	entity := &v1.Datasource{
+		Kind: v1.KindDatasource,
+		Metadata: v1.ProjectMetadata{
+			Metadata: v1.Metadata{
+				Name: "TestData",
+			},
+			Project: "perses",
+		},
+		Spec: &datasource.TestData{
+			BasicDatasource: datasource.BasicDatasource{
+				Kind:    datasource.TestDataKind,
+				Default: false,
+			},
+			Seed: 42,
+		},
+	}
+	entity.Metadata.CreateNow()
+	return entity
+}
+
+// NewDashboard returns a dashboard using the datasource returned by NewDatasource.
+// The panel "MixedCPU" has a second query overriding the datasource to use the global datasource "GlobalPrometheus".
+func NewDashboard(t *testing.T) *v1.Dashboard {
+	data := `{
+  "kind": "Dashboard",
+  "metadata": {
+    "name": "Demo",
+    "project": "perses"
+  },
+  "spec": {
+    "datasource": {
+      "name": "PrometheusDemo",
+      "kind": "Prometheus"
+    },
+    "duration": "6h",
+    "panels": {
+      "CPU": {
+        "kind": "LineChart",
+        "display": {
+          "name": "CPU"
+        },
+        "datasource": {
+          "kind": "PrometheusDatasource"
+        },
+        "options": {
+          "queries": [
+            {
+              "kind": "PrometheusGraphQuery",
+              "options": {
+                "query": "sum by (instance) (rate(node_cpu_seconds_total{mode!='idle'}[5m]))"
+              }
+            }
+          ]
+        }
+      },
+      "MixedCPU": {
+        "kind": "LineChart",
+        "display": {
+          "name": "CPU of both clusters"
+        },
+        "datasource": {
+          "kind": "PrometheusDatasource",
+          "name": "PrometheusDemo"
+        },
+        "options": {
+          "queries": [
+            {
+              "kind": "PrometheusGraphQuery",
+              "options": {
+                "query": "sum(rate(node_cpu_seconds_total{mode!='idle'}[5m]))"
+              }
+            },
+            {
+              "kind": "PrometheusGraphQuery",
+              "datasource": {
+                "kind": "PrometheusDatasource",
+                "name": "GlobalPrometheus",
+                "global": true
+              },
+              "options": {
+                "query": "sum(rate(node_cpu_seconds_total{mode!='idle'}[5m]))"
+              }
+            }
+          ]
+        }
+      }
+    },
+    "layouts": [
+      {
+        "kind": "Grid",
+        "spec": {
+          "items": [
+            {
+              "x": 0,
+              "y": 0,
+              "width": 12,
+              "height": 6,
+              "content": {
+                "$ref": "#/spec/panels/CPU"
+              }
+            },
+            {
+              "x": 12,
+              "y": 0,
+              "width": 12,
+              "height": 6,
+              "content": {
+                "$ref": "#/spec/panels/MixedCPU"
+              }
+            }
+          ]
+        }
+      }
+    ]
+  }
+}`
+	entity := &v1.Dashboard{}
+	if err := json.Unmarshal([]byte(data), entity); err != nil {
+		t.Fatal(err)
+	}
+	entity.Metadata.CreateNow()
+	return entity
+}
+
+// NewGlobalDatasource returns the global datasource "GlobalPrometheus" used by the dashboard returned by NewDashboard.
+func NewGlobalDatasource(t *testing.T) *v1.GlobalDatasource {
+	promURL, err := url.Parse("https://prometheus.demo.do.prometheus.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := &v1.GlobalDatasource{
+		Kind: v1.KindGlobalDatasource,
+		Metadata: v1.Metadata{
+			Name: "GlobalPrometheus",
+		},
+		Spec: &datasource.Prometheus{
+			BasicDatasource: datasource.BasicDatasource{
+				Kind:    datasource.PrometheusKind,
+				Default: false,
+			},
+			HTTP: datasource.HTTPConfig{
+				URL:    promURL,
+				Access: datasource.ServerHTTPAccess,
+			},
+		},
+	}
+	entity.Metadata.CreateNow()
+	return entity
+}
+
+// libraryPanelSpec is the spec of the library panels returned by NewPanel and NewGlobalPanel.
+const libraryPanelSpec = `{
+  "kind": "LineChart",
+  "display": {
+    "name": "Error budget burn rate"
+  },
+  "datasource": {
+    "kind": "PrometheusDatasource"
+  },
+  "options": {
+    "queries": [
+      {
+        "kind": "PrometheusGraphQuery",
+        "options": {
+          "query": "sum(rate(http_requests_total{code=~'5..'}[1h])) / sum(rate(http_requests_total[1h]))"
+        }
+      }
+    ]
+  }
+}`
+
+// NewPanel returns the library panel "BurnRate" of the project of the dashboard returned by NewDashboard.
+func NewPanel() *v1.Panel {
+	entity := &v1.Panel{
+		Kind: v1.KindPanel,
+		Metadata: v1.ProjectMetadata{
+			Metadata: v1.Metadata{
+				Name: "BurnRate",
+			},
+			Project: "perses",
+		},
+		Spec: json.RawMessage(libraryPanelSpec),
+	}
+	entity.Metadata.CreateNow()
+	return entity
+}
+
+// NewGlobalPanel returns the global library panel "GlobalBurnRate".
+func NewGlobalPanel() *v1.GlobalPanel {
+	entity := &v1.GlobalPanel{
+		Kind: v1.KindGlobalPanel,
+		Metadata: v1.Metadata{
+			Name: "GlobalBurnRate",
+		},
+		Spec: json.RawMessage(libraryPanelSpec),
+	}
+	entity.Metadata.CreateNow()
+	return entity
+}
+
 func NewUser() *v1.User {
 	entity := &v1.User{
 		Kind: v1.KindUser,
@@ -187,8 +420,26 @@ func CreateServer(t *testing.T) (*httptest.Server, dependency.PersistenceManager
 	if err != nil {
 		t.Fatal(err)
 	}
-	serviceManager := dependency.NewServiceManager(persistenceManager, config.Config{})
+	serviceManager := dependency.NewServiceManager(persistenceManager, config.Config{
+		Schemas: config.Schemas{
+			// the path is relative to the package running the e2e tests
+			PanelsPath:      "../../../schemas/panels",
+			QueriesPath:     "../../../schemas/queries",
+			VariablesPath:   "../../../schemas/variables",
+			AnnotationsPath: "../../../schemas/annotations",
+			LayoutsPath:     "../../../schemas/layouts",
+			DatasourcesPath: "../../../schemas/datasources",
+		},
+	})
+	validator := serviceManager.GetDashboard().GetValidator()
+	validator.LoadPanels()
+	validator.LoadQueries()
+	validator.LoadVariables()
+	validator.LoadAnnotations()
+	validator.LoadLayouts()
+	validator.LoadDatasources()
 	persesAPI := core.NewPersesAPI(serviceManager)
 	persesAPI.RegisterRoute(handler)
+	handler.Use(middleware.Proxy(persistenceManager.GetDatasource(), persistenceManager.GetGlobalDatasource()))
 	return httptest.NewServer(handler), persistenceManager
 }